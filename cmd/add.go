@@ -30,7 +30,9 @@ func runAdd(cmd *cobra.Command, args []string) error {
 	path, _ := cmd.Flags().GetString("path")
 
 	if domain == "" {
-		domain = name + "." + cfg.Settings.TLD
+		if domain, err = defaultDomain(name, cfg.Settings); err != nil {
+			return err
+		}
 	}
 
 	_, existed := cfg.Projects[name]
@@ -63,7 +65,7 @@ func runAdd(cmd *cobra.Command, args []string) error {
 func init() {
 	cwd, _ := os.Getwd()
 
-	addCmd.Flags().String("domain", "", "domain for the project (default: <name>.<tld>)")
+	addCmd.Flags().String("domain", "", "domain for the project (default: <name>.<first tld>)")
 	addCmd.Flags().String("proxy", "http://localhost:3000", "upstream proxy target")
 	addCmd.Flags().String("path", cwd, "project directory path")
 
@@ -0,0 +1,152 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/paulrose/hatch/internal/api"
+	"github.com/paulrose/hatch/internal/chaos"
+	"github.com/paulrose/hatch/internal/config"
+	"github.com/paulrose/hatch/internal/daemon"
+)
+
+var chaosCmd = &cobra.Command{
+	Use:   "chaos [<project>/<service>]",
+	Short: "Inject latency, errors and bandwidth limits into a service",
+	Long: `Degrades a service for a while, to test loading states and retry logic: requests wait --latency plus up to --jitter more, a --error-rate fraction of them fail with --error-status, and responses are throttled to --bandwidth kilobits per second. The faults replace the service's chaos settings in .hatch.yml until --for has passed (default 10m; 0 keeps them until 'hatch chaos --off').
+
+Without arguments, lists the services with faults injected.`,
+	Args:              cobra.MaximumNArgs(1),
+	ValidArgsFunction: completeServiceTargets,
+	RunE:              runChaos,
+}
+
+func runChaos(cmd *cobra.Command, args []string) error {
+	if running, _, _ := daemon.IsRunning(); !running {
+		return fmt.Errorf("daemon is not running — run 'hatch up' first")
+	}
+	client := api.NewClient()
+	ctx := context.Background()
+
+	if len(args) == 0 {
+		overrides, err := client.Chaos(ctx)
+		if err != nil {
+			return err
+		}
+		if len(overrides) == 0 {
+			fmt.Println("No chaos injected.")
+			return nil
+		}
+		for _, o := range overrides {
+			fmt.Printf("%s/%s  %s\n", o.Project, o.Service, describeChaos(o))
+		}
+		return nil
+	}
+
+	project, service := parseProcessTarget(args[0])
+	if project == "" || service == "" {
+		return fmt.Errorf("expected <project>/<service>, got %q", args[0])
+	}
+	green := color.New(color.FgGreen).SprintFunc()
+
+	if off, _ := cmd.Flags().GetBool("off"); off {
+		if err := client.ClearChaos(ctx, project, service); err != nil {
+			return err
+		}
+		fmt.Printf("%s Chaos cleared for %s/%s\n", green("✓"), project, service)
+		return nil
+	}
+
+	var c config.Chaos
+	if d, _ := cmd.Flags().GetDuration("latency"); d > 0 {
+		c.Latency = d.String()
+	}
+	if d, _ := cmd.Flags().GetDuration("jitter"); d > 0 {
+		c.Jitter = d.String()
+	}
+	c.ErrorRate, _ = cmd.Flags().GetFloat64("error-rate")
+	c.ErrorStatus, _ = cmd.Flags().GetInt("error-status")
+	c.BandwidthKbps, _ = cmd.Flags().GetInt("bandwidth")
+	if c == (config.Chaos{}) {
+		return fmt.Errorf("give at least one of --latency, --jitter, --error-rate or --bandwidth, or --off to clear")
+	}
+	dur, _ := cmd.Flags().GetDuration("for")
+
+	o, err := client.SetChaos(ctx, project, service, c, dur)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%s Chaos on for %s/%s  %s\n", green("✓"), o.Project, o.Service, describeChaos(o))
+	return nil
+}
+
+// describeChaos summarizes an override's faults and remaining time.
+func describeChaos(o chaos.Override) string {
+	var parts []string
+	if o.Chaos.Latency != "" {
+		latency := o.Chaos.Latency
+		if o.Chaos.Jitter != "" {
+			latency += " ± " + o.Chaos.Jitter
+		}
+		parts = append(parts, "latency "+latency)
+	} else if o.Chaos.Jitter != "" {
+		parts = append(parts, "jitter "+o.Chaos.Jitter)
+	}
+	if o.Chaos.ErrorRate > 0 {
+		status := o.Chaos.ErrorStatus
+		if status == 0 {
+			status = chaos.DefaultErrorStatus
+		}
+		parts = append(parts, fmt.Sprintf("%g%% %d errors", 100*o.Chaos.ErrorRate, status))
+	}
+	if o.Chaos.BandwidthKbps > 0 {
+		parts = append(parts, fmt.Sprintf("%d kbps", o.Chaos.BandwidthKbps))
+	}
+
+	faint := color.New(color.Faint).SprintFunc()
+	until := "until cleared"
+	if !o.Expires.IsZero() {
+		until = "for " + time.Until(o.Expires).Round(time.Second).String()
+	}
+	return strings.Join(parts, ", ") + "  " + faint(until)
+}
+
+// completeServiceTargets suggests project/service pairs for HTTP services.
+func completeServiceTargets(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	cfg, err := config.LoadRaw()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	var targets []string
+	for name, proj := range cfg.Projects {
+		for svcName, svc := range proj.Services {
+			if !svc.IsTCP() {
+				targets = append(targets, name+"/"+svcName)
+			}
+		}
+	}
+	sort.Strings(targets)
+	return targets, cobra.ShellCompDirectiveNoFileComp
+}
+
+func init() {
+	chaosCmd.Flags().Duration("latency", 0, "delay added before each request")
+	chaosCmd.Flags().Duration("jitter", 0, "up to this much more delay, chosen at random")
+	chaosCmd.Flags().Float64("error-rate", 0, "fraction of requests to fail, 0-1")
+	chaosCmd.Flags().Int("error-status", 0, "status code of failed requests (default 503)")
+	chaosCmd.Flags().Int("bandwidth", 0, "response throughput limit in kilobits per second")
+	chaosCmd.Flags().Duration("for", 10*time.Minute, "how long before the faults are cleared; 0 for until --off")
+	chaosCmd.Flags().Bool("off", false, "clear the faults injected into the service")
+
+	rootCmd.AddCommand(chaosCmd)
+}
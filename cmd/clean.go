@@ -33,11 +33,11 @@ func runClean(cmd *cobra.Command, args []string) error {
 
 	fmt.Printf("Cleaning up %s...\n\n", cyan("Hatch"))
 
-	// Load config before deleting it — we need the TLD for DNS cleanup.
-	// Fall back to default TLD if config can't be loaded.
-	tld := "test"
+	// Load config before deleting it — we need the DNS zones for resolver
+	// cleanup. Fall back to the default zones if config can't be loaded.
+	zones := config.DefaultConfig().Settings.DNSZones()
 	if cfg, err := config.Load(); err == nil {
-		tld = cfg.Settings.TLD
+		zones = cfg.Settings.DNSZones()
 	}
 
 	// Resolve CA cert path before deleting the config directory.
@@ -46,38 +46,43 @@ func runClean(cmd *cobra.Command, args []string) error {
 	anyCleaned := false
 
 	// Step 1: Stop daemon
-	if daemon.IsLoaded() {
-		if err := daemon.UnloadPlist(); err != nil {
+	svc := daemon.NewServiceManager()
+	if svc.IsLoaded() {
+		if err := svc.Stop(); err != nil {
 			fmt.Printf("  %s Failed to stop daemon: %v\n", red("✗"), err)
 			os.Exit(1)
 		}
-		if err := daemon.UninstallPlist(); err != nil {
-			fmt.Printf("  %s Failed to remove daemon plist: %v\n", red("✗"), err)
+		if err := svc.Uninstall(); err != nil {
+			fmt.Printf("  %s Failed to remove %s: %v\n", red("✗"), svc.Name(), err)
 			os.Exit(1)
 		}
 		fmt.Printf("  %s Daemon stopped\n", green("✓"))
 		anyCleaned = true
 	} else {
-		// Still remove the plist file if it exists even when not loaded.
-		_ = daemon.UninstallPlist()
+		// Still remove the service definition if it exists even when not loaded.
+		_ = svc.Uninstall()
 		fmt.Printf("  %s Daemon not running\n", green("✓"))
 	}
 
-	// Step 2: Remove DNS resolver
-	if dns.IsResolverInstalled(tld) {
-		if err := dns.RemoveResolverFile(&sudoRunner{}, tld); err != nil {
-			fmt.Printf("  %s Failed to remove DNS resolver: %v\n", red("✗"), err)
-			os.Exit(1)
+	// Step 2: Remove DNS resolvers
+	resolver := dns.NewResolver()
+	for _, zone := range zones {
+		if resolver.IsInstalled(zone) {
+			if err := resolver.Remove(&sudoRunner{}, zone); err != nil {
+				fmt.Printf("  %s Failed to remove DNS resolver for %s: %v\n", red("✗"), zone, err)
+				os.Exit(1)
+			}
+			fmt.Printf("  %s DNS resolver removed for %s\n", green("✓"), zone)
+			anyCleaned = true
+		} else {
+			fmt.Printf("  %s DNS resolver not installed for %s\n", green("✓"), zone)
 		}
-		fmt.Printf("  %s DNS resolver removed\n", green("✓"))
-		anyCleaned = true
-	} else {
-		fmt.Printf("  %s DNS resolver not installed\n", green("✓"))
 	}
 
-	// Step 3: Untrust CA from Keychain
-	if certs.CAExists(caPaths) && certs.IsCATrusted(caPaths.Cert) {
-		if err := certs.UntrustCA(&sudoRunner{}, caPaths.Cert); err != nil {
+	// Step 3: Untrust CA from the system trust store
+	store := certs.NewTrustStore()
+	if certs.CAExists(caPaths) && store.IsTrusted(caPaths.Cert) {
+		if err := store.Untrust(&sudoRunner{}, caPaths.Cert); err != nil {
 			fmt.Printf("  %s Failed to untrust root CA: %v\n", red("✗"), err)
 			os.Exit(1)
 		}
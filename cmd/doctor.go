@@ -6,6 +6,7 @@ import (
 	"net"
 	"os"
 	"sort"
+	"strings"
 	"time"
 
 	"github.com/fatih/color"
@@ -15,6 +16,7 @@ import (
 	"github.com/paulrose/hatch/internal/config"
 	"github.com/paulrose/hatch/internal/daemon"
 	"github.com/paulrose/hatch/internal/dns"
+	"github.com/paulrose/hatch/internal/tcpproxy"
 )
 
 var doctorCmd = &cobra.Command{
@@ -73,12 +75,19 @@ func runDoctor(cmd *cobra.Command, args []string) error {
 	}
 	pass("Config file is valid")
 
-	// Check 2: DNS resolver installed
-	tld := cfg.Settings.TLD
-	if dns.IsResolverInstalled(tld) {
-		pass(fmt.Sprintf("DNS resolver installed (.%s)", tld))
+	// Check 2: DNS resolver installed for every TLD and override host
+	zones := cfg.Settings.DNSZones()
+	resolver := dns.NewResolver()
+	var missing []string
+	for _, zone := range zones {
+		if !resolver.IsInstalled(zone) {
+			missing = append(missing, zone)
+		}
+	}
+	if len(missing) == 0 {
+		pass(fmt.Sprintf("DNS resolver installed (%s)", strings.Join(zones, ", ")))
 	} else {
-		fail(fmt.Sprintf("DNS resolver not installed (.%s)", tld), "Run 'hatch init' to install the DNS resolver")
+		fail(fmt.Sprintf("DNS resolver not installed (%s)", strings.Join(missing, ", ")), "Run 'hatch init' to install the DNS resolver")
 	}
 
 	// Check 3: Root CA exists
@@ -89,9 +98,10 @@ func runDoctor(cmd *cobra.Command, args []string) error {
 		fail("Root CA not found", "Run 'hatch init' to generate a root CA")
 	}
 
-	// Check 4: Root CA trusted in Keychain
-	if certs.IsCATrusted(caPaths.Cert) {
-		pass("Root CA trusted in Keychain")
+	// Check 4: Root CA trusted in the system trust store
+	store := certs.NewTrustStore()
+	if store.IsTrusted(caPaths.Cert) {
+		pass("Root CA trusted in " + store.Name())
 	} else {
 		fail("Root CA is not trusted", "Run 'hatch trust' to re-trust the root CA")
 	}
@@ -103,21 +113,22 @@ func runDoctor(cmd *cobra.Command, args []string) error {
 		fail("Intermediate CA not found", "Run 'hatch up' to generate an intermediate CA")
 	}
 
-	// Check 6: Launchd plist installed
-	plistPath, err := daemon.PlistPath()
+	// Check 6: Service definition installed
+	svc := daemon.NewServiceManager()
+	svcPath, err := svc.Path()
 	if err != nil {
-		fail(fmt.Sprintf("Could not determine plist path: %v", err), "")
-	} else if _, err := os.Stat(plistPath); err == nil {
-		pass("Launchd plist installed")
+		fail(fmt.Sprintf("Could not determine %s path: %v", svc.Name(), err), "")
+	} else if _, err := os.Stat(svcPath); err == nil {
+		pass(fmt.Sprintf("%s installed", svc.Name()))
 	} else {
-		fail("Launchd plist not installed", "Run 'hatch up' to install and start the daemon")
+		fail(fmt.Sprintf("%s not installed", svc.Name()), "Run 'hatch up' to install and start the daemon")
 	}
 
-	// Check 6: Launchd plist loaded
-	if daemon.IsLoaded() {
-		pass("Launchd plist loaded")
+	// Check 6: Service loaded
+	if svc.IsLoaded() {
+		pass(fmt.Sprintf("%s loaded", svc.Name()))
 	} else {
-		fail("Launchd plist not loaded", "Run 'hatch up' to start the daemon")
+		fail(fmt.Sprintf("%s not loaded", svc.Name()), "Run 'hatch up' to start the daemon")
 	}
 
 	// Check 7: Ports available
@@ -153,6 +164,20 @@ func runDoctor(cmd *cobra.Command, args []string) error {
 		}
 	}
 
+	// Check 7b: Listen ports of tcp services
+	for _, port := range tcpproxy.Ports(cfg) {
+		switch {
+		case running && checkDaemonListening(port):
+			pass(fmt.Sprintf("TCP port :%d is reachable", port))
+		case running:
+			fail(fmt.Sprintf("TCP port :%d is not reachable", port), "The daemon is running but not listening on this port")
+		case checkPortAvailable(port):
+			pass(fmt.Sprintf("TCP port :%d is available", port))
+		default:
+			fail(fmt.Sprintf("TCP port :%d is in use", port), portConflictHint(port))
+		}
+	}
+
 	// Check 8: No stale projects
 	staleProjects := findStaleProjects(cfg.Projects)
 	if len(staleProjects) == 0 {
@@ -19,23 +19,25 @@ var downCmd = &cobra.Command{
 }
 
 func runDown() error {
-	// Check if the launchd job is loaded (idempotent).
-	if !daemon.IsLoaded() {
+	svc := daemon.NewServiceManager()
+
+	// Check if the service is loaded (idempotent).
+	if !svc.IsLoaded() {
 		fmt.Printf("%s is not running\n", color.CyanString("Hatch"))
 		return nil
 	}
 
-	// Unload sends SIGTERM → graceful shutdown.
-	if err := daemon.UnloadPlist(); err != nil {
-		return fmt.Errorf("unload plist: %w", err)
+	// Stopping sends SIGTERM → graceful shutdown.
+	if err := svc.Stop(); err != nil {
+		return fmt.Errorf("stop service: %w", err)
 	}
-	log.Debug().Msg("plist unloaded")
+	log.Debug().Msgf("%s stopped", svc.Name())
 
-	// Remove plist to prevent KeepAlive restart.
-	if err := daemon.UninstallPlist(); err != nil {
-		return fmt.Errorf("uninstall plist: %w", err)
+	// Remove the service definition to prevent a KeepAlive restart.
+	if err := svc.Uninstall(); err != nil {
+		return fmt.Errorf("uninstall service: %w", err)
 	}
-	log.Debug().Msg("plist removed")
+	log.Debug().Msgf("%s removed", svc.Name())
 
 	fmt.Printf("%s stopped\n", color.New(color.FgCyan, color.Bold).Sprint("Hatch"))
 	return nil
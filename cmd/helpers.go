@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"fmt"
 	"sort"
 
 	"github.com/spf13/cobra"
@@ -24,3 +25,13 @@ func completeProjectNames(cmd *cobra.Command, args []string, toComplete string)
 
 	return names, cobra.ShellCompDirectiveNoFileComp
 }
+
+// defaultDomain returns name under the first configured TLD, for projects
+// added without --domain.
+func defaultDomain(name string, s config.Settings) (string, error) {
+	tlds := s.AllTLDs()
+	if len(tlds) == 0 {
+		return "", fmt.Errorf("no tld configured: set settings.tld in the config or pass --domain")
+	}
+	return name + "." + tlds[0], nil
+}
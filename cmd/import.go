@@ -0,0 +1,165 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/paulrose/hatch/internal/compose"
+	"github.com/paulrose/hatch/internal/config"
+)
+
+var importCmd = &cobra.Command{
+	Use:   "import",
+	Short: "Create a project from another tool's configuration",
+}
+
+var importComposeCmd = &cobra.Command{
+	Use:   "compose [file]",
+	Short: "Propose a project from a docker-compose.yml",
+	Long: `Reads a Docker Compose file (default: compose.yaml or docker-compose.yml in the
+current directory) and proposes a project with one service per HTTP port a
+Compose service publishes, at a subdomain named after the Compose service.
+
+The proposal is written to .hatch.yml next to the Compose file, for
+'hatch link'; with --link the project is merged into the config instead.`,
+	Args: cobra.MaximumNArgs(1),
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return []string{"yml", "yaml"}, cobra.ShellCompDirectiveFilterFileExt
+	},
+	RunE: runImportCompose,
+}
+
+func runImportCompose(cmd *cobra.Command, args []string) error {
+	var path string
+	if len(args) > 0 {
+		path = args[0]
+	} else {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return fmt.Errorf("get working directory: %w", err)
+		}
+		if path, err = compose.Find(cwd); err != nil {
+			return err
+		}
+	}
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return fmt.Errorf("resolve %s: %w", path, err)
+	}
+	dir := filepath.Dir(path)
+
+	f, err := compose.Load(path)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := config.LoadRaw()
+	if err != nil {
+		return fmt.Errorf("load config: %w", err)
+	}
+
+	name, _ := cmd.Flags().GetString("name")
+	if name == "" {
+		name = f.Name
+	}
+	if name == "" {
+		name = filepath.Base(dir)
+	}
+	domain, _ := cmd.Flags().GetString("domain")
+	if domain == "" {
+		if domain, err = defaultDomain(name, cfg.Settings); err != nil {
+			return err
+		}
+	}
+
+	pc, skipped := compose.ProjectConfig(f, domain)
+
+	green := color.New(color.FgGreen).SprintFunc()
+	yellow := color.New(color.FgYellow).SprintFunc()
+	faint := color.New(color.Faint).SprintFunc()
+
+	fmt.Printf("Project '%s' (%s) from %s\n", name, domain, path)
+	svcNames := make([]string, 0, len(pc.Services))
+	for svcName := range pc.Services {
+		svcNames = append(svcNames, svcName)
+	}
+	sort.Strings(svcNames)
+	nameW := 0
+	for _, svcName := range svcNames {
+		nameW = max(nameW, len(svcName))
+	}
+	for _, svcName := range svcNames {
+		svc := pc.Services[svcName]
+		fmt.Printf("  %s %-*s  %s → %s\n", green("+"), nameW, svcName, svc.Host(config.Project{Domain: domain}), svc.Proxy)
+	}
+	for _, err := range skipped {
+		fmt.Printf("  %s %s\n", yellow("-"), faint(err.Error()))
+	}
+	if len(pc.Services) == 0 {
+		return fmt.Errorf("no Compose service publishes an HTTP port")
+	}
+
+	link, _ := cmd.Flags().GetBool("link")
+	force, _ := cmd.Flags().GetBool("force")
+	hatchFile := filepath.Join(dir, ".hatch.yml")
+
+	if !force {
+		question := fmt.Sprintf("Write %s?", hatchFile)
+		if link {
+			question = fmt.Sprintf("Link project '%s'?", name)
+		} else if _, err := os.Stat(hatchFile); err == nil {
+			question = fmt.Sprintf("Overwrite %s?", hatchFile)
+		}
+		fmt.Printf("\n%s [y/N] ", question)
+		reader := bufio.NewReader(os.Stdin)
+		answer, _ := reader.ReadString('\n')
+		answer = strings.TrimSpace(strings.ToLower(answer))
+		if answer != "y" && answer != "yes" {
+			fmt.Println("Cancelled.")
+			return nil
+		}
+	}
+
+	if !link {
+		if err := config.SaveProjectConfig(hatchFile, pc); err != nil {
+			return err
+		}
+		hint := "hatch link"
+		if name != filepath.Base(dir) {
+			hint += " --name " + name
+		}
+		fmt.Printf("%s Wrote %s — run '%s' in %s to add the project\n", green("✓"), hatchFile, hint, dir)
+		return nil
+	}
+
+	_, existed := cfg.Projects[name]
+	if err := config.MergeProjectConfig(&cfg, name, dir, pc); err != nil {
+		return fmt.Errorf("link project: %w", err)
+	}
+	if err := config.Save(cfg); err != nil {
+		return fmt.Errorf("save config: %w", err)
+	}
+	if existed {
+		fmt.Printf("%s Project '%s' updated (%s)\n", green("✓"), name, domain)
+	} else {
+		fmt.Printf("%s Project '%s' linked (%s)\n", green("✓"), name, domain)
+	}
+	return nil
+}
+
+func init() {
+	importComposeCmd.Flags().String("name", "", "project name (default: the Compose project name or directory basename)")
+	importComposeCmd.Flags().String("domain", "", "domain for the project (default: <name>.<first tld>)")
+	importComposeCmd.Flags().Bool("link", false, "merge the project into the config instead of writing .hatch.yml")
+	importComposeCmd.Flags().BoolP("force", "f", false, "skip confirmation prompt")
+
+	importCmd.AddCommand(importComposeCmd)
+	rootCmd.AddCommand(importCmd)
+}
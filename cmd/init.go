@@ -16,7 +16,7 @@ import (
 var initCmd = &cobra.Command{
 	Use:   "init",
 	Short: "Initialize Hatch for first-time use",
-	Long:  `Sets up the Hatch config directory, generates a root CA, trusts it in Keychain, and installs the DNS resolver. Does not start the daemon.`,
+	Long:  `Sets up the Hatch config directory, generates a root CA, trusts it in the system trust store, and installs the DNS resolver. Does not start the daemon.`,
 	RunE:  runInit,
 }
 
@@ -99,27 +99,30 @@ func runInit(cmd *cobra.Command, args []string) error {
 	}
 
 	// Step 4: Trust CA
-	if certs.IsCATrusted(caPaths.Cert) {
+	store := certs.NewTrustStore()
+	if store.IsTrusted(caPaths.Cert) {
 		fmt.Printf("  %s Root CA already trusted\n", green("✓"))
 	} else {
-		if err := certs.TrustCA(&sudoRunner{}, caPaths.Cert); err != nil {
+		if err := store.Trust(&sudoRunner{}, caPaths.Cert); err != nil {
 			fmt.Printf("  %s Failed to trust root CA: %v\n", red("✗"), err)
 			os.Exit(1)
 		}
-		fmt.Printf("  %s Root CA trusted in Keychain\n", green("✓"))
+		fmt.Printf("  %s Root CA trusted in %s\n", green("✓"), store.Name())
 		anyCreated = true
 	}
 
-	// Step 5: DNS resolver
-	tld := cfg.Settings.TLD
-	if dns.IsResolverInstalled(tld) {
-		fmt.Printf("  %s DNS resolver already installed\n", green("✓"))
-	} else {
-		if err := dns.InstallResolverFile(&sudoRunner{}, tld, dns.DefaultListenIP, dns.DefaultPort); err != nil {
-			fmt.Printf("  %s Failed to install DNS resolver: %v\n", red("✗"), err)
+	// Step 5: DNS resolvers, one per TLD and override host
+	resolver := dns.NewResolver()
+	for _, zone := range cfg.Settings.DNSZones() {
+		if resolver.IsInstalled(zone) {
+			fmt.Printf("  %s DNS resolver already installed for %s\n", green("✓"), zone)
+			continue
+		}
+		if err := resolver.Install(&sudoRunner{}, zone, dns.DefaultListenIP, dns.DefaultPort); err != nil {
+			fmt.Printf("  %s Failed to install DNS resolver for %s: %v\n", red("✗"), zone, err)
 			os.Exit(1)
 		}
-		fmt.Printf("  %s DNS resolver installed for .%s\n", green("✓"), tld)
+		fmt.Printf("  %s DNS resolver installed for %s\n", green("✓"), zone)
 		anyCreated = true
 	}
 
@@ -3,6 +3,7 @@ package cmd
 import (
 	"fmt"
 	"sort"
+	"strings"
 
 	"github.com/fatih/color"
 	"github.com/spf13/cobra"
@@ -47,7 +48,7 @@ func runList() error {
 			status = red("✗") + " disabled"
 		}
 
-		fmt.Printf("%s (%s) %s\n", name, proj.Domain, status)
+		fmt.Printf("%s (%s) %s\n", name, strings.Join(proj.Hosts(), ", "), status)
 
 		svcNames := make([]string, 0, len(proj.Services))
 		for svcName := range proj.Services {
@@ -57,7 +58,19 @@ func runList() error {
 
 		for _, svcName := range svcNames {
 			svc := proj.Services[svcName]
-			fmt.Printf("  %s → %s\n", svcName, svc.Proxy)
+			if svc.IsTCP() {
+				fmt.Printf("  %s (tcp :%d) → %s\n", svcName, svc.Listen, svc.Proxy)
+				continue
+			}
+			if svc.IsStatic() {
+				fmt.Printf("  %s → %s (static)\n", svcName, svc.RootDir(proj))
+				continue
+			}
+			if svc.IsMock() {
+				fmt.Printf("  %s → mock (%d rules)\n", svcName, len(svc.Mock))
+				continue
+			}
+			fmt.Printf("  %s → %s\n", svcName, strings.Join(svc.ProxyURLs(), ", "))
 		}
 	}
 
@@ -11,13 +11,26 @@ import (
 )
 
 var logsCmd = &cobra.Command{
-	Use:   "logs",
-	Short: "View the Hatch daemon logs",
-	RunE:  runLogs,
+	Use:               "logs [<project>/<service>]",
+	Short:             "View the Hatch daemon logs",
+	Long:              `Shows the daemon's log or, given a project and service, the output of the service's command (see 'hatch start').`,
+	Args:              cobra.MaximumNArgs(1),
+	ValidArgsFunction: completeProcessTargets,
+	RunE:              runLogs,
 }
 
 func runLogs(cmd *cobra.Command, args []string) error {
 	path := config.LogFile()
+	if len(args) == 1 {
+		project, service := parseProcessTarget(args[0])
+		if service == "" {
+			return fmt.Errorf("name a service: hatch logs %s/<service>", project)
+		}
+		path = config.ServiceLogFile(project, service)
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			return fmt.Errorf("no output logged for %s/%s — start it with 'hatch start %s/%s'", project, service, project, service)
+		}
+	}
 
 	if _, err := os.Stat(path); os.IsNotExist(err) {
 		return fmt.Errorf("log file not found at %s — is the daemon running?", path)
@@ -0,0 +1,140 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/term"
+
+	"github.com/paulrose/hatch/internal/config"
+)
+
+var passwdCmd = &cobra.Command{
+	Use:   "passwd <project> <user>",
+	Short: "Set a basic auth password on a project",
+	Long: `Prompts for a password and stores its bcrypt hash as one of the project's basic auth users, so that its sites ask for a user name and password. Without a terminal, the password is read from the first line of standard input.
+
+Pair it with auth.allow_ips in the config to also limit which machines can connect.`,
+	Args: cobra.ExactArgs(2),
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) > 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		return completeProjectNames(cmd, args, toComplete)
+	},
+	RunE: runPasswd,
+}
+
+func runPasswd(cmd *cobra.Command, args []string) error {
+	name, user := args[0], args[1]
+	if strings.ContainsAny(user, ": \t") {
+		return fmt.Errorf("user name %q must not contain colons or spaces", user)
+	}
+
+	cfg, err := config.LoadRaw()
+	if err != nil {
+		return fmt.Errorf("load config: %w", err)
+	}
+	proj, exists := cfg.Projects[name]
+	if !exists {
+		return fmt.Errorf("project %q not found", name)
+	}
+	green := color.New(color.FgGreen).SprintFunc()
+
+	if del, _ := cmd.Flags().GetBool("delete"); del {
+		if proj.Auth == nil || proj.Auth.Basic == nil || proj.Auth.Basic.Users[user] == "" {
+			return fmt.Errorf("project %q has no user %q", name, user)
+		}
+		delete(proj.Auth.Basic.Users, user)
+		if len(proj.Auth.Basic.Users) == 0 {
+			proj.Auth.Basic = nil
+		}
+		if len(proj.Auth.AllowIPs) == 0 && proj.Auth.Basic == nil {
+			proj.Auth = nil
+		}
+		cfg.Projects[name] = proj
+		if err := config.Save(cfg); err != nil {
+			return fmt.Errorf("save config: %w", err)
+		}
+		fmt.Printf("%s User '%s' removed from project '%s'\n", green("✓"), user, name)
+		return nil
+	}
+
+	for svcName, svc := range proj.Services {
+		if svc.IsTCP() {
+			return fmt.Errorf("project %q has tcp service %q, which basic auth cannot protect", name, svcName)
+		}
+	}
+
+	password, err := readPassword()
+	if err != nil {
+		return err
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return fmt.Errorf("hash password: %w", err)
+	}
+
+	if proj.Auth == nil {
+		proj.Auth = &config.Auth{}
+	}
+	if proj.Auth.Basic == nil {
+		proj.Auth.Basic = &config.BasicAuth{Users: map[string]string{}}
+	}
+	if proj.Auth.Basic.Users == nil {
+		proj.Auth.Basic.Users = map[string]string{}
+	}
+	proj.Auth.Basic.Users[user] = string(hash)
+	cfg.Projects[name] = proj
+
+	if err := config.Save(cfg); err != nil {
+		return fmt.Errorf("save config: %w", err)
+	}
+	fmt.Printf("%s Password set for user '%s' on project '%s'\n", green("✓"), user, name)
+	return nil
+}
+
+// readPassword prompts for a password twice on a terminal, or reads one
+// line from standard input otherwise.
+func readPassword() (string, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		line, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		password := strings.TrimRight(line, "\r\n")
+		if password == "" {
+			return "", fmt.Errorf("no password on standard input")
+		}
+		return password, nil
+	}
+
+	fmt.Print("Password: ")
+	password, err := term.ReadPassword(fd)
+	fmt.Println()
+	if err != nil {
+		return "", fmt.Errorf("read password: %w", err)
+	}
+	if len(password) == 0 {
+		return "", fmt.Errorf("password must not be empty")
+	}
+	fmt.Print("Repeat password: ")
+	again, err := term.ReadPassword(fd)
+	fmt.Println()
+	if err != nil {
+		return "", fmt.Errorf("read password: %w", err)
+	}
+	if string(again) != string(password) {
+		return "", fmt.Errorf("passwords do not match")
+	}
+	return string(password), nil
+}
+
+func init() {
+	passwdCmd.Flags().Bool("delete", false, "remove the user instead of setting a password")
+
+	rootCmd.AddCommand(passwdCmd)
+}
@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+
+	"github.com/paulrose/hatch/internal/tunnel"
+)
+
+var relayCmd = &cobra.Command{
+	Use:   "relay",
+	Short: "Run a tunnel relay for publicly sharing projects",
+	Long: `Runs the public end of 'hatch share <project>' on a server you control. Point a wildcard DNS record (*.<domain>) at the server; each shared project is served at <project>.<domain>, and the hatch daemons connect to the relay at any host on the same port.
+
+Serve TLS directly with --cert and --key (a wildcard certificate for *.<domain>), or run plain HTTP behind a TLS-terminating proxy and keep --scheme https. Set a token with --token or HATCH_RELAY_TOKEN and put the same value in settings.tunnel.token on each machine that shares.`,
+	Args: cobra.NoArgs,
+	RunE: runRelay,
+}
+
+func runRelay(cmd *cobra.Command, args []string) error {
+	listen, _ := cmd.Flags().GetString("listen")
+	domain, _ := cmd.Flags().GetString("domain")
+	token, _ := cmd.Flags().GetString("token")
+	certFile, _ := cmd.Flags().GetString("cert")
+	keyFile, _ := cmd.Flags().GetString("key")
+	scheme, _ := cmd.Flags().GetString("scheme")
+	publicPort, _ := cmd.Flags().GetInt("public-port")
+
+	if domain == "" {
+		return fmt.Errorf("--domain is required")
+	}
+	if token == "" {
+		token = os.Getenv("HATCH_RELAY_TOKEN")
+	}
+	if token == "" {
+		log.Warn().Msg("no token set: anyone can open tunnels on this relay")
+	}
+	if (certFile == "") != (keyFile == "") {
+		return fmt.Errorf("--cert and --key must be used together")
+	}
+	if scheme != "http" && scheme != "https" {
+		return fmt.Errorf("--scheme must be http or https, got %q", scheme)
+	}
+
+	srv := &http.Server{
+		Addr: listen,
+		Handler: tunnel.NewRelay(tunnel.RelayConfig{
+			Domain: domain,
+			Token:  token,
+			Scheme: scheme,
+			Port:   publicPort,
+		}),
+		ReadHeaderTimeout: 10 * time.Second,
+		IdleTimeout:       120 * time.Second,
+	}
+
+	ctx, stop := signal.NotifyContext(cmd.Context(), syscall.SIGTERM, syscall.SIGINT)
+	defer stop()
+	errc := make(chan error, 1)
+	go func() {
+		if certFile != "" {
+			errc <- srv.ListenAndServeTLS(certFile, keyFile)
+		} else {
+			errc <- srv.ListenAndServe()
+		}
+	}()
+	log.Info().Str("listen", listen).Str("domain", domain).Msg("relay started")
+
+	select {
+	case err := <-errc:
+		return fmt.Errorf("relay: %w", err)
+	case <-ctx.Done():
+	}
+	shutCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(shutCtx); err != nil && !errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("relay shutdown: %w", err)
+	}
+	log.Info().Msg("relay stopped")
+	return nil
+}
+
+func init() {
+	relayCmd.Flags().String("listen", ":8080", "address to listen on")
+	relayCmd.Flags().String("domain", "", "public domain whose subdomains serve tunnels, e.g. tunnel.example.com")
+	relayCmd.Flags().String("token", "", "token clients must present (default $HATCH_RELAY_TOKEN)")
+	relayCmd.Flags().String("cert", "", "TLS certificate file, to serve HTTPS directly")
+	relayCmd.Flags().String("key", "", "TLS key file, to serve HTTPS directly")
+	relayCmd.Flags().String("scheme", "https", "scheme of public URLs")
+	relayCmd.Flags().Int("public-port", 0, "port of public URLs, when not the scheme's default")
+	rootCmd.AddCommand(relayCmd)
+}
@@ -0,0 +1,161 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/paulrose/hatch/internal/api"
+	"github.com/paulrose/hatch/internal/daemon"
+	"github.com/paulrose/hatch/internal/inspect"
+)
+
+var replayCmd = &cobra.Command{
+	Use:   "replay <request-id>",
+	Short: "Re-send a captured request and diff the responses",
+	Long: `Re-sends a request captured by 'hatch requests' through the project's current route and shows how the response differs from the original: status, headers and body.
+
+Edit the request before sending with --method, --header (repeatable; "Name:" with no value removes a header) and --data, which takes the body itself, @file or @- for stdin. A request whose body was truncated at capture can only be replayed with --data.`,
+	Example: `  hatch replay 42
+  hatch replay 42 -H "X-Signature: test" -d @payload.json`,
+	Args: cobra.ExactArgs(1),
+	RunE: runReplay,
+}
+
+func runReplay(cmd *cobra.Command, args []string) error {
+	id, err := strconv.ParseUint(args[0], 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid request ID %q", args[0])
+	}
+	method, _ := cmd.Flags().GetString("method")
+	headers, _ := cmd.Flags().GetStringArray("header")
+	data, _ := cmd.Flags().GetString("data")
+	showFull, _ := cmd.Flags().GetBool("full")
+
+	edit := inspect.Edit{Method: method}
+	for _, h := range headers {
+		name, value, ok := strings.Cut(h, ":")
+		if !ok || strings.TrimSpace(name) == "" {
+			return fmt.Errorf("invalid header %q: expected \"Name: value\"", h)
+		}
+		if edit.Headers == nil {
+			edit.Headers = map[string]string{}
+		}
+		edit.Headers[strings.TrimSpace(name)] = strings.TrimSpace(value)
+	}
+	if cmd.Flags().Changed("data") {
+		body, err := readData(data)
+		if err != nil {
+			return err
+		}
+		edit.Body = &body
+	}
+
+	if running, _, _ := daemon.IsRunning(); !running {
+		return fmt.Errorf("daemon is not running — run 'hatch up' first")
+	}
+	client := api.NewClient()
+	client.HTTPClient.Timeout = 40 * time.Second
+	res, err := client.Replay(context.Background(), id, edit)
+	if err != nil {
+		return err
+	}
+
+	if showFull {
+		printRequest(res.Replay)
+		fmt.Println()
+	}
+	printReplaySummary(res)
+	printDiff(res.Diff)
+	return nil
+}
+
+// readData returns a --data value: the body itself, @file or @- for stdin.
+func readData(data string) (string, error) {
+	path, ok := strings.CutPrefix(data, "@")
+	if !ok {
+		return data, nil
+	}
+	var (
+		b   []byte
+		err error
+	)
+	if path == "-" {
+		b, err = io.ReadAll(os.Stdin)
+	} else {
+		b, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return "", fmt.Errorf("read body: %w", err)
+	}
+	return string(b), nil
+}
+
+func printReplaySummary(res inspect.ReplayResult) {
+	faint := color.New(color.Faint).SprintFunc()
+	orig, replay := res.Original, res.Replay
+	fmt.Printf("%s %s\n", color.New(color.Bold).Sprintf("%s %s", replay.Method, replay.URL), faint(fmt.Sprintf("(replay of #%d)", orig.ID)))
+	fmt.Printf("  original  %s  %s\n", statusColor(orig.Status)(fmt.Sprintf("%d %s", orig.Status, http.StatusText(orig.Status))), faint(formatDuration(orig.Duration)))
+	fmt.Printf("  replay    %s  %s\n", statusColor(replay.Status)(fmt.Sprintf("%d %s", replay.Status, http.StatusText(replay.Status))), faint(formatDuration(replay.Duration)))
+	if replay.Error != "" {
+		fmt.Printf("  %s %s\n", color.RedString("error:"), replay.Error)
+	}
+	fmt.Println()
+}
+
+// printDiff prints a response diff, showing only changed lines and a line
+// of context either side.
+func printDiff(diff []inspect.DiffLine) {
+	changed := false
+	for _, l := range diff {
+		if l.Op != " " {
+			changed = true
+			break
+		}
+	}
+	if !changed {
+		fmt.Println(color.GreenString("Responses are identical"))
+		return
+	}
+
+	red := color.New(color.FgRed).SprintFunc()
+	green := color.New(color.FgGreen).SprintFunc()
+	faint := color.New(color.Faint).SprintFunc()
+	near := func(i int) bool {
+		return (i > 0 && diff[i-1].Op != " ") || (i+1 < len(diff) && diff[i+1].Op != " ")
+	}
+	skipped := false
+	for i, l := range diff {
+		switch {
+		case l.Op == "-":
+			fmt.Println(red("- " + l.Text))
+		case l.Op == "+":
+			fmt.Println(green("+ " + l.Text))
+		case near(i):
+			fmt.Println(faint("  " + l.Text))
+		default:
+			if !skipped {
+				fmt.Println(faint("  ..."))
+			}
+			skipped = true
+			continue
+		}
+		skipped = false
+	}
+}
+
+func init() {
+	replayCmd.Flags().StringP("method", "X", "", "send with this HTTP method instead")
+	replayCmd.Flags().StringArrayP("header", "H", nil, `set a header, "Name: value"; "Name:" removes it`)
+	replayCmd.Flags().StringP("data", "d", "", "send this body instead: the body itself, @file or @- for stdin")
+	replayCmd.Flags().Bool("full", false, "also print the full replayed request and response")
+	rootCmd.AddCommand(replayCmd)
+}
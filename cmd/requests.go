@@ -0,0 +1,281 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/paulrose/hatch/internal/api"
+	"github.com/paulrose/hatch/internal/config"
+	"github.com/paulrose/hatch/internal/daemon"
+	"github.com/paulrose/hatch/internal/inspect"
+)
+
+var requestsCmd = &cobra.Command{
+	Use:   "requests [id]",
+	Short: "Browse captured HTTP traffic",
+	Long: `Lists the requests the daemon captured for projects with capture enabled, newest first, or shows one request in full when given its ID. Filter with --project, --status (a code such as 404 or a class such as 5xx) and --method, or write the matching requests to an HTTP Archive with --har.
+
+The daemon keeps the most recent requests in memory, with bodies truncated. Turn capture on for a project with 'hatch requests capture <project> on', and re-send a request with 'hatch replay <id>'.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runRequests,
+}
+
+var requestsCaptureCmd = &cobra.Command{
+	Use:               "capture <project> [on|off]",
+	Short:             "Turn traffic capture on or off for a project",
+	Args:              cobra.RangeArgs(1, 2),
+	ValidArgsFunction: completeProjectNames,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		enabled := true
+		if len(args) == 2 {
+			switch args[1] {
+			case "on":
+			case "off":
+				enabled = false
+			default:
+				return fmt.Errorf("unknown argument %q: expected on or off", args[1])
+			}
+		}
+		return setCapture(args[0], enabled)
+	},
+}
+
+var requestsClearCmd = &cobra.Command{
+	Use:   "clear",
+	Short: "Discard all captured requests",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if running, _, _ := daemon.IsRunning(); !running {
+			return fmt.Errorf("daemon is not running")
+		}
+		if err := api.NewClient().ClearRequests(context.Background()); err != nil {
+			return err
+		}
+		green := color.New(color.FgGreen).SprintFunc()
+		fmt.Printf("%s Captured requests cleared\n", green("✓"))
+		return nil
+	},
+}
+
+func runRequests(cmd *cobra.Command, args []string) error {
+	if running, _, _ := daemon.IsRunning(); !running {
+		return fmt.Errorf("daemon is not running — captured requests are kept by the daemon; run 'hatch up' first")
+	}
+	client := api.NewClient()
+	ctx := context.Background()
+
+	if len(args) == 1 {
+		id, err := strconv.ParseUint(args[0], 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid request ID %q", args[0])
+		}
+		rec, err := client.Request(ctx, id)
+		if err != nil {
+			return err
+		}
+		printRequest(rec)
+		return nil
+	}
+
+	project, _ := cmd.Flags().GetString("project")
+	method, _ := cmd.Flags().GetString("method")
+	status, _ := cmd.Flags().GetString("status")
+	limit, _ := cmd.Flags().GetInt("limit")
+	harPath, _ := cmd.Flags().GetString("har")
+
+	f := inspect.Filter{Project: project, Method: method, Limit: limit}
+	var err error
+	if f.Status, err = inspect.ParseStatusFilter(status); err != nil {
+		return err
+	}
+	records, err := client.Requests(ctx, f)
+	if err != nil {
+		return err
+	}
+
+	if harPath != "" {
+		return writeHAR(harPath, records)
+	}
+	if len(records) == 0 {
+		fmt.Println("No captured requests.")
+		if cfg, err := config.Load(); err == nil && !anyCapture(cfg) {
+			fmt.Println("Turn capture on for a project with 'hatch requests capture <project> on'.")
+		}
+		return nil
+	}
+	printRequestTable(records)
+	return nil
+}
+
+// printRequestTable prints one line per request, oldest at the bottom.
+func printRequestTable(records []inspect.Record) {
+	faint := color.New(color.Faint).SprintFunc()
+	idW, projW, methodW := len("ID"), len("PROJECT"), len("METHOD")
+	for _, rec := range records {
+		idW = max(idW, len(strconv.FormatUint(rec.ID, 10)))
+		projW = max(projW, len(rec.Project))
+		methodW = max(methodW, len(rec.Method))
+	}
+
+	fmt.Printf("%-*s  %-8s  %-*s  %-*s  %-6s  %8s  %s\n", idW, "ID", "TIME", projW, "PROJECT", methodW, "METHOD", "STATUS", "DURATION", "URL")
+	for _, rec := range records {
+		fmt.Printf("%-*d  %-8s  %-*s  %-*s  %s  %8s  %s\n",
+			idW, rec.ID, rec.Time.Local().Format("15:04:05"), projW, rec.Project, methodW, rec.Method,
+			statusColor(rec.Status)(fmt.Sprintf("%-6d", rec.Status)), formatDuration(rec.Duration), rec.URL)
+		if rec.Error != "" {
+			fmt.Printf("%*s  %s\n", idW, "", faint(rec.Error))
+		}
+	}
+}
+
+// printRequest prints a request and its response in full.
+func printRequest(rec inspect.Record) {
+	bold := color.New(color.Bold).SprintFunc()
+	faint := color.New(color.Faint).SprintFunc()
+
+	title := fmt.Sprintf("%s/%s  %s", rec.Project, rec.Service, rec.Time.Local().Format(time.RFC3339))
+	if rec.ID != 0 {
+		title = bold(fmt.Sprintf("#%d", rec.ID)) + "  " + title
+	}
+	if rec.ReplayOf != 0 {
+		title += "  " + faint(fmt.Sprintf("(replay of #%d)", rec.ReplayOf))
+	}
+	fmt.Printf("%s\n\n", title)
+	fmt.Printf("%s %s %s\n", bold(rec.Method), rec.URL, faint(rec.Proto))
+	printHeaders(rec.RequestHeaders)
+	printBody(rec.RequestBody)
+
+	fmt.Println()
+	status := fmt.Sprintf("%d %s", rec.Status, http.StatusText(rec.Status))
+	fmt.Printf("%s %s\n", statusColor(rec.Status)(status),
+		faint(fmt.Sprintf("(%s, first byte after %s)", formatDuration(rec.Duration), formatDuration(rec.Wait))))
+	if rec.Error != "" {
+		fmt.Printf("%s %s\n", color.RedString("error:"), rec.Error)
+	}
+	printHeaders(rec.ResponseHeaders)
+	printBody(rec.ResponseBody)
+}
+
+func printHeaders(h http.Header) {
+	faint := color.New(color.Faint).SprintFunc()
+	names := make([]string, 0, len(h))
+	for name := range h {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, v := range h[name] {
+			fmt.Printf("%s %s\n", faint(name+":"), v)
+		}
+	}
+}
+
+func printBody(b inspect.Body) {
+	faint := color.New(color.Faint).SprintFunc()
+	switch {
+	case b.Size == 0:
+		return
+	case b.Encoding == "base64":
+		fmt.Printf("\n%s\n", faint(fmt.Sprintf("(binary body, %d bytes)", b.Size)))
+		return
+	}
+	fmt.Printf("\n%s\n", strings.TrimRight(b.Text, "\n"))
+	if b.Truncated {
+		fmt.Println(faint(fmt.Sprintf("(truncated: %d of %d bytes shown)", len(b.Text), b.Size)))
+	}
+}
+
+// statusColor returns a color function for an HTTP status class.
+func statusColor(status int) func(a ...any) string {
+	switch {
+	case status >= 500:
+		return color.New(color.FgRed).SprintFunc()
+	case status >= 400:
+		return color.New(color.FgYellow).SprintFunc()
+	case status >= 300:
+		return color.New(color.FgCyan).SprintFunc()
+	default:
+		return color.New(color.FgGreen).SprintFunc()
+	}
+}
+
+func formatDuration(d time.Duration) string {
+	if d < time.Second {
+		return fmt.Sprintf("%.1fms", float64(d)/float64(time.Millisecond))
+	}
+	return d.Round(10 * time.Millisecond).String()
+}
+
+// writeHAR writes records as an HTTP Archive to path, or stdout for "-".
+func writeHAR(path string, records []inspect.Record) error {
+	data, err := json.MarshalIndent(inspect.NewHAR(records, version), "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode har: %w", err)
+	}
+	if path == "-" {
+		_, err = os.Stdout.Write(append(data, '\n'))
+		return err
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("write har: %w", err)
+	}
+	green := color.New(color.FgGreen).SprintFunc()
+	fmt.Printf("%s Wrote %d requests to %s\n", green("✓"), len(records), path)
+	return nil
+}
+
+func anyCapture(cfg config.Config) bool {
+	for _, p := range cfg.Projects {
+		if p.Capture {
+			return true
+		}
+	}
+	return false
+}
+
+func setCapture(name string, enabled bool) error {
+	cfg, err := config.LoadRaw()
+	if err != nil {
+		return fmt.Errorf("load config: %w", err)
+	}
+	proj, ok := cfg.Projects[name]
+	if !ok {
+		return fmt.Errorf("project %q not found", name)
+	}
+
+	green := color.New(color.FgGreen).SprintFunc()
+	if proj.Capture != enabled {
+		proj.Capture = enabled
+		cfg.Projects[name] = proj
+		if err := config.Save(cfg); err != nil {
+			return fmt.Errorf("save config: %w", err)
+		}
+	}
+	if enabled {
+		fmt.Printf("%s Capturing traffic for %s — browse it with 'hatch requests --project %s'\n", green("✓"), name, name)
+	} else {
+		fmt.Printf("%s Stopped capturing traffic for %s\n", green("✓"), name)
+	}
+	return nil
+}
+
+func init() {
+	requestsCmd.Flags().StringP("project", "p", "", "only show requests for this project")
+	requestsCmd.Flags().StringP("status", "s", "", "only show this status code or class, e.g. 404 or 5xx")
+	requestsCmd.Flags().StringP("method", "m", "", "only show this HTTP method")
+	requestsCmd.Flags().IntP("limit", "n", 50, "number of most recent requests to show (0 for all)")
+	requestsCmd.Flags().String("har", "", "write the matching requests to this HAR file (- for stdout)")
+	requestsCmd.RegisterFlagCompletionFunc("project", completeProjectNames)
+	requestsCmd.AddCommand(requestsCaptureCmd, requestsClearCmd)
+	rootCmd.AddCommand(requestsCmd)
+}
@@ -12,8 +12,8 @@ var verbose bool
 
 var rootCmd = &cobra.Command{
 	Use:   "hatch",
-	Short: "Local HTTPS reverse proxy for macOS development",
-	Long:  `Hatch is a local HTTPS reverse proxy that makes developing with custom domains and TLS effortless on macOS.`,
+	Short: "Local HTTPS reverse proxy for macOS and Linux development",
+	Long:  `Hatch is a local HTTPS reverse proxy that makes developing with custom domains and TLS effortless on macOS and Linux.`,
 	SilenceUsage:  true,
 	SilenceErrors: true,
 	PersistentPreRun: func(cmd *cobra.Command, args []string) {
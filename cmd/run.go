@@ -16,7 +16,7 @@ import (
 
 var runCmd = &cobra.Command{
 	Use:    "_run",
-	Short:  "Run the daemon (internal, used by launchd)",
+	Short:  "Run the daemon (internal, used by launchd/systemd)",
 	Hidden: true,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		ctx, stop := signal.NotifyContext(cmd.Context(), syscall.SIGTERM, syscall.SIGINT)
@@ -0,0 +1,192 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/paulrose/hatch/internal/api"
+	"github.com/paulrose/hatch/internal/config"
+	"github.com/paulrose/hatch/internal/daemon"
+	"github.com/paulrose/hatch/internal/dns"
+	"github.com/paulrose/hatch/internal/share"
+)
+
+var shareCmd = &cobra.Command{
+	Use:   "share [on|off|<project>]",
+	Short: "Share projects on the local network or publicly",
+	Long: `With on or off, turns LAN sharing on or off; without an argument, shows its status. While sharing, the DNS server also listens on this machine's LAN address (port 53) and answers other devices with that address, so a phone on the same Wi-Fi can open your project domains.
+
+With a project name, shares that project publicly through the tunnel relay set in settings.tunnel (see 'hatch relay') and prints its public URL. Requests arriving there are sent to the project with the Host header rewritten to its domain. The tunnel stays open in the daemon until stopped with --stop.`,
+	Args:              cobra.MaximumNArgs(1),
+	ValidArgsFunction: completeShareArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		stop, _ := cmd.Flags().GetBool("stop")
+		if len(args) == 0 {
+			if stop {
+				return fmt.Errorf("--stop needs a project name")
+			}
+			return runShareStatus()
+		}
+		switch args[0] {
+		case "on":
+			return setSharing(true)
+		case "off":
+			return setSharing(false)
+		}
+		if stop {
+			return stopTunnel(args[0])
+		}
+		return startTunnel(args[0])
+	},
+}
+
+// completeShareArgs completes on, off and project names.
+func completeShareArgs(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	names, directive := completeProjectNames(cmd, args, toComplete)
+	return append([]string{"on", "off"}, names...), directive
+}
+
+func setSharing(enabled bool) error {
+	cfg, err := config.LoadRaw()
+	if err != nil {
+		return fmt.Errorf("load config: %w", err)
+	}
+
+	green := color.New(color.FgGreen).SprintFunc()
+	if cfg.Settings.LANSharing != enabled {
+		cfg.Settings.LANSharing = enabled
+		if err := config.Save(cfg); err != nil {
+			return fmt.Errorf("save config: %w", err)
+		}
+	}
+	if !enabled {
+		fmt.Printf("%s LAN sharing off\n", green("✓"))
+		return nil
+	}
+	fmt.Printf("%s LAN sharing on\n", green("✓"))
+
+	if running, _, _ := daemon.IsRunning(); !running {
+		fmt.Println("\nThe daemon is not running — sharing starts with 'hatch up'.")
+		return nil
+	}
+
+	// The daemon applies the change when its config watcher fires.
+	client := api.NewClient()
+	var info share.Info
+	for deadline := time.Now().Add(3 * time.Second); time.Now().Before(deadline); time.Sleep(200 * time.Millisecond) {
+		if info, err = client.Share(context.Background()); err == nil && info.Active {
+			break
+		}
+	}
+	if !info.Active {
+		return fmt.Errorf("daemon did not start sharing — check 'hatch logs' (binding port %d on the LAN address may need extra privileges)", dns.SharePort)
+	}
+	printShareInstructions(info)
+	return nil
+}
+
+func runShareStatus() error {
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("load config: %w", err)
+	}
+	if !cfg.Settings.LANSharing {
+		fmt.Println("LAN sharing is off. Turn it on with 'hatch share on'.")
+		return nil
+	}
+
+	if running, _, _ := daemon.IsRunning(); !running {
+		fmt.Println("LAN sharing is on, but the daemon is not running. Start it with 'hatch up'.")
+		return nil
+	}
+	info, err := api.NewClient().Share(context.Background())
+	if err != nil {
+		return err
+	}
+	if !info.Active {
+		return fmt.Errorf("LAN sharing is on, but the daemon could not start it — check 'hatch logs'")
+	}
+	printShareInstructions(info)
+	return nil
+}
+
+// printShareInstructions explains how to reach the shared projects from
+// another device, with a QR code for the root CA download.
+func printShareInstructions(info share.Info) {
+	bold := color.New(color.Bold).SprintFunc()
+	cyan := color.New(color.FgCyan).SprintFunc()
+	faint := color.New(color.Faint).SprintFunc()
+
+	fmt.Printf("\nSharing on %s. On a phone or tablet on the same network:\n\n", bold(info.LANIP))
+	fmt.Printf("  1. Set the Wi-Fi network's DNS server to %s\n", cyan(info.LANIP))
+	fmt.Printf("  2. Open %s (or scan the code below) and install the certificate\n", cyan(info.CAURL))
+	fmt.Println(faint("     iOS: Settings → General → VPN & Device Management → install the profile,"))
+	fmt.Println(faint("          then Settings → General → About → Certificate Trust Settings → enable full trust"))
+	fmt.Println(faint("     Android: Settings → Security → Encryption & credentials → Install a certificate → CA certificate"))
+	fmt.Println("  3. Open a project:")
+	for _, u := range info.URLs {
+		fmt.Printf("       %s  %s\n", cyan(u.URL), faint(u.Project))
+	}
+
+	if qr, err := share.TerminalQR(info.CAURL); err == nil {
+		fmt.Println()
+		fmt.Print(qr)
+	}
+}
+
+// startTunnel asks the daemon to share a project through the tunnel relay
+// and prints its public URL.
+func startTunnel(project string) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("load config: %w", err)
+	}
+	if _, ok := cfg.Projects[project]; !ok {
+		return fmt.Errorf("project %q not found", project)
+	}
+	if cfg.Settings.Tunnel == nil {
+		return fmt.Errorf("no tunnel relay configured — set settings.tunnel.relay (and token) in %s to a server running 'hatch relay'", config.ConfigFile())
+	}
+	if running, _, _ := daemon.IsRunning(); !running {
+		return fmt.Errorf("daemon is not running — run 'hatch up' first")
+	}
+
+	client := api.NewClient()
+	client.HTTPClient.Timeout = 30 * time.Second
+	st, err := client.StartTunnel(context.Background(), project)
+	if err != nil {
+		return err
+	}
+
+	green := color.New(color.FgGreen).SprintFunc()
+	cyan := color.New(color.FgCyan).SprintFunc()
+	faint := color.New(color.Faint).SprintFunc()
+	fmt.Printf("%s Sharing %s at %s\n", green("✓"), st.Domain, cyan(st.URL))
+	fmt.Println(faint("  Anyone with the URL can reach it. Stop with 'hatch share " + project + " --stop'."))
+	return nil
+}
+
+// stopTunnel asks the daemon to stop sharing a project publicly.
+func stopTunnel(project string) error {
+	if running, _, _ := daemon.IsRunning(); !running {
+		return fmt.Errorf("daemon is not running")
+	}
+	if err := api.NewClient().StopTunnel(context.Background(), project); err != nil {
+		return err
+	}
+	green := color.New(color.FgGreen).SprintFunc()
+	fmt.Printf("%s Stopped sharing %s\n", green("✓"), project)
+	return nil
+}
+
+func init() {
+	shareCmd.Flags().Bool("stop", false, "stop sharing the given project publicly")
+	rootCmd.AddCommand(shareCmd)
+}
@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/paulrose/hatch/internal/api"
+	"github.com/paulrose/hatch/internal/config"
+	"github.com/paulrose/hatch/internal/daemon"
+	"github.com/paulrose/hatch/internal/process"
+)
+
+var startCmd = &cobra.Command{
+	Use:   "start <project>[/<service>]",
+	Short: "Start the dev servers of a project's services",
+	Long: `Has the daemon run the command of each of the project's services that has one (the command, cwd and env settings in .hatch.yml), or only of the named service. The daemon restarts a command whenever it exits, waiting longer after each quick exit, until 'hatch stop'. Its output goes to a log file; view it with 'hatch logs <project>/<service>'.
+
+Commands already running are left alone unless --restart is given.`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeProcessTargets,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		restart, _ := cmd.Flags().GetBool("restart")
+		return runStart(args[0], restart)
+	},
+}
+
+// parseProcessTarget splits a project[/service] argument.
+func parseProcessTarget(arg string) (project, service string) {
+	project, service, _ = strings.Cut(arg, "/")
+	return project, service
+}
+
+func runStart(target string, restart bool) error {
+	project, service := parseProcessTarget(target)
+	if running, _, _ := daemon.IsRunning(); !running {
+		return fmt.Errorf("daemon is not running — run 'hatch up' first")
+	}
+
+	statuses, err := api.NewClient().StartProcesses(context.Background(), project, service, restart)
+	if err != nil {
+		return err
+	}
+
+	green := color.New(color.FgGreen).SprintFunc()
+	faint := color.New(color.Faint).SprintFunc()
+	for _, st := range statuses {
+		fmt.Printf("%s Started %s/%s  %s\n", green("✓"), st.Project, st.Service, faint(st.Command))
+	}
+	logTarget := project + "/<service>"
+	if len(statuses) == 1 {
+		logTarget = statuses[0].Project + "/" + statuses[0].Service
+	}
+	fmt.Println(faint("  Follow the output with 'hatch logs " + logTarget + " -f'."))
+	return nil
+}
+
+// describeProcess summarises a process status for 'hatch status'.
+func describeProcess(st process.Status) string {
+	green := color.New(color.FgGreen).SprintFunc()
+	yellow := color.New(color.FgYellow).SprintFunc()
+	switch st.State {
+	case process.StateRunning:
+		return fmt.Sprintf("%s (pid %d, up %s)", green("running"), st.PID, time.Since(st.Started).Truncate(time.Second))
+	case process.StateRestarting:
+		return fmt.Sprintf("%s (%s, %d restarts)", yellow("restarting"), st.Exit, st.Restarts)
+	}
+	return st.State
+}
+
+// completeProcessTargets suggests projects and project/service pairs for
+// services with a command.
+func completeProcessTargets(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	cfg, err := config.LoadRaw()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	var targets []string
+	for name, proj := range cfg.Projects {
+		var services []string
+		for svcName, svc := range proj.Services {
+			if svc.Command != "" {
+				services = append(services, name+"/"+svcName)
+			}
+		}
+		if len(services) > 0 {
+			targets = append(targets, name)
+			targets = append(targets, services...)
+		}
+	}
+	sort.Strings(targets)
+	return targets, cobra.ShellCompDirectiveNoFileComp
+}
+
+func init() {
+	startCmd.Flags().BoolP("restart", "r", false, "restart commands that are already running")
+	rootCmd.AddCommand(startCmd)
+}
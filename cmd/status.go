@@ -1,28 +1,42 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
+	"maps"
 	"net"
 	"net/url"
+	"os"
 	"sort"
+	"strings"
 	"time"
 
 	"github.com/fatih/color"
 	"github.com/spf13/cobra"
 
+	"github.com/paulrose/hatch/internal/api"
 	"github.com/paulrose/hatch/internal/config"
 	"github.com/paulrose/hatch/internal/daemon"
+	"github.com/paulrose/hatch/internal/process"
+	"github.com/paulrose/hatch/internal/tunnel"
 )
 
 var statusCmd = &cobra.Command{
 	Use:   "status",
 	Short: "Show daemon state, projects, and service health",
+	Long:  `Shows daemon state, projects, and service health. With --history, shows recent health check results recorded by the daemon, including when each service changed state.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
+		if history, _ := cmd.Flags().GetBool("history"); history {
+			limit, _ := cmd.Flags().GetInt("limit")
+			return runStatusHistory(limit)
+		}
 		return runStatus()
 	},
 }
 
 func init() {
+	statusCmd.Flags().Bool("history", false, "show recent health check history from the daemon")
+	statusCmd.Flags().Int("limit", 60, "number of recent checks to show per service with --history")
 	rootCmd.AddCommand(statusCmd)
 }
 
@@ -49,6 +63,34 @@ func runStatus() error {
 		fmt.Printf("  %s Run 'hatch up' to start the daemon\n", yellow("→"))
 	}
 
+	// Public tunnels, service processes and projects discovered from
+	// Docker are held by the daemon.
+	tunnels := make(map[string]tunnel.Status)
+	processes := make(map[string]process.Status)
+	discovered := make(map[string]bool)
+	if running {
+		client := api.NewClient()
+		if projects, err := client.DiscoveredProjects(context.Background()); err == nil && len(projects) > 0 {
+			all := make(map[string]config.Project, len(cfg.Projects)+len(projects))
+			maps.Copy(all, cfg.Projects)
+			for name, proj := range projects {
+				all[name] = proj
+				discovered[name] = true
+			}
+			cfg.Projects = all
+		}
+		if list, err := client.Tunnels(context.Background()); err == nil {
+			for _, st := range list {
+				tunnels[st.Project] = st
+			}
+		}
+		if list, err := client.Processes(context.Background()); err == nil {
+			for _, st := range list {
+				processes[st.Project+"/"+st.Service] = st
+			}
+		}
+	}
+
 	if len(cfg.Projects) == 0 {
 		fmt.Println()
 		fmt.Println("No projects configured.")
@@ -68,7 +110,9 @@ func runStatus() error {
 		fmt.Println()
 
 		// Project header
-		if proj.Enabled {
+		if discovered[name] {
+			fmt.Printf("%s (%s) %s docker\n", name, proj.Domain, green("✓"))
+		} else if proj.Enabled {
 			fmt.Printf("%s (%s) %s enabled\n", name, proj.Domain, green("✓"))
 		} else {
 			fmt.Printf("%s (%s) %s disabled\n", name, proj.Domain, red("✗"))
@@ -79,6 +123,14 @@ func runStatus() error {
 			continue
 		}
 
+		if st, ok := tunnels[name]; ok {
+			if st.Connected {
+				fmt.Printf("  Public: %s\n", st.URL)
+			} else {
+				fmt.Printf("  Public: %s %s\n", st.URL, yellow("(reconnecting)"))
+			}
+		}
+
 		// Sort service names
 		svcNames := make([]string, 0, len(proj.Services))
 		for svcName := range proj.Services {
@@ -96,12 +148,35 @@ func runStatus() error {
 		for _, svcName := range svcNames {
 			svc := proj.Services[svcName]
 			domain := resolveDomain(proj, svc)
-			upstream := extractDialAddr(svc.Proxy)
+			var addrs []string
+			healthy := 0
+			if svc.IsStatic() {
+				dir := svc.RootDir(proj)
+				addrs = append(addrs, dir)
+				if info, err := os.Stat(dir); err == nil && info.IsDir() {
+					healthy++
+				}
+			}
+			if svc.IsMock() {
+				addrs = append(addrs, fmt.Sprintf("mock (%d rules)", len(svc.Mock)))
+				healthy++
+			}
+			for _, proxyURL := range svc.ProxyURLs() {
+				addr := extractDialAddr(proxyURL)
+				addrs = append(addrs, addr)
+				if addr != "" && dialHealth(addr) {
+					healthy++
+				}
+			}
+			upstream := strings.Join(addrs, ", ")
 
 			var status string
-			if upstream != "" && dialHealth(upstream) {
+			switch {
+			case healthy > 0 && healthy == len(addrs):
 				status = green("✓") + " healthy"
-			} else {
+			case healthy > 0:
+				status = yellow("!") + fmt.Sprintf(" %d/%d healthy", healthy, len(addrs))
+			default:
 				status = red("✗") + " unhealthy"
 			}
 
@@ -125,6 +200,105 @@ func runStatus() error {
 		for _, r := range rows {
 			fmt.Printf("  %-*s  %-*s  %-*s  %s\n", nameW, r.name, domainW, r.domain, upstreamW, r.upstream, r.status)
 		}
+
+		// Commands run by the daemon
+		for _, svcName := range svcNames {
+			if proj.Services[svcName].Command == "" {
+				continue
+			}
+			desc := "stopped"
+			if st, ok := processes[name+"/"+svcName]; ok {
+				desc = describeProcess(st)
+			}
+			fmt.Printf("  %s %s: %s\n", yellow("▸"), svcName, desc)
+		}
+	}
+
+	return nil
+}
+
+// runStatusHistory prints each enabled service's recent health checks as
+// fetched from the daemon: a one-character-per-check timeline, a summary,
+// and every state transition.
+func runStatusHistory(limit int) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("load config: %w", err)
+	}
+
+	if running, _, _ := daemon.IsRunning(); !running {
+		return fmt.Errorf("daemon is not running — health history is kept by the daemon; run 'hatch up' first")
+	}
+
+	green := color.New(color.FgGreen).SprintFunc()
+	red := color.New(color.FgRed).SprintFunc()
+	faint := color.New(color.Faint).SprintFunc()
+
+	names := make([]string, 0, len(cfg.Projects))
+	for name, proj := range cfg.Projects {
+		if proj.Enabled {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	client := api.NewClient()
+	ctx := context.Background()
+
+	for _, name := range names {
+		proj := cfg.Projects[name]
+		svcNames := make([]string, 0, len(proj.Services))
+		for svcName := range proj.Services {
+			svcNames = append(svcNames, svcName)
+		}
+		sort.Strings(svcNames)
+
+		for _, svcName := range svcNames {
+			results, err := client.HealthHistory(ctx, name, svcName, limit)
+			if err != nil {
+				return fmt.Errorf("health history for %s/%s: %w", name, svcName, err)
+			}
+
+			fmt.Println()
+			if len(results) == 0 {
+				fmt.Printf("%s/%s  %s\n", name, svcName, faint("no checks yet"))
+				continue
+			}
+
+			var timeline strings.Builder
+			var healthy int
+			var totalLatency float64
+			for _, r := range results {
+				switch r.Status {
+				case "healthy":
+					healthy++
+					timeline.WriteString(green("▇"))
+				case "unhealthy":
+					timeline.WriteString(red("▇"))
+				default:
+					timeline.WriteString(faint("·"))
+				}
+				totalLatency += r.LatencyMS
+			}
+
+			fmt.Printf("%s/%s  %.1f%% healthy · avg %.1fms · last %d checks\n",
+				name, svcName, 100*float64(healthy)/float64(len(results)),
+				totalLatency/float64(len(results)), len(results))
+			fmt.Printf("  %s\n", timeline.String())
+
+			prev := results[0].Status
+			for _, r := range results[1:] {
+				if r.Status == prev {
+					continue
+				}
+				line := fmt.Sprintf("  %s  %s → %s", r.Time.Local().Format("15:04:05"), prev, r.Status)
+				if r.Error != "" {
+					line += "  " + faint(r.Error)
+				}
+				fmt.Println(line)
+				prev = r.Status
+			}
+		}
 	}
 
 	return nil
@@ -160,10 +334,10 @@ func extractDialAddr(proxyURL string) string {
 }
 
 // resolveDomain returns the full domain for a service, prepending the
-// subdomain if configured.
+// subdomain if set. TCP services include their listen port.
 func resolveDomain(proj config.Project, svc config.Service) string {
-	if svc.Subdomain != "" {
-		return svc.Subdomain + "." + proj.Domain
+	if svc.IsTCP() {
+		return fmt.Sprintf("%s:%d", svc.Host(proj), svc.Listen)
 	}
-	return proj.Domain
+	return svc.Host(proj)
 }
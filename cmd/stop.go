@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/paulrose/hatch/internal/api"
+	"github.com/paulrose/hatch/internal/daemon"
+)
+
+var stopCmd = &cobra.Command{
+	Use:               "stop <project>[/<service>]",
+	Short:             "Stop the dev servers of a project's services",
+	Long:              `Stops the commands the daemon runs for the project's services, or only for the named service, sending SIGTERM to each command's process group and SIGKILL if it has not exited after five seconds.`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeProcessTargets,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runStop(args[0])
+	},
+}
+
+func runStop(target string) error {
+	project, service := parseProcessTarget(target)
+	if running, _, _ := daemon.IsRunning(); !running {
+		return fmt.Errorf("daemon is not running")
+	}
+	if err := api.NewClient().StopProcesses(context.Background(), project, service); err != nil {
+		return err
+	}
+	green := color.New(color.FgGreen).SprintFunc()
+	fmt.Printf("%s Stopped %s\n", green("✓"), target)
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(stopCmd)
+}
@@ -13,8 +13,8 @@ import (
 
 var trustCmd = &cobra.Command{
 	Use:   "trust",
-	Short: "Trust the Hatch root CA in the macOS Keychain",
-	Long:  `Re-trusts the Hatch root CA certificate in the system Keychain. Useful if the certificate was removed or the Keychain was reset.`,
+	Short: "Trust the Hatch root CA in the system trust store",
+	Long:  `Re-trusts the Hatch root CA certificate in the system trust store (the Keychain on macOS). Useful if the certificate was removed or the trust store was reset.`,
 	RunE:  runTrust,
 }
 
@@ -50,16 +50,17 @@ func runTrust(cmd *cobra.Command, args []string) error {
 		fmt.Printf("  %s Intermediate CA generated\n", green("✓"))
 	}
 
-	if certs.IsCATrusted(caPaths.Cert) {
+	store := certs.NewTrustStore()
+	if store.IsTrusted(caPaths.Cert) {
 		fmt.Printf("  %s Root CA is already trusted\n", green("✓"))
 		return nil
 	}
 
-	if err := certs.TrustCA(&sudoRunner{}, caPaths.Cert); err != nil {
+	if err := store.Trust(&sudoRunner{}, caPaths.Cert); err != nil {
 		fmt.Printf("  %s Failed to trust root CA: %v\n", red("✗"), err)
 		os.Exit(1)
 	}
 
-	fmt.Printf("  %s Root CA trusted in Keychain\n", green("✓"))
+	fmt.Printf("  %s Root CA trusted in %s\n", green("✓"), store.Name())
 	return nil
 }
@@ -62,35 +62,36 @@ func runUp() error {
 	}
 
 	// Trust CA if needed.
-	if !certs.IsCATrusted(caPaths.Cert) {
+	store := certs.NewTrustStore()
+	if !store.IsTrusted(caPaths.Cert) {
 		log.Info().Msg("trusting root CA (may prompt for password)")
-		if err := certs.TrustCA(&sudoRunner{}, caPaths.Cert); err != nil {
+		if err := store.Trust(&sudoRunner{}, caPaths.Cert); err != nil {
 			return fmt.Errorf("trust CA: %w", err)
 		}
 	}
 
-	// Install DNS resolver if needed.
-	if !dns.IsResolverInstalled(cfg.Settings.TLD) {
-		log.Info().Str("tld", cfg.Settings.TLD).Msg("installing DNS resolver (may prompt for password)")
-		if err := dns.InstallResolverFile(&sudoRunner{}, cfg.Settings.TLD, dns.DefaultListenIP, dns.DefaultPort); err != nil {
-			return fmt.Errorf("install resolver: %w", err)
+	// Install DNS resolvers if needed.
+	resolver := dns.NewResolver()
+	for _, zone := range cfg.Settings.DNSZones() {
+		if resolver.IsInstalled(zone) {
+			continue
+		}
+		log.Info().Str("zone", zone).Msg("installing DNS resolver (may prompt for password)")
+		if err := resolver.Install(&sudoRunner{}, zone, dns.DefaultListenIP, dns.DefaultPort); err != nil {
+			return fmt.Errorf("install resolver for %s: %w", zone, err)
 		}
 	}
 
-	// Build launchd config and install plist.
-	launchdCfg, err := daemon.DefaultLaunchdConfig(cfg.Settings.AutoStart)
-	if err != nil {
-		return fmt.Errorf("launchd config: %w", err)
-	}
-
-	if err := daemon.InstallPlist(launchdCfg); err != nil {
-		return fmt.Errorf("install plist: %w", err)
+	// Install the service definition (launchd plist or systemd unit).
+	svc := daemon.NewServiceManager()
+	if err := svc.Install(cfg.Settings.AutoStart); err != nil {
+		return fmt.Errorf("install service: %w", err)
 	}
-	log.Debug().Msg("plist installed")
+	log.Debug().Msgf("%s installed", svc.Name())
 
-	// Load the plist into launchd.
-	if err := daemon.LoadPlist(); err != nil {
-		return fmt.Errorf("load plist: %w", err)
+	// Start the daemon via the service manager.
+	if err := svc.Start(); err != nil {
+		return fmt.Errorf("start service: %w", err)
 	}
 
 	fmt.Printf("%s started\n", color.New(color.FgCyan, color.Bold).Sprint("Hatch"))
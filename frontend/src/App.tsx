@@ -5,19 +5,23 @@ import { RouteMap } from "@/components/route-map";
 import { LogViewer } from "@/components/log-viewer";
 import { AddProjectDialog } from "@/components/add-project-dialog";
 import { EditProjectDialog } from "@/components/edit-project-dialog";
+import { ShareDialog } from "@/components/share-dialog";
 import { useProjects } from "@/hooks/use-projects";
 import { useHealth } from "@/hooks/use-health";
 import { useStatus } from "@/hooks/use-status";
+import { useTunnels } from "@/hooks/use-tunnels";
 
 function App() {
   const { projects, add, update, remove, toggle, loading, error } =
     useProjects();
   const { lookup } = useHealth();
   const { status } = useStatus();
+  const tunnels = useTunnels();
 
   const [addOpen, setAddOpen] = useState(false);
   const [editTarget, setEditTarget] = useState<string | null>(null);
   const [logsOpen, setLogsOpen] = useState(false);
+  const [shareOpen, setShareOpen] = useState(false);
 
   function handleDelete(name: string) {
     if (confirm(`Delete project "${name}"?`)) {
@@ -34,6 +38,7 @@ function App() {
         onAddProject={() => setAddOpen(true)}
         logsOpen={logsOpen}
         onToggleLogs={() => setLogsOpen((o) => !o)}
+        onShare={() => setShareOpen(true)}
       />
       <main className="mx-auto w-full max-w-5xl flex-1 overflow-y-auto p-4">
         {loading && (
@@ -44,6 +49,9 @@ function App() {
         )}
         {!loading && !error && (
           <>
+            {tunnels.error && (
+              <p className="pb-4 text-sm text-destructive">{tunnels.error}</p>
+            )}
             <ProjectList
               projects={projects}
               healthLookup={lookup}
@@ -51,6 +59,9 @@ function App() {
               onEdit={setEditTarget}
               onDelete={handleDelete}
               onAdd={() => setAddOpen(true)}
+              tunnels={tunnels.tunnels}
+              onStartTunnel={tunnels.start}
+              onStopTunnel={tunnels.stop}
             />
             <RouteMap projects={projects} healthLookup={lookup} />
           </>
@@ -58,6 +69,7 @@ function App() {
       </main>
       {logsOpen && <LogViewer />}
       <AddProjectDialog open={addOpen} onOpenChange={setAddOpen} onAdd={add} />
+      {shareOpen && <ShareDialog open={shareOpen} onOpenChange={setShareOpen} />}
       {editTarget && editProject && (
         <EditProjectDialog
           open={!!editTarget}
@@ -1,4 +1,11 @@
-import type { DaemonStatus, Project, ServiceHealth } from "./types";
+import type {
+  DaemonStatus,
+  HealthCheckResult,
+  Project,
+  ServiceHealth,
+  ShareInfo,
+  TunnelStatus,
+} from "./types";
 
 const BASE = "http://127.0.0.1:42824";
 
@@ -63,6 +70,50 @@ export function getHealth(): Promise<ServiceHealth[]> {
   return request("/api/health");
 }
 
+export function getHealthHistory(
+  project: string,
+  service: string,
+  limit?: number
+): Promise<HealthCheckResult[]> {
+  const query = limit ? `?limit=${limit}` : "";
+  return request(
+    `/api/health/${encodeURIComponent(project)}/${encodeURIComponent(service)}/history${query}`
+  );
+}
+
 export function restartDaemon(): Promise<{ status: string }> {
   return request("/api/restart", { method: "POST" });
 }
+
+export function getShare(): Promise<ShareInfo> {
+  return request("/api/share");
+}
+
+export function setShare(enabled: boolean): Promise<{ enabled: boolean }> {
+  return request("/api/share", {
+    method: "PUT",
+    headers: { "Content-Type": "application/json" },
+    body: JSON.stringify({ enabled }),
+  });
+}
+
+// shareQRUrl returns the URL of a PNG QR code encoding text.
+export function shareQRUrl(text: string): string {
+  return `${BASE}/api/share/qr?text=${encodeURIComponent(text)}`;
+}
+
+export function getTunnels(): Promise<TunnelStatus[]> {
+  return request("/api/tunnels");
+}
+
+export function startTunnel(project: string): Promise<TunnelStatus> {
+  return request(`/api/tunnels/${encodeURIComponent(project)}`, {
+    method: "POST",
+  });
+}
+
+export function stopTunnel(project: string): Promise<void> {
+  return request(`/api/tunnels/${encodeURIComponent(project)}`, {
+    method: "DELETE",
+  });
+}
@@ -11,7 +11,9 @@ import { Input } from "@/components/ui/input";
 import { Label } from "@/components/ui/label";
 import { Switch } from "@/components/ui/switch";
 import { Separator } from "@/components/ui/separator";
-import type { Project, Service } from "@/types";
+import { MockRulesEditor } from "@/components/mock-rules-editor";
+import { proxyTargets } from "@/lib/utils";
+import type { MockRule, Project, Service } from "@/types";
 import { Plus, Trash2 } from "lucide-react";
 
 interface EditProjectDialogProps {
@@ -24,19 +26,28 @@ interface EditProjectDialogProps {
 
 interface ServiceForm {
   name: string;
+  // Comma-separated upstream URLs; more than one makes the service load-balanced.
   proxy: string;
   route: string;
   subdomain: string;
   websocket: boolean;
+  // Canned responses; any rule makes the service a mock instead of a proxy.
+  mock: MockRule[];
+  // Fields the form does not edit, preserved on save.
+  extra: Omit<Service, "proxy" | "upstreams" | "route" | "subdomain" | "websocket" | "mock">;
 }
 
 function serviceToForm(name: string, svc: Service): ServiceForm {
+  const { proxy: _p, upstreams: _u, route: _r, subdomain: _s, websocket: _w, mock, ...extra } = svc;
   return {
     name,
-    proxy: svc.proxy,
+    // Static and mock services leave the proxy blank; entering one replaces the root.
+    proxy: svc.root || mock?.length ? "" : proxyTargets(svc),
+    extra,
     route: svc.route ?? "",
     subdomain: svc.subdomain ?? "",
     websocket: svc.websocket ?? false,
+    mock: mock ?? [],
   };
 }
 
@@ -73,7 +84,7 @@ export function EditProjectDialog({
   function addService() {
     setServices((prev) => [
       ...prev,
-      { name: "", proxy: "localhost:3000", route: "", subdomain: "", websocket: false },
+      { name: "", proxy: "localhost:3000", route: "", subdomain: "", websocket: false, mock: [], extra: {} },
     ]);
   }
 
@@ -89,19 +100,32 @@ export function EditProjectDialog({
     const svcMap: Record<string, Service> = {};
     for (const s of services) {
       if (!s.name) continue;
+      const targets = s.proxy.split(",").map((t) => t.trim()).filter(Boolean);
+      const { lb_policy, root, spa, browse, ...extra } = s.extra;
+      let target: Service;
+      if (s.mock.length > 0) {
+        target = { mock: s.mock };
+      } else if (targets.length === 0 && root) {
+        target = { root, ...(spa ? { spa } : {}), ...(browse ? { browse } : {}) };
+      } else if (targets.length > 1) {
+        target = { upstreams: targets, ...(lb_policy ? { lb_policy } : {}) };
+      } else {
+        target = { proxy: targets[0] ?? "" };
+      }
       svcMap[s.name] = {
-        proxy: s.proxy,
+        ...extra,
+        ...target,
         ...(s.route ? { route: s.route } : {}),
         ...(s.subdomain ? { subdomain: s.subdomain } : {}),
-        ...(s.websocket ? { websocket: true } : {}),
+        ...(s.websocket && s.mock.length === 0 ? { websocket: true } : {}),
       };
     }
 
     try {
       await onSave(name, {
+        ...project,
         domain,
         path,
-        enabled: project.enabled,
         services: svcMap,
       });
       onOpenChange(false);
@@ -181,14 +205,21 @@ export function EditProjectDialog({
                 </div>
                 <div className="grid grid-cols-2 gap-3">
                   <div className="space-y-1">
-                    <Label className="text-xs">Proxy</Label>
+                    <Label className="text-xs">Proxy (comma-separate to load-balance)</Label>
                     <Input
                       value={svc.proxy}
                       onChange={(e) =>
                         updateService(idx, { proxy: e.target.value })
                       }
-                      placeholder="localhost:3000"
-                      required
+                      placeholder={
+                        svc.mock.length > 0
+                          ? "mocked"
+                          : svc.extra.root
+                            ? `static: ${svc.extra.root}`
+                            : "localhost:3000"
+                      }
+                      disabled={svc.mock.length > 0}
+                      required={!svc.extra.root && svc.mock.length === 0}
                     />
                   </div>
                   <div className="space-y-1">
@@ -223,6 +254,10 @@ export function EditProjectDialog({
                     <Label className="text-xs">WebSocket</Label>
                   </div>
                 </div>
+                <MockRulesEditor
+                  rules={svc.mock}
+                  onChange={(mock) => updateService(idx, { mock })}
+                />
               </fieldset>
             ))}
           </div>
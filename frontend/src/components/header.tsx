@@ -1,7 +1,7 @@
 import { Badge } from "@/components/ui/badge";
 import { Button } from "@/components/ui/button";
 import type { DaemonStatus } from "@/types";
-import { Plus, ScrollText } from "lucide-react";
+import { Plus, ScrollText, Smartphone } from "lucide-react";
 import { cn } from "@/lib/utils";
 
 interface HeaderProps {
@@ -9,9 +9,10 @@ interface HeaderProps {
   onAddProject: () => void;
   logsOpen: boolean;
   onToggleLogs: () => void;
+  onShare: () => void;
 }
 
-export function Header({ status, onAddProject, logsOpen, onToggleLogs }: HeaderProps) {
+export function Header({ status, onAddProject, logsOpen, onToggleLogs, onShare }: HeaderProps) {
   return (
     <header
       className="border-b border-border bg-card/50 backdrop-blur-sm"
@@ -41,6 +42,10 @@ export function Header({ status, onAddProject, logsOpen, onToggleLogs }: HeaderP
             <ScrollText />
             Logs
           </Button>
+          <Button size="sm" variant="outline" onClick={onShare}>
+            <Smartphone />
+            Share
+          </Button>
           <Button size="sm" onClick={onAddProject}>
             <Plus />
             Add Project
@@ -35,6 +35,20 @@ export function HealthDot({ health }: HealthDotProps) {
       <TooltipContent>
         <p className="capitalize">{status}</p>
         <p className="text-xs text-muted-foreground">Since {since}</p>
+        {health?.status_code ? (
+          <p className="text-xs text-muted-foreground">
+            HTTP {health.status_code} · {Math.round(health.latency_ms)}ms
+          </p>
+        ) : null}
+        {health?.error && (
+          <p className="text-xs text-muted-foreground">{health.error}</p>
+        )}
+        {health && health.upstreams.length > 1 &&
+          health.upstreams.map((u) => (
+            <p key={u.addr} className="text-xs text-muted-foreground">
+              {u.addr}: {u.status}
+            </p>
+          ))}
       </TooltipContent>
     </Tooltip>
   );
@@ -0,0 +1,110 @@
+import { Button } from "@/components/ui/button";
+import { Input } from "@/components/ui/input";
+import { Label } from "@/components/ui/label";
+import { Switch } from "@/components/ui/switch";
+import { cn } from "@/lib/utils";
+import type { MockRule } from "@/types";
+import { Plus, Trash2 } from "lucide-react";
+
+interface MockRulesEditorProps {
+  rules: MockRule[];
+  onChange: (rules: MockRule[]) => void;
+}
+
+// Edits a service's mock rules. Rules are tried in order, so the list
+// reads top to bottom the way requests are matched.
+export function MockRulesEditor({ rules, onChange }: MockRulesEditorProps) {
+  function update(idx: number, partial: Partial<MockRule>) {
+    onChange(rules.map((r, i) => (i === idx ? { ...r, ...partial } : r)));
+  }
+
+  function add() {
+    onChange([...rules, { method: "GET", path: "/api/*", status: 200, body: "{}" }]);
+  }
+
+  function remove(idx: number) {
+    onChange(rules.filter((_, i) => i !== idx));
+  }
+
+  return (
+    <div className="space-y-2">
+      <div className="flex items-center justify-between">
+        <Label className="text-xs">Mock rules (first match wins)</Label>
+        <Button type="button" variant="outline" size="xs" onClick={add}>
+          <Plus />
+          Rule
+        </Button>
+      </div>
+      {rules.map((rule, idx) => (
+        <div key={idx} className="space-y-2 rounded-md border border-border p-2">
+          <div className="flex items-center gap-2">
+            <Input
+              value={rule.method ?? ""}
+              onChange={(e) =>
+                update(idx, { method: e.target.value.toUpperCase() || undefined })
+              }
+              placeholder="ANY"
+              className="h-7 w-20 text-xs"
+            />
+            <Input
+              value={rule.path}
+              onChange={(e) => update(idx, { path: e.target.value })}
+              placeholder="/api/payments/{id}"
+              className="h-7 flex-1 font-mono text-xs"
+              required
+            />
+            <Input
+              type="number"
+              min={100}
+              max={599}
+              value={rule.status ?? ""}
+              onChange={(e) =>
+                update(idx, { status: e.target.value ? Number(e.target.value) : undefined })
+              }
+              placeholder="200"
+              className="h-7 w-20 text-xs"
+            />
+            <Button
+              type="button"
+              variant="ghost"
+              size="icon-xs"
+              onClick={() => remove(idx)}
+            >
+              <Trash2 className="text-destructive" />
+            </Button>
+          </div>
+          {rule.file ? null : (
+            <textarea
+              value={rule.body ?? ""}
+              onChange={(e) => update(idx, { body: e.target.value || undefined })}
+              placeholder='{"id": "{{.Params.id}}"}'
+              rows={3}
+              className={cn(
+                "border-input dark:bg-input/30 w-full rounded-md border bg-transparent px-3 py-1 font-mono text-xs shadow-xs outline-none",
+                "focus-visible:border-ring focus-visible:ring-ring/50 focus-visible:ring-[3px]"
+              )}
+            />
+          )}
+          <div className="flex items-center gap-2">
+            <Input
+              value={rule.file ?? ""}
+              onChange={(e) =>
+                update(idx, {
+                  file: e.target.value || undefined,
+                  ...(e.target.value ? { body: undefined } : {}),
+                })
+              }
+              placeholder="or body file, e.g. mocks/payments.json"
+              className="h-7 flex-1 text-xs"
+            />
+            <Switch
+              checked={rule.template ?? false}
+              onCheckedChange={(v) => update(idx, { template: v || undefined })}
+            />
+            <Label className="text-xs">Template</Label>
+          </div>
+        </div>
+      ))}
+    </div>
+  );
+}
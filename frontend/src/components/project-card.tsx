@@ -13,8 +13,8 @@ import {
   TooltipTrigger,
 } from "@/components/ui/tooltip";
 import { ServiceRow } from "@/components/service-row";
-import type { Project, ServiceHealth } from "@/types";
-import { Copy, ExternalLink, Pencil, Trash2 } from "lucide-react";
+import type { Project, ServiceHealth, TunnelStatus } from "@/types";
+import { Copy, ExternalLink, Globe, Pencil, Trash2, X } from "lucide-react";
 import { cn } from "@/lib/utils";
 
 interface ProjectCardProps {
@@ -24,6 +24,9 @@ interface ProjectCardProps {
   onToggle: (name: string) => void;
   onEdit: (name: string) => void;
   onDelete: (name: string) => void;
+  tunnel?: TunnelStatus;
+  onStartTunnel: (name: string) => void;
+  onStopTunnel: (name: string) => void;
 }
 
 export function ProjectCard({
@@ -33,6 +36,9 @@ export function ProjectCard({
   onToggle,
   onEdit,
   onDelete,
+  tunnel,
+  onStartTunnel,
+  onStopTunnel,
 }: ProjectCardProps) {
   const url = `https://${project.domain}`;
 
@@ -57,6 +63,20 @@ export function ProjectCard({
         </CardTitle>
         <CardAction>
           <div className="flex gap-1">
+            {project.enabled && !tunnel && (
+              <Tooltip>
+                <TooltipTrigger asChild>
+                  <Button
+                    variant="ghost"
+                    size="icon-xs"
+                    onClick={() => onStartTunnel(name)}
+                  >
+                    <Globe />
+                  </Button>
+                </TooltipTrigger>
+                <TooltipContent>Share publicly</TooltipContent>
+              </Tooltip>
+            )}
             <Tooltip>
               <TooltipTrigger asChild>
                 <Button
@@ -104,6 +124,53 @@ export function ProjectCard({
             <TooltipContent>Open in browser</TooltipContent>
           </Tooltip>
         </div>
+        {(project.aliases?.length || project.wildcard) && (
+          <p className="text-xs text-text-muted truncate">
+            also{" "}
+            {[
+              ...(project.aliases ?? []),
+              ...(project.wildcard ? [`*.${project.domain}`] : []),
+            ].join(", ")}
+          </p>
+        )}
+        {tunnel?.url && (
+          <div className="flex items-center gap-2 text-sm">
+            <Globe
+              className={cn(
+                "size-3.5 shrink-0",
+                tunnel.connected ? "text-muted-teal" : "text-text-muted"
+              )}
+            />
+            <span className="truncate font-mono text-xs">{tunnel.url}</span>
+            {!tunnel.connected && (
+              <span className="text-xs text-text-muted">reconnecting…</span>
+            )}
+            <Tooltip>
+              <TooltipTrigger asChild>
+                <Button
+                  variant="ghost"
+                  size="icon-xs"
+                  onClick={() => navigator.clipboard.writeText(tunnel.url!)}
+                >
+                  <Copy />
+                </Button>
+              </TooltipTrigger>
+              <TooltipContent>Copy public URL</TooltipContent>
+            </Tooltip>
+            <Tooltip>
+              <TooltipTrigger asChild>
+                <Button
+                  variant="ghost"
+                  size="icon-xs"
+                  onClick={() => onStopTunnel(name)}
+                >
+                  <X />
+                </Button>
+              </TooltipTrigger>
+              <TooltipContent>Stop sharing</TooltipContent>
+            </Tooltip>
+          </div>
+        )}
         <p className="text-xs text-text-muted truncate">{project.path}</p>
         <div>
           {Object.entries(project.services).map(([svcName, svc]) => (
@@ -1,6 +1,6 @@
 import { ProjectCard } from "@/components/project-card";
 import { EmptyState } from "@/components/empty-state";
-import type { Project, ServiceHealth } from "@/types";
+import type { Project, ServiceHealth, TunnelStatus } from "@/types";
 
 interface ProjectListProps {
   projects: Record<string, Project>;
@@ -9,6 +9,9 @@ interface ProjectListProps {
   onEdit: (name: string) => void;
   onDelete: (name: string) => void;
   onAdd: () => void;
+  tunnels: Record<string, TunnelStatus>;
+  onStartTunnel: (name: string) => void;
+  onStopTunnel: (name: string) => void;
 }
 
 export function ProjectList({
@@ -18,6 +21,9 @@ export function ProjectList({
   onEdit,
   onDelete,
   onAdd,
+  tunnels,
+  onStartTunnel,
+  onStopTunnel,
 }: ProjectListProps) {
   const entries = Object.entries(projects);
 
@@ -36,6 +42,9 @@ export function ProjectList({
           onToggle={onToggle}
           onEdit={onEdit}
           onDelete={onDelete}
+          tunnel={tunnels[name]}
+          onStartTunnel={onStartTunnel}
+          onStopTunnel={onStopTunnel}
         />
       ))}
     </div>
@@ -1,6 +1,7 @@
 import { useMemo, useState } from "react";
 import { Button } from "@/components/ui/button";
 import { HealthDot } from "@/components/health-dot";
+import { proxyTargets } from "@/lib/utils";
 import type { Project, ServiceHealth } from "@/types";
 import { ChevronDown, ChevronRight, Map } from "lucide-react";
 
@@ -22,13 +23,14 @@ function buildRoutes(projects: Record<string, Project>): RouteEntry[] {
   for (const [name, proj] of Object.entries(projects)) {
     if (!proj.enabled) continue;
     for (const [svcName, svc] of Object.entries(proj.services)) {
-      const domain = svc.subdomain
+      const host = svc.subdomain
         ? `${svc.subdomain}.${proj.domain}`
         : proj.domain;
+      const tcp = svc.type === "tcp";
       routes.push({
-        domain,
-        route: svc.route || "/",
-        target: svc.proxy,
+        domain: tcp ? `${host}:${svc.listen}` : host,
+        route: tcp ? "tcp" : svc.route || "/",
+        target: proxyTargets(svc),
         project: name,
         service: svcName,
       });
@@ -1,5 +1,6 @@
 import { Badge } from "@/components/ui/badge";
 import { HealthDot } from "@/components/health-dot";
+import { proxyTargets } from "@/lib/utils";
 import type { Service, ServiceHealth } from "@/types";
 
 interface ServiceRowProps {
@@ -13,12 +14,33 @@ export function ServiceRow({ name, service, health }: ServiceRowProps) {
     <div className="flex items-center gap-2 text-sm py-1">
       <HealthDot health={health} />
       <span className="font-medium text-text-primary">{name}</span>
-      <span className="text-text-muted">{service.proxy}</span>
+      <span className="text-text-muted">{proxyTargets(service)}</span>
+      {service.type === "tcp" && (
+        <Badge variant="outline" className="text-xs">
+          tcp :{service.listen}
+          {service.tls ? ` · ${service.tls}` : ""}
+        </Badge>
+      )}
+      {service.root && (
+        <Badge variant="outline" className="text-xs">
+          {service.spa ? "static · spa" : "static"}
+        </Badge>
+      )}
+      {service.upstreams && service.upstreams.length > 1 && (
+        <Badge variant="outline" className="text-xs">
+          {service.lb_policy ?? "round_robin"}
+        </Badge>
+      )}
       {service.route && (
         <Badge variant="outline" className="text-xs">
           {service.route}
         </Badge>
       )}
+      {(service.strip_prefix || service.add_prefix || service.rewrite) && (
+        <Badge variant="outline" className="text-xs">
+          rewrite
+        </Badge>
+      )}
       {service.subdomain && (
         <Badge variant="secondary" className="text-xs">
           {service.subdomain}.*
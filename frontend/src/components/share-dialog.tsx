@@ -0,0 +1,119 @@
+import { useEffect, useState } from "react";
+import {
+  Dialog,
+  DialogContent,
+  DialogHeader,
+  DialogTitle,
+} from "@/components/ui/dialog";
+import { Label } from "@/components/ui/label";
+import { Switch } from "@/components/ui/switch";
+import { Separator } from "@/components/ui/separator";
+import { shareQRUrl } from "@/api";
+import { useShare } from "@/hooks/use-share";
+
+interface ShareDialogProps {
+  open: boolean;
+  onOpenChange: (open: boolean) => void;
+}
+
+export function ShareDialog({ open, onOpenChange }: ShareDialogProps) {
+  const { share, error, setEnabled } = useShare();
+  // The QR code shows the CA download until a project is picked.
+  const [qrTarget, setQrTarget] = useState<string | null>(null);
+
+  useEffect(() => {
+    if (!share?.active) setQrTarget(null);
+  }, [share?.active]);
+
+  const qrText = qrTarget ?? share?.ca_url;
+
+  return (
+    <Dialog open={open} onOpenChange={onOpenChange}>
+      <DialogContent className="sm:max-w-lg max-h-[80vh] overflow-y-auto">
+        <DialogHeader>
+          <DialogTitle className="text-muted-teal">
+            Share on local network
+          </DialogTitle>
+        </DialogHeader>
+
+        <div className="flex items-center justify-between">
+          <Label htmlFor="share-enabled">
+            Let phones and tablets on this network open your projects
+          </Label>
+          <Switch
+            id="share-enabled"
+            checked={share?.enabled ?? false}
+            disabled={!share}
+            onCheckedChange={setEnabled}
+          />
+        </div>
+
+        {error && <p className="text-sm text-destructive">{error}</p>}
+
+        {share?.enabled && !share.active && (
+          <p className="text-sm text-text-muted">
+            Starting… If this persists, check the logs: sharing needs a LAN
+            address and port 53 on it.
+          </p>
+        )}
+
+        {share?.active && (
+          <>
+            <Separator />
+            <div className="flex gap-4">
+              {qrText && (
+                <img
+                  src={shareQRUrl(qrText)}
+                  alt={`QR code for ${qrText}`}
+                  className="size-40 shrink-0 rounded-md border border-border bg-white p-2 [image-rendering:pixelated]"
+                />
+              )}
+              <ol className="list-decimal space-y-2 pl-4 text-sm">
+                <li>
+                  In the device's Wi-Fi settings, set the DNS server to{" "}
+                  <span className="font-mono">{share.lan_ip}</span>.
+                </li>
+                <li>
+                  Scan the code or open{" "}
+                  <button
+                    type="button"
+                    className="font-mono text-muted-teal underline"
+                    onClick={() => setQrTarget(null)}
+                  >
+                    {share.ca_url}
+                  </button>{" "}
+                  and install the certificate.
+                  <p className="mt-1 text-xs text-text-muted">
+                    iOS: Settings → General → VPN & Device Management to
+                    install, then Settings → General → About → Certificate
+                    Trust Settings to enable full trust.
+                  </p>
+                  <p className="mt-1 text-xs text-text-muted">
+                    Android: Settings → Security → Encryption & credentials →
+                    Install a certificate → CA certificate.
+                  </p>
+                </li>
+                <li>
+                  Open a project:
+                  <ul className="mt-1 space-y-1">
+                    {share.urls.map((u) => (
+                      <li key={u.project}>
+                        <button
+                          type="button"
+                          className="font-mono text-muted-teal underline"
+                          onClick={() => setQrTarget(u.url)}
+                        >
+                          {u.url}
+                        </button>
+                      </li>
+                    ))}
+                  </ul>
+                </li>
+              </ol>
+            </div>
+          </>
+        )}
+      </DialogContent>
+    </Dialog>
+  );
+}
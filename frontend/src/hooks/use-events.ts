@@ -0,0 +1,72 @@
+import { useEffect, useRef } from "react";
+import type { DaemonEvent } from "@/types";
+
+const SSE_URL = "http://127.0.0.1:42824/api/events";
+const RECONNECT_DELAY = 3000;
+
+// useEvents subscribes to the daemon's event stream and calls onEvent for
+// each event. The connection is re-established after failures.
+export function useEvents(onEvent: (event: DaemonEvent) => void) {
+  const handlerRef = useRef(onEvent);
+  handlerRef.current = onEvent;
+
+  useEffect(() => {
+    let cancelled = false;
+    let reconnectTimer: ReturnType<typeof setTimeout>;
+
+    async function connect() {
+      if (cancelled) return;
+
+      try {
+        const res = await fetch(SSE_URL);
+        if (cancelled) return;
+
+        if (!res.ok || !res.body) {
+          throw new Error("bad response");
+        }
+
+        const reader = res.body.getReader();
+        const decoder = new TextDecoder();
+        let buffer = "";
+
+        while (true) {
+          const { done, value } = await reader.read();
+          if (done || cancelled) break;
+
+          buffer += decoder.decode(value, { stream: true });
+          // Events are separated by a blank line.
+          const chunks = buffer.split("\n\n");
+          buffer = chunks.pop() ?? "";
+
+          for (const chunk of chunks) {
+            let type = "";
+            let data = "";
+            for (const line of chunk.split("\n")) {
+              if (line.startsWith("event: ")) type = line.slice(7);
+              else if (line.startsWith("data: ")) data = line.slice(6);
+            }
+            if (!type) continue;
+            try {
+              handlerRef.current({ type, data: data ? JSON.parse(data) : {} } as DaemonEvent);
+            } catch {
+              // ignore malformed events
+            }
+          }
+        }
+      } catch {
+        // connection failed or dropped
+      }
+
+      if (!cancelled) {
+        reconnectTimer = setTimeout(connect, RECONNECT_DELAY);
+      }
+    }
+
+    connect();
+
+    return () => {
+      cancelled = true;
+      clearTimeout(reconnectTimer);
+    };
+  }, []);
+}
@@ -1,5 +1,6 @@
 import { useCallback, useEffect, useRef, useState } from "react";
 import * as api from "@/api";
+import { useEvents } from "@/hooks/use-events";
 import type { ServiceHealth } from "@/types";
 
 export function useHealth() {
@@ -17,12 +18,19 @@ export function useHealth() {
 
   useEffect(() => {
     refresh();
-    intervalRef.current = setInterval(refresh, 10_000);
+    // Events drive updates; the slow poll only catches missed events.
+    intervalRef.current = setInterval(refresh, 60_000);
     return () => {
       if (intervalRef.current) clearInterval(intervalRef.current);
     };
   }, [refresh]);
 
+  useEvents((event) => {
+    if (event.type === "health.changed" || event.type === "config.reloaded") {
+      refresh();
+    }
+  });
+
   const lookup = useCallback(
     (project: string, service: string): ServiceHealth | undefined => {
       return health.find(
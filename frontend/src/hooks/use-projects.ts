@@ -1,5 +1,6 @@
 import { useCallback, useEffect, useState } from "react";
 import * as api from "@/api";
+import { useEvents } from "@/hooks/use-events";
 import type { Project } from "@/types";
 
 export function useProjects() {
@@ -23,6 +24,12 @@ export function useProjects() {
     refresh();
   }, [refresh]);
 
+  useEvents((event) => {
+    if (event.type.startsWith("project.") || event.type === "config.reloaded") {
+      refresh();
+    }
+  });
+
   const add = useCallback(
     async (name: string, project: Project) => {
       await api.addProject(name, project);
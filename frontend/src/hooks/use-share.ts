@@ -0,0 +1,43 @@
+import { useCallback, useEffect, useState } from "react";
+import * as api from "@/api";
+import { useEvents } from "@/hooks/use-events";
+import type { ShareInfo } from "@/types";
+
+export function useShare() {
+  const [share, setShareInfo] = useState<ShareInfo | null>(null);
+  const [error, setError] = useState<string | null>(null);
+
+  const refresh = useCallback(async () => {
+    try {
+      setShareInfo(await api.getShare());
+      setError(null);
+    } catch (err) {
+      setError(err instanceof Error ? err.message : "Failed to load sharing");
+    }
+  }, []);
+
+  useEffect(() => {
+    refresh();
+  }, [refresh]);
+
+  // The daemon starts or stops sharing when it reloads the config.
+  useEvents((event) => {
+    if (event.type === "config.reloaded") {
+      refresh();
+    }
+  });
+
+  const setEnabled = useCallback(
+    async (enabled: boolean) => {
+      try {
+        await api.setShare(enabled);
+        setShareInfo((s) => (s ? { ...s, enabled } : s));
+      } catch (err) {
+        setError(err instanceof Error ? err.message : "Failed to update sharing");
+      }
+    },
+    []
+  );
+
+  return { share, error, setEnabled };
+}
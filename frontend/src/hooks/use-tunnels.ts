@@ -0,0 +1,57 @@
+import { useCallback, useEffect, useState } from "react";
+import * as api from "@/api";
+import { useEvents } from "@/hooks/use-events";
+import type { TunnelStatus } from "@/types";
+
+// useTunnels tracks the daemon's public tunnels, keyed by project.
+export function useTunnels() {
+  const [tunnels, setTunnels] = useState<Record<string, TunnelStatus>>({});
+  const [error, setError] = useState<string | null>(null);
+
+  const refresh = useCallback(async () => {
+    try {
+      const list = await api.getTunnels();
+      setTunnels(Object.fromEntries((list ?? []).map((t) => [t.project, t])));
+    } catch {
+      // the daemon may be down; keep the last known state
+    }
+  }, []);
+
+  useEffect(() => {
+    refresh();
+  }, [refresh]);
+
+  useEvents((event) => {
+    if (event.type === "tunnel.changed") {
+      refresh();
+    }
+  });
+
+  const start = useCallback(
+    async (project: string) => {
+      try {
+        setError(null);
+        const status = await api.startTunnel(project);
+        setTunnels((t) => ({ ...t, [project]: status }));
+      } catch (err) {
+        setError(err instanceof Error ? err.message : "Failed to share project");
+      }
+    },
+    []
+  );
+
+  const stop = useCallback(
+    async (project: string) => {
+      try {
+        setError(null);
+        await api.stopTunnel(project);
+        await refresh();
+      } catch (err) {
+        setError(err instanceof Error ? err.message : "Failed to stop sharing");
+      }
+    },
+    [refresh]
+  );
+
+  return { tunnels, error, start, stop };
+}
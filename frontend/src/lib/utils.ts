@@ -4,3 +4,16 @@ import { twMerge } from "tailwind-merge";
 export function cn(...inputs: ClassValue[]) {
   return twMerge(clsx(inputs));
 }
+
+// proxyTargets returns a service's upstream URLs, the root directory of a
+// static service or the rule count of a mock, as a display string.
+export function proxyTargets(svc: {
+  proxy?: string;
+  upstreams?: string[];
+  root?: string;
+  mock?: unknown[];
+}): string {
+  if (svc.mock?.length) return `mock (${svc.mock.length} rules)`;
+  if (svc.root) return svc.root;
+  return svc.upstreams?.length ? svc.upstreams.join(", ") : (svc.proxy ?? "");
+}
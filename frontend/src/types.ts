@@ -1,32 +1,204 @@
+export type LBPolicy = "round_robin" | "least_conn" | "ip_hash" | "first";
+
+export type ServiceType = "http" | "tcp";
+
+export type TCPTLSMode = "passthrough" | "terminate";
+
 export interface Service {
-  proxy: string;
+  type?: ServiceType;
+  listen?: number;
+  tls?: TCPTLSMode;
+  proxy?: string;
+  upstreams?: string[];
+  lb_policy?: LBPolicy;
+  root?: string;
+  spa?: boolean;
+  browse?: boolean;
+  health_check?: HealthCheck;
   route?: string;
   subdomain?: string;
   websocket?: boolean;
+  // Hold requests while the service is unhealthy and deliver them on recovery.
+  buffer_when_down?: boolean;
+  // Faults injected to test loading states and retries.
+  chaos?: Chaos;
+  // Canned responses served instead of a backend, first match wins.
+  mock?: MockRule[];
+  // Dev server the daemon runs with "hatch start".
+  command?: string;
+  cwd?: string;
+  env?: Record<string, string>;
+  headers?: ServiceHeaders;
+  strip_prefix?: string;
+  rewrite?: { regex: string; replace: string };
+  add_prefix?: string;
+}
+
+export interface Chaos {
+  latency?: string;
+  jitter?: string;
+  error_rate?: number;
+  error_status?: number;
+  bandwidth_kbps?: number;
+}
+
+export interface MockRule {
+  // Empty matches any method.
+  method?: string;
+  // {name} matches one segment, a final * the rest.
+  path: string;
+  status?: number;
+  headers?: Record<string, string>;
+  body?: string;
+  // Relative to the project path, read on every request.
+  file?: string;
+  // Render the body as a Go template with .Params, .Query, .Method and .Path.
+  template?: boolean;
+}
+
+export interface HeaderOps {
+  set?: Record<string, string>;
+  add?: Record<string, string>;
+  delete?: string[];
+}
+
+export interface ServiceHeaders {
+  request?: HeaderOps;
+  response?: HeaderOps;
 }
 
 export interface Project {
   domain: string;
+  aliases?: string[];
+  wildcard?: boolean;
   path: string;
   enabled: boolean;
+  // Record traffic for "hatch requests".
+  capture?: boolean;
+  // HTML template shown when a service's upstream is unreachable.
+  error_page?: string;
+  // Who may reach the project; set passwords with "hatch passwd".
+  auth?: Auth;
   services: Record<string, Service>;
 }
 
+export interface Auth {
+  // User names mapped to bcrypt hashes.
+  basic?: { users: Record<string, string> };
+  // IPs or CIDR ranges; loopback is always allowed.
+  allow_ips?: string[];
+}
+
 export interface DaemonStatus {
   pid: number;
   uptime: string;
   version: string;
 }
 
+export interface ShareInfo {
+  enabled: boolean;
+  // False when sharing is enabled but the daemon has no LAN address yet.
+  active: boolean;
+  lan_ip?: string;
+  ca_url?: string;
+  urls: { project: string; url: string }[];
+}
+
+export interface TunnelStatus {
+  project: string;
+  domain: string;
+  // Public URL on the tunnel relay.
+  url?: string;
+  connected: boolean;
+  error?: string;
+}
+
+export interface ProcessStatus {
+  project: string;
+  service: string;
+  command: string;
+  state: "starting" | "running" | "restarting" | "stopped";
+  pid?: number;
+  started: string;
+  restarts: number;
+  // How the last run ended.
+  exit?: string;
+  log: string;
+}
+
 export interface ServiceHealth {
   project: string;
   service: string;
   status: "healthy" | "unhealthy" | "unknown";
   addr: string;
+  upstreams: UpstreamHealth[];
+  status_code?: number;
+  latency_ms: number;
+  error?: string;
   since: string;
   last_check: string;
 }
 
+export interface UpstreamHealth {
+  addr: string;
+  status: "healthy" | "unhealthy" | "unknown";
+  status_code?: number;
+  latency_ms: number;
+  error?: string;
+  since: string;
+}
+
+export interface HealthCheckResult {
+  time: string;
+  status: "healthy" | "unhealthy" | "unknown";
+  status_code?: number;
+  latency_ms: number;
+  error?: string;
+}
+
+export interface HealthCheck {
+  path: string;
+  method?: string;
+  expect_status?: number;
+  expect_body?: string;
+  interval?: string;
+  timeout?: string;
+}
+
+export type DaemonEvent =
+  | {
+      type: "health.changed";
+      data: { project: string; service: string; from: string; to: string };
+    }
+  | { type: "config.reloaded"; data: { projects: number } }
+  | {
+      type:
+        | "project.added"
+        | "project.removed"
+        | "project.toggled"
+        | "project.updated";
+      data: { project: string; enabled: boolean };
+    }
+  | { type: "caddy.load_failed"; data: { error: string } }
+  | {
+      type: "tunnel.changed";
+      data: { project: string; url?: string; connected: boolean; error?: string };
+    }
+  | {
+      type: "process.changed";
+      data: {
+        project: string;
+        service: string;
+        state: ProcessStatus["state"];
+        pid?: number;
+        exit?: string;
+      };
+    }
+  | {
+      type: "chaos.changed";
+      data: { project: string; service: string; active: boolean; expires?: string };
+    };
+
 export interface LogEntry {
   id: number;
   timestamp: string;
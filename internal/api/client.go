@@ -0,0 +1,285 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/paulrose/hatch/internal/chaos"
+	"github.com/paulrose/hatch/internal/config"
+	"github.com/paulrose/hatch/internal/inspect"
+	"github.com/paulrose/hatch/internal/process"
+	"github.com/paulrose/hatch/internal/share"
+	"github.com/paulrose/hatch/internal/tunnel"
+)
+
+// Client talks to a running daemon's API server. It is used by CLI
+// commands that need state only the daemon holds.
+type Client struct {
+	Addr       string
+	HTTPClient *http.Client
+}
+
+// NewClient returns a Client configured with the default API address.
+func NewClient() *Client {
+	return &Client{
+		Addr:       DefaultAddr,
+		HTTPClient: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// HealthHistory returns the recent health check results for a service,
+// oldest first. A positive limit returns at most that many of the most
+// recent results.
+func (c *Client) HealthHistory(ctx context.Context, project, service string, limit int) ([]HealthCheckResult, error) {
+	path := fmt.Sprintf("/api/health/%s/%s/history", url.PathEscape(project), url.PathEscape(service))
+	if limit > 0 {
+		path += fmt.Sprintf("?limit=%d", limit)
+	}
+
+	var out []HealthCheckResult
+	if err := c.get(ctx, path, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Chaos returns the runtime fault injection overrides in effect.
+func (c *Client) Chaos(ctx context.Context) ([]chaos.Override, error) {
+	var out []chaos.Override
+	if err := c.get(ctx, "/api/chaos", &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// SetChaos injects the faults ch describes into a service for d, or until
+// cleared with d zero.
+func (c *Client) SetChaos(ctx context.Context, project, service string, ch config.Chaos, d time.Duration) (chaos.Override, error) {
+	req := ChaosRequest{Chaos: ch}
+	if d > 0 {
+		req.For = d.String()
+	}
+	var out chaos.Override
+	if err := c.do(ctx, http.MethodPut, chaosPath(project, service), req, http.StatusOK, &out); err != nil {
+		return chaos.Override{}, err
+	}
+	return out, nil
+}
+
+// ClearChaos removes a service's runtime fault injection.
+func (c *Client) ClearChaos(ctx context.Context, project, service string) error {
+	return c.do(ctx, http.MethodDelete, chaosPath(project, service), nil, http.StatusNoContent, nil)
+}
+
+func chaosPath(project, service string) string {
+	return "/api/chaos/" + url.PathEscape(project) + "/" + url.PathEscape(service)
+}
+
+// DiscoveredProjects returns the projects the daemon discovered from
+// Docker containers.
+func (c *Client) DiscoveredProjects(ctx context.Context) (map[string]config.Project, error) {
+	var out map[string]config.Project
+	if err := c.get(ctx, "/api/docker/projects", &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Share returns the daemon's LAN sharing details.
+func (c *Client) Share(ctx context.Context) (share.Info, error) {
+	var out share.Info
+	if err := c.get(ctx, "/api/share", &out); err != nil {
+		return share.Info{}, err
+	}
+	return out, nil
+}
+
+// Tunnels returns the daemon's open public tunnels.
+func (c *Client) Tunnels(ctx context.Context) ([]tunnel.Status, error) {
+	var out []tunnel.Status
+	if err := c.get(ctx, "/api/tunnels", &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// StartTunnel shares a project through the tunnel relay and returns its
+// status, including the public URL.
+func (c *Client) StartTunnel(ctx context.Context, project string) (tunnel.Status, error) {
+	var out tunnel.Status
+	if err := c.do(ctx, http.MethodPost, "/api/tunnels/"+url.PathEscape(project), nil, http.StatusOK, &out); err != nil {
+		return tunnel.Status{}, err
+	}
+	return out, nil
+}
+
+// StopTunnel stops sharing a project through the tunnel relay.
+func (c *Client) StopTunnel(ctx context.Context, project string) error {
+	return c.do(ctx, http.MethodDelete, "/api/tunnels/"+url.PathEscape(project), nil, http.StatusNoContent, nil)
+}
+
+// Processes returns the status of every started service command.
+func (c *Client) Processes(ctx context.Context) ([]process.Status, error) {
+	var out []process.Status
+	if err := c.get(ctx, "/api/processes", &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// StartProcesses starts the commands of a project's services, or of one
+// service when service is non-empty, and returns their statuses. With
+// restart, commands already running are restarted.
+func (c *Client) StartProcesses(ctx context.Context, project, service string, restart bool) ([]process.Status, error) {
+	path := processPath(project, service)
+	if restart {
+		path += "?restart=true"
+	}
+	var out []process.Status
+	if err := c.do(ctx, http.MethodPost, path, nil, http.StatusOK, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// StopProcesses stops the commands of a project's services, or of one
+// service when service is non-empty.
+func (c *Client) StopProcesses(ctx context.Context, project, service string) error {
+	return c.do(ctx, http.MethodDelete, processPath(project, service), nil, http.StatusNoContent, nil)
+}
+
+func processPath(project, service string) string {
+	path := "/api/processes/" + url.PathEscape(project)
+	if service != "" {
+		path += "/" + url.PathEscape(service)
+	}
+	return path
+}
+
+// Requests returns the captured requests matching f, newest first.
+func (c *Client) Requests(ctx context.Context, f inspect.Filter) ([]inspect.Record, error) {
+	q := url.Values{}
+	if f.Project != "" {
+		q.Set("project", f.Project)
+	}
+	if f.Method != "" {
+		q.Set("method", f.Method)
+	}
+	if status := f.Status.String(); status != "" {
+		q.Set("status", status)
+	}
+	if f.Limit > 0 {
+		q.Set("limit", strconv.Itoa(f.Limit))
+	}
+
+	var out []inspect.Record
+	if err := c.get(ctx, "/api/requests?"+q.Encode(), &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Request returns a single captured request.
+func (c *Client) Request(ctx context.Context, id uint64) (inspect.Record, error) {
+	var out inspect.Record
+	if err := c.get(ctx, fmt.Sprintf("/api/requests/%d", id), &out); err != nil {
+		return inspect.Record{}, err
+	}
+	return out, nil
+}
+
+// ClearRequests discards all captured requests.
+func (c *Client) ClearRequests(ctx context.Context) error {
+	return c.do(ctx, http.MethodDelete, "/api/requests", nil, http.StatusNoContent, nil)
+}
+
+// Replay re-sends a captured request, with edit applied, and returns it
+// alongside the original and a diff of their responses.
+func (c *Client) Replay(ctx context.Context, id uint64, edit inspect.Edit) (inspect.ReplayResult, error) {
+	var out inspect.ReplayResult
+	if err := c.do(ctx, http.MethodPost, fmt.Sprintf("/api/requests/%d/replay", id), edit, http.StatusOK, &out); err != nil {
+		return inspect.ReplayResult{}, err
+	}
+	return out, nil
+}
+
+// get issues a GET request for path and decodes the JSON response into v.
+func (c *Client) get(ctx context.Context, path string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://"+c.Addr+path, nil)
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("contacting daemon: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return decodeError(resp)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("decoding response: %w", err)
+	}
+	return nil
+}
+
+// do issues a request with body, if non-nil, encoded as JSON and, when v
+// is non-nil, decodes the JSON response into it. Any status other than
+// want is an error.
+func (c *Client) do(ctx context.Context, method, path string, body any, want int, v any) error {
+	var r io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("encoding request: %w", err)
+		}
+		r = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, "http://"+c.Addr+path, r)
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("contacting daemon: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != want {
+		return decodeError(resp)
+	}
+	if v == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("decoding response: %w", err)
+	}
+	return nil
+}
+
+// decodeError turns a non-2xx API response into an error, using the
+// {"error": "..."} body written by writeError when present.
+func decodeError(resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 10*1024))
+	var apiErr struct {
+		Error string `json:"error"`
+	}
+	if json.Unmarshal(body, &apiErr) == nil && apiErr.Error != "" {
+		return fmt.Errorf("daemon API (HTTP %d): %s", resp.StatusCode, apiErr.Error)
+	}
+	return fmt.Errorf("daemon API (HTTP %d): %s", resp.StatusCode, strings.TrimSpace(string(body)))
+}
@@ -2,16 +2,22 @@ package api
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
+	"strconv"
 	"time"
 
 	"gopkg.in/yaml.v3"
 
 	"github.com/paulrose/hatch/internal/config"
+	"github.com/paulrose/hatch/internal/health"
+	"github.com/paulrose/hatch/internal/inspect"
+	"github.com/paulrose/hatch/internal/share"
 )
 
 // maxBodySize is the maximum allowed request body (1 MB).
@@ -83,6 +89,10 @@ func (s *Server) handleListProjects(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, projects)
 }
 
+func (s *Server) handleDiscoveredProjects(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.daemon.Discovered())
+}
+
 func (s *Server) handleAddProject(w http.ResponseWriter, r *http.Request) {
 	limitBody(r, w)
 
@@ -218,29 +228,105 @@ func (s *Server) handleToggleProject(w http.ResponseWriter, r *http.Request) {
 func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
 	statuses := s.health.ServiceStatuses()
 
+	type upstreamHealth struct {
+		Addr       string  `json:"addr"`
+		Status     string  `json:"status"`
+		StatusCode int     `json:"status_code,omitempty"`
+		LatencyMS  float64 `json:"latency_ms"`
+		Error      string  `json:"error,omitempty"`
+		Since      string  `json:"since"`
+	}
+
 	type serviceHealth struct {
-		Project   string `json:"project"`
-		Service   string `json:"service"`
-		Status    string `json:"status"`
-		Addr      string `json:"addr"`
-		Since     string `json:"since"`
-		LastCheck string `json:"last_check"`
+		Project    string           `json:"project"`
+		Service    string           `json:"service"`
+		Status     string           `json:"status"`
+		Addr       string           `json:"addr"`
+		Upstreams  []upstreamHealth `json:"upstreams"`
+		StatusCode int              `json:"status_code,omitempty"`
+		LatencyMS  float64          `json:"latency_ms"`
+		Error      string           `json:"error,omitempty"`
+		Since      string           `json:"since"`
+		LastCheck  string           `json:"last_check"`
 	}
 
 	result := make([]serviceHealth, 0, len(statuses))
 	for key, st := range statuses {
+		upstreams := make([]upstreamHealth, 0, len(st.Upstreams))
+		for _, u := range st.Upstreams {
+			upstreams = append(upstreams, upstreamHealth{
+				Addr:       u.Addr,
+				Status:     u.Status.String(),
+				StatusCode: u.StatusCode,
+				LatencyMS:  durationMS(u.Latency),
+				Error:      u.Error,
+				Since:      u.Since.Format(time.RFC3339),
+			})
+		}
 		result = append(result, serviceHealth{
-			Project:   key.Project,
-			Service:   key.Service,
-			Status:    st.Status.String(),
-			Addr:      st.Addr,
-			Since:     st.Since.Format(time.RFC3339),
-			LastCheck: st.LastCheck.Format(time.RFC3339),
+			Project:    key.Project,
+			Service:    key.Service,
+			Status:     st.Status.String(),
+			Addr:       st.Addr,
+			Upstreams:  upstreams,
+			StatusCode: st.StatusCode,
+			LatencyMS:  durationMS(st.Latency),
+			Error:      st.Error,
+			Since:      st.Since.Format(time.RFC3339),
+			LastCheck:  st.LastCheck.Format(time.RFC3339),
 		})
 	}
 	writeJSON(w, http.StatusOK, result)
 }
 
+// HealthCheckResult is a single entry of a service's health history as
+// returned by GET /api/health/{project}/{service}/history.
+type HealthCheckResult struct {
+	Time       time.Time `json:"time"`
+	Status     string    `json:"status"`
+	StatusCode int       `json:"status_code,omitempty"`
+	LatencyMS  float64   `json:"latency_ms"`
+	Error      string    `json:"error,omitempty"`
+}
+
+func (s *Server) handleHealthHistory(w http.ResponseWriter, r *http.Request) {
+	key := health.ServiceKey{Project: r.PathValue("project"), Service: r.PathValue("service")}
+
+	results, ok := s.health.History(key)
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("service %s/%s not found", key.Project, key.Service))
+		return
+	}
+
+	if v := r.URL.Query().Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 {
+			writeError(w, http.StatusBadRequest, "limit must be a positive integer")
+			return
+		}
+		if limit < len(results) {
+			results = results[len(results)-limit:]
+		}
+	}
+
+	out := make([]HealthCheckResult, 0, len(results))
+	for _, res := range results {
+		out = append(out, HealthCheckResult{
+			Time:       res.Time,
+			Status:     res.Status.String(),
+			StatusCode: res.StatusCode,
+			LatencyMS:  durationMS(res.Latency),
+			Error:      res.Error,
+		})
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+// durationMS converts d to fractional milliseconds for JSON responses.
+func durationMS(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
 func (s *Server) handleLogs(w http.ResponseWriter, r *http.Request) {
 	flusher, ok := w.(http.Flusher)
 	if !ok {
@@ -267,6 +353,38 @@ func (s *Server) handleLogs(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// handleEvents streams daemon events as SSE, using the event type as the
+// SSE event name and the JSON-encoded payload as its data.
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "streaming not supported")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	flusher.Flush()
+
+	ch, cleanup := s.events.Subscribe()
+	defer cleanup()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case ev := <-ch:
+			data, err := json.Marshal(ev.Data)
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", ev.Type, data)
+			flusher.Flush()
+		}
+	}
+}
+
 func (s *Server) handleGetConfig(w http.ResponseWriter, r *http.Request) {
 	data, err := os.ReadFile(config.ConfigFile())
 	if err != nil {
@@ -312,3 +430,320 @@ func (s *Server) handleRestart(w http.ResponseWriter, r *http.Request) {
 	}
 	writeJSON(w, http.StatusOK, map[string]string{"status": "reloaded"})
 }
+
+func (s *Server) handleGetShare(w http.ResponseWriter, r *http.Request) {
+	cfg, err := config.Load()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to load config")
+		return
+	}
+	writeJSON(w, http.StatusOK, share.NewInfo(cfg, s.daemon.SharingAddr()))
+}
+
+func (s *Server) handleSetShare(w http.ResponseWriter, r *http.Request) {
+	limitBody(r, w)
+
+	var req struct {
+		Enabled bool `json:"enabled"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+
+	s.cfgMu.Lock()
+	defer s.cfgMu.Unlock()
+
+	cfg, err := config.Load()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to load config")
+		return
+	}
+
+	// The config watcher picks up the change and starts or stops sharing.
+	cfg.Settings.LANSharing = req.Enabled
+	if err := config.Save(cfg); err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to save config")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]bool{"enabled": req.Enabled})
+}
+
+// maxQRText bounds the text accepted by handleShareQR; a URL for a phone
+// to open never needs more.
+const maxQRText = 1024
+
+func (s *Server) handleShareQR(w http.ResponseWriter, r *http.Request) {
+	text := r.URL.Query().Get("text")
+	if text == "" || len(text) > maxQRText {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("text must be 1-%d bytes", maxQRText))
+		return
+	}
+
+	code, err := share.QRCode(text)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("Cache-Control", "max-age=3600")
+	w.Write(code.PNG())
+}
+
+func (s *Server) handleListTunnels(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.daemon.Tunnels())
+}
+
+// tunnelStartTimeout bounds connecting to the relay when starting a tunnel.
+const tunnelStartTimeout = 15 * time.Second
+
+func (s *Server) handleStartTunnel(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("project")
+
+	cfg, err := config.Load()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to load config")
+		return
+	}
+	proj, exists := cfg.Projects[name]
+	if !exists {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("project %q not found", name))
+		return
+	}
+	if !proj.Enabled {
+		writeError(w, http.StatusConflict, fmt.Sprintf("project %q is disabled", name))
+		return
+	}
+	if cfg.Settings.Tunnel == nil {
+		writeError(w, http.StatusConflict, "no tunnel relay configured: set settings.tunnel.relay in the config")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), tunnelStartTimeout)
+	defer cancel()
+	st, err := s.daemon.StartTunnel(ctx, name)
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, st)
+}
+
+func (s *Server) handleStopTunnel(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("project")
+	if !s.daemon.StopTunnel(name) {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("project %q is not shared", name))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleListProcesses(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.daemon.Processes())
+}
+
+func (s *Server) handleStartProcesses(w http.ResponseWriter, r *http.Request) {
+	name, service := r.PathValue("project"), r.PathValue("service")
+
+	cfg, err := config.Load()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to load config")
+		return
+	}
+	proj, exists := cfg.Projects[name]
+	if !exists {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("project %q not found", name))
+		return
+	}
+	if _, exists := proj.Services[service]; service != "" && !exists {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("service %s/%s not found", name, service))
+		return
+	}
+
+	restart, _ := strconv.ParseBool(r.URL.Query().Get("restart"))
+	statuses, err := s.daemon.StartProcesses(name, service, restart)
+	if err != nil {
+		writeError(w, http.StatusConflict, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, statuses)
+}
+
+func (s *Server) handleStopProcesses(w http.ResponseWriter, r *http.Request) {
+	name, service := r.PathValue("project"), r.PathValue("service")
+	target := name
+	if service != "" {
+		target += "/" + service
+	}
+	if s.daemon.StopProcesses(name, service) == 0 {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("%s is not running", target))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ChaosRequest is the body of PUT /api/chaos/{project}/{service}: the
+// faults to inject and, as a duration, for how long. Without For they
+// last until cleared.
+type ChaosRequest struct {
+	config.Chaos
+	For string `json:"for,omitempty"`
+}
+
+func (s *Server) handleListChaos(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.daemon.Chaos())
+}
+
+func (s *Server) handleSetChaos(w http.ResponseWriter, r *http.Request) {
+	name, service := r.PathValue("project"), r.PathValue("service")
+	limitBody(r, w)
+
+	var req ChaosRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+	if req.Chaos == (config.Chaos{}) {
+		writeError(w, http.StatusBadRequest, "no faults given")
+		return
+	}
+	var dur time.Duration
+	if req.For != "" {
+		d, err := time.ParseDuration(req.For)
+		if err != nil || d <= 0 {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("for %q must be a positive duration", req.For))
+			return
+		}
+		dur = d
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to load config")
+		return
+	}
+	proj, exists := cfg.Projects[name]
+	if !exists {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("project %q not found", name))
+		return
+	}
+	svc, exists := proj.Services[service]
+	if !exists {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("service %s/%s not found", name, service))
+		return
+	}
+
+	// The loaded config is valid, so any error is in the requested faults.
+	svc.Chaos = &req.Chaos
+	proj.Services[service] = svc
+	if errs := config.Validate(cfg); len(errs) > 0 {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid chaos: %v", errs))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, s.daemon.SetChaos(name, service, req.Chaos, dur))
+}
+
+func (s *Server) handleClearChaos(w http.ResponseWriter, r *http.Request) {
+	name, service := r.PathValue("project"), r.PathValue("service")
+	if !s.daemon.ClearChaos(name, service) {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("no chaos set for %s/%s", name, service))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// requestFilter reads an inspect.Filter from the project, method, status
+// and limit query parameters.
+func requestFilter(r *http.Request) (inspect.Filter, error) {
+	q := r.URL.Query()
+	f := inspect.Filter{Project: q.Get("project"), Method: q.Get("method")}
+	var err error
+	if f.Status, err = inspect.ParseStatusFilter(q.Get("status")); err != nil {
+		return f, err
+	}
+	if v := q.Get("limit"); v != "" {
+		if f.Limit, err = strconv.Atoi(v); err != nil || f.Limit < 0 {
+			return f, fmt.Errorf("limit must be a non-negative integer, got %q", v)
+		}
+	}
+	return f, nil
+}
+
+func (s *Server) handleListRequests(w http.ResponseWriter, r *http.Request) {
+	f, err := requestFilter(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, s.requests.List(f))
+}
+
+// capturedRequest looks up the record named by the id path value,
+// writing an error response if there is none.
+func (s *Server) capturedRequest(w http.ResponseWriter, r *http.Request) (inspect.Record, bool) {
+	id, err := strconv.ParseUint(r.PathValue("id"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "id must be a positive integer")
+		return inspect.Record{}, false
+	}
+	rec, ok := s.requests.Get(id)
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("request %d not found", id))
+	}
+	return rec, ok
+}
+
+func (s *Server) handleGetRequest(w http.ResponseWriter, r *http.Request) {
+	if rec, ok := s.capturedRequest(w, r); ok {
+		writeJSON(w, http.StatusOK, rec)
+	}
+}
+
+// replayTimeout bounds a replayed request, including reading its response.
+const replayTimeout = 30 * time.Second
+
+func (s *Server) handleReplayRequest(w http.ResponseWriter, r *http.Request) {
+	rec, ok := s.capturedRequest(w, r)
+	if !ok {
+		return
+	}
+	limitBody(r, w)
+	var edit inspect.Edit
+	if err := json.NewDecoder(r.Body).Decode(&edit); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), replayTimeout)
+	defer cancel()
+	replay, err := s.daemon.Replay(ctx, rec, edit)
+	switch {
+	case errors.Is(err, inspect.ErrTruncatedBody):
+		writeError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	case err != nil:
+		writeError(w, http.StatusBadGateway, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, inspect.ReplayResult{
+		Original: rec,
+		Replay:   replay,
+		Diff:     inspect.DiffResponses(rec, replay),
+	})
+}
+
+func (s *Server) handleRequestsHAR(w http.ResponseWriter, r *http.Request) {
+	f, err := requestFilter(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	w.Header().Set("Content-Disposition", `attachment; filename="hatch.har"`)
+	writeJSON(w, http.StatusOK, inspect.NewHAR(s.requests.List(f), s.version))
+}
+
+func (s *Server) handleClearRequests(w http.ResponseWriter, r *http.Request) {
+	s.requests.Clear()
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -5,17 +5,56 @@ import (
 	"fmt"
 	"net"
 	"net/http"
+	"strconv"
 	"sync"
 	"time"
 
 	"github.com/rs/zerolog/log"
 
+	"github.com/paulrose/hatch/internal/chaos"
+	"github.com/paulrose/hatch/internal/config"
+	"github.com/paulrose/hatch/internal/events"
 	"github.com/paulrose/hatch/internal/health"
+	"github.com/paulrose/hatch/internal/inspect"
+	"github.com/paulrose/hatch/internal/process"
+	"github.com/paulrose/hatch/internal/tunnel"
 )
 
-// DaemonControl allows the API to trigger daemon operations.
+// DefaultAddr is the address the daemon's API server listens on.
+var DefaultAddr = net.JoinHostPort("127.0.0.1", strconv.Itoa(config.APIPort))
+
+// DaemonControl allows the API to trigger daemon operations and read
+// daemon state.
 type DaemonControl interface {
 	ReloadConfig() error
+	// SharingAddr returns the LAN address while LAN sharing is on, else nil.
+	SharingAddr() net.IP
+	// Tunnels returns the status of every open public tunnel.
+	Tunnels() []tunnel.Status
+	// StartTunnel shares a project through the tunnel relay.
+	StartTunnel(ctx context.Context, project string) (tunnel.Status, error)
+	// StopTunnel stops sharing a project, reporting whether it was shared.
+	StopTunnel(project string) bool
+	// Replay re-sends a captured request through the project's route.
+	Replay(ctx context.Context, rec inspect.Record, edit inspect.Edit) (inspect.Record, error)
+	// Processes returns the status of every started service command.
+	Processes() []process.Status
+	// StartProcesses starts the commands of a project's services, or of
+	// one service, restarting running ones with restart.
+	StartProcesses(project, service string, restart bool) ([]process.Status, error)
+	// StopProcesses stops the commands of a project's services, or of one
+	// service, returning how many were running.
+	StopProcesses(project, service string) int
+	// Chaos returns the runtime fault injection overrides in effect.
+	Chaos() []chaos.Override
+	// SetChaos injects faults into a service for d, or until cleared
+	// with d zero.
+	SetChaos(project, service string, c config.Chaos, d time.Duration) chaos.Override
+	// ClearChaos removes a service's runtime override, reporting whether
+	// it had one.
+	ClearChaos(project, service string) bool
+	// Discovered returns the projects discovered from Docker containers.
+	Discovered() map[string]config.Project
 }
 
 // Server is the HTTP API server for the Hatch dashboard.
@@ -26,6 +65,8 @@ type Server struct {
 	version   string
 	startTime time.Time
 	logHub    *LogHub
+	events    *events.Bus
+	requests  *inspect.Buffer
 	cfgMu     sync.Mutex // serializes config read-modify-write operations
 }
 
@@ -37,6 +78,8 @@ type ServerConfig struct {
 	Version   string
 	StartTime time.Time
 	LogHub    *LogHub
+	Events    *events.Bus
+	Requests  *inspect.Buffer // captured traffic
 }
 
 // NewServer creates a new API server with the given configuration.
@@ -47,6 +90,8 @@ func NewServer(cfg ServerConfig) *Server {
 		version:   cfg.Version,
 		startTime: cfg.StartTime,
 		logHub:    cfg.LogHub,
+		events:    cfg.Events,
+		requests:  cfg.Requests,
 	}
 
 	mux := http.NewServeMux()
@@ -90,9 +135,31 @@ func (s *Server) registerRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("PUT /api/projects/{name}", requireJSON(s.handleUpdateProject))
 	mux.HandleFunc("DELETE /api/projects/{name}", s.handleDeleteProject)
 	mux.HandleFunc("PATCH /api/projects/{name}/toggle", s.handleToggleProject)
+	mux.HandleFunc("GET /api/chaos", s.handleListChaos)
+	mux.HandleFunc("PUT /api/chaos/{project}/{service}", requireJSON(s.handleSetChaos))
+	mux.HandleFunc("DELETE /api/chaos/{project}/{service}", s.handleClearChaos)
+	mux.HandleFunc("GET /api/docker/projects", s.handleDiscoveredProjects)
 	mux.HandleFunc("GET /api/health", s.handleHealth)
+	mux.HandleFunc("GET /api/health/{project}/{service}/history", s.handleHealthHistory)
 	mux.HandleFunc("GET /api/logs", s.handleLogs)
+	mux.HandleFunc("GET /api/events", s.handleEvents)
 	mux.HandleFunc("GET /api/config", s.handleGetConfig)
 	mux.HandleFunc("PUT /api/config", s.handlePutConfig)
 	mux.HandleFunc("POST /api/restart", s.handleRestart)
+	mux.HandleFunc("GET /api/share", s.handleGetShare)
+	mux.HandleFunc("PUT /api/share", requireJSON(s.handleSetShare))
+	mux.HandleFunc("GET /api/share/qr", s.handleShareQR)
+	mux.HandleFunc("GET /api/tunnels", s.handleListTunnels)
+	mux.HandleFunc("POST /api/tunnels/{project}", s.handleStartTunnel)
+	mux.HandleFunc("DELETE /api/tunnels/{project}", s.handleStopTunnel)
+	mux.HandleFunc("GET /api/processes", s.handleListProcesses)
+	mux.HandleFunc("POST /api/processes/{project}", s.handleStartProcesses)
+	mux.HandleFunc("POST /api/processes/{project}/{service}", s.handleStartProcesses)
+	mux.HandleFunc("DELETE /api/processes/{project}", s.handleStopProcesses)
+	mux.HandleFunc("DELETE /api/processes/{project}/{service}", s.handleStopProcesses)
+	mux.HandleFunc("GET /api/requests", s.handleListRequests)
+	mux.HandleFunc("DELETE /api/requests", s.handleClearRequests)
+	mux.HandleFunc("GET /api/requests/har", s.handleRequestsHAR)
+	mux.HandleFunc("GET /api/requests/{id}", s.handleGetRequest)
+	mux.HandleFunc("POST /api/requests/{id}/replay", requireJSON(s.handleReplayRequest))
 }
@@ -3,14 +3,16 @@
 package caddy
 
 import (
+	"net"
 	"os"
 	"path/filepath"
+	"strconv"
 
 	"github.com/paulrose/hatch/internal/config"
 )
 
 // DefaultAdminAddr is the default Caddy admin API listen address.
-const DefaultAdminAddr = "localhost:2019"
+var DefaultAdminAddr = net.JoinHostPort("localhost", strconv.Itoa(config.CaddyAdminPort))
 
 // ServerConfig holds the settings needed to run the embedded Caddy server.
 // It is decoupled from config.Settings — the caller maps between them.
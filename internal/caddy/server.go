@@ -9,6 +9,12 @@ import (
 
 	caddyv2 "github.com/caddyserver/caddy/v2"
 	_ "github.com/caddyserver/caddy/v2/modules/standard"
+
+	_ "github.com/paulrose/hatch/internal/chaos"     // hatch_chaos handler
+	_ "github.com/paulrose/hatch/internal/errorpage" // hatch_error_page handler
+	_ "github.com/paulrose/hatch/internal/inspect"   // hatch_capture handler
+	_ "github.com/paulrose/hatch/internal/mock"      // hatch_mock handler
+	_ "github.com/paulrose/hatch/internal/webhook"   // hatch_buffer handler
 )
 
 // Server manages the lifecycle of an embedded Caddy instance.
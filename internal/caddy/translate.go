@@ -3,9 +3,11 @@ package caddy
 import (
 	"fmt"
 	"net/url"
+	"path/filepath"
 	"sort"
 
 	"github.com/paulrose/hatch/internal/config"
+	"github.com/paulrose/hatch/internal/share"
 )
 
 // PKIPaths holds the file paths for root and intermediate CA certificates
@@ -19,31 +21,41 @@ type PKIPaths struct {
 
 // Translate converts a Hatch config into a full Caddy JSON configuration.
 // It skips disabled projects and returns a map suitable for JSON marshaling.
+// Proxied services get error routes serving Hatch's error page when their
+// upstream is unreachable.
 // When pki.RootCert is non-empty, a PKI app is added so Caddy uses the
 // provided CA for issuing leaf certificates. dataDir controls where Caddy
 // stores certificates and PKI data.
 func Translate(cfg config.Config, pki PKIPaths, dataDir string) map[string]any {
 	httpsRoutes := buildRoutes(cfg)
 	httpRedirectRoutes := buildHTTPRedirectRoutes(cfg)
+	if cfg.Settings.LANSharing && pki.RootCert != "" {
+		httpRedirectRoutes = append([]map[string]any{buildCADownloadRoute(pki.RootCert)}, httpRedirectRoutes...)
+	}
 	tlsConfig := buildTLSConfig(cfg, pki.RootCert)
 
 	httpsPort := fmt.Sprintf(":%d", cfg.Settings.HTTPSPort)
 	httpPort := fmt.Sprintf(":%d", cfg.Settings.HTTPPort)
 
+	httpsServer := map[string]any{
+		"listen":                  []string{httpsPort},
+		"routes":                  httpsRoutes,
+		"tls_connection_policies": []map[string]any{{}},
+		"automatic_https": map[string]any{
+			"disable_redirects": true,
+		},
+		"logs": map[string]any{
+			"default_logger_name": "access",
+		},
+	}
+	if errorRoutes := buildErrorRoutes(cfg); len(errorRoutes) > 0 {
+		httpsServer["errors"] = map[string]any{"routes": errorRoutes}
+	}
+
 	apps := map[string]any{
 		"http": map[string]any{
 			"servers": map[string]any{
-				"hatch_https": map[string]any{
-					"listen":                 []string{httpsPort},
-					"routes":                 httpsRoutes,
-					"tls_connection_policies": []map[string]any{{}},
-					"automatic_https": map[string]any{
-						"disable_redirects": true,
-					},
-					"logs": map[string]any{
-						"default_logger_name": "access",
-					},
-				},
+				"hatch_https": httpsServer,
 				"hatch_http": map[string]any{
 					"listen": []string{httpPort},
 					"routes": httpRedirectRoutes,
@@ -72,26 +84,41 @@ func Translate(cfg config.Config, pki PKIPaths, dataDir string) map[string]any {
 
 // routeInfo holds metadata for sorting routes by specificity.
 type routeInfo struct {
-	domain  string
+	hosts   []string // primary host first
 	service config.Service
+	project string
+	name    string // service name
+	capture bool   // record traffic with hatch_capture
+	auth    *config.Auth
+	dir     string // project path
+	page    string // custom error page template, absolute
 }
 
-// buildRoutes builds HTTPS routes for all enabled projects, sorted by specificity.
-func buildRoutes(cfg config.Config) []map[string]any {
+// routeInfos collects the HTTP services of all enabled projects, sorted
+// by specificity. TCP services are not routed through Caddy.
+func routeInfos(cfg config.Config) []routeInfo {
 	var infos []routeInfo
 
-	for _, proj := range cfg.Projects {
+	for projName, proj := range cfg.Projects {
 		if !proj.Enabled {
 			continue
 		}
-		for _, svc := range proj.Services {
-			domain := proj.Domain
-			if svc.Subdomain != "" {
-				domain = svc.Subdomain + "." + proj.Domain
+		for svcName, svc := range proj.Services {
+			if svc.IsTCP() {
+				continue // served by the daemon's TCP proxy
 			}
+			hosts := svc.Hosts(proj)
+			svc.Root = svc.RootDir(proj) // resolve against the project path
+			svc.Mock = svc.MockRules(proj)
 			infos = append(infos, routeInfo{
-				domain:  domain,
+				hosts:   hosts,
 				service: svc,
+				project: projName,
+				name:    svcName,
+				capture: proj.Capture,
+				auth:    proj.Auth,
+				dir:     proj.Path,
+				page:    proj.ErrorPagePath(),
 			})
 		}
 	}
@@ -111,16 +138,145 @@ func buildRoutes(cfg config.Config) []map[string]any {
 			return infos[i].service.Route < infos[j].service.Route
 		}
 		// Alphabetical domain tiebreaker.
-		return infos[i].domain < infos[j].domain
+		return infos[i].hosts[0] < infos[j].hosts[0]
 	})
+	return infos
+}
 
+// buildRoutes builds HTTPS routes for all enabled projects, sorted by
+// specificity. Services of projects with an IP allowlist get a route
+// refusing other addresses ahead of their own.
+func buildRoutes(cfg config.Config) []map[string]any {
+	infos := routeInfos(cfg)
 	routes := make([]map[string]any, 0, len(infos))
 	for _, info := range infos {
-		routes = append(routes, buildRoute(info.domain, info.service))
+		if info.auth != nil && len(info.auth.AllowIPs) > 0 {
+			routes = append(routes, buildDenyRoute(info.hosts, info.service, info.auth.AllowIPs))
+		}
+		route := buildRoute(info.hosts, info.service)
+		handlers := route["handle"].([]map[string]any)
+		if info.service.BufferWhenDown {
+			// Ahead of any rewrite, so held requests are re-sent as received.
+			handlers = append([]map[string]any{buildBufferHandler(info.project, info.name)}, handlers...)
+		}
+		if info.auth != nil && info.auth.Basic != nil {
+			// Ahead of the buffer, so only authenticated requests are held.
+			handlers = append([]map[string]any{buildAuthHandler(info.project, *info.auth.Basic)}, handlers...)
+		}
+		if info.capture {
+			// First, so it sees the request before any rewrite.
+			handlers = append([]map[string]any{buildCaptureHandler(info.project, info.name)}, handlers...)
+		}
+		route["handle"] = handlers
+		routes = append(routes, route)
 	}
 	return routes
 }
 
+// loopbackRanges are always let through an IP allowlist: the developer's
+// own browser, and the daemon re-sending buffered or replayed requests.
+// Tunnelled requests also arrive from loopback; the tunnel client applies
+// the allowlist to them before they get here.
+var loopbackRanges = []string{"127.0.0.0/8", "::1/128"}
+
+// buildDenyRoute builds a route answering 403 to requests for a service
+// from outside allowIPs.
+func buildDenyRoute(hosts []string, svc config.Service, allowIPs []string) map[string]any {
+	match := buildMatch(hosts, svc)
+	match["not"] = []map[string]any{{
+		"remote_ip": map[string]any{
+			"ranges": append(append([]string{}, loopbackRanges...), allowIPs...),
+		},
+	}}
+	return map[string]any{
+		"match": []map[string]any{match},
+		"handle": []map[string]any{{
+			"handler":     "static_response",
+			"status_code": "403",
+			"body":        "Forbidden: this address is not allowed by the project's allow_ips\n",
+		}},
+		"terminal": true,
+	}
+}
+
+// buildAuthHandler builds an authentication handler requiring the
+// credentials of one of basic's users, whose passwords are bcrypt hashes.
+func buildAuthHandler(project string, basic config.BasicAuth) map[string]any {
+	users := make([]string, 0, len(basic.Users))
+	for user := range basic.Users {
+		users = append(users, user)
+	}
+	sort.Strings(users)
+	accounts := make([]map[string]any, 0, len(users))
+	for _, user := range users {
+		accounts = append(accounts, map[string]any{
+			"username": user,
+			"password": basic.Users[user],
+		})
+	}
+	return map[string]any{
+		"handler": "authentication",
+		"providers": map[string]any{
+			"http_basic": map[string]any{
+				"accounts": accounts,
+				"hash":     map[string]any{"algorithm": "bcrypt"},
+				"realm":    project,
+			},
+		},
+	}
+}
+
+// buildCaptureHandler builds a hatch_capture handler recording a service's
+// traffic into the daemon's request buffer (see package inspect).
+func buildCaptureHandler(project, service string) map[string]any {
+	return map[string]any{
+		"handler": "hatch_capture",
+		"project": project,
+		"service": service,
+	}
+}
+
+// buildBufferHandler builds a hatch_buffer handler holding a service's
+// requests while it is down (see package webhook).
+func buildBufferHandler(project, service string) map[string]any {
+	return map[string]any{
+		"handler": "hatch_buffer",
+		"project": project,
+		"service": service,
+	}
+}
+
+// buildMockHandler builds a hatch_mock handler answering from svc.Mock,
+// whose files must already be absolute (see package mock).
+func buildMockHandler(svc config.Service) map[string]any {
+	return map[string]any{
+		"handler": "hatch_mock",
+		"rules":   svc.Mock,
+	}
+}
+
+// buildChaosHandler builds a hatch_chaos handler injecting the faults c
+// describes (see package chaos). Durations were validated on load.
+func buildChaosHandler(c config.Chaos) map[string]any {
+	handler := map[string]any{"handler": "hatch_chaos"}
+	if c.Latency != "" {
+		handler["latency"] = c.Latency
+	}
+	if c.Jitter != "" {
+		handler["jitter"] = c.Jitter
+	}
+	if c.ErrorRate > 0 {
+		handler["error_rate"] = c.ErrorRate
+		if c.ErrorStatus != 0 {
+			handler["error_status"] = c.ErrorStatus
+		}
+	}
+	if c.BandwidthKbps > 0 {
+		handler["bandwidth_kbps"] = c.BandwidthKbps
+	}
+	return handler
+}
+
 // routeTier returns a sorting priority: 0 = subdomain, 1 = path, 2 = catch-all.
 func routeTier(info routeInfo) int {
 	if info.service.Subdomain != "" {
@@ -133,34 +289,116 @@ func routeTier(info routeInfo) int {
 }
 
 // buildRoute builds a single HTTPS route with host matcher, optional path matcher,
-// and reverse_proxy handler.
-func buildRoute(domain string, svc config.Service) map[string]any {
-	match := map[string]any{
-		"host": []string{domain},
+// optional headers, rewrite and chaos handlers, and either a reverse_proxy
+// handler, file_server handlers for static services or a hatch_mock
+// handler for mock services.
+func buildRoute(hosts []string, svc config.Service) map[string]any {
+	match := buildMatch(hosts, svc)
+
+	var handlers []map[string]any
+	if svc.Headers != nil {
+		handlers = append(handlers, buildHeadersHandler(*svc.Headers))
 	}
-	if svc.Route != "" {
-		match["path"] = []string{svc.Route}
+	handlers = append(handlers, buildRewriteHandlers(svc)...)
+	if svc.Chaos != nil {
+		handlers = append(handlers, buildChaosHandler(*svc.Chaos))
+	}
+	switch {
+	case svc.IsMock():
+		handlers = append(handlers, buildMockHandler(svc))
+	case svc.IsStatic():
+		handlers = append(handlers, buildFileServerHandlers(svc)...)
+	default:
+		handlers = append(handlers, buildReverseProxyHandler(svc))
 	}
-
-	handler := buildReverseProxyHandler(svc.Proxy, svc.WebSocket)
 
 	return map[string]any{
 		"match":    []map[string]any{match},
-		"handle":   []map[string]any{handler},
+		"handle":   handlers,
 		"terminal": true,
 	}
 }
 
-// buildReverseProxyHandler builds a reverse_proxy handler with the dial address
-// extracted from proxyURL. If websocket is true, it adds flush_interval: -1 and
-// Connection/Upgrade header forwarding.
-func buildReverseProxyHandler(proxyURL string, websocket bool) map[string]any {
+// buildMatch builds the matcher set for a service's routes: its hosts
+// and, for path-routed services, its route.
+func buildMatch(hosts []string, svc config.Service) map[string]any {
+	match := map[string]any{
+		"host": hosts,
+	}
+	if svc.Route != "" {
+		match["path"] = []string{svc.Route}
+	}
+	return match
+}
+
+// buildErrorRoutes builds the HTTPS server's error routes: one per proxied
+// service, matching like its main route, rendering Hatch's error page (see
+// package errorpage) when the upstream cannot be reached. Caddy runs them
+// against the original request, before any rewrite.
+func buildErrorRoutes(cfg config.Config) []map[string]any {
+	var routes []map[string]any
+	for _, info := range routeInfos(cfg) {
+		if info.service.IsStatic() || info.service.IsMock() {
+			continue
+		}
+		var upstreams []string
+		for _, u := range info.service.ProxyURLs() {
+			upstreams = append(upstreams, extractDialAddress(u))
+		}
+		page := map[string]any{
+			"handler":   "hatch_error_page",
+			"project":   info.project,
+			"service":   info.name,
+			"upstreams": upstreams,
+			"dir":       info.dir,
+		}
+		if info.page != "" {
+			page["template"] = info.page
+		}
+		routes = append(routes, map[string]any{
+			"match":    []map[string]any{buildMatch(info.hosts, info.service)},
+			"handle":   []map[string]any{page},
+			"terminal": true,
+		})
+	}
+	return routes
+}
+
+// buildReverseProxyHandler builds a reverse_proxy handler with one upstream
+// per proxy URL of svc. Services with several upstreams get a load_balancing
+// block using svc.LBPolicy (round_robin by default), retrying the remaining
+// upstreams when one cannot be dialed. If svc.WebSocket is true, it adds
+// flush_interval: -1 and Connection/Upgrade header forwarding.
+func buildReverseProxyHandler(svc config.Service) map[string]any {
+	proxyURLs := svc.ProxyURLs()
+	upstreams := make([]map[string]any, 0, len(proxyURLs))
+	for _, u := range proxyURLs {
+		upstreams = append(upstreams, map[string]any{"dial": extractDialAddress(u)})
+	}
+
 	handler := map[string]any{
 		"handler":   "reverse_proxy",
-		"upstreams": []map[string]any{{"dial": extractDialAddress(proxyURL)}},
+		"upstreams": upstreams,
+	}
+
+	if svc.LoadBalanced() {
+		policy := svc.LBPolicy
+		if policy == "" {
+			policy = config.LBRoundRobin
+		}
+		handler["load_balancing"] = map[string]any{
+			"selection_policy": map[string]any{"policy": policy},
+			"retries":          len(upstreams) - 1,
+		}
 	}
 
-	if websocket {
+	if svc.HealthCheck != nil {
+		handler["health_checks"] = map[string]any{
+			"active": buildActiveHealthCheck(*svc.HealthCheck),
+		}
+	}
+
+	if svc.WebSocket {
 		handler["flush_interval"] = -1
 		handler["headers"] = map[string]any{
 			"request": map[string]any{
@@ -175,6 +413,131 @@ func buildReverseProxyHandler(proxyURL string, websocket bool) map[string]any {
 	return handler
 }
 
+// buildFileServerHandlers builds a file_server handler for svc.Root, which
+// must already be absolute. With svc.SPA, a try_files subroute first
+// rewrites paths that match no file or directory to /index.html, so that
+// client-side routes load the app.
+func buildFileServerHandlers(svc config.Service) []map[string]any {
+	var handlers []map[string]any
+	if svc.SPA {
+		handlers = append(handlers, map[string]any{
+			"handler": "subroute",
+			"routes": []map[string]any{
+				{
+					"match": []map[string]any{
+						{"file": map[string]any{
+							"root":      svc.Root,
+							"try_files": []string{"{http.request.uri.path}", "{http.request.uri.path}/", "/index.html"},
+						}},
+					},
+					"handle": []map[string]any{
+						{"handler": "rewrite", "uri": "{http.matchers.file.relative}"},
+					},
+				},
+			},
+		})
+	}
+
+	fileServer := map[string]any{
+		"handler": "file_server",
+		"root":    svc.Root,
+	}
+	if svc.Browse {
+		fileServer["browse"] = map[string]any{}
+	}
+	return append(handlers, fileServer)
+}
+
+// buildRewriteHandlers builds the rewrite handlers for svc's path rewriting.
+// Each step is a separate handler because Caddy applies a rewrite's uri
+// before its strip_path_prefix, whereas add_prefix must come last.
+func buildRewriteHandlers(svc config.Service) []map[string]any {
+	var handlers []map[string]any
+	if svc.StripPrefix != "" {
+		handlers = append(handlers, map[string]any{
+			"handler":           "rewrite",
+			"strip_path_prefix": svc.StripPrefix,
+		})
+	}
+	if svc.Rewrite != nil {
+		handlers = append(handlers, map[string]any{
+			"handler": "rewrite",
+			"path_regexp": []map[string]any{
+				{"find": svc.Rewrite.Regex, "replace": svc.Rewrite.Replace},
+			},
+		})
+	}
+	if svc.AddPrefix != "" {
+		handlers = append(handlers, map[string]any{
+			"handler": "rewrite",
+			"uri":     svc.AddPrefix + "{http.request.uri.path}",
+		})
+	}
+	return handlers
+}
+
+// buildHeadersHandler builds a headers handler from h. Response operations
+// are deferred so they apply to the headers written by the upstream.
+func buildHeadersHandler(h config.Headers) map[string]any {
+	handler := map[string]any{"handler": "headers"}
+	if h.Request != nil {
+		handler["request"] = buildHeaderOps(*h.Request)
+	}
+	if h.Response != nil {
+		resp := buildHeaderOps(*h.Response)
+		resp["deferred"] = true
+		handler["response"] = resp
+	}
+	return handler
+}
+
+// buildHeaderOps converts ops into Caddy's header operations format, where
+// each header maps to a list of values.
+func buildHeaderOps(ops config.HeaderOps) map[string]any {
+	out := map[string]any{}
+	if len(ops.Set) > 0 {
+		out["set"] = headerValues(ops.Set)
+	}
+	if len(ops.Add) > 0 {
+		out["add"] = headerValues(ops.Add)
+	}
+	if len(ops.Delete) > 0 {
+		out["delete"] = ops.Delete
+	}
+	return out
+}
+
+func headerValues(fields map[string]string) map[string][]string {
+	out := make(map[string][]string, len(fields))
+	for name, value := range fields {
+		out[name] = []string{value}
+	}
+	return out
+}
+
+// buildActiveHealthCheck builds a Caddy active health check from hc so that
+// Caddy stops routing to upstreams that fail it. Unset fields fall back to
+// Caddy's defaults.
+func buildActiveHealthCheck(hc config.HealthCheck) map[string]any {
+	active := map[string]any{"uri": hc.Path}
+	if hc.Method != "" {
+		active["method"] = hc.Method
+	}
+	if hc.ExpectStatus != 0 {
+		active["expect_status"] = hc.ExpectStatus
+	}
+	if hc.ExpectBody != "" {
+		active["expect_body"] = hc.ExpectBody
+	}
+	if hc.Interval != "" {
+		active["interval"] = hc.Interval
+	}
+	if hc.Timeout != "" {
+		active["timeout"] = hc.Timeout
+	}
+	return active
+}
+
 // buildHTTPRedirectRoutes builds HTTP→HTTPS redirect routes using a static_response
 // handler with a 302 redirect for all project domains.
 func buildHTTPRedirectRoutes(cfg config.Config) []map[string]any {
@@ -201,6 +564,37 @@ func buildHTTPRedirectRoutes(cfg config.Config) []map[string]any {
 	}
 }
 
+// buildCADownloadRoute serves the root CA certificate at share.CAPath on
+// any host, so devices on the LAN can fetch it by IP address and install
+// it before they trust Hatch's HTTPS certificates.
+func buildCADownloadRoute(rootCert string) map[string]any {
+	return map[string]any{
+		"match": []map[string]any{
+			{"path": []string{share.CAPath}},
+		},
+		"handle": []map[string]any{
+			{
+				"handler": "headers",
+				"response": map[string]any{
+					"set": map[string][]string{
+						"Content-Type":        {"application/x-x509-ca-cert"},
+						"Content-Disposition": {`attachment; filename="hatch-root-ca.crt"`},
+					},
+				},
+			},
+			{
+				"handler": "rewrite",
+				"uri":     "/" + filepath.Base(rootCert),
+			},
+			{
+				"handler": "file_server",
+				"root":    filepath.Dir(rootCert),
+			},
+		},
+		"terminal": true,
+	}
+}
+
 // buildTLSConfig builds the TLS automation config with internal issuer.
 // When rootCACert is non-empty, the issuer references the "hatch" CA.
 func buildTLSConfig(cfg config.Config, rootCACert string) map[string]any {
@@ -251,6 +645,8 @@ func buildPKIConfig(pki PKIPaths) map[string]any {
 // collectDomains returns all unique domains across enabled projects, sorted.
 // Each service's full domain is listed explicitly so that Caddy's automatic
 // HTTPS exact-match check recognises them against the TLS automation policy.
+// Aliases are included, and wildcard projects contribute *. patterns for
+// which the internal issuer issues wildcard certificates.
 func collectDomains(cfg config.Config) []string {
 	domainSet := make(map[string]bool)
 
@@ -258,11 +654,15 @@ func collectDomains(cfg config.Config) []string {
 		if !proj.Enabled {
 			continue
 		}
-		domainSet[proj.Domain] = true
+		for _, h := range proj.Hosts() {
+			domainSet[h] = true
+		}
 
 		for _, svc := range proj.Services {
-			if svc.Subdomain != "" {
-				domainSet[svc.Subdomain+"."+proj.Domain] = true
+			if svc.Subdomain != "" && !svc.IsTCP() {
+				for _, h := range svc.Hosts(proj) {
+					domainSet[h] = true
+				}
 			}
 		}
 	}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"os"
 	"path/filepath"
+	"slices"
 	"testing"
 
 	"github.com/paulrose/hatch/internal/config"
@@ -211,6 +212,401 @@ func TestTranslate_WebSocket(t *testing.T) {
 	}
 }
 
+func TestTranslate_LoadBalancing(t *testing.T) {
+	tests := []struct {
+		name       string
+		policy     string
+		wantPolicy string
+	}{
+		{"default policy", "", "round_robin"},
+		{"least_conn", config.LBLeastConn, "least_conn"},
+		{"ip_hash", config.LBIPHash, "ip_hash"},
+		{"first", config.LBFirst, "first"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.Config{
+				Version:  1,
+				Settings: config.Settings{HTTPPort: 80, HTTPSPort: 443},
+				Projects: map[string]config.Project{
+					"myapp": {
+						Domain:  "myapp.test",
+						Enabled: true,
+						Services: map[string]config.Service{
+							"api": {
+								Upstreams: []string{"http://localhost:8001", "http://localhost:8002", "https://replica.local"},
+								LBPolicy:  tt.policy,
+							},
+						},
+					},
+				},
+			}
+
+			result := Translate(cfg, PKIPaths{}, "/test/data/caddy")
+
+			servers := result["apps"].(map[string]any)["http"].(map[string]any)["servers"].(map[string]any)
+			routes := servers["hatch_https"].(map[string]any)["routes"].([]map[string]any)
+			handler := routes[0]["handle"].([]map[string]any)[0]
+
+			upstreams := handler["upstreams"].([]map[string]any)
+			wantDials := []string{"localhost:8001", "localhost:8002", "replica.local:443"}
+			if len(upstreams) != len(wantDials) {
+				t.Fatalf("expected %d upstreams, got %d", len(wantDials), len(upstreams))
+			}
+			for i, want := range wantDials {
+				if upstreams[i]["dial"] != want {
+					t.Errorf("upstream %d: expected dial %q, got %v", i, want, upstreams[i]["dial"])
+				}
+			}
+
+			lb := handler["load_balancing"].(map[string]any)
+			policy := lb["selection_policy"].(map[string]any)["policy"]
+			if policy != tt.wantPolicy {
+				t.Errorf("expected policy %q, got %v", tt.wantPolicy, policy)
+			}
+			if lb["retries"] != 2 {
+				t.Errorf("expected retries 2, got %v", lb["retries"])
+			}
+		})
+	}
+}
+
+func TestTranslate_SingleUpstreamNoLoadBalancing(t *testing.T) {
+	cfg := config.Config{
+		Version:  1,
+		Settings: config.Settings{HTTPPort: 80, HTTPSPort: 443},
+		Projects: map[string]config.Project{
+			"myapp": {
+				Domain:  "myapp.test",
+				Enabled: true,
+				Services: map[string]config.Service{
+					"web": {Upstreams: []string{"http://localhost:3000"}},
+				},
+			},
+		},
+	}
+
+	result := Translate(cfg, PKIPaths{}, "/test/data/caddy")
+
+	servers := result["apps"].(map[string]any)["http"].(map[string]any)["servers"].(map[string]any)
+	routes := servers["hatch_https"].(map[string]any)["routes"].([]map[string]any)
+	handler := routes[0]["handle"].([]map[string]any)[0]
+
+	if _, ok := handler["load_balancing"]; ok {
+		t.Error("expected no load_balancing for a single upstream")
+	}
+}
+
+func TestTranslate_SkipsTCPServices(t *testing.T) {
+	cfg := config.Config{
+		Version:  1,
+		Settings: config.Settings{HTTPPort: 80, HTTPSPort: 443},
+		Projects: map[string]config.Project{
+			"myapp": {
+				Domain:  "myapp.test",
+				Enabled: true,
+				Services: map[string]config.Service{
+					"web": {Proxy: "http://localhost:3000"},
+					"db":  {Type: config.ServiceTCP, Listen: 5432, Proxy: "tcp://localhost:5432", Subdomain: "db", TLS: config.TLSTerminate},
+				},
+			},
+		},
+	}
+
+	result := Translate(cfg, PKIPaths{}, "/test/data/caddy")
+
+	servers := result["apps"].(map[string]any)["http"].(map[string]any)["servers"].(map[string]any)
+	routes := servers["hatch_https"].(map[string]any)["routes"].([]map[string]any)
+	if len(routes) != 1 {
+		t.Fatalf("expected 1 route, got %d", len(routes))
+	}
+
+	domains := collectDomains(cfg)
+	if len(domains) != 1 || domains[0] != "myapp.test" {
+		t.Errorf("expected only myapp.test, got %v", domains)
+	}
+}
+
+func TestTranslate_Headers(t *testing.T) {
+	cfg := config.Config{
+		Version:  1,
+		Settings: config.Settings{HTTPPort: 80, HTTPSPort: 443},
+		Projects: map[string]config.Project{
+			"myapp": {
+				Domain:  "myapp.test",
+				Enabled: true,
+				Services: map[string]config.Service{
+					"api": {
+						Proxy: "http://localhost:4000",
+						Route: "/api/*",
+						Headers: &config.Headers{
+							Request: &config.HeaderOps{
+								Set:    map[string]string{"X-Forwarded-Prefix": "/api"},
+								Add:    map[string]string{"X-User": "dev"},
+								Delete: []string{"Cookie"},
+							},
+							Response: &config.HeaderOps{
+								Delete: []string{"Strict-Transport-Security"},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	result := Translate(cfg, PKIPaths{}, "/test/data/caddy")
+
+	servers := result["apps"].(map[string]any)["http"].(map[string]any)["servers"].(map[string]any)
+	routes := servers["hatch_https"].(map[string]any)["routes"].([]map[string]any)
+	handlers := routes[0]["handle"].([]map[string]any)
+	if len(handlers) != 2 {
+		t.Fatalf("expected headers and reverse_proxy handlers, got %d", len(handlers))
+	}
+	if handlers[0]["handler"] != "headers" || handlers[1]["handler"] != "reverse_proxy" {
+		t.Fatalf("unexpected handler order: %v, %v", handlers[0]["handler"], handlers[1]["handler"])
+	}
+
+	req := handlers[0]["request"].(map[string]any)
+	if set := req["set"].(map[string][]string); set["X-Forwarded-Prefix"][0] != "/api" {
+		t.Errorf("unexpected request set: %v", set)
+	}
+	if add := req["add"].(map[string][]string); add["X-User"][0] != "dev" {
+		t.Errorf("unexpected request add: %v", add)
+	}
+	if del := req["delete"].([]string); len(del) != 1 || del[0] != "Cookie" {
+		t.Errorf("unexpected request delete: %v", del)
+	}
+
+	resp := handlers[0]["response"].(map[string]any)
+	if resp["deferred"] != true {
+		t.Error("expected response header operations to be deferred")
+	}
+	if del := resp["delete"].([]string); len(del) != 1 || del[0] != "Strict-Transport-Security" {
+		t.Errorf("unexpected response delete: %v", del)
+	}
+	if _, ok := resp["set"]; ok {
+		t.Error("expected no set operations in response")
+	}
+}
+
+func TestTranslate_PathRewrite(t *testing.T) {
+	cfg := config.Config{
+		Version:  1,
+		Settings: config.Settings{HTTPPort: 80, HTTPSPort: 443},
+		Projects: map[string]config.Project{
+			"myapp": {
+				Domain:  "myapp.test",
+				Enabled: true,
+				Services: map[string]config.Service{
+					"api": {
+						Proxy:       "http://localhost:4000",
+						Route:       "/api/*",
+						StripPrefix: "/api",
+						Rewrite:     &config.Rewrite{Regex: "^/v1/(.*)$", Replace: "/v2/$1"},
+						AddPrefix:   "/svc",
+					},
+				},
+			},
+		},
+	}
+
+	result := Translate(cfg, PKIPaths{}, "/test/data/caddy")
+
+	servers := result["apps"].(map[string]any)["http"].(map[string]any)["servers"].(map[string]any)
+	routes := servers["hatch_https"].(map[string]any)["routes"].([]map[string]any)
+	handlers := routes[0]["handle"].([]map[string]any)
+	if len(handlers) != 4 {
+		t.Fatalf("expected 3 rewrite handlers and reverse_proxy, got %d", len(handlers))
+	}
+	for i := 0; i < 3; i++ {
+		if handlers[i]["handler"] != "rewrite" {
+			t.Fatalf("handler %d: expected rewrite, got %v", i, handlers[i]["handler"])
+		}
+	}
+	if handlers[3]["handler"] != "reverse_proxy" {
+		t.Fatalf("expected reverse_proxy last, got %v", handlers[3]["handler"])
+	}
+
+	if got := handlers[0]["strip_path_prefix"]; got != "/api" {
+		t.Errorf("strip_path_prefix = %v", got)
+	}
+	re := handlers[1]["path_regexp"].([]map[string]any)[0]
+	if re["find"] != "^/v1/(.*)$" || re["replace"] != "/v2/$1" {
+		t.Errorf("unexpected path_regexp: %v", re)
+	}
+	if got := handlers[2]["uri"]; got != "/svc{http.request.uri.path}" {
+		t.Errorf("uri = %v", got)
+	}
+}
+
+func TestTranslate_NoRewriteHandlersByDefault(t *testing.T) {
+	handlers := buildRewriteHandlers(config.Service{Proxy: "http://localhost:3000", Route: "/api/*"})
+	if len(handlers) != 0 {
+		t.Errorf("expected no rewrite handlers, got %v", handlers)
+	}
+}
+
+func TestTranslate_StaticService(t *testing.T) {
+	cfg := config.Config{
+		Version:  1,
+		Settings: config.Settings{HTTPPort: 80, HTTPSPort: 443},
+		Projects: map[string]config.Project{
+			"myapp": {
+				Domain:  "myapp.test",
+				Path:    "/home/dev/myapp",
+				Enabled: true,
+				Services: map[string]config.Service{
+					"web":  {Root: "dist", SPA: true},
+					"docs": {Root: "/srv/docs", Route: "/docs/*", StripPrefix: "/docs", Browse: true},
+				},
+			},
+		},
+	}
+
+	result := Translate(cfg, PKIPaths{}, "/test/data/caddy")
+
+	servers := result["apps"].(map[string]any)["http"].(map[string]any)["servers"].(map[string]any)
+	routes := servers["hatch_https"].(map[string]any)["routes"].([]map[string]any)
+	if len(routes) != 2 {
+		t.Fatalf("expected 2 routes, got %d", len(routes))
+	}
+
+	// Path route sorts first.
+	docs := routes[0]["handle"].([]map[string]any)
+	if len(docs) != 2 || docs[0]["handler"] != "rewrite" || docs[1]["handler"] != "file_server" {
+		t.Fatalf("unexpected docs handlers: %v", docs)
+	}
+	if docs[1]["root"] != "/srv/docs" {
+		t.Errorf("expected absolute root to be kept, got %v", docs[1]["root"])
+	}
+	if _, ok := docs[1]["browse"]; !ok {
+		t.Error("expected browse to be enabled")
+	}
+
+	web := routes[1]["handle"].([]map[string]any)
+	if len(web) != 2 || web[0]["handler"] != "subroute" || web[1]["handler"] != "file_server" {
+		t.Fatalf("unexpected web handlers: %v", web)
+	}
+	if web[1]["root"] != "/home/dev/myapp/dist" {
+		t.Errorf("expected root relative to project path, got %v", web[1]["root"])
+	}
+	if _, ok := web[1]["browse"]; ok {
+		t.Error("expected browse to be disabled")
+	}
+	spa := web[0]["routes"].([]map[string]any)[0]
+	file := spa["match"].([]map[string]any)[0]["file"].(map[string]any)
+	tryFiles := file["try_files"].([]string)
+	if tryFiles[len(tryFiles)-1] != "/index.html" || file["root"] != "/home/dev/myapp/dist" {
+		t.Errorf("unexpected try_files matcher: %v", file)
+	}
+}
+
+func TestTranslate_AliasesAndWildcard(t *testing.T) {
+	cfg := config.Config{
+		Version:  1,
+		Settings: config.Settings{HTTPPort: 80, HTTPSPort: 443},
+		Projects: map[string]config.Project{
+			"myapp": {
+				Domain:   "myapp.test",
+				Aliases:  []string{"admin-myapp.test"},
+				Wildcard: true,
+				Enabled:  true,
+				Services: map[string]config.Service{
+					"web": {Proxy: "http://localhost:3000"},
+					"api": {Proxy: "http://localhost:4000", Subdomain: "api"},
+				},
+			},
+		},
+	}
+
+	result := Translate(cfg, PKIPaths{}, "/test/data/caddy")
+
+	servers := result["apps"].(map[string]any)["http"].(map[string]any)["servers"].(map[string]any)
+	routes := servers["hatch_https"].(map[string]any)["routes"].([]map[string]any)
+	if len(routes) != 2 {
+		t.Fatalf("expected 2 routes, got %d", len(routes))
+	}
+
+	// Subdomain route sorts before the wildcard catch-all.
+	apiHosts := routes[0]["match"].([]map[string]any)[0]["host"].([]string)
+	wantAPI := []string{"api.myapp.test", "api.admin-myapp.test"}
+	if !slices.Equal(apiHosts, wantAPI) {
+		t.Errorf("api hosts = %v, want %v", apiHosts, wantAPI)
+	}
+	webHosts := routes[1]["match"].([]map[string]any)[0]["host"].([]string)
+	wantWeb := []string{"myapp.test", "admin-myapp.test", "*.myapp.test", "*.admin-myapp.test"}
+	if !slices.Equal(webHosts, wantWeb) {
+		t.Errorf("web hosts = %v, want %v", webHosts, wantWeb)
+	}
+
+	policies := result["apps"].(map[string]any)["tls"].(map[string]any)["automation"].(map[string]any)["policies"].([]map[string]any)
+	subjects := policies[0]["subjects"].([]string)
+	wantSubjects := []string{"*.admin-myapp.test", "*.myapp.test", "admin-myapp.test", "api.admin-myapp.test", "api.myapp.test", "myapp.test"}
+	if !slices.Equal(subjects, wantSubjects) {
+		t.Errorf("subjects = %v, want %v", subjects, wantSubjects)
+	}
+}
+
+func TestTranslate_ActiveHealthCheck(t *testing.T) {
+	cfg := config.Config{
+		Version:  1,
+		Settings: config.Settings{HTTPPort: 80, HTTPSPort: 443},
+		Projects: map[string]config.Project{
+			"myapp": {
+				Domain:  "myapp.test",
+				Enabled: true,
+				Services: map[string]config.Service{
+					"web": {
+						Proxy: "http://localhost:3000",
+						HealthCheck: &config.HealthCheck{
+							Path:         "/healthz",
+							Method:       "HEAD",
+							ExpectStatus: 200,
+							ExpectBody:   "ok",
+							Interval:     "5s",
+							Timeout:      "1s",
+						},
+					},
+				},
+			},
+		},
+	}
+
+	result := Translate(cfg, PKIPaths{}, "/test/data/caddy")
+
+	servers := result["apps"].(map[string]any)["http"].(map[string]any)["servers"].(map[string]any)
+	routes := servers["hatch_https"].(map[string]any)["routes"].([]map[string]any)
+	handler := routes[0]["handle"].([]map[string]any)[0]
+
+	active := handler["health_checks"].(map[string]any)["active"].(map[string]any)
+	want := map[string]any{
+		"uri":           "/healthz",
+		"method":        "HEAD",
+		"expect_status": 200,
+		"expect_body":   "ok",
+		"interval":      "5s",
+		"timeout":       "1s",
+	}
+	for k, v := range want {
+		if active[k] != v {
+			t.Errorf("active[%q] = %v, want %v", k, active[k], v)
+		}
+	}
+
+	// A service without a health_check block gets no Caddy health checks.
+	svc := cfg.Projects["myapp"].Services["web"]
+	svc.HealthCheck = nil
+	cfg.Projects["myapp"].Services["web"] = svc
+	result = Translate(cfg, PKIPaths{}, "/test/data/caddy")
+	servers = result["apps"].(map[string]any)["http"].(map[string]any)["servers"].(map[string]any)
+	routes = servers["hatch_https"].(map[string]any)["routes"].([]map[string]any)
+	handler = routes[0]["handle"].([]map[string]any)[0]
+	if _, ok := handler["health_checks"]; ok {
+		t.Error("expected no health_checks without a health_check block")
+	}
+}
+
 func TestTranslate_RouteOrdering(t *testing.T) {
 	cfg := fullConfig()
 	result := Translate(cfg, PKIPaths{}, "/test/data/caddy")
@@ -645,3 +1041,339 @@ func TestExtractDialAddress(t *testing.T) {
 		})
 	}
 }
+
+func TestTranslate_LANSharingServesCA(t *testing.T) {
+	cfg := fullConfig()
+	pki := PKIPaths{RootCert: "/home/user/.hatch/certs/root.crt"}
+
+	httpRoutes := func(cfg config.Config) []map[string]any {
+		result := Translate(cfg, pki, "/test/data/caddy")
+		servers := result["apps"].(map[string]any)["http"].(map[string]any)["servers"].(map[string]any)
+		return servers["hatch_http"].(map[string]any)["routes"].([]map[string]any)
+	}
+
+	if routes := httpRoutes(cfg); len(routes) != 1 {
+		t.Fatalf("expected only the redirect route without sharing, got %d routes", len(routes))
+	}
+
+	cfg.Settings.LANSharing = true
+	routes := httpRoutes(cfg)
+	if len(routes) != 2 {
+		t.Fatalf("expected CA and redirect routes, got %d", len(routes))
+	}
+
+	ca := routes[0]
+	match := ca["match"].([]map[string]any)
+	if !slices.Equal(match[0]["path"].([]string), []string{"/hatch/ca.crt"}) {
+		t.Errorf("unexpected CA route match: %v", match)
+	}
+	if _, ok := match[0]["host"]; ok {
+		t.Error("CA route should match any host")
+	}
+	handle := ca["handle"].([]map[string]any)
+	if handle[1]["uri"] != "/root.crt" {
+		t.Errorf("expected rewrite to /root.crt, got %v", handle[1]["uri"])
+	}
+	if handle[2]["handler"] != "file_server" || handle[2]["root"] != "/home/user/.hatch/certs" {
+		t.Errorf("unexpected file server handler: %v", handle[2])
+	}
+	if routes[1]["handle"].([]map[string]any)[0]["handler"] != "static_response" {
+		t.Error("expected the redirect route after the CA route")
+	}
+}
+
+func TestTranslate_CaptureHandler(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Projects["web"] = config.Project{
+		Domain:  "web.test",
+		Path:    "/tmp/web",
+		Enabled: true,
+		Capture: true,
+		Services: map[string]config.Service{
+			"app": {Proxy: "http://localhost:3000", StripPrefix: "/app", Route: "/app/*"},
+		},
+	}
+	cfg.Projects["api"] = config.Project{
+		Domain:   "api.test",
+		Path:     "/tmp/api",
+		Enabled:  true,
+		Services: map[string]config.Service{"app": {Proxy: "http://localhost:4000"}},
+	}
+
+	result := Translate(cfg, PKIPaths{}, "/test/data/caddy")
+	servers := result["apps"].(map[string]any)["http"].(map[string]any)["servers"].(map[string]any)
+	routes := servers["hatch_https"].(map[string]any)["routes"].([]map[string]any)
+	for _, route := range routes {
+		host := route["match"].([]map[string]any)[0]["host"].([]string)[0]
+		handle := route["handle"].([]map[string]any)
+		switch host {
+		case "web.test":
+			capture := handle[0]
+			if capture["handler"] != "hatch_capture" || capture["project"] != "web" || capture["service"] != "app" {
+				t.Errorf("expected capture handler first, got %v", capture)
+			}
+			if handle[1]["handler"] != "rewrite" {
+				t.Errorf("expected the rewrite after the capture handler, got %v", handle[1])
+			}
+		case "api.test":
+			for _, h := range handle {
+				if h["handler"] == "hatch_capture" {
+					t.Error("expected no capture handler for a project without capture")
+				}
+			}
+		}
+	}
+}
+
+func TestTranslate_BufferHandler(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Projects["web"] = config.Project{
+		Domain:  "web.test",
+		Path:    "/tmp/web",
+		Enabled: true,
+		Capture: true,
+		Services: map[string]config.Service{
+			"hooks": {Proxy: "http://localhost:3000", Route: "/hooks/*", StripPrefix: "/hooks", BufferWhenDown: true},
+			"app":   {Proxy: "http://localhost:4000"},
+		},
+	}
+
+	result := Translate(cfg, PKIPaths{}, "/test/data/caddy")
+	servers := result["apps"].(map[string]any)["http"].(map[string]any)["servers"].(map[string]any)
+	routes := servers["hatch_https"].(map[string]any)["routes"].([]map[string]any)
+	if len(routes) != 2 {
+		t.Fatalf("expected 2 routes, got %d", len(routes))
+	}
+	for _, route := range routes {
+		handle := route["handle"].([]map[string]any)
+		if _, hasPath := route["match"].([]map[string]any)[0]["path"]; !hasPath {
+			for _, h := range handle {
+				if h["handler"] == "hatch_buffer" {
+					t.Error("expected no buffer handler without buffer_when_down")
+				}
+			}
+			continue
+		}
+		got := []any{handle[0]["handler"], handle[1]["handler"], handle[2]["handler"]}
+		want := []any{"hatch_capture", "hatch_buffer", "rewrite"}
+		if !slices.Equal(got, want) {
+			t.Errorf("expected handlers %v, got %v", want, got)
+		}
+		if handle[1]["project"] != "web" || handle[1]["service"] != "hooks" {
+			t.Errorf("unexpected buffer handler %v", handle[1])
+		}
+	}
+}
+
+func TestTranslate_ChaosHandler(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Projects["web"] = config.Project{
+		Domain:  "web.test",
+		Path:    "/tmp/web",
+		Enabled: true,
+		Services: map[string]config.Service{
+			"api": {Proxy: "http://localhost:3000", Subdomain: "api", StripPrefix: "/v1", Chaos: &config.Chaos{Latency: "500ms", ErrorRate: 0.1, BandwidthKbps: 64}},
+			"app": {Proxy: "http://localhost:4000"},
+		},
+	}
+
+	result := Translate(cfg, PKIPaths{}, "/test/data/caddy")
+	servers := result["apps"].(map[string]any)["http"].(map[string]any)["servers"].(map[string]any)
+	routes := servers["hatch_https"].(map[string]any)["routes"].([]map[string]any)
+	for _, route := range routes {
+		host := route["match"].([]map[string]any)[0]["host"].([]string)[0]
+		handle := route["handle"].([]map[string]any)
+		switch host {
+		case "api.web.test":
+			got := []any{handle[0]["handler"], handle[1]["handler"], handle[2]["handler"]}
+			want := []any{"rewrite", "hatch_chaos", "reverse_proxy"}
+			if !slices.Equal(got, want) {
+				t.Fatalf("expected handlers %v, got %v", want, got)
+			}
+			chaos := handle[1]
+			if chaos["latency"] != "500ms" || chaos["error_rate"] != 0.1 || chaos["bandwidth_kbps"] != 64 {
+				t.Errorf("unexpected chaos handler %v", chaos)
+			}
+			if _, ok := chaos["jitter"]; ok {
+				t.Errorf("expected unset settings to be left out, got %v", chaos)
+			}
+		case "web.test":
+			for _, h := range handle {
+				if h["handler"] == "hatch_chaos" {
+					t.Error("expected no chaos handler without chaos settings")
+				}
+			}
+		}
+	}
+}
+
+func TestTranslate_MockHandler(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Projects["web"] = config.Project{
+		Domain:  "web.test",
+		Path:    "/tmp/web",
+		Enabled: true,
+		Services: map[string]config.Service{
+			"payments": {Route: "/api/payments/*", Mock: []config.MockRule{
+				{Method: "GET", Path: "/api/payments", File: "mocks/payments.json"},
+				{Path: "/api/payments/*", Status: 404},
+			}},
+			"app": {Proxy: "http://localhost:3000"},
+		},
+	}
+
+	result := Translate(cfg, PKIPaths{}, "/test/data/caddy")
+	server := result["apps"].(map[string]any)["http"].(map[string]any)["servers"].(map[string]any)["hatch_https"].(map[string]any)
+	routes := server["routes"].([]map[string]any)
+	handle := routes[0]["handle"].([]map[string]any)
+	if len(handle) != 1 || handle[0]["handler"] != "hatch_mock" {
+		t.Fatalf("expected only a mock handler on the mock route, got %v", handle)
+	}
+	rules := handle[0]["rules"].([]config.MockRule)
+	if len(rules) != 2 || rules[0].File != "/tmp/web/mocks/payments.json" {
+		t.Errorf("expected the rules with files resolved, got %+v", rules)
+	}
+	if cfg.Projects["web"].Services["payments"].Mock[0].File != "mocks/payments.json" {
+		t.Error("expected Translate to leave the config unchanged")
+	}
+
+	errorRoutes := server["errors"].(map[string]any)["routes"].([]map[string]any)
+	if len(errorRoutes) != 1 {
+		t.Errorf("expected an error route for the proxied service only, got %d", len(errorRoutes))
+	}
+}
+
+func TestTranslate_ErrorRoutes(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Projects["web"] = config.Project{
+		Domain:    "web.test",
+		Path:      "/tmp/web",
+		Enabled:   true,
+		ErrorPage: "errors/down.html",
+		Services: map[string]config.Service{
+			"app":  {Proxy: "http://localhost:3000"},
+			"api":  {Upstreams: []string{"http://localhost:4000", "http://localhost:4001"}, Route: "/api/*"},
+			"docs": {Root: "docs", Route: "/docs/*"},
+		},
+	}
+
+	result := Translate(cfg, PKIPaths{}, "/test/data/caddy")
+	server := result["apps"].(map[string]any)["http"].(map[string]any)["servers"].(map[string]any)["hatch_https"].(map[string]any)
+	routes := server["errors"].(map[string]any)["routes"].([]map[string]any)
+	if len(routes) != 2 {
+		t.Fatalf("expected error routes for the 2 proxied services, got %d", len(routes))
+	}
+
+	api := routes[0]
+	if path := api["match"].([]map[string]any)[0]["path"]; !slices.Equal(path.([]string), []string{"/api/*"}) {
+		t.Errorf("expected the api error route first, matching its path; got %v", path)
+	}
+	page := api["handle"].([]map[string]any)[0]
+	if page["handler"] != "hatch_error_page" || page["project"] != "web" || page["service"] != "api" || page["dir"] != "/tmp/web" {
+		t.Errorf("unexpected error page handler %v", page)
+	}
+	if !slices.Equal(page["upstreams"].([]string), []string{"localhost:4000", "localhost:4001"}) {
+		t.Errorf("unexpected upstreams %v", page["upstreams"])
+	}
+	if page["template"] != "/tmp/web/errors/down.html" {
+		t.Errorf("expected the template resolved against the project path, got %v", page["template"])
+	}
+}
+
+func TestTranslate_NoErrorRoutesWithoutProxies(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Projects["docs"] = config.Project{
+		Domain:   "docs.test",
+		Path:     "/tmp/docs",
+		Enabled:  true,
+		Services: map[string]config.Service{"site": {Root: "public"}},
+	}
+
+	result := Translate(cfg, PKIPaths{}, "/test/data/caddy")
+	server := result["apps"].(map[string]any)["http"].(map[string]any)["servers"].(map[string]any)["hatch_https"].(map[string]any)
+	if _, ok := server["errors"]; ok {
+		t.Error("expected no error routes for static services")
+	}
+}
+
+func TestTranslate_Auth(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Projects["web"] = config.Project{
+		Domain:  "web.test",
+		Path:    "/tmp/web",
+		Enabled: true,
+		Capture: true,
+		Auth: &config.Auth{
+			Basic:    &config.BasicAuth{Users: map[string]string{"client": "$2a$10$hash", "alice": "$2a$10$other"}},
+			AllowIPs: []string{"192.168.1.0/24"},
+		},
+		Services: map[string]config.Service{
+			"app": {Proxy: "http://localhost:3000", BufferWhenDown: true},
+		},
+	}
+	cfg.Projects["open"] = config.Project{
+		Domain:   "open.test",
+		Path:     "/tmp/open",
+		Enabled:  true,
+		Services: map[string]config.Service{"app": {Proxy: "http://localhost:4000"}},
+	}
+
+	result := Translate(cfg, PKIPaths{}, "/test/data/caddy")
+	servers := result["apps"].(map[string]any)["http"].(map[string]any)["servers"].(map[string]any)
+	routes := servers["hatch_https"].(map[string]any)["routes"].([]map[string]any)
+	if len(routes) != 3 {
+		t.Fatalf("expected a deny route and two service routes, got %d routes", len(routes))
+	}
+
+	var deny, web map[string]any
+	for i, route := range routes {
+		if route["match"].([]map[string]any)[0]["host"].([]string)[0] != "web.test" {
+			continue
+		}
+		if route["handle"].([]map[string]any)[0]["handler"] == "static_response" {
+			deny = route
+			if i+1 >= len(routes) || routes[i+1]["match"].([]map[string]any)[0]["host"].([]string)[0] != "web.test" {
+				t.Error("expected the deny route right before the service's route")
+			}
+		} else {
+			web = route
+		}
+	}
+	if deny == nil || web == nil {
+		t.Fatalf("expected deny and service routes for web.test, got %v", routes)
+	}
+
+	not := deny["match"].([]map[string]any)[0]["not"].([]map[string]any)
+	ranges := not[0]["remote_ip"].(map[string]any)["ranges"].([]string)
+	if want := []string{"127.0.0.0/8", "::1/128", "192.168.1.0/24"}; !slices.Equal(ranges, want) {
+		t.Errorf("expected ranges %v, got %v", want, ranges)
+	}
+	if status := deny["handle"].([]map[string]any)[0]["status_code"]; status != "403" {
+		t.Errorf("expected 403, got %v", status)
+	}
+
+	handle := web["handle"].([]map[string]any)
+	got := []any{handle[0]["handler"], handle[1]["handler"], handle[2]["handler"]}
+	if want := []any{"hatch_capture", "authentication", "hatch_buffer"}; !slices.Equal(got, want) {
+		t.Fatalf("expected handlers %v, got %v", want, got)
+	}
+	basic := handle[1]["providers"].(map[string]any)["http_basic"].(map[string]any)
+	accounts := basic["accounts"].([]map[string]any)
+	if len(accounts) != 2 || accounts[0]["username"] != "alice" || accounts[1]["password"] != "$2a$10$hash" {
+		t.Errorf("unexpected accounts %v", accounts)
+	}
+	if basic["realm"] != "web" {
+		t.Errorf("expected the project name as realm, got %v", basic["realm"])
+	}
+
+	for _, route := range routes {
+		if route["match"].([]map[string]any)[0]["host"].([]string)[0] != "open.test" {
+			continue
+		}
+		for _, h := range route["handle"].([]map[string]any) {
+			if h["handler"] == "authentication" {
+				t.Error("expected no authentication without auth settings")
+			}
+		}
+	}
+}
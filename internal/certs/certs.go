@@ -1,4 +1,4 @@
-// Package certs handles root CA generation and macOS Keychain trust for
+// Package certs handles root CA generation and system trust-store integration for
 // local HTTPS development certificates.
 package certs
 
@@ -0,0 +1,79 @@
+package certs
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"math/big"
+	"time"
+)
+
+// LeafValidity is how long certificates issued by IssueLeafCert are valid.
+// They are kept in memory only, so a short lifetime costs nothing.
+const LeafValidity = 7 * 24 * time.Hour
+
+// LoadIntermediateCA reads the intermediate CA certificate and key from the
+// paths in CAPaths.
+func LoadIntermediateCA(paths CAPaths) (*x509.Certificate, *ecdsa.PrivateKey, error) {
+	return LoadCA(CAPaths{Cert: paths.IntermediateCert, Key: paths.IntermediateKey})
+}
+
+// IssueLeafCert issues an in-memory ECDSA P-256 server certificate for hosts,
+// signed by the intermediate CA. The returned certificate carries the
+// intermediate in its chain so clients trusting the root can verify it.
+// Caddy issues its own certificates for HTTP services; this is for TLS
+// that Hatch terminates itself.
+func IssueLeafCert(paths CAPaths, hosts ...string) (*tls.Certificate, error) {
+	if len(hosts) == 0 {
+		return nil, fmt.Errorf("no hosts to issue a certificate for")
+	}
+
+	caCert, caKey, err := LoadIntermediateCA(paths)
+	if err != nil {
+		return nil, fmt.Errorf("loading intermediate CA: %w", err)
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("generating leaf key: %w", err)
+	}
+
+	serialLimit := new(big.Int).Lsh(big.NewInt(1), 128)
+	serial, err := rand.Int(rand.Reader, serialLimit)
+	if err != nil {
+		return nil, fmt.Errorf("generating serial number: %w", err)
+	}
+
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject: pkix.Name{
+			CommonName:   hosts[0],
+			Organization: []string{CAOrg},
+		},
+		DNSNames:    hosts,
+		NotBefore:   now.Add(-time.Minute),
+		NotAfter:    now.Add(LeafValidity),
+		KeyUsage:    x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+
+	certDER, err := x509.CreateCertificate(rand.Reader, template, caCert, &key.PublicKey, caKey)
+	if err != nil {
+		return nil, fmt.Errorf("creating leaf certificate: %w", err)
+	}
+	leaf, err := x509.ParseCertificate(certDER)
+	if err != nil {
+		return nil, fmt.Errorf("parsing leaf certificate: %w", err)
+	}
+
+	return &tls.Certificate{
+		Certificate: [][]byte{certDER, caCert.Raw},
+		PrivateKey:  key,
+		Leaf:        leaf,
+	}, nil
+}
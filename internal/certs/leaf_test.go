@@ -0,0 +1,55 @@
+package certs
+
+import (
+	"crypto/x509"
+	"testing"
+)
+
+func TestIssueLeafCert(t *testing.T) {
+	paths := NewCAPaths(t.TempDir())
+	if err := GenerateCA(paths); err != nil {
+		t.Fatalf("GenerateCA: %v", err)
+	}
+	if err := GenerateIntermediateCA(paths); err != nil {
+		t.Fatalf("GenerateIntermediateCA: %v", err)
+	}
+
+	cert, err := IssueLeafCert(paths, "db.myapp.test")
+	if err != nil {
+		t.Fatalf("IssueLeafCert: %v", err)
+	}
+	if len(cert.Certificate) != 2 {
+		t.Fatalf("expected leaf and intermediate in chain, got %d certs", len(cert.Certificate))
+	}
+
+	root, _, err := LoadCA(paths)
+	if err != nil {
+		t.Fatal(err)
+	}
+	intermediate, err := x509.ParseCertificate(cert.Certificate[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	roots := x509.NewCertPool()
+	roots.AddCert(root)
+	inters := x509.NewCertPool()
+	inters.AddCert(intermediate)
+
+	if _, err := cert.Leaf.Verify(x509.VerifyOptions{
+		DNSName:       "db.myapp.test",
+		Roots:         roots,
+		Intermediates: inters,
+	}); err != nil {
+		t.Errorf("leaf does not verify against root: %v", err)
+	}
+}
+
+func TestIssueLeafCert_NoIntermediate(t *testing.T) {
+	paths := NewCAPaths(t.TempDir())
+	if err := GenerateCA(paths); err != nil {
+		t.Fatalf("GenerateCA: %v", err)
+	}
+	if _, err := IssueLeafCert(paths, "db.myapp.test"); err == nil {
+		t.Error("expected error without an intermediate CA")
+	}
+}
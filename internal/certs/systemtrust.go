@@ -0,0 +1,159 @@
+package certs
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+// anchorFileName is the file name used for the root CA in distribution
+// anchor directories. update-ca-certificates requires a .crt extension.
+const anchorFileName = "hatch-rootCA.crt"
+
+// systemAnchor describes a distribution's CA anchor directory and the
+// command that rebuilds the system bundle from it.
+type systemAnchor struct {
+	Dir    string
+	Update string
+}
+
+// systemAnchors lists the supported anchor layouts in detection order.
+var systemAnchors = []systemAnchor{
+	{Dir: "/usr/local/share/ca-certificates", Update: "update-ca-certificates"},   // Debian, Ubuntu
+	{Dir: "/etc/pki/ca-trust/source/anchors", Update: "update-ca-trust extract"},  // Fedora, RHEL
+	{Dir: "/etc/ca-certificates/trust-source/anchors", Update: "update-ca-trust"}, // Arch
+}
+
+// LinuxTrustStore trusts the root CA in the distribution CA bundle and in
+// the user's NSS databases (Chromium's ~/.pki/nssdb and Firefox profiles),
+// which do not consult the system bundle.
+type LinuxTrustStore struct {
+	anchor *systemAnchor // nil when no supported layout was found
+	nssDBs []string      // NSS database directories
+	user   CommandRunner // runs certutil as the current user
+}
+
+// NewLinuxTrustStore detects the distribution's anchor directory and the
+// user's NSS databases. NSS databases are skipped when certutil is not
+// installed. certutil commands are issued through user so the databases
+// stay owned by the current user.
+func NewLinuxTrustStore(user CommandRunner) *LinuxTrustStore {
+	s := &LinuxTrustStore{user: user}
+	for i := range systemAnchors {
+		if info, err := os.Stat(systemAnchors[i].Dir); err == nil && info.IsDir() {
+			s.anchor = &systemAnchors[i]
+			break
+		}
+	}
+	if _, err := exec.LookPath("certutil"); err == nil {
+		if home, err := os.UserHomeDir(); err == nil {
+			s.nssDBs = findNSSDBs(home)
+		}
+	}
+	return s
+}
+
+// Name returns a human-readable name for the trust store.
+func (s *LinuxTrustStore) Name() string { return "system trust store" }
+
+// Trust copies the certificate into the anchor directory, rebuilds the
+// system bundle, and adds it to each NSS database.
+func (s *LinuxTrustStore) Trust(runner CommandRunner, certPath string) error {
+	if s.anchor == nil {
+		return errors.New("trusting CA certificate: no supported system trust store found")
+	}
+	dest := filepath.Join(s.anchor.Dir, anchorFileName)
+	cmd := fmt.Sprintf("install -m 644 %s %s && %s", certPath, dest, s.anchor.Update)
+	if err := runner.Run(cmd); err != nil {
+		return fmt.Errorf("trusting CA certificate: %w", err)
+	}
+
+	for _, db := range s.nssDBs {
+		cmd := fmt.Sprintf("certutil -A -d sql:%s -t C,, -n '%s' -i %s", db, CACommonName, certPath)
+		if err := s.user.Run(cmd); err != nil {
+			return fmt.Errorf("trusting CA certificate in %s: %w", db, err)
+		}
+	}
+	return nil
+}
+
+// Untrust removes the certificate from the anchor directory, rebuilds the
+// system bundle, and deletes it from each NSS database.
+func (s *LinuxTrustStore) Untrust(runner CommandRunner, certPath string) error {
+	if s.anchor != nil {
+		dest := filepath.Join(s.anchor.Dir, anchorFileName)
+		cmd := fmt.Sprintf("rm -f %s && %s", dest, s.anchor.Update)
+		if err := runner.Run(cmd); err != nil {
+			return fmt.Errorf("untrusting CA certificate: %w", err)
+		}
+	}
+
+	// certutil fails when the nickname is absent; that is not an error here.
+	for _, db := range s.nssDBs {
+		_ = s.user.Run(fmt.Sprintf("certutil -D -d sql:%s -n '%s'", db, CACommonName))
+	}
+	return nil
+}
+
+// IsTrusted reports whether the certificate at certPath verifies against
+// the system root pool.
+func (s *LinuxTrustStore) IsTrusted(certPath string) bool {
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		return false
+	}
+	return verifiesAgainstPool(certPath, pool)
+}
+
+// verifiesAgainstPool reports whether the PEM certificate at certPath
+// chains to a root in pool.
+func verifiesAgainstPool(certPath string, pool *x509.CertPool) bool {
+	data, err := os.ReadFile(certPath)
+	if err != nil {
+		return false
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return false
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return false
+	}
+	_, err = cert.Verify(x509.VerifyOptions{
+		Roots:     pool,
+		KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	return err == nil
+}
+
+// findNSSDBs returns the NSS database directories under home: the shared
+// Chromium database and every Firefox profile (including the snap
+// package's profiles). Only directories containing cert9.db are returned.
+func findNSSDBs(home string) []string {
+	var dbs []string
+	if hasNSSDB(filepath.Join(home, ".pki", "nssdb")) {
+		dbs = append(dbs, filepath.Join(home, ".pki", "nssdb"))
+	}
+	for _, pattern := range []string{
+		filepath.Join(home, ".mozilla", "firefox", "*"),
+		filepath.Join(home, "snap", "firefox", "common", ".mozilla", "firefox", "*"),
+	} {
+		matches, _ := filepath.Glob(pattern)
+		for _, dir := range matches {
+			if hasNSSDB(dir) {
+				dbs = append(dbs, dir)
+			}
+		}
+	}
+	return dbs
+}
+
+func hasNSSDB(dir string) bool {
+	_, err := os.Stat(filepath.Join(dir, "cert9.db"))
+	return err == nil
+}
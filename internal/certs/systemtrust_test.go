@@ -0,0 +1,158 @@
+package certs
+
+import (
+	"crypto/x509"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func testLinuxTrustStore(user CommandRunner, nssDBs ...string) *LinuxTrustStore {
+	return &LinuxTrustStore{
+		anchor: &systemAnchors[0],
+		nssDBs: nssDBs,
+		user:   user,
+	}
+}
+
+func TestLinuxTrustStore_Trust(t *testing.T) {
+	runner := &MockRunner{}
+	user := &MockRunner{}
+	s := testLinuxTrustStore(user, "/home/u/.pki/nssdb")
+
+	if err := s.Trust(runner, "/tmp/certs/rootCA.pem"); err != nil {
+		t.Fatalf("Trust: %v", err)
+	}
+
+	wantSystem := "install -m 644 /tmp/certs/rootCA.pem /usr/local/share/ca-certificates/hatch-rootCA.crt && update-ca-certificates"
+	if len(runner.Commands) != 1 || runner.Commands[0] != wantSystem {
+		t.Errorf("system commands = %v, want [%s]", runner.Commands, wantSystem)
+	}
+
+	wantNSS := "certutil -A -d sql:/home/u/.pki/nssdb -t C,, -n 'Hatch Local CA' -i /tmp/certs/rootCA.pem"
+	if len(user.Commands) != 1 || user.Commands[0] != wantNSS {
+		t.Errorf("nss commands = %v, want [%s]", user.Commands, wantNSS)
+	}
+}
+
+func TestLinuxTrustStore_TrustRHEL(t *testing.T) {
+	runner := &MockRunner{}
+	s := &LinuxTrustStore{anchor: &systemAnchors[1], user: &MockRunner{}}
+
+	if err := s.Trust(runner, "/tmp/certs/rootCA.pem"); err != nil {
+		t.Fatalf("Trust: %v", err)
+	}
+
+	want := "install -m 644 /tmp/certs/rootCA.pem /etc/pki/ca-trust/source/anchors/hatch-rootCA.crt && update-ca-trust extract"
+	if runner.Commands[0] != want {
+		t.Errorf("unexpected command:\n  got:  %s\n  want: %s", runner.Commands[0], want)
+	}
+}
+
+func TestLinuxTrustStore_TrustNoAnchor(t *testing.T) {
+	runner := &MockRunner{}
+	s := &LinuxTrustStore{user: &MockRunner{}}
+
+	if err := s.Trust(runner, "/tmp/certs/rootCA.pem"); err == nil {
+		t.Fatal("expected error when no trust store is available")
+	}
+	if len(runner.Commands) != 0 {
+		t.Errorf("expected no commands, got %v", runner.Commands)
+	}
+}
+
+func TestLinuxTrustStore_TrustError(t *testing.T) {
+	runner := &MockRunner{Err: errors.New("permission denied")}
+	user := &MockRunner{}
+	s := testLinuxTrustStore(user, "/home/u/.pki/nssdb")
+
+	err := s.Trust(runner, "/tmp/certs/rootCA.pem")
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if !strings.Contains(err.Error(), "trusting CA certificate") {
+		t.Errorf("expected wrapped error, got: %v", err)
+	}
+	if len(user.Commands) != 0 {
+		t.Errorf("expected NSS databases to be skipped, got %v", user.Commands)
+	}
+}
+
+func TestLinuxTrustStore_Untrust(t *testing.T) {
+	runner := &MockRunner{}
+	user := &MockRunner{Err: errors.New("could not find certificate")}
+	s := testLinuxTrustStore(user, "/home/u/.pki/nssdb", "/home/u/.mozilla/firefox/abc.default")
+
+	if err := s.Untrust(runner, "/tmp/certs/rootCA.pem"); err != nil {
+		t.Fatalf("Untrust: %v", err)
+	}
+
+	wantSystem := "rm -f /usr/local/share/ca-certificates/hatch-rootCA.crt && update-ca-certificates"
+	if len(runner.Commands) != 1 || runner.Commands[0] != wantSystem {
+		t.Errorf("system commands = %v, want [%s]", runner.Commands, wantSystem)
+	}
+	if len(user.Commands) != 2 {
+		t.Fatalf("expected 2 NSS commands, got %v", user.Commands)
+	}
+	if user.Commands[1] != "certutil -D -d sql:/home/u/.mozilla/firefox/abc.default -n 'Hatch Local CA'" {
+		t.Errorf("unexpected NSS command: %s", user.Commands[1])
+	}
+}
+
+func TestVerifiesAgainstPool(t *testing.T) {
+	dir := t.TempDir()
+	paths := NewCAPaths(dir)
+	if err := GenerateCA(paths); err != nil {
+		t.Fatalf("GenerateCA: %v", err)
+	}
+
+	if verifiesAgainstPool(paths.Cert, x509.NewCertPool()) {
+		t.Error("expected CA not to verify against an empty pool")
+	}
+
+	cert, _, err := LoadCA(paths)
+	if err != nil {
+		t.Fatalf("LoadCA: %v", err)
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	if !verifiesAgainstPool(paths.Cert, pool) {
+		t.Error("expected CA to verify against a pool containing it")
+	}
+
+	if verifiesAgainstPool(filepath.Join(dir, "missing.pem"), pool) {
+		t.Error("expected missing certificate not to verify")
+	}
+}
+
+func TestFindNSSDBs(t *testing.T) {
+	home := t.TempDir()
+	for _, dir := range []string{
+		filepath.Join(home, ".pki", "nssdb"),
+		filepath.Join(home, ".mozilla", "firefox", "abc.default-release"),
+		filepath.Join(home, ".mozilla", "firefox", "Crash Reports"),
+	} {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, dir := range []string{
+		filepath.Join(home, ".pki", "nssdb"),
+		filepath.Join(home, ".mozilla", "firefox", "abc.default-release"),
+	} {
+		if err := os.WriteFile(filepath.Join(dir, "cert9.db"), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got := findNSSDBs(home)
+	want := []string{
+		filepath.Join(home, ".pki", "nssdb"),
+		filepath.Join(home, ".mozilla", "firefox", "abc.default-release"),
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("findNSSDBs() = %v, want %v", got, want)
+	}
+}
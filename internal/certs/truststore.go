@@ -0,0 +1,51 @@
+package certs
+
+import (
+	"runtime"
+
+	"github.com/paulrose/hatch/internal/shell"
+)
+
+// TrustStore installs the root CA into the operating system's trust store.
+type TrustStore interface {
+	// Name returns a human-readable name for the trust store, e.g.
+	// "Keychain".
+	Name() string
+	// Trust adds the certificate at certPath as a trusted root. Requires
+	// elevated privileges via the provided runner.
+	Trust(runner CommandRunner, certPath string) error
+	// Untrust removes the certificate at certPath. Requires elevated
+	// privileges via the provided runner.
+	Untrust(runner CommandRunner, certPath string) error
+	// IsTrusted reports whether the certificate at certPath is trusted.
+	IsTrusted(certPath string) bool
+}
+
+// NewTrustStore returns the TrustStore for the current platform: the
+// System Keychain on macOS, and the distribution CA bundle plus NSS
+// databases on Linux.
+func NewTrustStore() TrustStore {
+	switch runtime.GOOS {
+	case "linux":
+		// NSS databases live in the user's home directory, so they are
+		// updated as the current user.
+		return NewLinuxTrustStore(shell.Runner{})
+	default:
+		return keychain{}
+	}
+}
+
+// keychain adapts the macOS security helpers to TrustStore.
+type keychain struct{}
+
+func (keychain) Name() string { return "Keychain" }
+
+func (keychain) Trust(runner CommandRunner, certPath string) error {
+	return TrustCA(runner, certPath)
+}
+
+func (keychain) Untrust(runner CommandRunner, certPath string) error {
+	return UntrustCA(runner, certPath)
+}
+
+func (keychain) IsTrusted(certPath string) bool { return IsCATrusted(certPath) }
@@ -0,0 +1,157 @@
+package chaos
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	caddyv2 "github.com/caddyserver/caddy/v2"
+	"github.com/caddyserver/caddy/v2/modules/caddyhttp"
+
+	"github.com/paulrose/hatch/internal/config"
+	"github.com/paulrose/hatch/internal/errorpage"
+)
+
+func serve(t *testing.T, h *Handler, req *http.Request, body []byte) (*httptest.ResponseRecorder, bool) {
+	t.Helper()
+	passed := false
+	next := caddyhttp.HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+		passed = true
+		_, err := w.Write(body)
+		return err
+	})
+	w := httptest.NewRecorder()
+	if err := h.ServeHTTP(w, req, next); err != nil {
+		t.Fatal(err)
+	}
+	return w, passed
+}
+
+func TestHandler_Latency(t *testing.T) {
+	h := &Handler{Latency: caddyv2.Duration(50 * time.Millisecond), Jitter: caddyv2.Duration(20 * time.Millisecond)}
+	start := time.Now()
+	_, passed := serve(t, h, httptest.NewRequest("GET", "https://web.test/", nil), nil)
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond || !passed {
+		t.Errorf("expected the request to pass after at least 50ms, took %s", elapsed)
+	}
+
+	// Requests cancelled while waiting give up.
+	slow := &Handler{Latency: caddyv2.Duration(time.Minute)}
+	req := httptest.NewRequest("GET", "https://web.test/", nil)
+	ctx, cancel := context.WithCancel(req.Context())
+	cancel()
+	if err := slow.ServeHTTP(httptest.NewRecorder(), req.WithContext(ctx), caddyhttp.HandlerFunc(func(http.ResponseWriter, *http.Request) error {
+		t.Error("expected a cancelled request not to reach the upstream")
+		return nil
+	})); err == nil {
+		t.Error("expected an error for a cancelled request")
+	}
+}
+
+func TestHandler_Errors(t *testing.T) {
+	h := &Handler{ErrorRate: 1, ErrorStatus: http.StatusBadGateway}
+	w, passed := serve(t, h, httptest.NewRequest("GET", "https://web.test/", nil), nil)
+	if passed || w.Code != http.StatusBadGateway || w.Header().Get(Header) != "error" {
+		t.Errorf("expected an injected 502, got %d %v (passed %v)", w.Code, w.Header(), passed)
+	}
+
+	w, _ = serve(t, &Handler{ErrorRate: 1}, httptest.NewRequest("GET", "https://web.test/", nil), nil)
+	if w.Code != DefaultErrorStatus {
+		t.Errorf("expected the default status, got %d", w.Code)
+	}
+
+	// The error page's probes are let through.
+	probe := httptest.NewRequest("GET", "https://web.test/", nil)
+	probe.Header.Set(errorpage.ProbeHeader, "1")
+	if _, passed := serve(t, h, probe, nil); !passed {
+		t.Error("expected probes to pass through")
+	}
+}
+
+func TestHandler_Bandwidth(t *testing.T) {
+	// 80 kbps is 10 KB/s, so 2.5 KB takes about a quarter of a second.
+	h := &Handler{BandwidthKbps: 80}
+	body := bytes.Repeat([]byte("x"), 2500)
+	start := time.Now()
+	w, _ := serve(t, h, httptest.NewRequest("GET", "https://web.test/", nil), body)
+	if elapsed := time.Since(start); elapsed < 200*time.Millisecond {
+		t.Errorf("expected the response to be throttled, took %s", elapsed)
+	}
+	if !bytes.Equal(w.Body.Bytes(), body) {
+		t.Errorf("expected the whole body, got %d bytes", w.Body.Len())
+	}
+}
+
+func testConfig() config.Config {
+	cfg := config.DefaultConfig()
+	cfg.Projects["web"] = config.Project{
+		Domain:  "web.test",
+		Enabled: true,
+		Services: map[string]config.Service{
+			"app": {Proxy: "http://localhost:3000", Chaos: &config.Chaos{Latency: "1s"}},
+			"api": {Proxy: "http://localhost:4000"},
+		},
+	}
+	return cfg
+}
+
+func TestManager(t *testing.T) {
+	var mu sync.Mutex
+	var changes []bool
+	expired := make(chan struct{}, 1)
+	m := NewManager(ManagerConfig{OnChange: func(o Override, active bool) {
+		mu.Lock()
+		changes = append(changes, active)
+		mu.Unlock()
+		if !active && o.Service == "api" {
+			expired <- struct{}{}
+		}
+	}})
+	defer m.Close()
+
+	cfg := testConfig()
+	m.Set("web", "app", config.Chaos{ErrorRate: 0.5}, 0)
+	m.Set("web", "api", config.Chaos{BandwidthKbps: 64}, 50*time.Millisecond)
+	m.Set("gone", "app", config.Chaos{Latency: "1s"}, 0)
+
+	applied := m.Apply(cfg)
+	if c := applied.Projects["web"].Services["app"].Chaos; c == nil || c.ErrorRate != 0.5 || c.Latency != "" {
+		t.Errorf("expected the override to replace the configured chaos, got %+v", c)
+	}
+	if c := applied.Projects["web"].Services["api"].Chaos; c == nil || c.BandwidthKbps != 64 {
+		t.Errorf("expected the override to apply, got %+v", c)
+	}
+	if cfg.Projects["web"].Services["app"].Chaos.Latency != "1s" || cfg.Projects["web"].Services["api"].Chaos != nil {
+		t.Error("expected Apply to leave its argument unchanged")
+	}
+	if _, ok := applied.Projects["gone"]; ok {
+		t.Error("expected overrides of missing projects to be ignored")
+	}
+
+	select {
+	case <-expired:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the override to expire")
+	}
+	if list := m.List(); len(list) != 2 || list[0].Project != "gone" || list[1].Service != "app" {
+		t.Errorf("unexpected overrides after expiry %+v", list)
+	}
+
+	if !m.Clear("web", "app") || m.Clear("web", "app") {
+		t.Error("expected Clear to report whether an override was removed")
+	}
+	if c := m.Apply(cfg).Projects["web"].Services["app"].Chaos; c == nil || c.Latency != "1s" {
+		t.Errorf("expected the configured chaos back after clearing, got %+v", c)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if want := []bool{true, true, true, false, false}; !slices.Equal(changes, want) {
+		t.Errorf("expected changes %v, got %v", want, changes)
+	}
+}
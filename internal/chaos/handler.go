@@ -0,0 +1,149 @@
+// Package chaos injects faults into HTTP services: the hatch_chaos Caddy
+// handler delays requests, fails a fraction of them and throttles
+// responses as a service's chaos settings ask, and the Manager holds the
+// settings "hatch chaos" applies for a limited time.
+package chaos
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"time"
+
+	caddyv2 "github.com/caddyserver/caddy/v2"
+	"github.com/caddyserver/caddy/v2/modules/caddyhttp"
+
+	"github.com/paulrose/hatch/internal/errorpage"
+)
+
+// Header marks responses of failed requests, so that they can be told
+// apart from real upstream errors.
+const Header = "X-Hatch-Chaos"
+
+// DefaultErrorStatus is the status of failed requests unless configured.
+const DefaultErrorStatus = http.StatusServiceUnavailable
+
+func init() {
+	caddyv2.RegisterModule(Handler{})
+}
+
+// Handler is the hatch_chaos Caddy handler. It sits in front of a
+// service's reverse_proxy or file_server.
+type Handler struct {
+	Latency       caddyv2.Duration `json:"latency,omitempty"`
+	Jitter        caddyv2.Duration `json:"jitter,omitempty"`
+	ErrorRate     float64          `json:"error_rate,omitempty"`
+	ErrorStatus   int              `json:"error_status,omitempty"`
+	BandwidthKbps int              `json:"bandwidth_kbps,omitempty"`
+}
+
+// CaddyModule returns the Caddy module information.
+func (Handler) CaddyModule() caddyv2.ModuleInfo {
+	return caddyv2.ModuleInfo{
+		ID:  "http.handlers.hatch_chaos",
+		New: func() caddyv2.Module { return new(Handler) },
+	}
+}
+
+// ServeHTTP waits out the latency, then either fails the request or
+// passes it to next with the response throttled.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request, next caddyhttp.Handler) error {
+	if r.Header.Get(errorpage.ProbeHeader) != "" {
+		return next.ServeHTTP(w, r)
+	}
+
+	if delay := h.delay(); delay > 0 {
+		t := time.NewTimer(delay)
+		select {
+		case <-r.Context().Done():
+			t.Stop()
+			return r.Context().Err()
+		case <-t.C:
+		}
+	}
+
+	if h.ErrorRate > 0 && rand.Float64() < h.ErrorRate {
+		status := h.ErrorStatus
+		if status == 0 {
+			status = DefaultErrorStatus
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Header().Set(Header, "error")
+		w.WriteHeader(status)
+		fmt.Fprintf(w, "hatch chaos: injected %d %s\n", status, http.StatusText(status))
+		return nil
+	}
+
+	if h.BandwidthKbps > 0 {
+		w = newThrottledWriter(w, h.BandwidthKbps)
+	}
+	return next.ServeHTTP(w, r)
+}
+
+// delay returns the latency plus a random share of the jitter.
+func (h *Handler) delay() time.Duration {
+	d := time.Duration(h.Latency)
+	if h.Jitter > 0 {
+		d += rand.N(time.Duration(h.Jitter))
+	}
+	return d
+}
+
+// throttledWriter limits the rate a response body is written at. It
+// deliberately has no ReadFrom, so that copies go through Write.
+type throttledWriter struct {
+	http.ResponseWriter
+	bytesPerSec int
+	start       time.Time
+	written     int
+}
+
+func newThrottledWriter(w http.ResponseWriter, kbps int) *throttledWriter {
+	return &throttledWriter{
+		ResponseWriter: w,
+		bytesPerSec:    max(kbps*1000/8, 1),
+	}
+}
+
+// Write writes p in chunks of at most a tenth of a second's allowance,
+// sleeping whenever the body is ahead of the allowed rate.
+func (tw *throttledWriter) Write(p []byte) (int, error) {
+	if tw.start.IsZero() {
+		tw.start = time.Now()
+	}
+	chunk := max(tw.bytesPerSec/10, 1)
+	var n int
+	for len(p) > 0 {
+		size := min(chunk, len(p))
+		m, err := tw.ResponseWriter.Write(p[:size])
+		n += m
+		tw.written += m
+		if err != nil {
+			return n, err
+		}
+		p = p[size:]
+
+		due := tw.start.Add(time.Duration(tw.written) * time.Second / time.Duration(tw.bytesPerSec))
+		if wait := time.Until(due); wait > 0 {
+			tw.Flush()
+			time.Sleep(wait)
+		}
+	}
+	return n, nil
+}
+
+// Flush sends what has been written so far to the client.
+func (tw *throttledWriter) Flush() {
+	http.NewResponseController(tw.ResponseWriter).Flush()
+}
+
+// Unwrap returns the underlying writer, for http.ResponseController.
+func (tw *throttledWriter) Unwrap() http.ResponseWriter {
+	return tw.ResponseWriter
+}
+
+// Interface guards.
+var (
+	_ caddyhttp.MiddlewareHandler = (*Handler)(nil)
+	_ http.Flusher                = (*throttledWriter)(nil)
+)
@@ -0,0 +1,166 @@
+package chaos
+
+import (
+	"maps"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/paulrose/hatch/internal/config"
+	"github.com/paulrose/hatch/internal/health"
+)
+
+// Override is chaos applied to a service at runtime in place of its
+// configured settings. Expires is zero for an override that lasts until
+// cleared.
+type Override struct {
+	Project string       `json:"project"`
+	Service string       `json:"service"`
+	Chaos   config.Chaos `json:"chaos"`
+	Expires time.Time    `json:"expires,omitzero"`
+}
+
+// ManagerConfig holds the configuration for a Manager.
+type ManagerConfig struct {
+	// OnChange is called whenever an override is set, cleared or expires,
+	// without the Manager's lock held.
+	OnChange func(Override, bool)
+}
+
+// Manager holds the runtime overrides and clears each when its time is up.
+type Manager struct {
+	onChange func(Override, bool)
+
+	mu        sync.Mutex
+	overrides map[health.ServiceKey]*entry
+}
+
+type entry struct {
+	Override
+	timer *time.Timer
+}
+
+// NewManager creates a Manager with no overrides.
+func NewManager(cfg ManagerConfig) *Manager {
+	return &Manager{
+		onChange:  cfg.OnChange,
+		overrides: make(map[health.ServiceKey]*entry),
+	}
+}
+
+// Set applies c to the service for d, replacing any override it has.
+// With d zero the override lasts until cleared.
+func (m *Manager) Set(project, service string, c config.Chaos, d time.Duration) Override {
+	key := health.ServiceKey{Project: project, Service: service}
+	e := &entry{Override: Override{Project: project, Service: service, Chaos: c}}
+
+	m.mu.Lock()
+	if old, ok := m.overrides[key]; ok && old.timer != nil {
+		old.timer.Stop()
+	}
+	if d > 0 {
+		e.Expires = time.Now().Add(d)
+		e.timer = time.AfterFunc(d, func() { m.expire(key, e) })
+	}
+	m.overrides[key] = e
+	m.mu.Unlock()
+
+	m.changed(e.Override, true)
+	return e.Override
+}
+
+// Clear removes the service's override, reporting whether it had one.
+func (m *Manager) Clear(project, service string) bool {
+	key := health.ServiceKey{Project: project, Service: service}
+	m.mu.Lock()
+	e, ok := m.overrides[key]
+	if ok {
+		if e.timer != nil {
+			e.timer.Stop()
+		}
+		delete(m.overrides, key)
+	}
+	m.mu.Unlock()
+
+	if ok {
+		m.changed(e.Override, false)
+	}
+	return ok
+}
+
+// expire removes e when its timer fires, unless it has been replaced.
+func (m *Manager) expire(key health.ServiceKey, e *entry) {
+	m.mu.Lock()
+	current := m.overrides[key] == e
+	if current {
+		delete(m.overrides, key)
+	}
+	m.mu.Unlock()
+
+	if current {
+		m.changed(e.Override, false)
+	}
+}
+
+func (m *Manager) changed(o Override, active bool) {
+	if m.onChange != nil {
+		m.onChange(o, active)
+	}
+}
+
+// List returns the overrides in effect, ordered by project and service.
+func (m *Manager) List() []Override {
+	m.mu.Lock()
+	out := make([]Override, 0, len(m.overrides))
+	for _, e := range m.overrides {
+		out = append(out, e.Override)
+	}
+	m.mu.Unlock()
+
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Project != out[j].Project {
+			return out[i].Project < out[j].Project
+		}
+		return out[i].Service < out[j].Service
+	})
+	return out
+}
+
+// Apply returns cfg with the overrides in effect replacing the chaos
+// settings of their services. Overrides of services cfg lacks are ignored.
+func (m *Manager) Apply(cfg config.Config) config.Config {
+	overrides := m.List()
+	if len(overrides) == 0 {
+		return cfg
+	}
+
+	out := cfg
+	out.Projects = maps.Clone(cfg.Projects)
+	for _, o := range overrides {
+		proj, ok := out.Projects[o.Project]
+		if !ok {
+			continue
+		}
+		svc, ok := proj.Services[o.Service]
+		if !ok || svc.IsTCP() {
+			continue
+		}
+		c := o.Chaos
+		svc.Chaos = &c
+		proj.Services = maps.Clone(proj.Services)
+		proj.Services[o.Service] = svc
+		out.Projects[o.Project] = proj
+	}
+	return out
+}
+
+// Close stops the expiry timers. The overrides stay in place.
+func (m *Manager) Close() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, e := range m.overrides {
+		if e.timer != nil {
+			e.timer.Stop()
+		}
+	}
+}
@@ -0,0 +1,165 @@
+// Package compose reads Docker Compose files and proposes a Hatch project
+// for them: one service per HTTP port a Compose service publishes on the
+// host, reached at a subdomain named after the Compose service.
+package compose
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FileNames are the names Docker Compose looks for, in order of
+// preference.
+var FileNames = []string{"compose.yaml", "compose.yml", "docker-compose.yaml", "docker-compose.yml"}
+
+// File is the part of a Compose file Hatch reads.
+type File struct {
+	Name     string             `yaml:"name"`
+	Services map[string]Service `yaml:"services"`
+}
+
+// Service is a Compose service.
+type Service struct {
+	Ports []Port `yaml:"ports"`
+}
+
+// Port is an entry of a service's ports, in either the short
+// ("127.0.0.1:8080:80/tcp") or the long syntax. Published is empty when
+// Docker picks the host port.
+type Port struct {
+	HostIP    string
+	Published string
+	Target    string
+	Protocol  string
+}
+
+// UnmarshalYAML decodes either port syntax, interpolating environment
+// variables as Compose does.
+func (p *Port) UnmarshalYAML(n *yaml.Node) error {
+	if n.Kind == yaml.ScalarNode {
+		parsed, err := parsePort(interpolate(n.Value))
+		if err != nil {
+			return fmt.Errorf("line %d: %w", n.Line, err)
+		}
+		*p = parsed
+		return nil
+	}
+
+	var long struct {
+		HostIP    string `yaml:"host_ip"`
+		Published string `yaml:"published"`
+		Target    string `yaml:"target"`
+		Protocol  string `yaml:"protocol"`
+	}
+	if err := n.Decode(&long); err != nil {
+		return err
+	}
+	*p = Port{
+		HostIP:    interpolate(long.HostIP),
+		Published: interpolate(long.Published),
+		Target:    interpolate(long.Target),
+		Protocol:  strings.ToLower(long.Protocol),
+	}
+	if p.Protocol == "" {
+		p.Protocol = "tcp"
+	}
+	return nil
+}
+
+// parsePort parses the short port syntax: [[host_ip:]published:]target[/protocol].
+func parsePort(s string) (Port, error) {
+	p := Port{Protocol: "tcp"}
+	if i := strings.LastIndex(s, "/"); i >= 0 {
+		s, p.Protocol = s[:i], strings.ToLower(s[i+1:])
+	}
+
+	// An IPv6 host address is bracketed: [::1]:8080:80.
+	if strings.HasPrefix(s, "[") {
+		end := strings.Index(s, "]:")
+		if end < 0 {
+			return Port{}, fmt.Errorf("invalid port %q", s)
+		}
+		p.HostIP, s = s[1:end], s[end+2:]
+		published, target, ok := strings.Cut(s, ":")
+		if !ok {
+			return Port{}, fmt.Errorf("invalid port %q", s)
+		}
+		p.Published, p.Target = published, target
+		return p, nil
+	}
+
+	parts := strings.Split(s, ":")
+	switch len(parts) {
+	case 1:
+		p.Target = parts[0]
+	case 2:
+		p.Published, p.Target = parts[0], parts[1]
+	case 3:
+		p.HostIP, p.Published, p.Target = parts[0], parts[1], parts[2]
+	default:
+		return Port{}, fmt.Errorf("invalid port %q", s)
+	}
+	return p, nil
+}
+
+// interpolate substitutes $VAR, ${VAR}, ${VAR:-default} and
+// ${VAR-default} from the environment. $$ is a literal $.
+func interpolate(s string) string {
+	if !strings.Contains(s, "$") {
+		return s
+	}
+	return os.Expand(s, func(name string) string {
+		if name == "$" {
+			return "$"
+		}
+		if key, def, ok := strings.Cut(name, ":-"); ok {
+			if v := os.Getenv(key); v != "" {
+				return v
+			}
+			return def
+		}
+		if key, def, ok := strings.Cut(name, "-"); ok {
+			if v, set := os.LookupEnv(key); set {
+				return v
+			}
+			return def
+		}
+		return os.Getenv(name)
+	})
+}
+
+// Find returns the Compose file in dir, trying FileNames in order.
+func Find(dir string) (string, error) {
+	for _, name := range FileNames {
+		path := filepath.Join(dir, name)
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("no compose file found in %s (looked for %s)", dir, strings.Join(FileNames, ", "))
+}
+
+// Load reads and parses the Compose file at path.
+func Load(path string) (File, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return File{}, fmt.Errorf("reading compose file: %w", err)
+	}
+	return Parse(data)
+}
+
+// Parse parses the contents of a Compose file.
+func Parse(data []byte) (File, error) {
+	var f File
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return File{}, fmt.Errorf("parsing compose file: %w", err)
+	}
+	if len(f.Services) == 0 {
+		return File{}, fmt.Errorf("compose file has no services")
+	}
+	return f, nil
+}
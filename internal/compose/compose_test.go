@@ -0,0 +1,141 @@
+package compose
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const sample = `
+name: shop
+services:
+  web:
+    image: nginx
+    ports:
+      - "8080:80"
+      - "127.0.0.1:8443:443/tcp"
+  api_v2:
+    build: ./api
+    ports:
+      - target: 3000
+        published: ${API_PORT:-3001}
+        host_ip: 127.0.0.1
+  db:
+    image: postgres
+    ports:
+      - "5432:5432"
+  worker:
+    image: worker
+  metrics:
+    image: statsd
+    ports:
+      - "8125:8125/udp"
+      - "9102"
+`
+
+func TestParsePort(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Port
+	}{
+		{"80", Port{Target: "80", Protocol: "tcp"}},
+		{"8080:80", Port{Published: "8080", Target: "80", Protocol: "tcp"}},
+		{"127.0.0.1:8080:80/udp", Port{HostIP: "127.0.0.1", Published: "8080", Target: "80", Protocol: "udp"}},
+		{"127.0.0.1::80", Port{HostIP: "127.0.0.1", Target: "80", Protocol: "tcp"}},
+		{"[::1]:8080:80", Port{HostIP: "::1", Published: "8080", Target: "80", Protocol: "tcp"}},
+	}
+	for _, tt := range tests {
+		got, err := parsePort(tt.in)
+		if err != nil {
+			t.Errorf("parsePort(%q): %v", tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parsePort(%q) = %+v, want %+v", tt.in, got, tt.want)
+		}
+	}
+
+	if _, err := parsePort("1:2:3:4"); err == nil {
+		t.Error("expected an error for too many fields")
+	}
+}
+
+func TestInterpolate(t *testing.T) {
+	t.Setenv("WEB_PORT", "9000")
+	t.Setenv("EMPTY", "")
+
+	tests := map[string]string{
+		"${WEB_PORT}:80":      "9000:80",
+		"$WEB_PORT:80":        "9000:80",
+		"${MISSING:-8080}:80": "8080:80",
+		"${EMPTY:-8080}:80":   "8080:80",
+		"${EMPTY-8080}:80":    ":80",
+		"${MISSING-8080}:80":  "8080:80",
+		"$$WEB_PORT":          "$WEB_PORT",
+		"no variables at all": "no variables at all",
+	}
+	for in, want := range tests {
+		if got := interpolate(in); got != want {
+			t.Errorf("interpolate(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestProjectConfig(t *testing.T) {
+	f, err := Parse([]byte(sample))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Name != "shop" {
+		t.Errorf("expected the compose project name, got %q", f.Name)
+	}
+
+	pc, skipped := ProjectConfig(f, "shop.test")
+	if pc.Domain != "shop.test" || len(pc.Services) != 3 {
+		t.Fatalf("unexpected project %+v", pc)
+	}
+	if web := pc.Services["web"]; web.Proxy != "http://127.0.0.1:8080" || web.Subdomain != "web" {
+		t.Errorf("unexpected web service %+v", web)
+	}
+	if tls := pc.Services["web-443"]; tls.Proxy != "http://127.0.0.1:8443" || tls.Subdomain != "web-443" {
+		t.Errorf("unexpected second web port %+v", tls)
+	}
+	if api := pc.Services["api_v2"]; api.Proxy != "http://127.0.0.1:3001" || api.Subdomain != "api-v2" {
+		t.Errorf("expected a valid subdomain and the default port, got %+v", api)
+	}
+
+	// The database, the udp port and the unpublished port are reported.
+	if len(skipped) != 3 {
+		t.Errorf("expected three skipped ports, got %v", skipped)
+	}
+}
+
+func TestFind(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := Find(dir); err == nil {
+		t.Fatal("expected an error without a compose file")
+	}
+
+	for _, name := range []string{"docker-compose.yml", "compose.yaml"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(sample), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	path, err := Find(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Base(path) != "compose.yaml" {
+		t.Errorf("expected compose.yaml to be preferred, got %s", path)
+	}
+
+	if _, err := Load(path); err != nil {
+		t.Errorf("Load: %v", err)
+	}
+}
+
+func TestParse_NoServices(t *testing.T) {
+	if _, err := Parse([]byte("name: empty\n")); err == nil {
+		t.Error("expected an error for a file without services")
+	}
+}
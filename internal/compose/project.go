@@ -0,0 +1,122 @@
+package compose
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/paulrose/hatch/internal/config"
+)
+
+// nonHTTPPorts are the default container ports of common services that
+// do not speak HTTP, such as databases and message brokers.
+var nonHTTPPorts = map[int]string{
+	1433:  "SQL Server",
+	1521:  "Oracle",
+	2181:  "ZooKeeper",
+	3306:  "MySQL",
+	4222:  "NATS",
+	5432:  "PostgreSQL",
+	5672:  "AMQP",
+	6379:  "Redis",
+	9042:  "Cassandra",
+	9092:  "Kafka",
+	11211: "Memcached",
+	27017: "MongoDB",
+}
+
+// invalidLabel matches the runs of characters not allowed in a hostname
+// label.
+var invalidLabel = regexp.MustCompile(`[^a-z0-9-]+`)
+
+// ProjectConfig proposes a project serving f under domain. Every HTTP port
+// a Compose service publishes on a fixed host port becomes a service named
+// after the Compose service, at that name as subdomain; further ports of
+// the same Compose service are suffixed with their container port. Ports
+// left out are reported in skipped.
+func ProjectConfig(f File, domain string) (pc config.ProjectConfig, skipped []error) {
+	pc = config.ProjectConfig{Domain: domain, Services: make(map[string]config.Service)}
+
+	names := make([]string, 0, len(f.Services))
+	for name := range f.Services {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		label := strings.Trim(invalidLabel.ReplaceAllString(strings.ToLower(name), "-"), "-")
+		found := 0
+		for _, p := range f.Services[name].Ports {
+			addr, err := upstream(p)
+			if err != nil {
+				skipped = append(skipped, fmt.Errorf("%s: port %s: %w", name, p, err))
+				continue
+			}
+
+			svcName, subdomain := name, label
+			if found > 0 {
+				svcName = name + "-" + p.Target
+				subdomain = label + "-" + p.Target
+			}
+			found++
+			pc.Services[svcName] = config.Service{
+				Proxy:     "http://" + addr,
+				Subdomain: subdomain,
+			}
+		}
+	}
+	return pc, skipped
+}
+
+// upstream returns the host address Hatch reaches p at, or why p cannot
+// be proxied.
+func upstream(p Port) (string, error) {
+	if p.Protocol != "tcp" {
+		return "", fmt.Errorf("%s is not tcp", p.Protocol)
+	}
+	if strings.Contains(p.Target, "-") || strings.Contains(p.Published, "-") {
+		return "", fmt.Errorf("port ranges are not supported")
+	}
+	target, err := strconv.Atoi(p.Target)
+	if err != nil {
+		return "", fmt.Errorf("invalid container port %q", p.Target)
+	}
+	if kind, ok := nonHTTPPorts[target]; ok {
+		return "", fmt.Errorf("%s does not speak HTTP", kind)
+	}
+	if p.Published == "" {
+		return "", fmt.Errorf("not published on a fixed host port")
+	}
+	published, err := strconv.Atoi(p.Published)
+	if err != nil || published < 1 || published > 65535 {
+		return "", fmt.Errorf("invalid host port %q", p.Published)
+	}
+
+	host := p.HostIP
+	if host == "" || host == "0.0.0.0" || host == "::" {
+		host = "127.0.0.1"
+	}
+	return net.JoinHostPort(host, strconv.Itoa(published)), nil
+}
+
+// String returns p in the short syntax.
+func (p Port) String() string {
+	s := p.Target
+	if p.Published != "" || p.HostIP != "" {
+		s = p.Published + ":" + s
+	}
+	if p.HostIP != "" {
+		host := p.HostIP
+		if strings.Contains(host, ":") {
+			host = "[" + host + "]"
+		}
+		s = host + ":" + s
+	}
+	if p.Protocol != "" && p.Protocol != "tcp" {
+		s += "/" + p.Protocol
+	}
+	return s
+}
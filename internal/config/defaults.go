@@ -1,11 +1,18 @@
 package config
 
+// Ports of the daemon's own loopback listeners, which tcp services cannot
+// listen on.
+const (
+	APIPort        = 42824 // the API server, see api.DefaultAddr
+	CaddyAdminPort = 2019  // Caddy's admin endpoint, see caddy.DefaultAdminAddr
+)
+
 // DefaultConfig returns a Config populated with sensible defaults.
 func DefaultConfig() Config {
 	return Config{
 		Version: 1,
 		Settings: Settings{
-			TLD:       "test",
+			TLDs:      []string{"test"},
 			HTTPPort:  80,
 			HTTPSPort: 443,
 			AutoStart: true,
@@ -110,6 +110,18 @@ func LoadProjectConfig(path string) (ProjectConfig, error) {
 	return pc, nil
 }
 
+// SaveProjectConfig writes pc to path as a per-project .hatch.yml file.
+func SaveProjectConfig(path string, pc ProjectConfig) error {
+	data, err := yaml.Marshal(pc)
+	if err != nil {
+		return fmt.Errorf("marshaling project config: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("writing project config: %w", err)
+	}
+	return nil
+}
+
 // LoadRaw reads the config file without validation, useful for merging.
 func LoadRaw() (Config, error) {
 	data, err := os.ReadFile(ConfigFile())
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
 )
@@ -42,6 +43,9 @@ func TestLoadSaveRoundTrip(t *testing.T) {
 	if loaded.Settings.TLD != cfg.Settings.TLD {
 		t.Errorf("tld: got %q, want %q", loaded.Settings.TLD, cfg.Settings.TLD)
 	}
+	if !slices.Equal(loaded.Settings.TLDs, cfg.Settings.TLDs) {
+		t.Errorf("tlds: got %v, want %v", loaded.Settings.TLDs, cfg.Settings.TLDs)
+	}
 	p, ok := loaded.Projects["myapp"]
 	if !ok {
 		t.Fatal("project myapp not found after round-trip")
@@ -152,6 +156,24 @@ func TestLoadProjectConfig_NoServices(t *testing.T) {
 	}
 }
 
+func TestSaveProjectConfig_RoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".hatch.yml")
+	pc := ProjectConfig{
+		Domain:   "myapp.test",
+		Services: map[string]Service{"api": {Proxy: "http://127.0.0.1:8080", Subdomain: "api"}},
+	}
+	if err := SaveProjectConfig(path, pc); err != nil {
+		t.Fatalf("SaveProjectConfig: %v", err)
+	}
+	loaded, err := LoadProjectConfig(path)
+	if err != nil {
+		t.Fatalf("LoadProjectConfig: %v", err)
+	}
+	if loaded.Domain != pc.Domain || loaded.Services["api"].Subdomain != "api" {
+		t.Errorf("round trip: got %+v", loaded)
+	}
+}
+
 func TestSave_BackupFirstSave(t *testing.T) {
 	home := setupTestHome(t)
 	if err := EnsureConfigDir(); err != nil {
@@ -1,26 +1,44 @@
 package config
 
-import "fmt"
+import (
+	"fmt"
+	"slices"
+)
 
 // MergeProjectConfig adds or updates a project in the config from a ProjectConfig.
 // The name is the project key (e.g. "myapp"), and projectPath is the filesystem path.
 func MergeProjectConfig(cfg *Config, name string, projectPath string, pc ProjectConfig) error {
-	// Check for domain conflicts with other projects
+	if reservedProjectNames[name] {
+		return fmt.Errorf("project name %q is reserved", name)
+	}
+	proj := Project{
+		Domain:   pc.Domain,
+		Aliases:  pc.Aliases,
+		Wildcard: pc.Wildcard,
+		Path:     projectPath,
+		Enabled:  true,
+		Services: pc.Services,
+	}
+
+	// Check for domain, alias and wildcard conflicts with other projects
 	for existingName, existingProj := range cfg.Projects {
 		if existingName == name {
 			continue // same project, allow update
 		}
-		if existingProj.Domain == pc.Domain {
-			return fmt.Errorf("domain %q is already used by project %q", pc.Domain, existingName)
+		for _, host := range proj.BaseHosts() {
+			if slices.Contains(existingProj.BaseHosts(), host) {
+				return fmt.Errorf("domain %q is already used by project %q", host, existingName)
+			}
+		}
+		if host, base, ok := wildcardOverlap(proj, existingProj); ok {
+			return fmt.Errorf("wildcard *.%s would shadow %q of project %q", base, host, existingName)
+		}
+		if host, base, ok := wildcardOverlap(existingProj, proj); ok {
+			return fmt.Errorf("domain %q is already matched by wildcard *.%s of project %q", host, base, existingName)
 		}
 	}
 
-	cfg.Projects[name] = Project{
-		Domain:   pc.Domain,
-		Path:     projectPath,
-		Enabled:  true,
-		Services: pc.Services,
-	}
+	cfg.Projects[name] = proj
 
 	return nil
 }
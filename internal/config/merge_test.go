@@ -1,6 +1,9 @@
 package config
 
-import "testing"
+import (
+	"strings"
+	"testing"
+)
 
 func TestMergeProjectConfig_Add(t *testing.T) {
 	cfg := DefaultConfig()
@@ -67,6 +70,71 @@ func TestMergeProjectConfig_DomainConflict(t *testing.T) {
 	}
 }
 
+func TestMergeProjectConfig_AliasConflict(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Projects["existing"] = Project{
+		Domain: "app.test", Aliases: []string{"admin-app.test"}, Path: "/tmp/existing", Enabled: true,
+		Services: map[string]Service{"web": {Proxy: "http://localhost:3000"}},
+	}
+
+	pc := ProjectConfig{
+		Domain:   "other.test",
+		Aliases:  []string{"admin-app.test"},
+		Services: map[string]Service{"web": {Proxy: "http://localhost:4000"}},
+	}
+
+	err := MergeProjectConfig(&cfg, "newproj", "/tmp/new", pc)
+	if err == nil || !strings.Contains(err.Error(), "admin-app.test") {
+		t.Fatalf("expected alias conflict error, got %v", err)
+	}
+}
+
+func TestMergeProjectConfig_WildcardConflict(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Projects["api"] = Project{
+		Domain: "api.app.test", Path: "/tmp/api", Enabled: true,
+		Services: map[string]Service{"web": {Proxy: "http://localhost:3000"}},
+	}
+
+	// A linked wildcard project would shadow an existing host...
+	pc := ProjectConfig{
+		Domain:   "app.test",
+		Wildcard: true,
+		Services: map[string]Service{"web": {Proxy: "http://localhost:4000"}},
+	}
+	err := MergeProjectConfig(&cfg, "app", "/tmp/app", pc)
+	if err == nil || !strings.Contains(err.Error(), `wildcard *.app.test would shadow "api.app.test" of project "api"`) {
+		t.Fatalf("expected wildcard conflict error, got %v", err)
+	}
+
+	// ...and a linked host can fall under an existing wildcard.
+	delete(cfg.Projects, "api")
+	if err := MergeProjectConfig(&cfg, "app", "/tmp/app", pc); err != nil {
+		t.Fatal(err)
+	}
+	pc = ProjectConfig{
+		Domain:   "api.app.test",
+		Services: map[string]Service{"web": {Proxy: "http://localhost:3000"}},
+	}
+	err = MergeProjectConfig(&cfg, "api", "/tmp/api", pc)
+	if err == nil || !strings.Contains(err.Error(), `"api.app.test" is already matched by wildcard *.app.test of project "app"`) {
+		t.Fatalf("expected wildcard conflict error, got %v", err)
+	}
+}
+
+func TestMergeProjectConfig_ReservedName(t *testing.T) {
+	cfg := DefaultConfig()
+	pc := ProjectConfig{
+		Domain:   "on.test",
+		Services: map[string]Service{"web": {Proxy: "http://localhost:3000"}},
+	}
+
+	err := MergeProjectConfig(&cfg, "on", "/tmp/on", pc)
+	if err == nil || !strings.Contains(err.Error(), `project name "on" is reserved`) {
+		t.Fatalf("expected reserved name error, got %v", err)
+	}
+}
+
 func TestMergeProjectConfig_SameProjectSameDomain(t *testing.T) {
 	cfg := DefaultConfig()
 	cfg.Projects["myapp"] = Project{
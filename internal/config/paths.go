@@ -52,7 +52,19 @@ func LogFile() string {
 	return filepath.Join(LogsDir(), "hatch.log")
 }
 
+// ServiceLogFile returns the path to the file capturing the output of a
+// service's command.
+func ServiceLogFile(project, service string) string {
+	return filepath.Join(LogsDir(), project, service+".log")
+}
+
 // CaddyDir returns the path to the isolated Caddy data directory.
 func CaddyDir() string {
 	return filepath.Join(Dir(), "caddy")
 }
+
+// QueueDir returns the path to the directory holding requests buffered
+// for services that are down.
+func QueueDir() string {
+	return filepath.Join(Dir(), "queue")
+}
@@ -1,5 +1,13 @@
 package config
 
+import (
+	"net"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+)
+
 // Config is the top-level Hatch configuration.
 type Config struct {
 	Version  int                `yaml:"version" json:"version"`
@@ -7,33 +15,399 @@ type Config struct {
 	Projects map[string]Project `yaml:"projects" json:"projects"`
 }
 
-// Settings holds global Hatch settings.
+// Settings holds global Hatch settings. TLD is the original single-TLD
+// setting and is still honoured alongside TLDs; use AllTLDs to read them.
+// DNSOverrides maps hostnames outside those TLDs to the IP the embedded
+// DNS server answers with, for that name and every name below it.
+// LANSharing exposes project domains to other devices on the local
+// network (see "hatch share"). Tunnel configures the relay used to share
+// projects publicly (see "hatch share <project>"). Docker, when set, has
+// the daemon discover projects from labelled Docker containers.
 type Settings struct {
-	TLD       string `yaml:"tld" json:"tld"`
-	HTTPPort  int    `yaml:"http_port" json:"http_port"`
-	HTTPSPort int    `yaml:"https_port" json:"https_port"`
-	AutoStart bool   `yaml:"auto_start" json:"auto_start"`
-	LogLevel  string `yaml:"log_level" json:"log_level"`
+	TLD          string            `yaml:"tld,omitempty" json:"tld,omitempty"`
+	TLDs         []string          `yaml:"tlds,omitempty" json:"tlds,omitempty"`
+	DNSOverrides map[string]string `yaml:"dns_overrides,omitempty" json:"dns_overrides,omitempty"`
+	HTTPPort     int               `yaml:"http_port" json:"http_port"`
+	HTTPSPort    int               `yaml:"https_port" json:"https_port"`
+	AutoStart    bool              `yaml:"auto_start" json:"auto_start"`
+	LogLevel     string            `yaml:"log_level" json:"log_level"`
+	LANSharing   bool              `yaml:"lan_sharing,omitempty" json:"lan_sharing,omitempty"`
+	Tunnel       *TunnelSettings   `yaml:"tunnel,omitempty" json:"tunnel,omitempty"`
+	Docker       *DockerSettings   `yaml:"docker,omitempty" json:"docker,omitempty"`
+}
+
+// TunnelSettings points at a tunnel relay ("hatch relay") and holds the
+// token it requires, if any.
+type TunnelSettings struct {
+	Relay string `yaml:"relay" json:"relay"`
+	Token string `yaml:"token,omitempty" json:"token,omitempty"`
+}
+
+// DockerSettings configures discovery of running Docker containers
+// labelled hatch.domain, which become projects for as long as they run.
+type DockerSettings struct {
+	Socket string `yaml:"socket,omitempty" json:"socket,omitempty"` // Engine API socket; default /var/run/docker.sock
+}
+
+// AllTLDs returns the configured TLDs: TLD, if set, followed by TLDs
+// without duplicates.
+func (s Settings) AllTLDs() []string {
+	var tlds []string
+	if s.TLD != "" {
+		tlds = append(tlds, s.TLD)
+	}
+	for _, tld := range s.TLDs {
+		if !slices.Contains(tlds, tld) {
+			tlds = append(tlds, tld)
+		}
+	}
+	return tlds
+}
+
+// DNSZones returns every domain the OS resolver should route to the
+// embedded DNS server: each TLD followed by the DNS override hosts in
+// sorted order, skipping hosts already below another zone.
+func (s Settings) DNSZones() []string {
+	tlds := s.AllTLDs()
+	candidates := make([]string, 0, len(tlds)+len(s.DNSOverrides))
+	candidates = append(candidates, tlds...)
+	for host := range s.DNSOverrides {
+		candidates = append(candidates, host)
+	}
+
+	var hosts []string
+	for host := range s.DNSOverrides {
+		covered := slices.ContainsFunc(candidates, func(zone string) bool {
+			return strings.HasSuffix(host, "."+zone)
+		})
+		if !covered {
+			hosts = append(hosts, host)
+		}
+	}
+	sort.Strings(hosts)
+	return append(tlds, hosts...)
 }
 
-// Project defines a single project's proxy configuration.
+// Project defines a single project's proxy configuration. Besides Domain,
+// a project answers on each of its Aliases and, with Wildcard, on any
+// single-label subdomain of those hosts (*.myapp.test). With Capture, the
+// daemon records the project's HTTP traffic (see "hatch requests").
+// ErrorPage is an html/template file, relative to Path, shown instead of
+// Hatch's own page when a service's upstream cannot be reached. Auth
+// gates the project's services, which must then all be HTTP.
 type Project struct {
-	Domain   string             `yaml:"domain" json:"domain"`
-	Path     string             `yaml:"path" json:"path"`
-	Enabled  bool               `yaml:"enabled" json:"enabled"`
-	Services map[string]Service `yaml:"services" json:"services"`
+	Domain    string             `yaml:"domain" json:"domain"`
+	Aliases   []string           `yaml:"aliases,omitempty" json:"aliases,omitempty"`
+	Wildcard  bool               `yaml:"wildcard,omitempty" json:"wildcard,omitempty"`
+	Path      string             `yaml:"path" json:"path"`
+	Enabled   bool               `yaml:"enabled" json:"enabled"`
+	Capture   bool               `yaml:"capture,omitempty" json:"capture,omitempty"`
+	ErrorPage string             `yaml:"error_page,omitempty" json:"error_page,omitempty"`
+	Auth      *Auth              `yaml:"auth,omitempty" json:"auth,omitempty"`
+	Services  map[string]Service `yaml:"services" json:"services"`
+}
+
+// Auth restricts who can reach a project, for when it is shared on the
+// LAN or a screen. Requests from outside AllowIPs (IPs or CIDR ranges)
+// are refused; loopback addresses are always allowed, so the list only
+// restricts other machines, and tunnelled requests are checked against
+// the visitor's address reported by the relay. With Basic, requests must
+// also carry the credentials of one of its users.
+type Auth struct {
+	Basic    *BasicAuth `yaml:"basic,omitempty" json:"basic,omitempty"`
+	AllowIPs []string   `yaml:"allow_ips,omitempty" json:"allow_ips,omitempty"`
+}
+
+// Allows reports whether AllowIPs covers ip. A nil Auth or one without
+// AllowIPs allows every address; an unparsable ip is never allowed by a
+// list. Loopback addresses get no special treatment here.
+func (a *Auth) Allows(ip string) bool {
+	if a == nil || len(a.AllowIPs) == 0 {
+		return true
+	}
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return false
+	}
+	for _, entry := range a.AllowIPs {
+		if _, network, err := net.ParseCIDR(entry); err == nil {
+			if network.Contains(addr) {
+				return true
+			}
+		} else if allowed := net.ParseIP(entry); allowed != nil && allowed.Equal(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// BasicAuth maps user names to bcrypt hashes of their passwords, as set
+// by "hatch passwd".
+type BasicAuth struct {
+	Users map[string]string `yaml:"users" json:"users"`
 }
 
-// Service defines how a single service is proxied.
+// ErrorPagePath returns the absolute path of the project's error page
+// template, or "" if it has none.
+func (p Project) ErrorPagePath() string {
+	if p.ErrorPage == "" || filepath.IsAbs(p.ErrorPage) {
+		return p.ErrorPage
+	}
+	return filepath.Join(p.Path, p.ErrorPage)
+}
+
+// Service defines how a single service is proxied. A service proxies to
+// either a single Proxy URL or a list of Upstreams load-balanced using
+// LBPolicy, or serves static files from Root.
+//
+// Services of Type "tcp" are not HTTP: Hatch listens on Listen and forwards
+// raw connections to Proxy (a tcp://host:port URL). With TLS set, the
+// service is reached at its hostname on that port, either passed through
+// to the upstream by SNI or terminated by Hatch first.
 type Service struct {
-	Proxy     string `yaml:"proxy" json:"proxy"`
-	Route     string `yaml:"route,omitempty" json:"route,omitempty"`
-	Subdomain string `yaml:"subdomain,omitempty" json:"subdomain,omitempty"`
-	WebSocket bool   `yaml:"websocket,omitempty" json:"websocket,omitempty"`
+	Type      string   `yaml:"type,omitempty" json:"type,omitempty"` // http (default) or tcp
+	Listen    int      `yaml:"listen,omitempty" json:"listen,omitempty"`
+	TLS       string   `yaml:"tls,omitempty" json:"tls,omitempty"`
+	Proxy     string   `yaml:"proxy,omitempty" json:"proxy,omitempty"`
+	Upstreams []string `yaml:"upstreams,omitempty" json:"upstreams,omitempty"`
+	LBPolicy  string   `yaml:"lb_policy,omitempty" json:"lb_policy,omitempty"`
+	Root      string   `yaml:"root,omitempty" json:"root,omitempty"`     // directory, relative to the project path
+	SPA       bool     `yaml:"spa,omitempty" json:"spa,omitempty"`       // serve /index.html for unknown paths
+	Browse    bool     `yaml:"browse,omitempty" json:"browse,omitempty"` // list directories without an index
+	Route     string   `yaml:"route,omitempty" json:"route,omitempty"`
+	Subdomain string   `yaml:"subdomain,omitempty" json:"subdomain,omitempty"`
+	WebSocket bool     `yaml:"websocket,omitempty" json:"websocket,omitempty"`
+
+	// Mock, in place of proxy, upstreams or root, has Hatch answer the
+	// service's requests itself from the first matching rule, for routes
+	// whose backend does not exist yet.
+	Mock []MockRule `yaml:"mock,omitempty" json:"mock,omitempty"`
+
+	// BufferWhenDown queues requests while the health checker reports the
+	// service unhealthy, answering 202 Accepted, and delivers them in
+	// order once it recovers. Meant for webhook receivers.
+	BufferWhenDown bool `yaml:"buffer_when_down,omitempty" json:"buffer_when_down,omitempty"`
+
+	// Chaos degrades the service on purpose, to exercise loading states
+	// and retry logic. "hatch chaos" sets it for a limited time instead.
+	Chaos *Chaos `yaml:"chaos,omitempty" json:"chaos,omitempty"`
+
+	// Command, when set, starts the service's dev server. "hatch start"
+	// has the daemon run it through the user's shell in Cwd (relative to
+	// the project path, default the project path) with Env added to its
+	// environment, restarting it whenever it exits until "hatch stop".
+	Command string            `yaml:"command,omitempty" json:"command,omitempty"`
+	Cwd     string            `yaml:"cwd,omitempty" json:"cwd,omitempty"`
+	Env     map[string]string `yaml:"env,omitempty" json:"env,omitempty"`
+
+	// Path rewriting, applied in the order strip_prefix, rewrite,
+	// add_prefix before the request is proxied.
+	StripPrefix string   `yaml:"strip_prefix,omitempty" json:"strip_prefix,omitempty"`
+	Rewrite     *Rewrite `yaml:"rewrite,omitempty" json:"rewrite,omitempty"`
+	AddPrefix   string   `yaml:"add_prefix,omitempty" json:"add_prefix,omitempty"`
+
+	HealthCheck *HealthCheck `yaml:"health_check,omitempty" json:"health_check,omitempty"`
+	Headers     *Headers     `yaml:"headers,omitempty" json:"headers,omitempty"`
+}
+
+// HealthCheck configures an active HTTP health check for a service. When
+// absent, the service is checked with a plain TCP dial.
+type HealthCheck struct {
+	Path         string `yaml:"path" json:"path"`
+	Method       string `yaml:"method,omitempty" json:"method,omitempty"`               // default GET
+	ExpectStatus int    `yaml:"expect_status,omitempty" json:"expect_status,omitempty"` // exact code, or class such as 2 for 2xx; default 2xx
+	ExpectBody   string `yaml:"expect_body,omitempty" json:"expect_body,omitempty"`     // regular expression
+	Interval     string `yaml:"interval,omitempty" json:"interval,omitempty"`           // duration, e.g. "5s"
+	Timeout      string `yaml:"timeout,omitempty" json:"timeout,omitempty"`             // duration, e.g. "2s"
+}
+
+// MockRule is a canned response of a mock service. Requests are answered
+// by the first rule whose method and path match. Path segments written
+// {name} match any single segment, and a final /* matches the rest of the
+// path. With Template set, the body is a Go text/template given .Method,
+// .Path, .Params (the named segments) and .Query.
+type MockRule struct {
+	Method   string            `yaml:"method,omitempty" json:"method,omitempty"` // default any
+	Path     string            `yaml:"path" json:"path"`
+	Status   int               `yaml:"status,omitempty" json:"status,omitempty"` // default 200
+	Headers  map[string]string `yaml:"headers,omitempty" json:"headers,omitempty"`
+	Body     string            `yaml:"body,omitempty" json:"body,omitempty"`
+	File     string            `yaml:"file,omitempty" json:"file,omitempty"` // body file, relative to the project path
+	Template bool              `yaml:"template,omitempty" json:"template,omitempty"`
+}
+
+// Chaos configures faults injected into an HTTP service's responses:
+// added latency, failed requests and throttled bandwidth.
+type Chaos struct {
+	Latency       string  `yaml:"latency,omitempty" json:"latency,omitempty"`               // duration added before each request, e.g. "500ms"
+	Jitter        string  `yaml:"jitter,omitempty" json:"jitter,omitempty"`                 // up to this much more, chosen at random
+	ErrorRate     float64 `yaml:"error_rate,omitempty" json:"error_rate,omitempty"`         // fraction of requests failed, 0-1
+	ErrorStatus   int     `yaml:"error_status,omitempty" json:"error_status,omitempty"`     // status of failed requests; default 503
+	BandwidthKbps int     `yaml:"bandwidth_kbps,omitempty" json:"bandwidth_kbps,omitempty"` // response throughput limit in kilobits per second
+}
+
+// Rewrite replaces matches of Regex in the request path with Replace,
+// which may refer to capture groups as $1, $2 and so on.
+type Rewrite struct {
+	Regex   string `yaml:"regex" json:"regex"`
+	Replace string `yaml:"replace" json:"replace"`
+}
+
+// Headers configures header manipulation for an HTTP service. Request
+// operations apply before the request is proxied; response operations
+// apply to the upstream's response before it reaches the client.
+type Headers struct {
+	Request  *HeaderOps `yaml:"request,omitempty" json:"request,omitempty"`
+	Response *HeaderOps `yaml:"response,omitempty" json:"response,omitempty"`
+}
+
+// HeaderOps lists header operations, applied in the order delete, add,
+// set. Values may use Caddy placeholders such as {http.request.host}.
+type HeaderOps struct {
+	Set    map[string]string `yaml:"set,omitempty" json:"set,omitempty"`       // replace any existing value
+	Add    map[string]string `yaml:"add,omitempty" json:"add,omitempty"`       // append to existing values
+	Delete []string          `yaml:"delete,omitempty" json:"delete,omitempty"` // header names, * wildcards allowed
+}
+
+// Load-balancing policies accepted in Service.LBPolicy.
+const (
+	LBRoundRobin = "round_robin"
+	LBLeastConn  = "least_conn"
+	LBIPHash     = "ip_hash"
+	LBFirst      = "first"
+)
+
+// Service types accepted in Service.Type.
+const (
+	ServiceHTTP = "http"
+	ServiceTCP  = "tcp"
+)
+
+// TLS modes accepted in Service.TLS for tcp services. Without one, the
+// listen port carries plain TCP to a single upstream.
+const (
+	TLSPassthrough = "passthrough"
+	TLSTerminate   = "terminate"
+)
+
+// ProxyURLs returns the upstream URLs of the service: Upstreams when set,
+// otherwise the single Proxy URL.
+func (s Service) ProxyURLs() []string {
+	if len(s.Upstreams) > 0 {
+		return s.Upstreams
+	}
+	if s.Proxy == "" {
+		return nil
+	}
+	return []string{s.Proxy}
+}
+
+// LoadBalanced reports whether the service proxies to more than one upstream.
+func (s Service) LoadBalanced() bool {
+	return len(s.Upstreams) > 1
+}
+
+// IsTCP reports whether the service is a raw TCP service rather than an
+// HTTP route.
+func (s Service) IsTCP() bool {
+	return s.Type == ServiceTCP
+}
+
+// IsStatic reports whether the service serves files from Root instead of
+// proxying.
+func (s Service) IsStatic() bool {
+	return s.Root != ""
+}
+
+// IsMock reports whether the service answers requests from its mock rules
+// instead of proxying.
+func (s Service) IsMock() bool {
+	return len(s.Mock) > 0
+}
+
+// MockRules returns the mock rules of the service in project p with each
+// relative File resolved against the project path.
+func (s Service) MockRules(p Project) []MockRule {
+	if len(s.Mock) == 0 {
+		return nil
+	}
+	rules := make([]MockRule, len(s.Mock))
+	copy(rules, s.Mock)
+	for i, r := range rules {
+		if r.File != "" && !filepath.IsAbs(r.File) {
+			rules[i].File = filepath.Join(p.Path, r.File)
+		}
+	}
+	return rules
+}
+
+// RootDir returns the absolute directory served by a static service in
+// project p. A relative Root is resolved against the project path.
+func (s Service) RootDir(p Project) string {
+	if s.Root == "" || filepath.IsAbs(s.Root) {
+		return s.Root
+	}
+	return filepath.Join(p.Path, s.Root)
+}
+
+// WorkDir returns the directory the service's command runs in within
+// project p. A relative Cwd is resolved against the project path.
+func (s Service) WorkDir(p Project) string {
+	if s.Cwd == "" {
+		return p.Path
+	}
+	if filepath.IsAbs(s.Cwd) {
+		return s.Cwd
+	}
+	return filepath.Join(p.Path, s.Cwd)
+}
+
+// Host returns the hostname the service is reached at within project p.
+func (s Service) Host(p Project) string {
+	if s.Subdomain != "" {
+		return s.Subdomain + "." + p.Domain
+	}
+	return p.Domain
+}
+
+// Hosts returns every hostname the service answers on within project p:
+// the project's hosts, or with Subdomain set, that subdomain of the domain
+// and of each alias.
+func (s Service) Hosts(p Project) []string {
+	if s.Subdomain == "" {
+		return p.Hosts()
+	}
+	hosts := make([]string, 0, 1+len(p.Aliases))
+	for _, base := range p.BaseHosts() {
+		hosts = append(hosts, s.Subdomain+"."+base)
+	}
+	return hosts
+}
+
+// BaseHosts returns the project's domain followed by its aliases.
+func (p Project) BaseHosts() []string {
+	return append([]string{p.Domain}, p.Aliases...)
+}
+
+// Hosts returns the hostnames the project answers on: its domain, its
+// aliases and, for wildcard projects, a *. pattern for each of those.
+func (p Project) Hosts() []string {
+	base := p.BaseHosts()
+	if !p.Wildcard {
+		return base
+	}
+	hosts := make([]string, 0, 2*len(base))
+	hosts = append(hosts, base...)
+	for _, h := range base {
+		hosts = append(hosts, "*."+h)
+	}
+	return hosts
 }
 
 // ProjectConfig is the schema for a per-project .hatch.yml file.
 type ProjectConfig struct {
 	Domain   string             `yaml:"domain"`
+	Aliases  []string           `yaml:"aliases,omitempty"`
+	Wildcard bool               `yaml:"wildcard,omitempty"`
 	Services map[string]Service `yaml:"services"`
 }
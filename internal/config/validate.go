@@ -2,18 +2,80 @@ package config
 
 import (
 	"fmt"
+	"net"
+	"net/http"
 	"net/url"
+	"path/filepath"
 	"regexp"
+	"sort"
 	"strings"
+	"text/template"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/paulrose/hatch/internal/dns"
 )
 
 var validHostnameLabel = regexp.MustCompile(`^[a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?$`)
 
-var allowedTLDs = map[string]bool{
-	"test":      true,
-	"localhost": true,
-	"local":     true,
-	"dev":       true,
+// validHeaderName matches an HTTP header field name (RFC 9110 token).
+var validHeaderName = regexp.MustCompile("^[!#$%&'*+.^_`|~0-9A-Za-z-]+$")
+
+// validEnvName matches a portable environment variable name.
+var validEnvName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// validMockParam matches the name of a {name} segment in a mock rule's
+// path, usable as a template field.
+var validMockParam = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// validTLD matches a single lowercase DNS label usable as a TLD.
+var validTLD = regexp.MustCompile(`^[a-z]([a-z0-9-]{0,61}[a-z0-9])?$`)
+
+// reservedTLDs are TLDs Hatch refuses to take over: common public TLDs,
+// whose real sites would stop resolving, and .invalid, which RFC 6761
+// reserves for names that must never resolve. Real hostnames are served
+// through settings.dns_overrides instead.
+var reservedTLDs = map[string]bool{
+	"com":     true,
+	"net":     true,
+	"org":     true,
+	"edu":     true,
+	"gov":     true,
+	"io":      true,
+	"co":      true,
+	"invalid": true,
+}
+
+// reservedProjectNames are arguments "hatch share" takes itself, so a
+// project with one of these names could not be shared publicly.
+var reservedProjectNames = map[string]bool{
+	"on":  true,
+	"off": true,
+}
+
+var allowedServiceTypes = map[string]bool{
+	ServiceHTTP: true,
+	ServiceTCP:  true,
+}
+
+var allowedTLSModes = map[string]bool{
+	TLSPassthrough: true,
+	TLSTerminate:   true,
+}
+
+var allowedLBPolicies = map[string]bool{
+	LBRoundRobin: true,
+	LBLeastConn:  true,
+	LBIPHash:     true,
+	LBFirst:      true,
+}
+
+var allowedHealthCheckMethods = map[string]bool{
+	http.MethodGet:     true,
+	http.MethodHead:    true,
+	http.MethodPost:    true,
+	http.MethodOptions: true,
 }
 
 var allowedLogLevels = map[string]bool{
@@ -39,8 +101,13 @@ func Validate(cfg Config) []error {
 	// Projects
 	domains := make(map[string]string) // domain -> project name
 	for name, proj := range cfg.Projects {
-		errs = append(errs, validateProject(name, proj, cfg.Settings.TLD, domains)...)
+		if proj.Path == "" {
+			errs = append(errs, fmt.Errorf("projects.%s.path is required", name))
+		}
+		errs = append(errs, validateProject(name, proj, cfg.Settings, domains)...)
 	}
+	errs = append(errs, validateWildcards(cfg)...)
+	errs = append(errs, validateTCPListeners(cfg)...)
 
 	return errs
 }
@@ -48,9 +115,26 @@ func Validate(cfg Config) []error {
 func validateSettings(s Settings) []error {
 	var errs []error
 
-	if !allowedTLDs[s.TLD] {
-		errs = append(errs, fmt.Errorf("settings.tld must be one of: test, localhost, local, dev; got %q", s.TLD))
+	if s.TLD != "" {
+		if err := validateTLD("settings.tld", s.TLD); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	seenTLDs := map[string]bool{s.TLD: true}
+	for i, tld := range s.TLDs {
+		field := fmt.Sprintf("settings.tlds[%d]", i)
+		if err := validateTLD(field, tld); err != nil {
+			errs = append(errs, err)
+		} else if seenTLDs[tld] {
+			errs = append(errs, fmt.Errorf("%s %q is listed more than once", field, tld))
+		}
+		seenTLDs[tld] = true
 	}
+	if len(s.AllTLDs()) == 0 {
+		errs = append(errs, fmt.Errorf("settings.tlds must list at least one TLD"))
+	}
+
+	errs = append(errs, validateDNSOverrides(s)...)
 
 	if s.HTTPPort < 1 || s.HTTPPort > 65535 {
 		errs = append(errs, fmt.Errorf("settings.http_port must be 1-65535, got %d", s.HTTPPort))
@@ -68,18 +152,76 @@ func validateSettings(s Settings) []error {
 		errs = append(errs, fmt.Errorf("settings.log_level must be one of: debug, info, warn, error; got %q", s.LogLevel))
 	}
 
+	if s.Tunnel != nil {
+		if u, err := url.Parse(s.Tunnel.Relay); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, fmt.Errorf("settings.tunnel.relay must be an http:// or https:// URL, got %q", s.Tunnel.Relay))
+		}
+	}
+
+	if s.Docker != nil && s.Docker.Socket != "" && !filepath.IsAbs(s.Docker.Socket) {
+		errs = append(errs, fmt.Errorf("settings.docker.socket must be an absolute path, got %q", s.Docker.Socket))
+	}
+
+	return errs
+}
+
+// validateTLD checks a single entry of settings.tld or settings.tlds.
+func validateTLD(field, tld string) error {
+	switch {
+	case !validTLD.MatchString(tld):
+		return fmt.Errorf("%s %q must be a single lowercase DNS label", field, tld)
+	case reservedTLDs[tld]:
+		return fmt.Errorf("%s %q is a public or reserved TLD; use settings.dns_overrides for real hostnames", field, tld)
+	}
+	return nil
+}
+
+// validateDNSOverrides checks that each override maps a lowercase,
+// multi-label hostname to an IP address.
+func validateDNSOverrides(s Settings) []error {
+	var errs []error
+
+	hosts := make([]string, 0, len(s.DNSOverrides))
+	for host := range s.DNSOverrides {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+
+	for _, host := range hosts {
+		field := fmt.Sprintf("settings.dns_overrides.%s", host)
+		if !isValidHostname(host) || host != strings.ToLower(host) || !strings.Contains(host, ".") {
+			errs = append(errs, fmt.Errorf("%s: %q must be a lowercase hostname with at least two labels", field, host))
+		}
+		if net.ParseIP(s.DNSOverrides[host]) == nil {
+			errs = append(errs, fmt.Errorf("%s must be an IP address, got %q", field, s.DNSOverrides[host]))
+		}
+	}
+
 	return errs
 }
 
-func validateProject(name string, p Project, tld string, domains map[string]string) []error {
+// ValidateProject checks a single project against settings s, as Validate
+// does for each configured project, except that p may have no Path, like
+// the projects discovered from Docker. Clashes with other projects are
+// not checked.
+func ValidateProject(name string, p Project, s Settings) []error {
+	return validateProject(name, p, s, make(map[string]string))
+}
+
+func validateProject(name string, p Project, s Settings, domains map[string]string) []error {
 	var errs []error
 	prefix := fmt.Sprintf("projects.%s", name)
+	zones := describeZones(s)
 
-	// Domain: valid hostname ending with configured TLD
+	if reservedProjectNames[name] {
+		errs = append(errs, fmt.Errorf("%s: project name %q is reserved", prefix, name))
+	}
+
+	// Domain: valid hostname under a configured TLD or DNS override
 	if p.Domain == "" {
 		errs = append(errs, fmt.Errorf("%s.domain is required", prefix))
-	} else if !isValidDomain(p.Domain, tld) {
-		errs = append(errs, fmt.Errorf("%s.domain %q must be a valid hostname ending with .%s", prefix, p.Domain, tld))
+	} else if !isValidDomain(p.Domain, s) {
+		errs = append(errs, fmt.Errorf("%s.domain %q must be a valid hostname ending with %s", prefix, p.Domain, zones))
 	} else {
 		if other, exists := domains[p.Domain]; exists {
 			errs = append(errs, fmt.Errorf("duplicate domain %q in projects %q and %q", p.Domain, other, name))
@@ -87,9 +229,32 @@ func validateProject(name string, p Project, tld string, domains map[string]stri
 		domains[p.Domain] = name
 	}
 
-	// Path
-	if p.Path == "" {
-		errs = append(errs, fmt.Errorf("%s.path is required", prefix))
+	// Aliases: extra hostnames under the same zones, so the DNS server and
+	// resolver already answer for them.
+	seen := map[string]bool{p.Domain: true}
+	for i, alias := range p.Aliases {
+		switch {
+		case !isValidDomain(alias, s):
+			errs = append(errs, fmt.Errorf("%s.aliases[%d] %q must be a valid hostname ending with %s", prefix, i, alias, zones))
+		case seen[alias]:
+			errs = append(errs, fmt.Errorf("%s.aliases[%d] %q duplicates the domain or another alias", prefix, i, alias))
+		default:
+			if other, exists := domains[alias]; exists {
+				errs = append(errs, fmt.Errorf("duplicate domain %q in projects %q and %q", alias, other, name))
+			}
+			domains[alias] = name
+		}
+		seen[alias] = true
+	}
+
+	if p.Auth != nil {
+		errs = append(errs, validateAuth(prefix+".auth", *p.Auth)...)
+		// TCP services bypass Caddy, so auth would not protect them.
+		for svcName, svc := range p.Services {
+			if svc.IsTCP() {
+				errs = append(errs, fmt.Errorf("%s.auth is not supported for projects with tcp services (%s)", prefix, svcName))
+			}
+		}
 	}
 
 	// Services
@@ -98,6 +263,7 @@ func validateProject(name string, p Project, tld string, domains map[string]stri
 	}
 	for svcName, svc := range p.Services {
 		errs = append(errs, validateService(prefix, svcName, svc)...)
+		errs = append(errs, validateMockFiles(fmt.Sprintf("%s.services.%s.mock", prefix, svcName), p.Path, svc.Mock)...)
 	}
 
 	return errs
@@ -107,14 +273,96 @@ func validateService(prefix, name string, s Service) []error {
 	var errs []error
 	svcPrefix := fmt.Sprintf("%s.services.%s", prefix, name)
 
-	// Proxy URL
-	if s.Proxy == "" {
-		errs = append(errs, fmt.Errorf("%s.proxy is required", svcPrefix))
-	} else {
-		u, err := url.Parse(s.Proxy)
-		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+	if s.Type != "" && !allowedServiceTypes[s.Type] {
+		errs = append(errs, fmt.Errorf("%s.type must be one of: http, tcp; got %q", svcPrefix, s.Type))
+		return errs
+	}
+	errs = append(errs, validateCommand(svcPrefix, s)...)
+	if s.IsTCP() {
+		return append(errs, validateTCPService(svcPrefix, s)...)
+	}
+	if s.Listen != 0 {
+		errs = append(errs, fmt.Errorf("%s.listen is only valid for tcp services", svcPrefix))
+	}
+	if s.TLS != "" {
+		errs = append(errs, fmt.Errorf("%s.tls is only valid for tcp services", svcPrefix))
+	}
+
+	// Proxy URL, upstreams, root or mock (exactly one)
+	switch {
+	case s.Proxy == "" && len(s.Upstreams) == 0 && s.Root == "" && !s.IsMock():
+		errs = append(errs, fmt.Errorf("%s.proxy is required unless upstreams, root or mock is set", svcPrefix))
+	case s.IsMock() && (s.Proxy != "" || len(s.Upstreams) > 0 || s.Root != ""):
+		errs = append(errs, fmt.Errorf("%s.mock cannot be combined with proxy, upstreams or root", svcPrefix))
+	case s.IsMock():
+		errs = append(errs, validateMock(svcPrefix+".mock", s.Mock)...)
+	case s.Root != "" && (s.Proxy != "" || len(s.Upstreams) > 0):
+		errs = append(errs, fmt.Errorf("%s.root cannot be combined with proxy or upstreams", svcPrefix))
+	case s.Proxy != "" && len(s.Upstreams) > 0:
+		errs = append(errs, fmt.Errorf("%s.proxy and %s.upstreams are mutually exclusive", svcPrefix, svcPrefix))
+	case s.Root != "":
+		// Static files; checked below.
+	case s.Proxy != "":
+		if !isValidProxyURL(s.Proxy) {
 			errs = append(errs, fmt.Errorf("%s.proxy %q must be a valid URL with http or https scheme", svcPrefix, s.Proxy))
 		}
+	default:
+		seen := make(map[string]bool, len(s.Upstreams))
+		for i, up := range s.Upstreams {
+			if !isValidProxyURL(up) {
+				errs = append(errs, fmt.Errorf("%s.upstreams[%d] %q must be a valid URL with http or https scheme", svcPrefix, i, up))
+				continue
+			}
+			if seen[up] {
+				errs = append(errs, fmt.Errorf("%s.upstreams[%d] %q is listed more than once", svcPrefix, i, up))
+			}
+			seen[up] = true
+		}
+	}
+
+	// Mocked responses
+	if s.IsMock() {
+		for _, f := range []struct {
+			name string
+			set  bool
+		}{
+			{"websocket", s.WebSocket},
+			{"health_check", s.HealthCheck != nil},
+			{"buffer_when_down", s.BufferWhenDown},
+		} {
+			if f.set {
+				errs = append(errs, fmt.Errorf("%s.%s is not supported for mock services", svcPrefix, f.name))
+			}
+		}
+	}
+
+	// Static file options
+	if s.IsStatic() {
+		if s.WebSocket {
+			errs = append(errs, fmt.Errorf("%s.websocket is not supported for static services", svcPrefix))
+		}
+		if s.HealthCheck != nil {
+			errs = append(errs, fmt.Errorf("%s.health_check is not supported for static services", svcPrefix))
+		}
+		if s.BufferWhenDown {
+			errs = append(errs, fmt.Errorf("%s.buffer_when_down is not supported for static services", svcPrefix))
+		}
+	} else {
+		if s.SPA {
+			errs = append(errs, fmt.Errorf("%s.spa requires root", svcPrefix))
+		}
+		if s.Browse {
+			errs = append(errs, fmt.Errorf("%s.browse requires root", svcPrefix))
+		}
+	}
+
+	// Load-balancing policy (optional, requires upstreams)
+	if s.LBPolicy != "" {
+		if !allowedLBPolicies[s.LBPolicy] {
+			errs = append(errs, fmt.Errorf("%s.lb_policy must be one of: round_robin, least_conn, ip_hash, first; got %q", svcPrefix, s.LBPolicy))
+		} else if len(s.Upstreams) == 0 {
+			errs = append(errs, fmt.Errorf("%s.lb_policy requires upstreams", svcPrefix))
+		}
 	}
 
 	// Subdomain (optional)
@@ -122,28 +370,548 @@ func validateService(prefix, name string, s Service) []error {
 		errs = append(errs, fmt.Errorf("%s.subdomain %q must be a valid hostname label", svcPrefix, s.Subdomain))
 	}
 
+	// Path rewriting (optional)
+	errs = append(errs, validatePathRewrite(svcPrefix, s)...)
+
+	// Health check (optional)
+	if s.HealthCheck != nil {
+		errs = append(errs, validateHealthCheck(svcPrefix+".health_check", *s.HealthCheck)...)
+	}
+
+	// Fault injection (optional)
+	if s.Chaos != nil {
+		errs = append(errs, validateChaos(svcPrefix+".chaos", *s.Chaos)...)
+	}
+
+	// Headers (optional)
+	if s.Headers != nil {
+		if s.Headers.Request != nil {
+			errs = append(errs, validateHeaderOps(svcPrefix+".headers.request", *s.Headers.Request)...)
+		}
+		if s.Headers.Response != nil {
+			errs = append(errs, validateHeaderOps(svcPrefix+".headers.response", *s.Headers.Response)...)
+		}
+	}
+
 	return errs
 }
 
-// isValidDomain checks that domain is a valid hostname ending with .<tld>.
-func isValidDomain(domain, tld string) bool {
-	suffix := "." + tld
-	if !strings.HasSuffix(domain, suffix) {
-		return false
+// validateCommand checks the settings for running a service's dev server,
+// which apply to http and tcp services alike.
+func validateCommand(svcPrefix string, s Service) []error {
+	var errs []error
+
+	if s.Command == "" {
+		if s.Cwd != "" {
+			errs = append(errs, fmt.Errorf("%s.cwd requires command", svcPrefix))
+		}
+		if len(s.Env) > 0 {
+			errs = append(errs, fmt.Errorf("%s.env requires command", svcPrefix))
+		}
+	}
+
+	names := make([]string, 0, len(s.Env))
+	for name := range s.Env {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if !validEnvName.MatchString(name) {
+			errs = append(errs, fmt.Errorf("%s.env %q must be a valid environment variable name", svcPrefix, name))
+		}
 	}
 
-	base := strings.TrimSuffix(domain, suffix)
-	if base == "" {
+	return errs
+}
+
+func validatePathRewrite(svcPrefix string, s Service) []error {
+	var errs []error
+
+	if s.StripPrefix != "" {
+		switch {
+		case !isValidPathPrefix(s.StripPrefix):
+			errs = append(errs, fmt.Errorf("%s.strip_prefix %q must start with / and not end with /", svcPrefix, s.StripPrefix))
+		case s.Route == "":
+			errs = append(errs, fmt.Errorf("%s.strip_prefix requires route", svcPrefix))
+		case !routeHasPrefix(s.Route, s.StripPrefix):
+			errs = append(errs, fmt.Errorf("%s.strip_prefix %q is not a prefix of route %q", svcPrefix, s.StripPrefix, s.Route))
+		}
+	}
+
+	if s.AddPrefix != "" && !isValidPathPrefix(s.AddPrefix) {
+		errs = append(errs, fmt.Errorf("%s.add_prefix %q must start with / and not end with /", svcPrefix, s.AddPrefix))
+	}
+
+	if s.Rewrite != nil {
+		if s.Rewrite.Regex == "" {
+			errs = append(errs, fmt.Errorf("%s.rewrite.regex is required", svcPrefix))
+		} else if _, err := regexp.Compile(s.Rewrite.Regex); err != nil {
+			errs = append(errs, fmt.Errorf("%s.rewrite.regex %q must be a valid regular expression: %v", svcPrefix, s.Rewrite.Regex, err))
+		}
+	}
+
+	return errs
+}
+
+// isValidPathPrefix checks that p is an absolute path without a trailing
+// slash, such as /api.
+func isValidPathPrefix(p string) bool {
+	return strings.HasPrefix(p, "/") && len(p) > 1 && !strings.HasSuffix(p, "/")
+}
+
+// routeHasPrefix reports whether every path matched by route starts with
+// the whole path segments of prefix: /api matches /api/* and /api/v1/*,
+// but not /apiv2/*.
+func routeHasPrefix(route, prefix string) bool {
+	base := strings.TrimSuffix(route, "*")
+	return base == prefix || strings.HasPrefix(base, prefix+"/")
+}
+
+func validateHeaderOps(prefix string, ops HeaderOps) []error {
+	var errs []error
+
+	for _, op := range []struct {
+		name   string
+		fields map[string]string
+	}{
+		{"set", ops.Set},
+		{"add", ops.Add},
+	} {
+		names := make([]string, 0, len(op.fields))
+		for name := range op.fields {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if !validHeaderName.MatchString(name) {
+				errs = append(errs, fmt.Errorf("%s.%s: %q is not a valid header name", prefix, op.name, name))
+			}
+		}
+	}
+
+	for i, name := range ops.Delete {
+		if !validHeaderName.MatchString(name) {
+			errs = append(errs, fmt.Errorf("%s.delete[%d]: %q is not a valid header name", prefix, i, name))
+		}
+	}
+
+	return errs
+}
+
+func validateTCPService(svcPrefix string, s Service) []error {
+	var errs []error
+
+	if s.Listen < 1 || s.Listen > 65535 {
+		errs = append(errs, fmt.Errorf("%s.listen must be 1-65535, got %d", svcPrefix, s.Listen))
+	}
+
+	if s.Proxy == "" {
+		errs = append(errs, fmt.Errorf("%s.proxy is required", svcPrefix))
+	} else if !isValidTCPURL(s.Proxy) {
+		errs = append(errs, fmt.Errorf("%s.proxy %q must be a tcp://host:port URL", svcPrefix, s.Proxy))
+	}
+
+	if s.TLS != "" && !allowedTLSModes[s.TLS] {
+		errs = append(errs, fmt.Errorf("%s.tls must be one of: passthrough, terminate; got %q", svcPrefix, s.TLS))
+	}
+
+	if s.Subdomain != "" && !validHostnameLabel.MatchString(s.Subdomain) {
+		errs = append(errs, fmt.Errorf("%s.subdomain %q must be a valid hostname label", svcPrefix, s.Subdomain))
+	}
+
+	// HTTP-only settings.
+	for _, f := range []struct {
+		name string
+		set  bool
+	}{
+		{"upstreams", len(s.Upstreams) > 0},
+		{"root", s.Root != ""},
+		{"mock", s.IsMock()},
+		{"spa", s.SPA},
+		{"browse", s.Browse},
+		{"lb_policy", s.LBPolicy != ""},
+		{"route", s.Route != ""},
+		{"websocket", s.WebSocket},
+		{"health_check", s.HealthCheck != nil},
+		{"buffer_when_down", s.BufferWhenDown},
+		{"chaos", s.Chaos != nil},
+		{"headers", s.Headers != nil},
+		{"strip_prefix", s.StripPrefix != ""},
+		{"add_prefix", s.AddPrefix != ""},
+		{"rewrite", s.Rewrite != nil},
+	} {
+		if f.set {
+			errs = append(errs, fmt.Errorf("%s.%s is not supported for tcp services", svcPrefix, f.name))
+		}
+	}
+
+	return errs
+}
+
+// validateWildcards checks that no project's host is also matched by the
+// wildcard of another project, which would make routing depend on order.
+func validateWildcards(cfg Config) []error {
+	var errs []error
+
+	names := make([]string, 0, len(cfg.Projects))
+	for name := range cfg.Projects {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, wName := range names {
+		w := cfg.Projects[wName]
+		if !w.Wildcard {
+			continue
+		}
+		for _, base := range w.BaseHosts() {
+			for _, name := range names {
+				if name == wName {
+					continue
+				}
+				for _, host := range cfg.Projects[name].BaseHosts() {
+					if wildcardMatches(base, host) {
+						errs = append(errs, fmt.Errorf("projects.%s: %q is also matched by wildcard *.%s of project %q", name, host, base, wName))
+					}
+				}
+			}
+		}
+	}
+
+	return errs
+}
+
+// wildcardMatches reports whether *.base matches host.
+func wildcardMatches(base, host string) bool {
+	label, ok := strings.CutSuffix(host, "."+base)
+	return ok && label != "" && !strings.Contains(label, ".")
+}
+
+// wildcardOverlap returns a host of p matched by a wildcard of w, and the
+// base host of that wildcard.
+func wildcardOverlap(w, p Project) (host, base string, ok bool) {
+	if !w.Wildcard {
+		return "", "", false
+	}
+	for _, base := range w.BaseHosts() {
+		for _, host := range p.BaseHosts() {
+			if wildcardMatches(base, host) {
+				return host, base, true
+			}
+		}
+	}
+	return "", "", false
+}
+
+// tcpListener is a tcp service's claim on a listen port.
+type tcpListener struct {
+	owner string // projects.<name>.services.<name>
+	host  string
+	sni   bool // routed by SNI, so the port can be shared
+}
+
+// reservedPorts are the ports of the daemon's own listeners.
+var reservedPorts = map[int]string{
+	dns.DefaultPort: "the embedded DNS server",
+	APIPort:         "the API server",
+	CaddyAdminPort:  "Caddy's admin endpoint",
+}
+
+// validateTCPListeners checks that tcp services do not collide with the
+// HTTP ports, the daemon's own ports or each other. Services routed by SNI
+// may share a port as long as their hostnames differ; a plain TCP service
+// needs the port to itself.
+func validateTCPListeners(cfg Config) []error {
+	var errs []error
+
+	byPort := make(map[int][]tcpListener)
+	for projName, proj := range cfg.Projects {
+		for svcName, svc := range proj.Services {
+			if !svc.IsTCP() || svc.Listen < 1 || svc.Listen > 65535 {
+				continue
+			}
+			byPort[svc.Listen] = append(byPort[svc.Listen], tcpListener{
+				owner: fmt.Sprintf("projects.%s.services.%s", projName, svcName),
+				host:  svc.Host(proj),
+				sni:   svc.TLS != "",
+			})
+		}
+	}
+
+	ports := make([]int, 0, len(byPort))
+	for port := range byPort {
+		ports = append(ports, port)
+	}
+	sort.Ints(ports)
+
+	for _, port := range ports {
+		ls := byPort[port]
+		sort.Slice(ls, func(i, j int) bool { return ls[i].owner < ls[j].owner })
+
+		for _, l := range ls {
+			if port == cfg.Settings.HTTPPort || port == cfg.Settings.HTTPSPort {
+				errs = append(errs, fmt.Errorf("%s.listen %d conflicts with the HTTP/HTTPS ports", l.owner, port))
+			}
+			if daemon, ok := reservedPorts[port]; ok {
+				errs = append(errs, fmt.Errorf("%s.listen %d conflicts with %s", l.owner, port, daemon))
+			}
+		}
+		if len(ls) < 2 {
+			continue
+		}
+
+		hosts := make(map[string]string) // host -> owner
+		for _, l := range ls {
+			if !l.sni {
+				others := make([]string, 0, len(ls)-1)
+				for _, o := range ls {
+					if o.owner != l.owner {
+						others = append(others, o.owner)
+					}
+				}
+				errs = append(errs, fmt.Errorf("%s.listen %d overlaps with %s; only tls services can share a port", l.owner, port, strings.Join(others, ", ")))
+				continue
+			}
+			if other, exists := hosts[l.host]; exists {
+				errs = append(errs, fmt.Errorf("%s and %s both serve %s on port %d", other, l.owner, l.host, port))
+			}
+			hosts[l.host] = l.owner
+		}
+	}
+
+	return errs
+}
+
+func validateHealthCheck(prefix string, h HealthCheck) []error {
+	var errs []error
+
+	if !strings.HasPrefix(h.Path, "/") {
+		errs = append(errs, fmt.Errorf("%s.path %q must start with /", prefix, h.Path))
+	}
+
+	if h.Method != "" && !allowedHealthCheckMethods[h.Method] {
+		errs = append(errs, fmt.Errorf("%s.method must be one of: GET, HEAD, POST, OPTIONS; got %q", prefix, h.Method))
+	}
+
+	if h.ExpectStatus != 0 && (h.ExpectStatus < 1 || h.ExpectStatus > 5) && (h.ExpectStatus < 100 || h.ExpectStatus > 599) {
+		errs = append(errs, fmt.Errorf("%s.expect_status must be a status code (100-599) or class (1-5), got %d", prefix, h.ExpectStatus))
+	}
+
+	if h.ExpectBody != "" {
+		if _, err := regexp.Compile(h.ExpectBody); err != nil {
+			errs = append(errs, fmt.Errorf("%s.expect_body %q must be a valid regular expression: %v", prefix, h.ExpectBody, err))
+		}
+	}
+
+	var interval, timeout time.Duration
+	if h.Interval != "" {
+		d, err := time.ParseDuration(h.Interval)
+		if err != nil || d <= 0 {
+			errs = append(errs, fmt.Errorf("%s.interval %q must be a positive duration", prefix, h.Interval))
+		}
+		interval = d
+	}
+	if h.Timeout != "" {
+		d, err := time.ParseDuration(h.Timeout)
+		if err != nil || d <= 0 {
+			errs = append(errs, fmt.Errorf("%s.timeout %q must be a positive duration", prefix, h.Timeout))
+		}
+		timeout = d
+	}
+	if interval > 0 && timeout > 0 && timeout >= interval {
+		errs = append(errs, fmt.Errorf("%s.timeout must be shorter than interval", prefix))
+	}
+
+	return errs
+}
+
+func validateAuth(prefix string, a Auth) []error {
+	var errs []error
+
+	if a.Basic != nil {
+		if len(a.Basic.Users) == 0 {
+			errs = append(errs, fmt.Errorf("%s.basic.users must have at least one entry", prefix))
+		}
+		for user, hash := range a.Basic.Users {
+			if user == "" || strings.ContainsAny(user, ": \t") {
+				errs = append(errs, fmt.Errorf("%s.basic.users %q must be a non-empty name without colons or spaces", prefix, user))
+			}
+			if _, err := bcrypt.Cost([]byte(hash)); err != nil {
+				errs = append(errs, fmt.Errorf("%s.basic.users.%s must be a bcrypt hash (set it with 'hatch passwd')", prefix, user))
+			}
+		}
+	}
+
+	for i, entry := range a.AllowIPs {
+		if _, _, err := net.ParseCIDR(entry); err == nil {
+			continue
+		}
+		if net.ParseIP(entry) == nil {
+			errs = append(errs, fmt.Errorf("%s.allow_ips[%d] %q must be an IP address or CIDR range", prefix, i, entry))
+		}
+	}
+
+	return errs
+}
+
+func validateMock(prefix string, rules []MockRule) []error {
+	var errs []error
+
+	for i, r := range rules {
+		rulePrefix := fmt.Sprintf("%s[%d]", prefix, i)
+		if r.Method != "" && (!validHeaderName.MatchString(r.Method) || r.Method != strings.ToUpper(r.Method)) {
+			errs = append(errs, fmt.Errorf("%s.method %q must be an uppercase HTTP method", rulePrefix, r.Method))
+		}
+		if err := validateMockPath(r.Path); err != nil {
+			errs = append(errs, fmt.Errorf("%s.path %q %v", rulePrefix, r.Path, err))
+		}
+		if r.Status != 0 && (r.Status < 100 || r.Status > 599) {
+			errs = append(errs, fmt.Errorf("%s.status must be a status code (100-599), got %d", rulePrefix, r.Status))
+		}
+		if r.Body != "" && r.File != "" {
+			errs = append(errs, fmt.Errorf("%s.body and %s.file are mutually exclusive", rulePrefix, rulePrefix))
+		}
+		for name := range r.Headers {
+			if !validHeaderName.MatchString(name) {
+				errs = append(errs, fmt.Errorf("%s.headers %q is not a valid header name", rulePrefix, name))
+			}
+		}
+		if r.Template && r.Body != "" {
+			if _, err := template.New("body").Parse(r.Body); err != nil {
+				errs = append(errs, fmt.Errorf("%s.body must be a valid template: %v", rulePrefix, err))
+			}
+		}
+	}
+
+	return errs
+}
+
+// validateMockFiles checks that the rules' body files stay inside the
+// project directory, so that a project's config cannot serve arbitrary
+// files of the user's to whoever it is shared with.
+func validateMockFiles(prefix, dir string, rules []MockRule) []error {
+	var errs []error
+
+	for i, r := range rules {
+		if r.File == "" {
+			continue
+		}
+		if filepath.IsAbs(r.File) {
+			errs = append(errs, fmt.Errorf("%s[%d].file %q must be relative to the project path", prefix, i, r.File))
+			continue
+		}
+		rel, err := filepath.Rel(dir, filepath.Join(dir, r.File))
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			errs = append(errs, fmt.Errorf("%s[%d].file %q must be inside the project path", prefix, i, r.File))
+		}
+	}
+
+	return errs
+}
+
+// validateMockPath checks a mock rule's path pattern: segments are
+// literals or {name}, and only the last may be *.
+func validateMockPath(path string) error {
+	if !strings.HasPrefix(path, "/") {
+		return fmt.Errorf("must start with /")
+	}
+	segments := strings.Split(path[1:], "/")
+	for i, seg := range segments {
+		switch {
+		case seg == "*":
+			if i != len(segments)-1 {
+				return fmt.Errorf("may only end with *")
+			}
+		case strings.HasPrefix(seg, "{") && strings.HasSuffix(seg, "}"):
+			if !validMockParam.MatchString(seg[1 : len(seg)-1]) {
+				return fmt.Errorf("has an invalid parameter %s", seg)
+			}
+		case strings.ContainsAny(seg, "{}*"):
+			return fmt.Errorf("segments must be literal, {name} or a final *")
+		}
+	}
+	return nil
+}
+
+func validateChaos(prefix string, c Chaos) []error {
+	var errs []error
+
+	for _, f := range []struct{ name, value string }{
+		{"latency", c.Latency},
+		{"jitter", c.Jitter},
+	} {
+		if f.value == "" {
+			continue
+		}
+		if d, err := time.ParseDuration(f.value); err != nil || d < 0 {
+			errs = append(errs, fmt.Errorf("%s.%s %q must be a non-negative duration", prefix, f.name, f.value))
+		}
+	}
+
+	if c.ErrorRate < 0 || c.ErrorRate > 1 {
+		errs = append(errs, fmt.Errorf("%s.error_rate must be between 0 and 1, got %g", prefix, c.ErrorRate))
+	}
+	if c.ErrorStatus != 0 && (c.ErrorStatus < 400 || c.ErrorStatus > 599) {
+		errs = append(errs, fmt.Errorf("%s.error_status must be an error status code (400-599), got %d", prefix, c.ErrorStatus))
+	}
+	if c.BandwidthKbps < 0 {
+		errs = append(errs, fmt.Errorf("%s.bandwidth_kbps must not be negative, got %d", prefix, c.BandwidthKbps))
+	}
+
+	return errs
+}
+
+// isValidProxyURL checks that raw is an http or https URL with a host.
+func isValidProxyURL(raw string) bool {
+	u, err := url.Parse(raw)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// isValidTCPURL checks that raw is a tcp URL with a host and port.
+func isValidTCPURL(raw string) bool {
+	u, err := url.Parse(raw)
+	return err == nil && u.Scheme == "tcp" && u.Hostname() != "" && u.Port() != ""
+}
+
+// isValidDomain checks that domain is a valid hostname below one of the
+// configured TLDs, or equal to or below a DNS override host.
+func isValidDomain(domain string, s Settings) bool {
+	if !isValidHostname(domain) {
 		return false
 	}
+	for _, tld := range s.AllTLDs() {
+		if strings.HasSuffix(domain, "."+tld) {
+			return true
+		}
+	}
+	for host := range s.DNSOverrides {
+		if domain == host || strings.HasSuffix(domain, "."+host) {
+			return true
+		}
+	}
+	return false
+}
 
-	// Check each label
-	labels := strings.Split(base, ".")
-	for _, label := range labels {
+// isValidHostname checks that every label of host is a valid hostname
+// label.
+func isValidHostname(host string) bool {
+	for _, label := range strings.Split(host, ".") {
 		if !validHostnameLabel.MatchString(label) {
 			return false
 		}
 	}
-
 	return true
 }
+
+// describeZones lists the accepted domain suffixes for error messages,
+// e.g. ".test or .dev or a settings.dns_overrides host".
+func describeZones(s Settings) string {
+	parts := make([]string, 0, len(s.AllTLDs())+1)
+	for _, tld := range s.AllTLDs() {
+		parts = append(parts, "."+tld)
+	}
+	if len(s.DNSOverrides) > 0 {
+		parts = append(parts, "a settings.dns_overrides host")
+	}
+	return strings.Join(parts, " or ")
+}
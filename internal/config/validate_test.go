@@ -1,8 +1,11 @@
 package config
 
 import (
+	"slices"
 	"strings"
 	"testing"
+
+	"golang.org/x/crypto/bcrypt"
 )
 
 func validConfig() Config {
@@ -47,7 +50,7 @@ func TestValidate_Version(t *testing.T) {
 }
 
 func TestValidate_TLD(t *testing.T) {
-	for _, tld := range []string{"test", "localhost", "local", "dev"} {
+	for _, tld := range []string{"test", "localhost", "local", "dev", "internal", "corp-1"} {
 		cfg := validConfig()
 		cfg.Settings.TLD = tld
 		cfg.Projects["myapp"] = Project{
@@ -60,10 +63,132 @@ func TestValidate_TLD(t *testing.T) {
 		}
 	}
 
+	tests := []struct {
+		tld      string
+		errSubst string
+	}{
+		{"com", `settings.tld "com" is a public or reserved TLD`},
+		{"invalid", `settings.tld "invalid" is a public or reserved TLD`},
+		{"Test", `settings.tld "Test" must be a single lowercase DNS label`},
+		{"my.test", `settings.tld "my.test" must be a single lowercase DNS label`},
+	}
+	for _, tt := range tests {
+		cfg := validConfig()
+		cfg.Settings.TLD = tt.tld
+		requireError(t, Validate(cfg), tt.errSubst)
+	}
+}
+
+func TestValidate_TLDs(t *testing.T) {
 	cfg := validConfig()
-	cfg.Settings.TLD = "com"
+	cfg.Settings.TLD = ""
+	cfg.Settings.TLDs = []string{"test", "internal"}
+	cfg.Projects["other"] = Project{
+		Domain: "other.internal", Aliases: []string{"other.test"}, Path: "/tmp", Enabled: true,
+		Services: map[string]Service{"web": {Proxy: "http://localhost:3001"}},
+	}
+	if errs := Validate(cfg); len(errs) != 0 {
+		t.Fatalf("expected no errors, got %v", errs)
+	}
+
+	cfg.Settings.TLDs = []string{"test", "test", "net"}
 	errs := Validate(cfg)
-	requireError(t, errs, "settings.tld must be one of")
+	requireError(t, errs, `settings.tlds[1] "test" is listed more than once`)
+	requireError(t, errs, `settings.tlds[2] "net" is a public or reserved TLD`)
+	requireError(t, errs, `projects.other.domain "other.internal" must be a valid hostname ending with .test`)
+
+	cfg.Settings.TLD = "test"
+	cfg.Settings.TLDs = []string{"internal"}
+	if errs := Validate(cfg); len(errs) != 0 {
+		t.Fatalf("expected tld and tlds to combine, got %v", errs)
+	}
+
+	cfg.Settings.TLD = ""
+	cfg.Settings.TLDs = nil
+	requireError(t, Validate(cfg), "settings.tlds must list at least one TLD")
+}
+
+func TestValidate_DNSOverrides(t *testing.T) {
+	cfg := validConfig()
+	cfg.Settings.DNSOverrides = map[string]string{
+		"api.staging.company.com": "127.0.0.1",
+		"db.company.com":          "::1",
+	}
+	cfg.Projects["api"] = Project{
+		Domain: "api.staging.company.com", Aliases: []string{"v2.api.staging.company.com"}, Path: "/tmp", Enabled: true,
+		Services: map[string]Service{"web": {Proxy: "http://localhost:8080"}},
+	}
+	if errs := Validate(cfg); len(errs) != 0 {
+		t.Fatalf("expected no errors, got %v", errs)
+	}
+
+	cfg.Projects["api"] = Project{
+		Domain: "staging.company.com", Path: "/tmp", Enabled: true,
+		Services: map[string]Service{"web": {Proxy: "http://localhost:8080"}},
+	}
+	requireError(t, Validate(cfg), `must be a valid hostname ending with .test or a settings.dns_overrides host`)
+
+	tests := []struct {
+		host, ip string
+		errSubst string
+	}{
+		{"company", "127.0.0.1", `settings.dns_overrides.company: "company" must be a lowercase hostname with at least two labels`},
+		{"API.company.com", "127.0.0.1", "must be a lowercase hostname"},
+		{"bad_host.com", "127.0.0.1", "must be a lowercase hostname"},
+		{"app.company.com", "localhost", `settings.dns_overrides.app.company.com must be an IP address, got "localhost"`},
+	}
+	for _, tt := range tests {
+		cfg := validConfig()
+		cfg.Settings.DNSOverrides = map[string]string{tt.host: tt.ip}
+		requireError(t, Validate(cfg), tt.errSubst)
+	}
+}
+
+func TestValidate_Tunnel(t *testing.T) {
+	cfg := validConfig()
+	cfg.Settings.Tunnel = &TunnelSettings{Relay: "https://tunnel.example.com", Token: "s3cret"}
+	if errs := Validate(cfg); len(errs) != 0 {
+		t.Fatalf("expected no errors, got %v", errs)
+	}
+
+	for _, relay := range []string{"", "tunnel.example.com", "ftp://tunnel.example.com"} {
+		cfg.Settings.Tunnel.Relay = relay
+		requireError(t, Validate(cfg), "settings.tunnel.relay must be an http:// or https:// URL")
+	}
+}
+
+func TestValidate_Docker(t *testing.T) {
+	cfg := validConfig()
+	cfg.Settings.Docker = &DockerSettings{}
+	if errs := Validate(cfg); len(errs) != 0 {
+		t.Fatalf("expected no errors, got %v", errs)
+	}
+	cfg.Settings.Docker.Socket = "/run/user/1000/docker.sock"
+	if errs := Validate(cfg); len(errs) != 0 {
+		t.Fatalf("expected no errors, got %v", errs)
+	}
+	cfg.Settings.Docker.Socket = "docker.sock"
+	requireError(t, Validate(cfg), "settings.docker.socket must be an absolute path")
+}
+
+func TestSettings_DNSZones(t *testing.T) {
+	s := Settings{
+		TLD:  "test",
+		TLDs: []string{"internal", "test"},
+		DNSOverrides: map[string]string{
+			"api.staging.company.com": "127.0.0.1",
+			"staging.company.com":     "127.0.0.1",
+			"db.test":                 "10.0.0.2",
+			"other.org":               "10.0.0.3",
+		},
+	}
+	if got, want := s.AllTLDs(), []string{"test", "internal"}; !slices.Equal(got, want) {
+		t.Errorf("AllTLDs() = %v, want %v", got, want)
+	}
+	want := []string{"test", "internal", "other.org", "staging.company.com"}
+	if got := s.DNSZones(); !slices.Equal(got, want) {
+		t.Errorf("DNSZones() = %v, want %v", got, want)
+	}
 }
 
 func TestValidate_Ports(t *testing.T) {
@@ -138,6 +263,14 @@ func TestValidate_ProjectPath(t *testing.T) {
 	requireError(t, errs, "path is required")
 }
 
+func TestValidate_ProjectName(t *testing.T) {
+	cfg := validConfig()
+	cfg.Projects["off"] = cfg.Projects["myapp"]
+	delete(cfg.Projects, "myapp")
+	errs := Validate(cfg)
+	requireError(t, errs, `project name "off" is reserved`)
+}
+
 func TestValidate_ProjectServicesEmpty(t *testing.T) {
 	cfg := validConfig()
 	p := cfg.Projects["myapp"]
@@ -169,6 +302,508 @@ func TestValidate_ServiceProxy(t *testing.T) {
 	}
 }
 
+func TestValidate_ServiceUpstreams(t *testing.T) {
+	tests := []struct {
+		name     string
+		svc      Service
+		errSubst string
+	}{
+		{"both proxy and upstreams", Service{Proxy: "http://localhost:3000", Upstreams: []string{"http://localhost:3001"}}, "mutually exclusive"},
+		{"invalid upstream", Service{Upstreams: []string{"http://localhost:3001", "localhost:3002"}}, "upstreams[1]"},
+		{"duplicate upstream", Service{Upstreams: []string{"http://localhost:3001", "http://localhost:3001"}}, "listed more than once"},
+		{"unknown policy", Service{Upstreams: []string{"http://localhost:3001"}, LBPolicy: "random"}, "lb_policy must be one of"},
+		{"policy without upstreams", Service{Proxy: "http://localhost:3000", LBPolicy: LBFirst}, "lb_policy requires upstreams"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := validConfig()
+			p := cfg.Projects["myapp"]
+			p.Services = map[string]Service{"web": tt.svc}
+			cfg.Projects["myapp"] = p
+			errs := Validate(cfg)
+			requireError(t, errs, tt.errSubst)
+		})
+	}
+}
+
+func TestValidate_ServiceUpstreamsValid(t *testing.T) {
+	cfg := validConfig()
+	p := cfg.Projects["myapp"]
+	p.Services = map[string]Service{"web": {
+		Upstreams: []string{"http://localhost:3001", "http://localhost:3002"},
+		LBPolicy:  LBLeastConn,
+	}}
+	cfg.Projects["myapp"] = p
+	if errs := Validate(cfg); len(errs) != 0 {
+		t.Fatalf("expected no errors, got %v", errs)
+	}
+}
+
+func TestValidate_ServiceHealthCheck(t *testing.T) {
+	tests := []struct {
+		name     string
+		hc       HealthCheck
+		errSubst string
+	}{
+		{"relative path", HealthCheck{Path: "healthz"}, "must start with /"},
+		{"bad method", HealthCheck{Path: "/healthz", Method: "DELETE"}, "method must be one of"},
+		{"bad status", HealthCheck{Path: "/healthz", ExpectStatus: 42}, "expect_status must be"},
+		{"bad regexp", HealthCheck{Path: "/healthz", ExpectBody: "ok("}, "valid regular expression"},
+		{"bad interval", HealthCheck{Path: "/healthz", Interval: "soon"}, "interval \"soon\" must be a positive duration"},
+		{"negative timeout", HealthCheck{Path: "/healthz", Timeout: "-1s"}, "timeout \"-1s\" must be a positive duration"},
+		{"timeout exceeds interval", HealthCheck{Path: "/healthz", Interval: "2s", Timeout: "5s"}, "timeout must be shorter than interval"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := validConfig()
+			p := cfg.Projects["myapp"]
+			hc := tt.hc
+			p.Services = map[string]Service{"web": {Proxy: "http://localhost:3000", HealthCheck: &hc}}
+			cfg.Projects["myapp"] = p
+			errs := Validate(cfg)
+			requireError(t, errs, tt.errSubst)
+		})
+	}
+}
+
+func TestValidate_ServiceHealthCheckValid(t *testing.T) {
+	cfg := validConfig()
+	p := cfg.Projects["myapp"]
+	p.Services = map[string]Service{"web": {
+		Proxy: "http://localhost:3000",
+		HealthCheck: &HealthCheck{
+			Path:         "/healthz",
+			Method:       "HEAD",
+			ExpectStatus: 2,
+			ExpectBody:   "^ok$",
+			Interval:     "5s",
+			Timeout:      "1s",
+		},
+	}}
+	cfg.Projects["myapp"] = p
+	if errs := Validate(cfg); len(errs) != 0 {
+		t.Fatalf("expected no errors, got %v", errs)
+	}
+}
+
+func TestValidate_ServiceHeaders(t *testing.T) {
+	tests := []struct {
+		name     string
+		headers  Headers
+		errSubst string
+	}{
+		{"bad set name", Headers{Request: &HeaderOps{Set: map[string]string{"X Bad": "1"}}}, "headers.request.set: \"X Bad\" is not a valid header name"},
+		{"bad add name", Headers{Response: &HeaderOps{Add: map[string]string{"X:Bad": "1"}}}, "headers.response.add"},
+		{"empty delete", Headers{Response: &HeaderOps{Delete: []string{""}}}, "headers.response.delete[0]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := validConfig()
+			p := cfg.Projects["myapp"]
+			h := tt.headers
+			p.Services = map[string]Service{"web": {Proxy: "http://localhost:3000", Headers: &h}}
+			cfg.Projects["myapp"] = p
+			errs := Validate(cfg)
+			requireError(t, errs, tt.errSubst)
+		})
+	}
+}
+
+func TestValidate_ServiceHeadersValid(t *testing.T) {
+	cfg := validConfig()
+	p := cfg.Projects["myapp"]
+	p.Services = map[string]Service{"web": {
+		Proxy: "http://localhost:3000",
+		Headers: &Headers{
+			Request:  &HeaderOps{Set: map[string]string{"X-Forwarded-Prefix": "/api"}, Add: map[string]string{"X-User": "{http.request.host}"}},
+			Response: &HeaderOps{Delete: []string{"Strict-Transport-Security", "X-Debug-*"}},
+		},
+	}}
+	cfg.Projects["myapp"] = p
+	if errs := Validate(cfg); len(errs) != 0 {
+		t.Fatalf("expected no errors, got %v", errs)
+	}
+}
+
+func TestValidate_ServicePathRewrite(t *testing.T) {
+	tests := []struct {
+		name     string
+		svc      Service
+		errSubst string
+	}{
+		{"strip without route", Service{StripPrefix: "/api"}, "strip_prefix requires route"},
+		{"strip not under route", Service{Route: "/api/*", StripPrefix: "/admin"}, "is not a prefix of route"},
+		{"strip partial segment", Service{Route: "/apiv2/*", StripPrefix: "/api"}, "is not a prefix of route"},
+		{"strip trailing slash", Service{Route: "/api/*", StripPrefix: "/api/"}, "must start with / and not end with /"},
+		{"relative add prefix", Service{AddPrefix: "v2"}, "add_prefix \"v2\" must start with /"},
+		{"missing regex", Service{Rewrite: &Rewrite{Replace: "/x"}}, "rewrite.regex is required"},
+		{"bad regex", Service{Rewrite: &Rewrite{Regex: "(", Replace: "/x"}}, "must be a valid regular expression"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := validConfig()
+			p := cfg.Projects["myapp"]
+			svc := tt.svc
+			svc.Proxy = "http://localhost:3000"
+			p.Services = map[string]Service{"web": svc}
+			cfg.Projects["myapp"] = p
+			errs := Validate(cfg)
+			requireError(t, errs, tt.errSubst)
+		})
+	}
+}
+
+func TestValidate_ServicePathRewriteValid(t *testing.T) {
+	for _, svc := range []Service{
+		{Route: "/api/*", StripPrefix: "/api"},
+		{Route: "/api", StripPrefix: "/api"},
+		{Route: "/v1/api/*", StripPrefix: "/v1"},
+		{Route: "/api/*", StripPrefix: "/api", AddPrefix: "/internal", Rewrite: &Rewrite{Regex: "^/old/(.*)$", Replace: "/new/$1"}},
+	} {
+		cfg := validConfig()
+		p := cfg.Projects["myapp"]
+		svc.Proxy = "http://localhost:3000"
+		p.Services = map[string]Service{"web": svc}
+		cfg.Projects["myapp"] = p
+		if errs := Validate(cfg); len(errs) != 0 {
+			t.Errorf("%+v: expected no errors, got %v", svc, errs)
+		}
+	}
+}
+
+func TestValidate_StaticService(t *testing.T) {
+	tests := []struct {
+		name     string
+		svc      Service
+		errSubst string
+	}{
+		{"root and proxy", Service{Root: "dist", Proxy: "http://localhost:3000"}, "root cannot be combined with proxy or upstreams"},
+		{"root and upstreams", Service{Root: "dist", Upstreams: []string{"http://localhost:3000"}}, "root cannot be combined with proxy or upstreams"},
+		{"spa without root", Service{Proxy: "http://localhost:3000", SPA: true}, "spa requires root"},
+		{"browse without root", Service{Proxy: "http://localhost:3000", Browse: true}, "browse requires root"},
+		{"websocket", Service{Root: "dist", WebSocket: true}, "websocket is not supported for static services"},
+		{"health check", Service{Root: "dist", HealthCheck: &HealthCheck{Path: "/"}}, "health_check is not supported for static services"},
+		{"buffer when down", Service{Root: "dist", BufferWhenDown: true}, "buffer_when_down is not supported for static services"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := validConfig()
+			p := cfg.Projects["myapp"]
+			p.Services = map[string]Service{"web": tt.svc}
+			cfg.Projects["myapp"] = p
+			errs := Validate(cfg)
+			requireError(t, errs, tt.errSubst)
+		})
+	}
+}
+
+func TestValidate_StaticServiceValid(t *testing.T) {
+	cfg := validConfig()
+	p := cfg.Projects["myapp"]
+	p.Services = map[string]Service{
+		"web":  {Root: "dist", SPA: true},
+		"docs": {Root: "/srv/docs", Route: "/docs/*", StripPrefix: "/docs", Browse: true},
+	}
+	cfg.Projects["myapp"] = p
+	if errs := Validate(cfg); len(errs) != 0 {
+		t.Fatalf("expected no errors, got %v", errs)
+	}
+}
+
+func TestService_RootDir(t *testing.T) {
+	p := Project{Path: "/home/dev/myapp"}
+	tests := []struct {
+		root, want string
+	}{
+		{"dist", "/home/dev/myapp/dist"},
+		{"./build/web", "/home/dev/myapp/build/web"},
+		{"/srv/docs", "/srv/docs"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := (Service{Root: tt.root}).RootDir(p); got != tt.want {
+			t.Errorf("RootDir(%q) = %q, want %q", tt.root, got, tt.want)
+		}
+	}
+}
+
+func TestValidate_ServiceCommand(t *testing.T) {
+	tests := []struct {
+		name     string
+		svc      Service
+		errSubst string
+	}{
+		{"cwd without command", Service{Proxy: "http://localhost:3000", Cwd: "web"}, "cwd requires command"},
+		{"env without command", Service{Proxy: "http://localhost:3000", Env: map[string]string{"PORT": "3000"}}, "env requires command"},
+		{"bad env name", Service{Proxy: "http://localhost:3000", Command: "npm run dev", Env: map[string]string{"NODE-ENV": "dev"}}, `env "NODE-ENV" must be a valid environment variable name`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := validConfig()
+			p := cfg.Projects["myapp"]
+			p.Services = map[string]Service{"web": tt.svc}
+			cfg.Projects["myapp"] = p
+			requireError(t, Validate(cfg), tt.errSubst)
+		})
+	}
+
+	cfg := validConfig()
+	p := cfg.Projects["myapp"]
+	p.Services = map[string]Service{
+		"web": {Proxy: "http://localhost:3000", Command: "npm run dev", Cwd: "web", Env: map[string]string{"PORT": "3000", "_DEBUG": "1"}},
+		"db":  {Type: ServiceTCP, Listen: 5432, Proxy: "tcp://localhost:15432", Command: "postgres -D data -p 15432"},
+	}
+	cfg.Projects["myapp"] = p
+	if errs := Validate(cfg); len(errs) != 0 {
+		t.Fatalf("expected no errors, got %v", errs)
+	}
+}
+
+func TestValidate_Chaos(t *testing.T) {
+	tests := []struct {
+		name     string
+		svc      Service
+		errSubst string
+	}{
+		{"bad latency", Service{Proxy: "http://localhost:3000", Chaos: &Chaos{Latency: "slow"}}, `chaos.latency "slow" must be a non-negative duration`},
+		{"negative jitter", Service{Proxy: "http://localhost:3000", Chaos: &Chaos{Jitter: "-1s"}}, `chaos.jitter "-1s" must be a non-negative duration`},
+		{"error rate above 1", Service{Proxy: "http://localhost:3000", Chaos: &Chaos{ErrorRate: 1.5}}, "chaos.error_rate must be between 0 and 1"},
+		{"success status", Service{Proxy: "http://localhost:3000", Chaos: &Chaos{ErrorRate: 0.1, ErrorStatus: 200}}, "chaos.error_status must be an error status code"},
+		{"negative bandwidth", Service{Proxy: "http://localhost:3000", Chaos: &Chaos{BandwidthKbps: -1}}, "chaos.bandwidth_kbps must not be negative"},
+		{"tcp service", Service{Type: ServiceTCP, Listen: 5432, Proxy: "tcp://localhost:5432", Chaos: &Chaos{Latency: "1s"}}, "chaos is not supported for tcp services"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := validConfig()
+			p := cfg.Projects["myapp"]
+			p.Services = map[string]Service{"web": tt.svc}
+			cfg.Projects["myapp"] = p
+			requireError(t, Validate(cfg), tt.errSubst)
+		})
+	}
+
+	cfg := validConfig()
+	p := cfg.Projects["myapp"]
+	p.Services = map[string]Service{
+		"web": {Proxy: "http://localhost:3000", Chaos: &Chaos{Latency: "500ms", Jitter: "100ms", ErrorRate: 0.1, ErrorStatus: 503, BandwidthKbps: 256}},
+	}
+	cfg.Projects["myapp"] = p
+	if errs := Validate(cfg); len(errs) != 0 {
+		t.Fatalf("expected no errors, got %v", errs)
+	}
+}
+
+func TestValidate_Mock(t *testing.T) {
+	tests := []struct {
+		name     string
+		svc      Service
+		errSubst string
+	}{
+		{"mock and proxy", Service{Proxy: "http://localhost:3000", Mock: []MockRule{{Path: "/"}}}, "mock cannot be combined with proxy, upstreams or root"},
+		{"relative path", Service{Mock: []MockRule{{Path: "api/payments"}}}, `mock[0].path "api/payments" must start with /`},
+		{"star in the middle", Service{Mock: []MockRule{{Path: "/api/*/items"}}}, "may only end with *"},
+		{"bad parameter", Service{Mock: []MockRule{{Path: "/api/{payment-id}"}}}, "has an invalid parameter {payment-id}"},
+		{"partial parameter", Service{Mock: []MockRule{{Path: "/api/v{version}"}}}, "segments must be literal"},
+		{"lowercase method", Service{Mock: []MockRule{{Method: "get", Path: "/"}}}, "must be an uppercase HTTP method"},
+		{"bad status", Service{Mock: []MockRule{{Path: "/", Status: 42}}}, "mock[0].status must be a status code"},
+		{"body and file", Service{Mock: []MockRule{{Path: "/", Body: "{}", File: "mocks/a.json"}}}, "body and projects.myapp.services.web.mock[0].file are mutually exclusive"},
+		{"bad header", Service{Mock: []MockRule{{Path: "/", Headers: map[string]string{"Bad Header": "x"}}}}, `headers "Bad Header" is not a valid header name`},
+		{"bad template", Service{Mock: []MockRule{{Path: "/", Body: "{{.Params.id", Template: true}}}, "body must be a valid template"},
+		{"health check", Service{Mock: []MockRule{{Path: "/"}}, HealthCheck: &HealthCheck{Path: "/"}}, "health_check is not supported for mock services"},
+		{"absolute file", Service{Mock: []MockRule{{Path: "/", File: "/etc/passwd"}}}, `mock[0].file "/etc/passwd" must be relative to the project path`},
+		{"file outside the project", Service{Mock: []MockRule{{Path: "/", File: "mocks/../../../.ssh/id_rsa"}}}, `mock[0].file "mocks/../../../.ssh/id_rsa" must be inside the project path`},
+		{"tcp service", Service{Type: ServiceTCP, Listen: 5432, Proxy: "tcp://localhost:5432", Mock: []MockRule{{Path: "/"}}}, "mock is not supported for tcp services"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := validConfig()
+			p := cfg.Projects["myapp"]
+			p.Services = map[string]Service{"web": tt.svc}
+			cfg.Projects["myapp"] = p
+			requireError(t, Validate(cfg), tt.errSubst)
+		})
+	}
+
+	cfg := validConfig()
+	p := cfg.Projects["myapp"]
+	p.Services = map[string]Service{
+		"payments": {Route: "/api/payments/*", Mock: []MockRule{
+			{Method: "GET", Path: "/api/payments/{id}", Body: `{"id": "{{.Params.id}}"}`, Template: true},
+			{Method: "POST", Path: "/api/payments", Status: 201, Headers: map[string]string{"Location": "/api/payments/1"}, File: "mocks/payment.json"},
+			{Path: "/api/payments/*", Status: 404},
+		}},
+	}
+	cfg.Projects["myapp"] = p
+	if errs := Validate(cfg); len(errs) != 0 {
+		t.Fatalf("expected no errors, got %v", errs)
+	}
+}
+
+func TestValidate_Auth(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		auth     Auth
+		errSubst string
+	}{
+		{"no users", Auth{Basic: &BasicAuth{}}, "auth.basic.users must have at least one entry"},
+		{"plain password", Auth{Basic: &BasicAuth{Users: map[string]string{"client": "secret"}}}, "auth.basic.users.client must be a bcrypt hash"},
+		{"colon in name", Auth{Basic: &BasicAuth{Users: map[string]string{"a:b": string(hash)}}}, `auth.basic.users "a:b" must be a non-empty name`},
+		{"bad range", Auth{AllowIPs: []string{"192.168.1.0/33"}}, `auth.allow_ips[0] "192.168.1.0/33" must be an IP address or CIDR range`},
+		{"hostname", Auth{AllowIPs: []string{"10.0.0.1", "laptop.local"}}, `auth.allow_ips[1] "laptop.local"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := validConfig()
+			p := cfg.Projects["myapp"]
+			p.Auth = &tt.auth
+			cfg.Projects["myapp"] = p
+			requireError(t, Validate(cfg), tt.errSubst)
+		})
+	}
+
+	cfg := validConfig()
+	p := cfg.Projects["myapp"]
+	p.Auth = &Auth{AllowIPs: []string{"192.168.1.0/24"}}
+	p.Services = map[string]Service{
+		"web": {Proxy: "http://localhost:3000"},
+		"db":  {Type: ServiceTCP, Listen: 5432, Proxy: "tcp://localhost:5432"},
+	}
+	cfg.Projects["myapp"] = p
+	requireError(t, Validate(cfg), "projects.myapp.auth is not supported for projects with tcp services (db)")
+
+	cfg = validConfig()
+	p = cfg.Projects["myapp"]
+	p.Auth = &Auth{
+		Basic:    &BasicAuth{Users: map[string]string{"client": string(hash)}},
+		AllowIPs: []string{"192.168.1.0/24", "10.0.0.7", "fd00::/8"},
+	}
+	cfg.Projects["myapp"] = p
+	if errs := Validate(cfg); len(errs) != 0 {
+		t.Fatalf("expected no errors, got %v", errs)
+	}
+}
+
+func TestService_MockRules(t *testing.T) {
+	p := Project{Path: "/home/dev/myapp"}
+	svc := Service{Mock: []MockRule{{Path: "/a", File: "mocks/a.json"}, {Path: "/b", File: "/srv/b.json"}, {Path: "/c"}}}
+	rules := svc.MockRules(p)
+	if rules[0].File != "/home/dev/myapp/mocks/a.json" || rules[1].File != "/srv/b.json" || rules[2].File != "" {
+		t.Errorf("unexpected rules %+v", rules)
+	}
+	if svc.Mock[0].File != "mocks/a.json" {
+		t.Error("expected MockRules to leave the service's rules unchanged")
+	}
+}
+
+func TestService_WorkDir(t *testing.T) {
+	p := Project{Path: "/home/dev/myapp"}
+	tests := []struct {
+		cwd, want string
+	}{
+		{"", "/home/dev/myapp"},
+		{"frontend", "/home/dev/myapp/frontend"},
+		{"/srv/api", "/srv/api"},
+	}
+	for _, tt := range tests {
+		if got := (Service{Cwd: tt.cwd}).WorkDir(p); got != tt.want {
+			t.Errorf("WorkDir(%q) = %q, want %q", tt.cwd, got, tt.want)
+		}
+	}
+}
+
+func TestProject_ErrorPagePath(t *testing.T) {
+	tests := []struct {
+		page, want string
+	}{
+		{"errors/502.html", "/home/dev/myapp/errors/502.html"},
+		{"/srv/error.html", "/srv/error.html"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		p := Project{Path: "/home/dev/myapp", ErrorPage: tt.page}
+		if got := p.ErrorPagePath(); got != tt.want {
+			t.Errorf("ErrorPagePath(%q) = %q, want %q", tt.page, got, tt.want)
+		}
+	}
+}
+
+func TestValidate_TCPService(t *testing.T) {
+	tests := []struct {
+		name     string
+		svc      Service
+		errSubst string
+	}{
+		{"unknown type", Service{Type: "udp", Proxy: "tcp://localhost:5432"}, "type must be one of"},
+		{"missing listen", Service{Type: ServiceTCP, Proxy: "tcp://localhost:5432"}, "listen must be 1-65535"},
+		{"missing proxy", Service{Type: ServiceTCP, Listen: 5432}, "proxy is required"},
+		{"http proxy", Service{Type: ServiceTCP, Listen: 5432, Proxy: "http://localhost:5432"}, "must be a tcp://host:port URL"},
+		{"no port", Service{Type: ServiceTCP, Listen: 5432, Proxy: "tcp://localhost"}, "must be a tcp://host:port URL"},
+		{"bad tls", Service{Type: ServiceTCP, Listen: 5432, Proxy: "tcp://localhost:5432", TLS: "mutual"}, "tls must be one of"},
+		{"route", Service{Type: ServiceTCP, Listen: 5432, Proxy: "tcp://localhost:5432", Route: "/db"}, "route is not supported for tcp services"},
+		{"listen on http service", Service{Proxy: "http://localhost:3000", Listen: 3000}, "listen is only valid for tcp services"},
+		{"tls on http service", Service{Proxy: "http://localhost:3000", TLS: TLSTerminate}, "tls is only valid for tcp services"},
+		{"https port", Service{Type: ServiceTCP, Listen: 443, Proxy: "tcp://localhost:5432"}, "conflicts with the HTTP/HTTPS ports"},
+		{"dns port", Service{Type: ServiceTCP, Listen: 5053, Proxy: "tcp://localhost:5432"}, "listen 5053 conflicts with the embedded DNS server"},
+		{"api port", Service{Type: ServiceTCP, Listen: APIPort, Proxy: "tcp://localhost:5432"}, "listen 42824 conflicts with the API server"},
+		{"caddy admin port", Service{Type: ServiceTCP, Listen: CaddyAdminPort, Proxy: "tcp://localhost:5432"}, "listen 2019 conflicts with Caddy's admin endpoint"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := validConfig()
+			p := cfg.Projects["myapp"]
+			p.Services = map[string]Service{"db": tt.svc}
+			cfg.Projects["myapp"] = p
+			errs := Validate(cfg)
+			requireError(t, errs, tt.errSubst)
+		})
+	}
+}
+
+func TestValidate_TCPListenOverlap(t *testing.T) {
+	tcpProject := func(domain string, svc Service) Project {
+		return Project{
+			Domain: domain, Path: "/tmp", Enabled: true,
+			Services: map[string]Service{"db": svc},
+		}
+	}
+
+	t.Run("plain tcp", func(t *testing.T) {
+		cfg := validConfig()
+		cfg.Projects["a"] = tcpProject("a.test", Service{Type: ServiceTCP, Listen: 5432, Proxy: "tcp://localhost:5433"})
+		cfg.Projects["b"] = tcpProject("b.test", Service{Type: ServiceTCP, Listen: 5432, Proxy: "tcp://localhost:5434"})
+		requireError(t, Validate(cfg), "projects.a.services.db.listen 5432 overlaps with projects.b.services.db")
+	})
+
+	t.Run("plain and tls", func(t *testing.T) {
+		cfg := validConfig()
+		cfg.Projects["a"] = tcpProject("a.test", Service{Type: ServiceTCP, Listen: 5432, Proxy: "tcp://localhost:5433"})
+		cfg.Projects["b"] = tcpProject("b.test", Service{Type: ServiceTCP, Listen: 5432, Proxy: "tcp://localhost:5434", TLS: TLSPassthrough})
+		requireError(t, Validate(cfg), "only tls services can share a port")
+	})
+
+	t.Run("same host", func(t *testing.T) {
+		cfg := validConfig()
+		p := tcpProject("a.test", Service{Type: ServiceTCP, Listen: 5432, Proxy: "tcp://localhost:5433", TLS: TLSTerminate})
+		p.Services["db2"] = Service{Type: ServiceTCP, Listen: 5432, Proxy: "tcp://localhost:5434", TLS: TLSPassthrough}
+		cfg.Projects["a"] = p
+		requireError(t, Validate(cfg), "both serve a.test on port 5432")
+	})
+
+	t.Run("shared by sni", func(t *testing.T) {
+		cfg := validConfig()
+		cfg.Projects["a"] = tcpProject("a.test", Service{Type: ServiceTCP, Listen: 5432, Proxy: "tcp://localhost:5433", TLS: TLSTerminate})
+		cfg.Projects["b"] = tcpProject("b.test", Service{Type: ServiceTCP, Listen: 5432, Proxy: "tcp://localhost:5434", TLS: TLSPassthrough})
+		if errs := Validate(cfg); len(errs) != 0 {
+			t.Fatalf("expected no errors, got %v", errs)
+		}
+	})
+}
+
 func TestValidate_ServiceSubdomain(t *testing.T) {
 	cfg := validConfig()
 	p := cfg.Projects["myapp"]
@@ -188,6 +823,74 @@ func TestValidate_DuplicateDomains(t *testing.T) {
 	requireError(t, errs, "duplicate domain")
 }
 
+func TestValidate_Aliases(t *testing.T) {
+	tests := []struct {
+		name     string
+		aliases  []string
+		errSubst string
+	}{
+		{"wrong tld", []string{"admin.com"}, "aliases[0] \"admin.com\" must be a valid hostname ending with .test"},
+		{"same as domain", []string{"myapp.test"}, "duplicates the domain or another alias"},
+		{"repeated", []string{"a.test", "a.test"}, "aliases[1] \"a.test\" duplicates"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := validConfig()
+			p := cfg.Projects["myapp"]
+			p.Aliases = tt.aliases
+			cfg.Projects["myapp"] = p
+			errs := Validate(cfg)
+			requireError(t, errs, tt.errSubst)
+		})
+	}
+}
+
+func TestValidate_AliasConflictsWithOtherProject(t *testing.T) {
+	cfg := validConfig()
+	cfg.Projects["other"] = Project{
+		Domain: "other.test", Aliases: []string{"myapp.test"}, Path: "/tmp/other", Enabled: true,
+		Services: map[string]Service{"web": {Proxy: "http://localhost:4000"}},
+	}
+	errs := Validate(cfg)
+	requireError(t, errs, "duplicate domain \"myapp.test\"")
+}
+
+func TestValidate_WildcardOverlap(t *testing.T) {
+	cfg := validConfig()
+	p := cfg.Projects["myapp"]
+	p.Wildcard = true
+	cfg.Projects["myapp"] = p
+	cfg.Projects["tenant"] = Project{
+		Domain: "acme.myapp.test", Path: "/tmp/tenant", Enabled: true,
+		Services: map[string]Service{"web": {Proxy: "http://localhost:4000"}},
+	}
+	errs := Validate(cfg)
+	requireError(t, errs, "\"acme.myapp.test\" is also matched by wildcard *.myapp.test of project \"myapp\"")
+
+	// Deeper names are not matched by a single-label wildcard.
+	cfg.Projects["tenant"] = Project{
+		Domain: "eu.acme.myapp.test", Path: "/tmp/tenant", Enabled: true,
+		Services: map[string]Service{"web": {Proxy: "http://localhost:4000"}},
+	}
+	if errs := Validate(cfg); len(errs) != 0 {
+		t.Fatalf("expected no errors, got %v", errs)
+	}
+}
+
+func TestProject_Hosts(t *testing.T) {
+	p := Project{Domain: "myapp.test", Aliases: []string{"admin-myapp.test"}, Wildcard: true}
+	want := []string{"myapp.test", "admin-myapp.test", "*.myapp.test", "*.admin-myapp.test"}
+	if got := p.Hosts(); !slices.Equal(got, want) {
+		t.Errorf("Hosts() = %v, want %v", got, want)
+	}
+
+	svc := Service{Subdomain: "api"}
+	want = []string{"api.myapp.test", "api.admin-myapp.test"}
+	if got := svc.Hosts(p); !slices.Equal(got, want) {
+		t.Errorf("Service.Hosts() = %v, want %v", got, want)
+	}
+}
+
 func TestValidate_NoProjects(t *testing.T) {
 	cfg := validConfig()
 	cfg.Projects = map[string]Project{}
@@ -0,0 +1,58 @@
+package daemon
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/paulrose/hatch/internal/chaos"
+	"github.com/paulrose/hatch/internal/config"
+	"github.com/paulrose/hatch/internal/events"
+)
+
+// Chaos returns the runtime fault injection overrides in effect.
+func (d *Daemon) Chaos() []chaos.Override {
+	return d.chaos.List()
+}
+
+// SetChaos injects the faults c describes into a service for dur, or
+// until cleared with dur zero, in place of its configured chaos settings.
+func (d *Daemon) SetChaos(project, service string, c config.Chaos, dur time.Duration) chaos.Override {
+	return d.chaos.Set(project, service, c, dur)
+}
+
+// ClearChaos removes a service's runtime override, reporting whether it
+// had one. Its configured chaos settings, if any, apply again.
+func (d *Daemon) ClearChaos(project, service string) bool {
+	return d.chaos.Clear(project, service)
+}
+
+// onChaosChange reloads Caddy with the overrides now in effect and
+// publishes the change.
+func (d *Daemon) onChaosChange(o chaos.Override, active bool) {
+	d.reloadMu.Lock()
+	defer d.reloadMu.Unlock()
+
+	d.mu.Lock()
+	running, cfg := d.running, d.cfg
+	d.mu.Unlock()
+	if !running {
+		return
+	}
+
+	if err := d.caddy.LoadConfig(context.Background(), d.translate(d.routedConfig(cfg))); err != nil {
+		log.Error().Err(err).Msg("failed to load caddy config with chaos overrides")
+		d.events.Publish(events.CaddyLoadFailed, events.CaddyLoadFailure{Error: err.Error()})
+		return
+	}
+
+	change := events.ChaosChange{Project: o.Project, Service: o.Service, Active: active}
+	if active {
+		change.Expires = o.Expires
+		log.Info().Str("project", o.Project).Str("service", o.Service).Time("expires", o.Expires).Msg("chaos on")
+	} else {
+		log.Info().Str("project", o.Project).Str("service", o.Service).Msg("chaos off")
+	}
+	d.events.Publish(events.ChaosChanged, change)
+}
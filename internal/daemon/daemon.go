@@ -4,8 +4,12 @@ package daemon
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
+	"net"
 	"os"
+	"reflect"
+	"sort"
 	"sync"
 	"time"
 
@@ -14,14 +18,25 @@ import (
 	"github.com/paulrose/hatch/internal/api"
 	"github.com/paulrose/hatch/internal/caddy"
 	"github.com/paulrose/hatch/internal/certs"
+	"github.com/paulrose/hatch/internal/chaos"
 	"github.com/paulrose/hatch/internal/config"
 	"github.com/paulrose/hatch/internal/dns"
+	"github.com/paulrose/hatch/internal/docker"
+	"github.com/paulrose/hatch/internal/errorpage"
+	"github.com/paulrose/hatch/internal/events"
 	"github.com/paulrose/hatch/internal/health"
+	"github.com/paulrose/hatch/internal/inspect"
+	"github.com/paulrose/hatch/internal/process"
+	"github.com/paulrose/hatch/internal/share"
+	"github.com/paulrose/hatch/internal/tcpproxy"
+	"github.com/paulrose/hatch/internal/tunnel"
+	"github.com/paulrose/hatch/internal/webhook"
 )
 
 // Daemon orchestrates all Hatch subsystems as a long-running background process.
 type Daemon struct {
 	caddy     *caddy.Server
+	tcp       *tcpproxy.Server
 	dns       *dns.Server
 	health    *health.Checker
 	watcher   *config.Watcher
@@ -34,14 +49,56 @@ type Daemon struct {
 	version   string
 	startTime time.Time
 	logHub    *api.LogHub
+	events    *events.Bus
+	requests  *inspect.Buffer
+	webhooks  *webhook.Manager
+	processes *process.Manager
+	chaos     *chaos.Manager
+	lanIP     net.IP // LAN address while sharing, guarded by mu
+
+	// startedProcesses are the services whose commands were started with
+	// "hatch start" and not since stopped, guarded by mu.
+	startedProcesses map[health.ServiceKey]bool
+
+	tunnels     *tunnel.Client // nil without settings.tunnel, guarded by mu
+	tunnelSetup tunnelSetup    // what tunnels was built from, guarded by mu
+
+	// docker discovers projects from containers; nil without
+	// settings.docker. It and dockerSocket are guarded by mu.
+	docker       *docker.Provider
+	dockerSocket string
+	discovered   map[string]config.ProjectConfig // guarded by mu
+
+	// reloadMu serializes applying config changes and discovered projects
+	// to Caddy and the health checker.
+	reloadMu sync.Mutex
 }
 
 // New creates a new Daemon instance with the given version and log hub.
 func New(version string, logHub *api.LogHub) *Daemon {
-	return &Daemon{
-		version: version,
-		logHub:  logHub,
+	d := &Daemon{
+		version:  version,
+		logHub:   logHub,
+		events:   events.NewBus(),
+		requests: inspect.NewBuffer(inspect.DefaultBufferSize),
 	}
+	d.webhooks = webhook.NewManager(webhook.ManagerConfig{
+		Dir:    config.QueueDir(),
+		Client: d.localClient,
+	})
+	d.processes = process.NewManager(process.Config{
+		OnChange: func(st process.Status) {
+			d.events.Publish(events.ProcessChanged, events.ProcessChange{
+				Project: st.Project,
+				Service: st.Service,
+				State:   st.State,
+				PID:     st.PID,
+				Exit:    st.Exit,
+			})
+		},
+	})
+	d.chaos = chaos.NewManager(chaos.ManagerConfig{OnChange: d.onChaosChange})
+	return d
 }
 
 // Run starts all subsystems and blocks until ctx is cancelled.
@@ -65,8 +122,10 @@ func (d *Daemon) Run(ctx context.Context) error {
 	}
 	d.cfg = cfg
 
-	// Pre-flight: check that required ports are free.
-	for _, port := range []int{cfg.Settings.HTTPPort, cfg.Settings.HTTPSPort} {
+	// Pre-flight: check that required ports, including the listen ports of
+	// tcp services, are free.
+	ports := append([]int{cfg.Settings.HTTPPort, cfg.Settings.HTTPSPort}, tcpproxy.Ports(cfg)...)
+	for _, port := range ports {
 		info, err := CheckPort(port)
 		if err != nil {
 			log.Warn().Err(err).Int("port", port).Msg("could not check port availability")
@@ -88,11 +147,7 @@ func (d *Daemon) Run(ctx context.Context) error {
 	}
 
 	// Start DNS server.
-	dnsSrv, err := dns.NewServer(dns.ServerConfig{
-		TLD:      cfg.Settings.TLD,
-		ListenIP: dns.DefaultListenIP,
-		Port:     dns.DefaultPort,
-	})
+	dnsSrv, err := dns.NewServer(dnsConfig(cfg.Settings))
 	if err != nil {
 		RemovePID(d.pidFile)
 		return fmt.Errorf("create dns server: %w", err)
@@ -102,14 +157,20 @@ func (d *Daemon) Run(ctx context.Context) error {
 		return fmt.Errorf("start dns: %w", err)
 	}
 	d.dns = dnsSrv
-	log.Info().Str("tld", cfg.Settings.TLD).Int("port", dns.DefaultPort).Msg("dns server started")
+	log.Info().Strs("tlds", cfg.Settings.AllTLDs()).Int("overrides", len(cfg.Settings.DNSOverrides)).
+		Int("port", dns.DefaultPort).Msg("dns server started")
+	d.applySharing(cfg)
 
 	// Clear Caddy's cached PKI so it uses our intermediate CA.
 	if err := caddy.ClearPKICache(); err != nil {
 		log.Warn().Err(err).Msg("failed to clear caddy PKI cache")
 	}
 
-	// Start Caddy server.
+	// Start Caddy server. Projects with capture enabled record their
+	// traffic into d.requests, and services with buffer_when_down hold
+	// requests with d.webhooks while down.
+	inspect.SetBuffer(d.requests)
+	webhook.SetManager(d.webhooks)
 	caddySrv := caddy.NewServer(caddy.ServerConfig{
 		AdminAddr: caddy.DefaultAdminAddr,
 	})
@@ -121,42 +182,71 @@ func (d *Daemon) Run(ctx context.Context) error {
 	log.Info().Msg("caddy server started")
 
 	// Load translated config into Caddy.
-	caddyCfg := caddy.Translate(cfg, caddy.PKIPaths{
-		RootCert:         d.caPaths.Cert,
-		RootKey:          d.caPaths.Key,
-		IntermediateCert: d.caPaths.IntermediateCert,
-		IntermediateKey:  d.caPaths.IntermediateKey,
-	}, caddy.DataDir())
-	if err := caddySrv.LoadConfig(ctx, caddyCfg); err != nil {
+	if err := caddySrv.LoadConfig(ctx, d.translate(cfg)); err != nil {
+		d.events.Publish(events.CaddyLoadFailed, events.CaddyLoadFailure{Error: err.Error()})
 		d.shutdownPartial()
 		return fmt.Errorf("load caddy config: %w", err)
 	}
 	log.Info().Msg("caddy config loaded")
 
+	// Start TCP proxy for tcp services.
+	tcpSrv := tcpproxy.NewServer(tcpproxy.ServerConfig{
+		ListenIP: tcpproxy.DefaultListenIP,
+		Issuer: func(host string) (*tls.Certificate, error) {
+			return certs.IssueLeafCert(d.caPaths, host)
+		},
+	})
+	d.tcp = tcpSrv
+	if err := tcpSrv.Apply(cfg); err != nil {
+		d.shutdownPartial()
+		return fmt.Errorf("start tcp proxy: %w", err)
+	}
+	log.Info().Ints("ports", tcpproxy.Ports(cfg)).Msg("tcp proxy started")
+
+	// Set up the tunnel client; tunnels are opened on request.
+	d.applyTunnels(cfg)
+
+	// Resume the service commands that were running when the daemon last
+	// stopped.
+	d.applyProcesses(cfg)
+
 	// Start health checker.
-	checker := health.NewChecker(health.CheckerConfig{})
+	checker := health.NewChecker(health.CheckerConfig{
+		OnChange: func(key health.ServiceKey, from, to health.Status) {
+			d.events.Publish(events.HealthChanged, events.HealthChange{
+				Project: key.Project,
+				Service: key.Service,
+				From:    from.String(),
+				To:      to.String(),
+			})
+			d.webhooks.SetStatus(key, to)
+		},
+	})
 	if err := checker.Start(cfg); err != nil {
 		d.shutdownPartial()
 		return fmt.Errorf("start health checker: %w", err)
 	}
 	d.health = checker
+	errorpage.SetStatusFunc(checker.ServiceStatus)
 	log.Info().Msg("health checker started")
 
 	// Start API server.
 	apiSrv := api.NewServer(api.ServerConfig{
-		Addr:      "127.0.0.1:42824",
+		Addr:      api.DefaultAddr,
 		Health:    d.health,
 		Daemon:    d,
 		Version:   d.version,
 		StartTime: d.startTime,
 		LogHub:    d.logHub,
+		Events:    d.events,
+		Requests:  d.requests,
 	})
 	if err := apiSrv.Start(); err != nil {
 		d.shutdownPartial()
 		return fmt.Errorf("start api server: %w", err)
 	}
 	d.api = apiSrv
-	log.Info().Str("addr", "127.0.0.1:42824").Msg("api server started")
+	log.Info().Str("addr", api.DefaultAddr).Msg("api server started")
 
 	// Start config watcher.
 	watcher, err := config.NewWatcher(d.onConfigReload)
@@ -171,6 +261,10 @@ func (d *Daemon) Run(ctx context.Context) error {
 	d.running = true
 	d.mu.Unlock()
 
+	// Discover projects from Docker containers; they are added to the
+	// routes as they are found.
+	d.applyDocker(cfg)
+
 	log.Info().Msg("daemon running")
 
 	// Block until context is cancelled.
@@ -210,6 +304,15 @@ func (d *Daemon) Shutdown() error {
 		log.Info().Msg("config watcher stopped")
 	}
 
+	// Docker discovery.
+	if d.docker != nil {
+		d.docker.Close()
+		log.Info().Msg("docker discovery stopped")
+	}
+
+	// Fault injection timers.
+	d.chaos.Close()
+
 	// Health checker.
 	if d.health != nil {
 		if err := d.health.Stop(); err != nil {
@@ -218,6 +321,24 @@ func (d *Daemon) Shutdown() error {
 		log.Info().Msg("health checker stopped")
 	}
 
+	// Service processes.
+	d.processes.Close()
+	log.Info().Msg("service processes stopped")
+
+	// Tunnels.
+	if d.tunnels != nil {
+		d.tunnels.Close()
+		log.Info().Msg("tunnels closed")
+	}
+
+	// TCP proxy.
+	if d.tcp != nil {
+		if err := d.tcp.Stop(); err != nil {
+			errs = append(errs, fmt.Errorf("stop tcp proxy: %w", err))
+		}
+		log.Info().Msg("tcp proxy stopped")
+	}
+
 	// Caddy.
 	if d.caddy != nil {
 		if err := d.caddy.Stop(); err != nil {
@@ -250,30 +371,156 @@ func (d *Daemon) Shutdown() error {
 	return nil
 }
 
+// applySharing starts or stops LAN sharing on the DNS server to match
+// cfg. Failures are logged rather than returned: the daemon keeps serving
+// this machine when the LAN address cannot be found or bound.
+func (d *Daemon) applySharing(cfg config.Config) {
+	var lanIP net.IP
+	if cfg.Settings.LANSharing {
+		ip, err := share.LANAddress()
+		if err != nil {
+			log.Error().Err(err).Msg("lan sharing: cannot determine LAN address")
+		} else if err := d.dns.StartSharing(ip, dns.SharePort); err != nil {
+			log.Error().Err(err).Msg("lan sharing: cannot start dns listener")
+		} else {
+			lanIP = ip
+		}
+	}
+	if lanIP == nil {
+		if err := d.dns.StopSharing(); err != nil {
+			log.Warn().Err(err).Msg("lan sharing: stopping dns listener")
+		}
+	}
+
+	d.mu.Lock()
+	changed := !d.lanIP.Equal(lanIP)
+	d.lanIP = lanIP
+	d.mu.Unlock()
+	if changed && lanIP != nil {
+		log.Info().Str("lan_ip", lanIP.String()).Int("dns_port", dns.SharePort).Msg("lan sharing on")
+	} else if changed {
+		log.Info().Msg("lan sharing off")
+	}
+}
+
+// SharingAddr returns the LAN address the daemon is sharing on, or nil
+// when LAN sharing is off or could not start.
+func (d *Daemon) SharingAddr() net.IP {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.lanIP
+}
+
+// dnsConfig maps settings to the embedded DNS server's config.
+func dnsConfig(s config.Settings) dns.ServerConfig {
+	return dns.ServerConfig{
+		TLDs:      s.AllTLDs(),
+		Overrides: s.DNSOverrides,
+		ListenIP:  dns.DefaultListenIP,
+		Port:      dns.DefaultPort,
+	}
+}
+
 // onConfigReload is called by the config watcher when the config file changes.
-// It re-translates the Caddy config and updates the health checker.
+// It re-translates the Caddy config, applies tcp services, tunnels and
+// service processes, updates the health checker, and publishes project and reload events.
+// Docker discovery is started, stopped or replaced last.
 func (d *Daemon) onConfigReload(cfg config.Config) {
+	d.applyConfig(cfg)
+	d.applyDocker(cfg)
+}
+
+// applyConfig applies cfg to every subsystem but Docker discovery.
+func (d *Daemon) applyConfig(cfg config.Config) {
+	d.reloadMu.Lock()
+	defer d.reloadMu.Unlock()
+
 	d.mu.Lock()
 	if !d.running {
 		d.mu.Unlock()
 		return
 	}
+	prev := d.cfg
 	d.cfg = cfg
 	d.mu.Unlock()
 
-	caddyCfg := caddy.Translate(cfg, caddy.PKIPaths{
+	for _, ev := range diffProjects(prev.Projects, cfg.Projects) {
+		d.events.Publish(ev.Type, ev.Data)
+	}
+
+	if err := d.caddy.LoadConfig(context.Background(), d.translate(d.routedConfig(cfg))); err != nil {
+		log.Error().Err(err).Msg("failed to reload caddy config")
+		d.events.Publish(events.CaddyLoadFailed, events.CaddyLoadFailure{Error: err.Error()})
+		return
+	}
+
+	if err := d.tcp.Apply(cfg); err != nil {
+		log.Error().Err(err).Msg("failed to apply tcp services")
+	}
+	d.applyTunnels(cfg)
+	d.applyProcesses(cfg)
+
+	d.dns.Update(dnsConfig(cfg.Settings))
+	d.applySharing(cfg)
+	resolver := dns.NewResolver()
+	for _, zone := range cfg.Settings.DNSZones() {
+		if !resolver.IsInstalled(zone) {
+			log.Warn().Str("zone", zone).Msg("no DNS resolver for zone; run 'hatch init' to install it")
+		}
+	}
+
+	d.health.UpdateConfig(d.routedConfig(cfg))
+	log.Info().Msg("config reloaded successfully")
+	d.events.Publish(events.ConfigReloaded, events.ConfigReload{Projects: len(cfg.Projects)})
+}
+
+// translate translates cfg, with the runtime chaos overrides applied, into
+// Caddy's JSON config.
+func (d *Daemon) translate(cfg config.Config) map[string]any {
+	return caddy.Translate(d.chaos.Apply(cfg), caddy.PKIPaths{
 		RootCert:         d.caPaths.Cert,
 		RootKey:          d.caPaths.Key,
 		IntermediateCert: d.caPaths.IntermediateCert,
 		IntermediateKey:  d.caPaths.IntermediateKey,
 	}, caddy.DataDir())
-	if err := d.caddy.LoadConfig(context.Background(), caddyCfg); err != nil {
-		log.Error().Err(err).Msg("failed to reload caddy config")
-		return
-	}
+}
 
-	d.health.UpdateConfig(cfg)
-	log.Info().Msg("config reloaded successfully")
+// diffProjects returns the project events implied by a change from prev to
+// next, ordered by project name. Time is left unset for Publish to stamp.
+func diffProjects(prev, next map[string]config.Project) []events.Event {
+	names := make(map[string]bool, len(prev)+len(next))
+	for name := range prev {
+		names[name] = true
+	}
+	for name := range next {
+		names[name] = true
+	}
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+
+	var out []events.Event
+	for _, name := range sorted {
+		old, hadOld := prev[name]
+		cur, hasNew := next[name]
+		switch {
+		case !hadOld:
+			out = append(out, events.Event{Type: events.ProjectAdded, Data: events.ProjectChange{Project: name, Enabled: cur.Enabled}})
+		case !hasNew:
+			out = append(out, events.Event{Type: events.ProjectRemoved, Data: events.ProjectChange{Project: name, Enabled: old.Enabled}})
+		default:
+			if old.Enabled != cur.Enabled {
+				out = append(out, events.Event{Type: events.ProjectToggled, Data: events.ProjectChange{Project: name, Enabled: cur.Enabled}})
+			}
+			old.Enabled = cur.Enabled
+			if !reflect.DeepEqual(old, cur) {
+				out = append(out, events.Event{Type: events.ProjectUpdated, Data: events.ProjectChange{Project: name, Enabled: cur.Enabled}})
+			}
+		}
+	}
+	return out
 }
 
 // ReloadConfig loads the current config and applies it to Caddy and the health checker.
@@ -296,6 +543,12 @@ func (d *Daemon) shutdownPartial() {
 	if d.health != nil {
 		d.health.Stop()
 	}
+	if d.tunnels != nil {
+		d.tunnels.Close()
+	}
+	if d.tcp != nil {
+		d.tcp.Stop()
+	}
 	if d.caddy != nil {
 		d.caddy.Stop()
 	}
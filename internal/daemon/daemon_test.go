@@ -0,0 +1,57 @@
+package daemon
+
+import (
+	"testing"
+
+	"github.com/paulrose/hatch/internal/config"
+	"github.com/paulrose/hatch/internal/events"
+)
+
+func TestDiffProjects(t *testing.T) {
+	web := map[string]config.Service{"web": {Proxy: "http://localhost:3000"}}
+	prev := map[string]config.Project{
+		"kept":    {Domain: "kept.test", Enabled: true, Services: web},
+		"removed": {Domain: "removed.test", Enabled: true, Services: web},
+		"toggled": {Domain: "toggled.test", Enabled: true, Services: web},
+		"updated": {Domain: "updated.test", Enabled: true, Services: web},
+	}
+	next := map[string]config.Project{
+		"added":   {Domain: "added.test", Enabled: true, Services: web},
+		"kept":    {Domain: "kept.test", Enabled: true, Services: web},
+		"toggled": {Domain: "toggled.test", Enabled: false, Services: web},
+		"updated": {Domain: "updated.test", Enabled: true, Services: map[string]config.Service{
+			"web": {Proxy: "http://localhost:4000"},
+		}},
+	}
+
+	got := diffProjects(prev, next)
+
+	want := []struct {
+		typ     events.Type
+		project string
+		enabled bool
+	}{
+		{events.ProjectAdded, "added", true},
+		{events.ProjectRemoved, "removed", true},
+		{events.ProjectToggled, "toggled", false},
+		{events.ProjectUpdated, "updated", true},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("expected %d events, got %d: %+v", len(want), len(got), got)
+	}
+	for i, w := range want {
+		pc, ok := got[i].Data.(events.ProjectChange)
+		if got[i].Type != w.typ || !ok || pc.Project != w.project || pc.Enabled != w.enabled {
+			t.Errorf("event %d = %s %+v, want %s %s enabled=%v", i, got[i].Type, got[i].Data, w.typ, w.project, w.enabled)
+		}
+	}
+}
+
+func TestDiffProjects_NoChanges(t *testing.T) {
+	projects := map[string]config.Project{
+		"myapp": {Domain: "myapp.test", Enabled: true},
+	}
+	if got := diffProjects(projects, projects); len(got) != 0 {
+		t.Errorf("expected no events, got %+v", got)
+	}
+}
@@ -0,0 +1,154 @@
+package daemon
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"sort"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/paulrose/hatch/internal/config"
+	"github.com/paulrose/hatch/internal/docker"
+	"github.com/paulrose/hatch/internal/events"
+)
+
+// withDiscovered returns cfg with the projects discovered from Docker
+// added. Discovered projects that are invalid, or whose name or domain is
+// taken by another project, are left out and reported in errs.
+func withDiscovered(cfg config.Config, discovered map[string]config.ProjectConfig) (config.Config, []error) {
+	if len(discovered) == 0 {
+		return cfg, nil
+	}
+	merged := cfg
+	merged.Projects = make(map[string]config.Project, len(cfg.Projects)+len(discovered))
+	maps.Copy(merged.Projects, cfg.Projects)
+
+	names := make([]string, 0, len(discovered))
+	for name := range discovered {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var errs []error
+	for _, name := range names {
+		if _, exists := cfg.Projects[name]; exists {
+			errs = append(errs, fmt.Errorf("docker project %q: a configured project has the same name", name))
+			continue
+		}
+		if err := config.MergeProjectConfig(&merged, name, "", discovered[name]); err != nil {
+			errs = append(errs, fmt.Errorf("docker project %q: %w", name, err))
+			continue
+		}
+		// Labels are not checked on their way in; a project Caddy cannot
+		// load would otherwise block every later reload.
+		if verrs := config.ValidateProject(name, merged.Projects[name], cfg.Settings); len(verrs) > 0 {
+			delete(merged.Projects, name)
+			errs = append(errs, fmt.Errorf("docker project %q: %w", name, errors.Join(verrs...)))
+		}
+	}
+	return merged, errs
+}
+
+// routedConfig returns cfg with the discovered projects added: the config
+// Caddy and the health checker are given.
+func (d *Daemon) routedConfig(cfg config.Config) config.Config {
+	d.mu.Lock()
+	discovered := d.discovered
+	d.mu.Unlock()
+	merged, _ := withDiscovered(cfg, discovered)
+	return merged
+}
+
+// Discovered returns the projects discovered from Docker that are being
+// served.
+func (d *Daemon) Discovered() map[string]config.Project {
+	d.mu.Lock()
+	cfg, discovered := d.cfg, d.discovered
+	d.mu.Unlock()
+
+	merged, _ := withDiscovered(cfg, discovered)
+	out := make(map[string]config.Project, len(discovered))
+	for name := range discovered {
+		if p, ok := merged.Projects[name]; ok {
+			if _, configured := cfg.Projects[name]; !configured {
+				out[name] = p
+			}
+		}
+	}
+	return out
+}
+
+// setDiscovered replaces the discovered projects and applies the change
+// to Caddy and the health checker.
+func (d *Daemon) setDiscovered(projects map[string]config.ProjectConfig) {
+	d.reloadMu.Lock()
+	defer d.reloadMu.Unlock()
+
+	d.mu.Lock()
+	running, cfg, before := d.running, d.cfg, d.discovered
+	d.discovered = projects
+	d.mu.Unlock()
+	if !running {
+		return
+	}
+
+	prev, _ := withDiscovered(cfg, before)
+	next, errs := withDiscovered(cfg, projects)
+	for _, err := range errs {
+		log.Warn().Err(err).Msg("docker: skipping project")
+	}
+	for _, ev := range diffProjects(prev.Projects, next.Projects) {
+		d.events.Publish(ev.Type, ev.Data)
+	}
+
+	if err := d.caddy.LoadConfig(context.Background(), d.translate(next)); err != nil {
+		log.Error().Err(err).Msg("failed to load caddy config with docker projects")
+		d.events.Publish(events.CaddyLoadFailed, events.CaddyLoadFailure{Error: err.Error()})
+		return
+	}
+	d.health.UpdateConfig(next)
+	log.Info().Int("projects", len(projects)).Msg("docker projects updated")
+}
+
+// applyDocker starts, stops or replaces the Docker provider to match
+// settings.docker.
+func (d *Daemon) applyDocker(cfg config.Config) {
+	var socket string
+	if cfg.Settings.Docker != nil {
+		socket = cfg.Settings.Docker.Socket
+		if socket == "" {
+			socket = docker.DefaultSocket
+		}
+	}
+
+	d.mu.Lock()
+	old, oldSocket, running := d.docker, d.dockerSocket, d.running
+	d.mu.Unlock()
+	if socket == oldSocket || !running {
+		return
+	}
+
+	if old != nil {
+		old.Close()
+		d.setDiscovered(nil)
+	}
+	var p *docker.Provider
+	if socket != "" {
+		p = docker.NewProvider(docker.ProviderConfig{
+			Socket:   socket,
+			OnChange: d.setDiscovered,
+		})
+	}
+	d.mu.Lock()
+	d.docker, d.dockerSocket = p, socket
+	d.mu.Unlock()
+
+	if p == nil {
+		log.Info().Msg("docker discovery off")
+		return
+	}
+	p.Start()
+	log.Info().Str("socket", socket).Msg("docker discovery on")
+}
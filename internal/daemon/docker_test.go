@@ -0,0 +1,55 @@
+package daemon
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/paulrose/hatch/internal/config"
+	"github.com/paulrose/hatch/internal/docker"
+)
+
+func TestWithDiscovered(t *testing.T) {
+	port := docker.Port{PrivatePort: 80, PublicPort: 8080, Type: "tcp"}
+	containers := []docker.Container{
+		{Names: []string{"/shop"}, Labels: map[string]string{docker.LabelDomain: "shop.test"}, Ports: []docker.Port{port}},
+		// Outside the configured zones: Caddy would route it, but it would
+		// never resolve.
+		{Names: []string{"/outside"}, Labels: map[string]string{docker.LabelDomain: "outside.example"}, Ports: []docker.Port{port}},
+		{Names: []string{"/badsub"}, Labels: map[string]string{docker.LabelDomain: "badsub.test", docker.LabelSubdomain: "-api-"}, Ports: []docker.Port{port}},
+		{Names: []string{"/taken"}, Labels: map[string]string{docker.LabelDomain: "web.test"}, Ports: []docker.Port{port}},
+	}
+	discovered, errs := docker.Projects(containers)
+	if len(errs) != 0 {
+		t.Fatalf("unexpected label errors %v", errs)
+	}
+
+	cfg := config.DefaultConfig()
+	cfg.Projects["web"] = config.Project{
+		Domain:   "web.test",
+		Path:     "/tmp/web",
+		Enabled:  true,
+		Services: map[string]config.Service{"app": {Proxy: "http://localhost:3000"}},
+	}
+
+	merged, errs := withDiscovered(cfg, discovered)
+	if _, ok := merged.Projects[docker.ProjectName("shop.test")]; !ok {
+		t.Errorf("expected the valid project to be added, got %v", merged.Projects)
+	}
+	skipped := []string{docker.ProjectName("badsub.test"), docker.ProjectName("outside.example"), docker.ProjectName("web.test")}
+	for _, name := range skipped {
+		if _, ok := merged.Projects[name]; ok {
+			t.Errorf("expected %s to be skipped", name)
+		}
+	}
+	if len(errs) != 3 {
+		t.Fatalf("expected three skipped projects, got %v", errs)
+	}
+	for i, name := range skipped {
+		if !strings.Contains(errs[i].Error(), `"`+name+`"`) {
+			t.Errorf("error %d = %q, want it to name %s", i, errs[i], name)
+		}
+	}
+	if len(cfg.Projects) != 1 {
+		t.Error("expected withDiscovered to leave cfg unchanged")
+	}
+}
@@ -0,0 +1,48 @@
+package daemon
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/paulrose/hatch/internal/certs"
+)
+
+// localClient returns a client that sends requests through local Caddy,
+// so they take the project's current route. Whatever host a request is
+// for, it connects to Caddy on the loopback port for the URL's scheme;
+// TLS still verifies the host, against the hatch root CA. Redirects are
+// not followed.
+func (d *Daemon) localClient() (*http.Client, error) {
+	d.mu.Lock()
+	httpPort, httpsPort := d.cfg.Settings.HTTPPort, d.cfg.Settings.HTTPSPort
+	d.mu.Unlock()
+
+	root, _, err := certs.LoadCA(d.caPaths)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(root)
+
+	dialer := &net.Dialer{Timeout: 5 * time.Second}
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			port := httpsPort
+			if _, p, _ := net.SplitHostPort(addr); p == "80" || p == strconv.Itoa(httpPort) {
+				port = httpPort
+			}
+			return dialer.DialContext(ctx, network, net.JoinHostPort("127.0.0.1", strconv.Itoa(port)))
+		},
+		TLSClientConfig:   &tls.Config{RootCAs: pool},
+		ForceAttemptHTTP2: true,
+	}
+	return &http.Client{
+		Transport:     transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}, nil
+}
@@ -0,0 +1,191 @@
+package daemon
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/paulrose/hatch/internal/config"
+	"github.com/paulrose/hatch/internal/health"
+	"github.com/paulrose/hatch/internal/process"
+)
+
+// processesFileName is the file recording which service commands were
+// started, so they survive a daemon restart.
+const processesFileName = "processes.json"
+
+func processesFile() string {
+	return filepath.Join(config.Dir(), processesFileName)
+}
+
+// loadStartedProcesses reads the services whose commands were started.
+func loadStartedProcesses() map[health.ServiceKey]bool {
+	started := make(map[health.ServiceKey]bool)
+	data, err := os.ReadFile(processesFile())
+	if err != nil {
+		return started
+	}
+	var keys []health.ServiceKey
+	if err := json.Unmarshal(data, &keys); err != nil {
+		log.Warn().Err(err).Msg("process: ignoring unreadable " + processesFileName)
+		return started
+	}
+	for _, key := range keys {
+		started[key] = true
+	}
+	return started
+}
+
+// saveStartedProcesses records the services whose commands were started.
+func saveStartedProcesses(started map[health.ServiceKey]bool) {
+	keys := make([]health.ServiceKey, 0, len(started))
+	for key := range started {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].Project != keys[j].Project {
+			return keys[i].Project < keys[j].Project
+		}
+		return keys[i].Service < keys[j].Service
+	})
+	data, err := json.MarshalIndent(keys, "", "  ")
+	if err == nil {
+		err = os.WriteFile(processesFile(), data, 0o644)
+	}
+	if err != nil {
+		log.Warn().Err(err).Msg("process: cannot save " + processesFileName)
+	}
+}
+
+// applyProcesses brings service commands in line with cfg: commands of
+// removed services and disabled projects stop, changed commands restart,
+// and commands that were started are started again, such as after a
+// daemon restart or when their project is re-enabled.
+func (d *Daemon) applyProcesses(cfg config.Config) {
+	specs := process.Specs(cfg)
+	d.processes.Apply(specs)
+
+	d.mu.Lock()
+	if d.startedProcesses == nil {
+		d.startedProcesses = loadStartedProcesses()
+	}
+	var resume []health.ServiceKey
+	for key := range d.startedProcesses {
+		if _, ok := specs[key]; ok {
+			resume = append(resume, key)
+		}
+	}
+	d.mu.Unlock()
+
+	for _, key := range resume {
+		if err := d.processes.Start(key, specs[key]); err != nil {
+			log.Error().Err(err).Str("project", key.Project).Str("service", key.Service).Msg("process: cannot start")
+		}
+	}
+}
+
+// processSpecs returns the specs of the commands to act on: those of
+// every service in project with a command, or of the one named service.
+func (d *Daemon) processSpecs(project, service string) (map[health.ServiceKey]process.Spec, error) {
+	d.mu.Lock()
+	p, ok := d.cfg.Projects[project]
+	cfg := d.cfg
+	d.mu.Unlock()
+
+	switch {
+	case !ok:
+		return nil, fmt.Errorf("project %q not found", project)
+	case !p.Enabled:
+		return nil, fmt.Errorf("project %q is disabled", project)
+	}
+	if service != "" {
+		svc, ok := p.Services[service]
+		switch {
+		case !ok:
+			return nil, fmt.Errorf("service %s/%s not found", project, service)
+		case svc.Command == "":
+			return nil, fmt.Errorf("service %s/%s has no command", project, service)
+		}
+	}
+
+	specs := make(map[health.ServiceKey]process.Spec)
+	for key, spec := range process.Specs(cfg) {
+		if key.Project == project && (service == "" || key.Service == service) {
+			specs[key] = spec
+		}
+	}
+	if len(specs) == 0 {
+		return nil, fmt.Errorf("project %q has no services with a command", project)
+	}
+	return specs, nil
+}
+
+// StartProcesses starts the commands of project's services, or of the
+// one named service, and returns their statuses. Commands already running
+// are left alone unless restart is set.
+func (d *Daemon) StartProcesses(project, service string, restart bool) ([]process.Status, error) {
+	specs, err := d.processSpecs(project, service)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]health.ServiceKey, 0, len(specs))
+	for key := range specs {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].Service < keys[j].Service })
+
+	d.mu.Lock()
+	for _, key := range keys {
+		d.startedProcesses[key] = true
+	}
+	saveStartedProcesses(d.startedProcesses)
+	d.mu.Unlock()
+
+	out := make([]process.Status, 0, len(keys))
+	for _, key := range keys {
+		start := d.processes.Start
+		if restart {
+			start = d.processes.Restart
+		}
+		if err := start(key, specs[key]); err != nil {
+			return out, fmt.Errorf("start %s/%s: %w", key.Project, key.Service, err)
+		}
+		if st, ok := d.processes.Status(key); ok {
+			out = append(out, st)
+		}
+	}
+	return out, nil
+}
+
+// StopProcesses stops the commands of project's services, or of the one
+// named service, and returns how many were running.
+func (d *Daemon) StopProcesses(project, service string) int {
+	d.mu.Lock()
+	for key := range d.startedProcesses {
+		if key.Project == project && (service == "" || key.Service == service) {
+			delete(d.startedProcesses, key)
+		}
+	}
+	saveStartedProcesses(d.startedProcesses)
+	d.mu.Unlock()
+
+	stopped := 0
+	for _, st := range d.processes.List() {
+		if st.Project == project && (service == "" || st.Service == service) {
+			if d.processes.Stop(health.ServiceKey{Project: st.Project, Service: st.Service}) {
+				stopped++
+			}
+		}
+	}
+	return stopped
+}
+
+// Processes returns the status of every started service command.
+func (d *Daemon) Processes() []process.Status {
+	return d.processes.List()
+}
@@ -0,0 +1,32 @@
+package daemon
+
+import (
+	"testing"
+
+	"github.com/paulrose/hatch/internal/health"
+)
+
+func TestStartedProcesses_RoundTrip(t *testing.T) {
+	t.Setenv("HATCH_HOME", t.TempDir())
+
+	if started := loadStartedProcesses(); len(started) != 0 {
+		t.Fatalf("expected nothing started without a file, got %v", started)
+	}
+
+	want := map[health.ServiceKey]bool{
+		{Project: "myapp", Service: "web"}: true,
+		{Project: "myapp", Service: "api"}: true,
+		{Project: "blog", Service: "web"}:  true,
+	}
+	saveStartedProcesses(want)
+
+	got := loadStartedProcesses()
+	if len(got) != len(want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+	for key := range want {
+		if !got[key] {
+			t.Errorf("expected %v to be recorded", key)
+		}
+	}
+}
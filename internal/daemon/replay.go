@@ -0,0 +1,18 @@
+package daemon
+
+import (
+	"context"
+
+	"github.com/paulrose/hatch/internal/inspect"
+)
+
+// Replay re-sends a captured request through local Caddy and returns the
+// new exchange.
+func (d *Daemon) Replay(ctx context.Context, rec inspect.Record, edit inspect.Edit) (inspect.Record, error) {
+	client, err := d.localClient()
+	if err != nil {
+		return inspect.Record{}, err
+	}
+	defer client.CloseIdleConnections()
+	return inspect.Replay(ctx, client, rec, edit)
+}
@@ -0,0 +1,68 @@
+package daemon
+
+import (
+	"fmt"
+	"runtime"
+
+	"github.com/paulrose/hatch/internal/shell"
+)
+
+// ServiceManager installs and controls the daemon under the platform's
+// user-level service manager (launchd on macOS, systemd on Linux).
+type ServiceManager interface {
+	// Name returns a human-readable name for the service definition,
+	// e.g. "Launchd plist" or "Systemd unit".
+	Name() string
+	// Path returns the path to the installed service definition file.
+	Path() (string, error)
+	// Install writes the service definition. When autoStart is true the
+	// daemon is started at login and restarted if it exits.
+	Install(autoStart bool) error
+	// Uninstall removes the service definition.
+	Uninstall() error
+	// Start starts the daemon via the service manager.
+	Start() error
+	// Stop stops the daemon via the service manager.
+	Stop() error
+	// IsLoaded reports whether the service manager has the daemon loaded.
+	IsLoaded() bool
+}
+
+// CommandRunner is the interface for executing shell commands, allowing
+// injection for testing.
+type CommandRunner interface {
+	Run(command string) error
+}
+
+// NewServiceManager returns the ServiceManager for the current platform.
+func NewServiceManager() ServiceManager {
+	switch runtime.GOOS {
+	case "linux":
+		return NewSystemdManager(shell.Runner{})
+	default:
+		return launchdManager{}
+	}
+}
+
+// launchdManager adapts the launchd plist helpers to ServiceManager.
+type launchdManager struct{}
+
+func (launchdManager) Name() string { return "Launchd plist" }
+
+func (launchdManager) Path() (string, error) { return PlistPath() }
+
+func (launchdManager) Install(autoStart bool) error {
+	cfg, err := DefaultLaunchdConfig(autoStart)
+	if err != nil {
+		return fmt.Errorf("launchd config: %w", err)
+	}
+	return InstallPlist(cfg)
+}
+
+func (launchdManager) Uninstall() error { return UninstallPlist() }
+
+func (launchdManager) Start() error { return LoadPlist() }
+
+func (launchdManager) Stop() error { return UnloadPlist() }
+
+func (launchdManager) IsLoaded() bool { return IsLoaded() }
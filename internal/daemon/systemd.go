@@ -0,0 +1,183 @@
+package daemon
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"text/template"
+
+	"github.com/paulrose/hatch/internal/config"
+)
+
+// UnitName is the systemd user unit name for the Hatch daemon.
+const UnitName = "hatch.service"
+
+// SystemdConfig holds the configuration for generating a systemd user unit.
+type SystemdConfig struct {
+	Description      string
+	BinaryPath       string
+	WorkingDirectory string
+	LogDir           string
+	Restart          bool
+}
+
+const unitTemplate = `[Unit]
+Description={{ .Description }}
+After=network.target
+
+[Service]
+Type=simple
+ExecStart="{{ .BinaryPath }}" _run
+WorkingDirectory={{ .WorkingDirectory }}
+Restart={{ if .Restart }}always{{ else }}no{{ end }}
+RestartSec=2
+StandardOutput=append:{{ .LogDir }}/daemon.log
+StandardError=append:{{ .LogDir }}/daemon.log
+
+[Install]
+WantedBy=default.target
+`
+
+// UnitPath returns the path to the systemd user unit file, honouring
+// XDG_CONFIG_HOME when set.
+func UnitPath() (string, error) {
+	base := os.Getenv("XDG_CONFIG_HOME")
+	if base == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("get home dir: %w", err)
+		}
+		base = filepath.Join(home, ".config")
+	}
+	return filepath.Join(base, "systemd", "user", UnitName), nil
+}
+
+// GenerateUnit renders the systemd unit file from the given config.
+func GenerateUnit(cfg SystemdConfig) ([]byte, error) {
+	tmpl, err := template.New("unit").Parse(unitTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("parse unit template: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, cfg); err != nil {
+		return nil, fmt.Errorf("execute unit template: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// DefaultSystemdConfig returns a SystemdConfig with sensible defaults.
+func DefaultSystemdConfig(autoStart bool) (SystemdConfig, error) {
+	bin, err := os.Executable()
+	if err != nil {
+		return SystemdConfig{}, fmt.Errorf("get executable path: %w", err)
+	}
+
+	return SystemdConfig{
+		Description:      "Hatch local HTTPS reverse proxy",
+		BinaryPath:       bin,
+		WorkingDirectory: config.Dir(),
+		LogDir:           config.LogsDir(),
+		Restart:          autoStart,
+	}, nil
+}
+
+// SystemdManager controls the daemon as a systemd --user unit.
+type SystemdManager struct {
+	runner CommandRunner
+}
+
+// NewSystemdManager returns a SystemdManager that issues systemctl
+// commands through runner.
+func NewSystemdManager(runner CommandRunner) *SystemdManager {
+	return &SystemdManager{runner: runner}
+}
+
+// Name returns a human-readable name for the service definition.
+func (m *SystemdManager) Name() string { return "Systemd unit" }
+
+// Path returns the path to the unit file.
+func (m *SystemdManager) Path() (string, error) { return UnitPath() }
+
+// Install writes the unit file, reloads the user manager, and enables the
+// unit at login when autoStart is true.
+func (m *SystemdManager) Install(autoStart bool) error {
+	cfg, err := DefaultSystemdConfig(autoStart)
+	if err != nil {
+		return fmt.Errorf("systemd config: %w", err)
+	}
+	return m.install(cfg)
+}
+
+func (m *SystemdManager) install(cfg SystemdConfig) error {
+	data, err := GenerateUnit(cfg)
+	if err != nil {
+		return err
+	}
+
+	path, err := UnitPath()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("create systemd user dir: %w", err)
+	}
+
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("write unit: %w", err)
+	}
+
+	if err := m.systemctl("daemon-reload"); err != nil {
+		return err
+	}
+
+	action := "disable"
+	if cfg.Restart {
+		action = "enable"
+	}
+	return m.systemctl(action + " " + UnitName)
+}
+
+// Uninstall disables the unit, removes the unit file, and reloads the
+// user manager.
+func (m *SystemdManager) Uninstall() error {
+	path, err := UnitPath()
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil
+	}
+
+	// Disabling fails harmlessly if the unit was never enabled.
+	_ = m.systemctl("disable " + UnitName)
+
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("remove unit: %w", err)
+	}
+	return m.systemctl("daemon-reload")
+}
+
+// Start starts the unit.
+func (m *SystemdManager) Start() error {
+	return m.systemctl("start " + UnitName)
+}
+
+// Stop stops the unit.
+func (m *SystemdManager) Stop() error {
+	return m.systemctl("stop " + UnitName)
+}
+
+// IsLoaded reports whether the unit is currently active.
+func (m *SystemdManager) IsLoaded() bool {
+	return m.runner.Run("systemctl --user is-active --quiet "+UnitName) == nil
+}
+
+func (m *SystemdManager) systemctl(args string) error {
+	if err := m.runner.Run("systemctl --user " + args); err != nil {
+		return fmt.Errorf("systemctl --user %s: %w", args, err)
+	}
+	return nil
+}
@@ -0,0 +1,193 @@
+package daemon
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// MockRunner records commands instead of executing them.
+type MockRunner struct {
+	Commands []string
+	Err      error // error to return from Run, if any
+}
+
+func (m *MockRunner) Run(command string) error {
+	m.Commands = append(m.Commands, command)
+	return m.Err
+}
+
+func testSystemdConfig(restart bool) SystemdConfig {
+	return SystemdConfig{
+		Description:      "Hatch",
+		BinaryPath:       "/usr/local/bin/hatch",
+		WorkingDirectory: "/tmp",
+		LogDir:           "/tmp/logs",
+		Restart:          restart,
+	}
+}
+
+func TestGenerateUnit_RestartAlways(t *testing.T) {
+	data, err := GenerateUnit(testSystemdConfig(true))
+	if err != nil {
+		t.Fatalf("GenerateUnit() error: %v", err)
+	}
+	if !strings.Contains(string(data), "Restart=always") {
+		t.Errorf("expected Restart=always, got:\n%s", data)
+	}
+}
+
+func TestGenerateUnit_RestartNo(t *testing.T) {
+	data, err := GenerateUnit(testSystemdConfig(false))
+	if err != nil {
+		t.Fatalf("GenerateUnit() error: %v", err)
+	}
+	if !strings.Contains(string(data), "Restart=no") {
+		t.Errorf("expected Restart=no, got:\n%s", data)
+	}
+}
+
+func TestGenerateUnit_ExecStartAndLogs(t *testing.T) {
+	data, err := GenerateUnit(testSystemdConfig(true))
+	if err != nil {
+		t.Fatalf("GenerateUnit() error: %v", err)
+	}
+
+	s := string(data)
+	for _, want := range []string{
+		`ExecStart="/usr/local/bin/hatch" _run`,
+		"WorkingDirectory=/tmp",
+		"StandardOutput=append:/tmp/logs/daemon.log",
+		"StandardError=append:/tmp/logs/daemon.log",
+		"WantedBy=default.target",
+	} {
+		if !strings.Contains(s, want) {
+			t.Errorf("unit should contain %q, got:\n%s", want, s)
+		}
+	}
+}
+
+func TestUnitPath_XDGConfigHome(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+
+	path, err := UnitPath()
+	if err != nil {
+		t.Fatalf("UnitPath() error: %v", err)
+	}
+	want := filepath.Join(dir, "systemd", "user", UnitName)
+	if path != want {
+		t.Errorf("UnitPath() = %q, want %q", path, want)
+	}
+}
+
+func TestSystemdManager_Install(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	runner := &MockRunner{}
+	m := NewSystemdManager(runner)
+
+	if err := m.install(testSystemdConfig(true)); err != nil {
+		t.Fatalf("install: %v", err)
+	}
+
+	path, _ := UnitPath()
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("expected unit file at %s: %v", path, err)
+	}
+
+	want := []string{
+		"systemctl --user daemon-reload",
+		"systemctl --user enable hatch.service",
+	}
+	if strings.Join(runner.Commands, "\n") != strings.Join(want, "\n") {
+		t.Errorf("commands = %v, want %v", runner.Commands, want)
+	}
+}
+
+func TestSystemdManager_InstallNoAutoStartDisables(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	runner := &MockRunner{}
+	m := NewSystemdManager(runner)
+
+	if err := m.install(testSystemdConfig(false)); err != nil {
+		t.Fatalf("install: %v", err)
+	}
+
+	last := runner.Commands[len(runner.Commands)-1]
+	if last != "systemctl --user disable hatch.service" {
+		t.Errorf("expected disable command, got %q", last)
+	}
+}
+
+func TestSystemdManager_StartStop(t *testing.T) {
+	runner := &MockRunner{}
+	m := NewSystemdManager(runner)
+
+	if err := m.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	if err := m.Stop(); err != nil {
+		t.Fatalf("Stop: %v", err)
+	}
+
+	want := []string{
+		"systemctl --user start hatch.service",
+		"systemctl --user stop hatch.service",
+	}
+	if strings.Join(runner.Commands, "\n") != strings.Join(want, "\n") {
+		t.Errorf("commands = %v, want %v", runner.Commands, want)
+	}
+}
+
+func TestSystemdManager_IsLoaded(t *testing.T) {
+	m := NewSystemdManager(&MockRunner{})
+	if !m.IsLoaded() {
+		t.Error("expected IsLoaded true when is-active succeeds")
+	}
+
+	m = NewSystemdManager(&MockRunner{Err: errors.New("inactive")})
+	if m.IsLoaded() {
+		t.Error("expected IsLoaded false when is-active fails")
+	}
+}
+
+func TestSystemdManager_Uninstall(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	runner := &MockRunner{}
+	m := NewSystemdManager(runner)
+
+	if err := m.install(testSystemdConfig(true)); err != nil {
+		t.Fatalf("install: %v", err)
+	}
+	runner.Commands = nil
+
+	if err := m.Uninstall(); err != nil {
+		t.Fatalf("Uninstall: %v", err)
+	}
+
+	path, _ := UnitPath()
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("expected unit file to be removed")
+	}
+	want := []string{
+		"systemctl --user disable hatch.service",
+		"systemctl --user daemon-reload",
+	}
+	if strings.Join(runner.Commands, "\n") != strings.Join(want, "\n") {
+		t.Errorf("commands = %v, want %v", runner.Commands, want)
+	}
+}
+
+func TestSystemdManager_StartError(t *testing.T) {
+	m := NewSystemdManager(&MockRunner{Err: errors.New("boom")})
+
+	err := m.Start()
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if !strings.Contains(err.Error(), "systemctl --user start hatch.service") {
+		t.Errorf("expected wrapped error, got: %v", err)
+	}
+}
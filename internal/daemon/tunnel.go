@@ -0,0 +1,168 @@
+package daemon
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/paulrose/hatch/internal/certs"
+	"github.com/paulrose/hatch/internal/config"
+	"github.com/paulrose/hatch/internal/events"
+	"github.com/paulrose/hatch/internal/tunnel"
+)
+
+// tunnelSetup is what a tunnel client is built from; a change to it on
+// reload replaces the client.
+type tunnelSetup struct {
+	relay, token string
+	httpsPort    int
+}
+
+func newTunnelSetup(s config.Settings) tunnelSetup {
+	if s.Tunnel == nil {
+		return tunnelSetup{}
+	}
+	return tunnelSetup{relay: s.Tunnel.Relay, token: s.Tunnel.Token, httpsPort: s.HTTPSPort}
+}
+
+// newTunnelClient creates the tunnel client for setup, or returns nil when
+// no relay is configured. Tunnelled requests go to local Caddy over HTTPS,
+// verified against the hatch root CA.
+func (d *Daemon) newTunnelClient(setup tunnelSetup) (*tunnel.Client, error) {
+	if setup.relay == "" {
+		return nil, nil
+	}
+	root, _, err := certs.LoadCA(d.caPaths)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(root)
+
+	return tunnel.NewClient(tunnel.ClientConfig{
+		RelayURL: setup.relay,
+		Token:    setup.token,
+		Local:    &url.URL{Scheme: "https", Host: "127.0.0.1:" + strconv.Itoa(setup.httpsPort)},
+		Transport: func(domain string) http.RoundTripper {
+			return &http.Transport{
+				TLSClientConfig:     &tls.Config{ServerName: domain, RootCAs: pool},
+				ForceAttemptHTTP2:   true,
+				MaxIdleConnsPerHost: 16,
+			}
+		},
+		Allow: d.allowTunnel,
+		OnChange: func(st tunnel.Status) {
+			d.events.Publish(events.TunnelChanged, events.TunnelChange{
+				Project:   st.Project,
+				URL:       st.URL,
+				Connected: st.Connected,
+				Error:     st.Error,
+			})
+		},
+	})
+}
+
+// applyTunnels brings the tunnels in line with cfg: a changed relay
+// replaces the client, moving open tunnels over to the new one, and
+// tunnels for removed or disabled projects are stopped.
+func (d *Daemon) applyTunnels(cfg config.Config) {
+	setup := newTunnelSetup(cfg.Settings)
+
+	d.mu.Lock()
+	old, oldSetup := d.tunnels, d.tunnelSetup
+	d.mu.Unlock()
+
+	client := old
+	var reopen []tunnel.Status
+	if setup != oldSetup {
+		var err error
+		if client, err = d.newTunnelClient(setup); err != nil {
+			log.Error().Err(err).Msg("tunnel: cannot create client")
+		}
+		if old != nil {
+			reopen = old.List()
+			old.Close()
+		}
+		d.mu.Lock()
+		d.tunnels, d.tunnelSetup = client, setup
+		d.mu.Unlock()
+	}
+	if client == nil {
+		return
+	}
+
+	for _, st := range client.List() {
+		if p, ok := cfg.Projects[st.Project]; !ok || !p.Enabled {
+			client.Stop(st.Project)
+		}
+	}
+	for _, st := range reopen {
+		p, ok := cfg.Projects[st.Project]
+		if !ok || !p.Enabled {
+			continue
+		}
+		go func() {
+			if _, err := client.Start(context.Background(), st.Project, p.Domain); err != nil {
+				log.Error().Err(err).Str("project", st.Project).Msg("tunnel: cannot reopen on new relay")
+			}
+		}()
+	}
+}
+
+// allowTunnel reports whether a tunnelled request from clientIP may reach
+// project. Caddy sees tunnelled requests come from loopback, which its
+// allow_ips routes let through, so the allowlist is applied here instead.
+func (d *Daemon) allowTunnel(project, clientIP string) bool {
+	d.mu.Lock()
+	p, ok := d.cfg.Projects[project]
+	d.mu.Unlock()
+	return ok && p.Auth.Allows(clientIP)
+}
+
+// errNoRelay is returned by StartTunnel when settings.tunnel is not set.
+var errNoRelay = errors.New("no tunnel relay configured: set settings.tunnel.relay in the config")
+
+// StartTunnel shares project through the tunnel relay and returns its
+// status once connected.
+func (d *Daemon) StartTunnel(ctx context.Context, project string) (tunnel.Status, error) {
+	d.mu.Lock()
+	client := d.tunnels
+	p, ok := d.cfg.Projects[project]
+	d.mu.Unlock()
+
+	switch {
+	case client == nil:
+		return tunnel.Status{}, errNoRelay
+	case !ok:
+		return tunnel.Status{}, fmt.Errorf("project %q not found", project)
+	case !p.Enabled:
+		return tunnel.Status{}, fmt.Errorf("project %q is disabled", project)
+	}
+	return client.Start(ctx, project, p.Domain)
+}
+
+// StopTunnel stops sharing project. It reports whether it was shared.
+func (d *Daemon) StopTunnel(project string) bool {
+	d.mu.Lock()
+	client := d.tunnels
+	d.mu.Unlock()
+	return client != nil && client.Stop(project)
+}
+
+// Tunnels returns the status of every open tunnel.
+func (d *Daemon) Tunnels() []tunnel.Status {
+	d.mu.Lock()
+	client := d.tunnels
+	d.mu.Unlock()
+	if client == nil {
+		return []tunnel.Status{}
+	}
+	return client.List()
+}
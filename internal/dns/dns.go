@@ -1,6 +1,7 @@
 // Package dns manages local DNS resolution for development TLDs,
 // embedding a lightweight DNS server that resolves wildcard queries
-// for the configured TLD and forwards everything else upstream.
+// for the configured TLDs and overridden hostnames and forwards
+// everything else upstream.
 package dns
 
 const (
@@ -11,14 +12,22 @@ const (
 	// DefaultListenIP is the default IP address for the DNS server.
 	DefaultListenIP = "127.0.0.1"
 
+	// SharePort is the port the DNS server listens on at the LAN address
+	// while sharing. Phones and tablets cannot be pointed at a DNS server
+	// on any other port.
+	SharePort = 53
+
 	// ResolverDir is the macOS per-TLD resolver directory.
 	ResolverDir = "/etc/resolver"
+	// ResolvedDropInPath is the systemd-resolved drop-in used on Linux.
+	ResolvedDropInPath = "/etc/systemd/resolved.conf.d/hatch.conf"
 )
 
 // ServerConfig holds the settings needed to run the embedded DNS server.
 // It is decoupled from config.Settings — the caller maps between them.
 type ServerConfig struct {
-	TLD      string // e.g. "test"
-	ListenIP string // e.g. "127.0.0.1"
-	Port     int    // e.g. 5053
+	TLDs      []string          // e.g. ["test", "dev"]
+	Overrides map[string]string // hostname -> IP, e.g. "api.staging.company.com" -> "127.0.0.1"
+	ListenIP  string            // e.g. "127.0.0.1"
+	Port      int               // e.g. 5053
 }
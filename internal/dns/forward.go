@@ -3,7 +3,9 @@ package dns
 import (
 	"fmt"
 	"net"
+	"os"
 	"os/exec"
+	"runtime"
 	"strings"
 )
 
@@ -11,11 +13,20 @@ import (
 // no usable servers.
 var defaultUpstreams = []string{"8.8.8.8:53", "1.1.1.1:53"}
 
-// SystemDNSServers discovers the system's configured DNS servers by
-// parsing the output of "scutil --dns". It filters out loopback
-// addresses to avoid forwarding loops. If discovery fails or returns
-// no servers, it falls back to defaultUpstreams.
+// resolvConfPath is the standard resolver configuration file on Linux.
+const resolvConfPath = "/etc/resolv.conf"
+
+// SystemDNSServers discovers the system's configured DNS servers. On
+// macOS it parses "scutil --dns"; on Linux it parses /etc/resolv.conf
+// and falls back to "resolvectl status" when resolv.conf only points at
+// the local systemd-resolved stub. Loopback addresses are filtered out
+// to avoid forwarding loops. If discovery fails or returns no servers,
+// it falls back to defaultUpstreams.
 func SystemDNSServers() ([]string, error) {
+	if runtime.GOOS == "linux" {
+		return linuxDNSServers()
+	}
+
 	out, err := exec.Command("scutil", "--dns").Output()
 	if err != nil {
 		return defaultUpstreams, fmt.Errorf("running scutil --dns: %w", err)
@@ -70,3 +81,124 @@ func parseScutilDNS(output string) []string {
 
 	return servers
 }
+
+// linuxDNSServers discovers upstream servers from /etc/resolv.conf, then
+// from resolvectl when resolv.conf yields nothing usable.
+func linuxDNSServers() ([]string, error) {
+	if data, err := os.ReadFile(resolvConfPath); err == nil {
+		if servers := parseResolvConf(string(data)); len(servers) > 0 {
+			return servers, nil
+		}
+	}
+
+	out, err := exec.Command("resolvectl", "status").Output()
+	if err != nil {
+		return defaultUpstreams, fmt.Errorf("running resolvectl status: %w", err)
+	}
+	servers := parseResolvectlStatus(string(out))
+	if len(servers) == 0 {
+		return defaultUpstreams, nil
+	}
+	return servers, nil
+}
+
+// parseResolvConf extracts "nameserver" entries from resolv.conf content,
+// returning each unique non-loopback server as "ip:53".
+func parseResolvConf(content string) []string {
+	seen := make(map[string]bool)
+	var servers []string
+	for _, line := range strings.Split(content, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || fields[0] != "nameserver" {
+			continue
+		}
+		if addr, ok := upstreamAddr(fields[1]); ok && !seen[addr] {
+			seen[addr] = true
+			servers = append(servers, addr)
+		}
+	}
+	return servers
+}
+
+// parseResolvectlStatus extracts servers from the "Current DNS Server"
+// and "DNS Servers" entries of "resolvectl status" output, including
+// servers listed on indented continuation lines. Loopback addresses
+// (such as Hatch itself) are filtered out.
+func parseResolvectlStatus(output string) []string {
+	seen := make(map[string]bool)
+	var servers []string
+	add := func(tokens []string) {
+		for _, tok := range tokens {
+			if addr, ok := upstreamAddr(tok); ok && !seen[addr] {
+				seen[addr] = true
+				servers = append(servers, addr)
+			}
+		}
+	}
+
+	inServers := false
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			inServers = false
+			continue
+		}
+		// Continuation lines hold only addresses.
+		if _, ok := parseServerToken(fields[0]); ok {
+			if inServers {
+				add(fields)
+			}
+			continue
+		}
+
+		label, value, ok := strings.Cut(line, ":")
+		if !ok {
+			inServers = false
+			continue
+		}
+		switch strings.TrimSpace(label) {
+		case "Current DNS Server", "DNS Servers":
+			inServers = true
+			add(strings.Fields(value))
+		default:
+			inServers = false
+		}
+	}
+	return servers
+}
+
+// upstreamAddr converts a server token into a dialable "host:port"
+// address, rejecting loopback servers.
+func upstreamAddr(tok string) (string, bool) {
+	addr, ok := parseServerToken(tok)
+	if !ok {
+		return "", false
+	}
+	host, _, _ := net.SplitHostPort(addr)
+	if net.ParseIP(stripZone(host)).IsLoopback() {
+		return "", false
+	}
+	return addr, true
+}
+
+// parseServerToken parses a resolver address as printed by resolv.conf or
+// resolvectl: "ip", "ip%iface", "ip#server-name", "ip:port" or
+// "[ipv6]:port". The result is "host:port", defaulting to port 53.
+func parseServerToken(tok string) (string, bool) {
+	tok, _, _ = strings.Cut(tok, "#")
+	if net.ParseIP(stripZone(tok)) != nil {
+		return net.JoinHostPort(tok, "53"), true
+	}
+	host, port, err := net.SplitHostPort(tok)
+	if err != nil || net.ParseIP(stripZone(host)) == nil {
+		return "", false
+	}
+	return net.JoinHostPort(host, port), true
+}
+
+// stripZone removes an IPv6 zone suffix such as "%eth0".
+func stripZone(s string) string {
+	host, _, _ := strings.Cut(s, "%")
+	return host
+}
@@ -83,3 +83,71 @@ func TestParseScutilDNS_Empty(t *testing.T) {
 		t.Errorf("expected empty result, got %v", servers)
 	}
 }
+
+func TestParseResolvConf(t *testing.T) {
+	input := `# Generated by NetworkManager
+search lan
+nameserver 192.168.1.1
+nameserver 127.0.0.53
+nameserver 2001:4860:4860::8888
+nameserver 192.168.1.1
+options edns0
+`
+	servers := parseResolvConf(input)
+	expected := []string{"192.168.1.1:53", "[2001:4860:4860::8888]:53"}
+	if len(servers) != len(expected) {
+		t.Fatalf("expected %d servers, got %d: %v", len(expected), len(servers), servers)
+	}
+	for i, want := range expected {
+		if servers[i] != want {
+			t.Errorf("servers[%d] = %q, want %q", i, servers[i], want)
+		}
+	}
+}
+
+func TestParseResolvConf_StubOnly(t *testing.T) {
+	servers := parseResolvConf("nameserver 127.0.0.53\noptions edns0 trust-ad\n")
+	if len(servers) != 0 {
+		t.Errorf("expected stub resolver to be filtered, got %v", servers)
+	}
+}
+
+const resolvectlFixture = `Global
+         Protocols: +LLMNR +mDNS -DNSOverTLS DNSSEC=no/unsupported
+  resolv.conf mode: stub
+       DNS Servers: 127.0.0.1:5053
+        DNS Domain: ~test
+
+Link 2 (enp3s0)
+    Current Scopes: DNS LLMNR/IPv4 LLMNR/IPv6
+         Protocols: +DefaultRoute +LLMNR -mDNS -DNSOverTLS DNSSEC=no/unsupported
+Current DNS Server: 192.168.1.1
+       DNS Servers: 192.168.1.1 1.1.1.1#cloudflare-dns.com
+                    fe80::1%enp3s0
+        DNS Domain: lan
+
+Link 3 (wlp2s0)
+    Current Scopes: none
+         Protocols: -DefaultRoute +LLMNR -mDNS -DNSOverTLS DNSSEC=no/unsupported
+`
+
+func TestParseResolvectlStatus(t *testing.T) {
+	servers := parseResolvectlStatus(resolvectlFixture)
+	// Hatch's own 127.0.0.1:5053 is filtered; duplicates are removed.
+	expected := []string{"192.168.1.1:53", "1.1.1.1:53", "[fe80::1%enp3s0]:53"}
+	if len(servers) != len(expected) {
+		t.Fatalf("expected %d servers, got %d: %v", len(expected), len(servers), servers)
+	}
+	for i, want := range expected {
+		if servers[i] != want {
+			t.Errorf("servers[%d] = %q, want %q", i, servers[i], want)
+		}
+	}
+}
+
+func TestParseResolvectlStatus_Empty(t *testing.T) {
+	servers := parseResolvectlStatus("")
+	if len(servers) != 0 {
+		t.Errorf("expected empty result, got %v", servers)
+	}
+}
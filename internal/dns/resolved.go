@@ -0,0 +1,116 @@
+package dns
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ResolvedResolver routes TLDs to the embedded DNS server using a
+// systemd-resolved drop-in. NetworkManager hands DNS to systemd-resolved
+// on most distributions, so this also covers NetworkManager split DNS.
+// All routed domains share the one drop-in, so Install and Remove edit
+// its Domains line rather than replacing the file outright.
+type ResolvedResolver struct {
+	path string
+}
+
+// NewResolvedResolver returns a ResolvedResolver that manages the drop-in
+// file at path (normally ResolvedDropInPath).
+func NewResolvedResolver(path string) *ResolvedResolver {
+	return &ResolvedResolver{path: path}
+}
+
+// ResolvedDropInContent returns the drop-in content that sends queries
+// for each of tlds to listenIP:port. The "~" prefix makes each a
+// routing-only domain, so it is not added to the search list.
+func ResolvedDropInContent(tlds []string, listenIP string, port int) string {
+	return dropInContent(net.JoinHostPort(listenIP, strconv.Itoa(port)), tlds)
+}
+
+func dropInContent(server string, tlds []string) string {
+	routed := make([]string, len(tlds))
+	for i, tld := range tlds {
+		routed[i] = "~" + tld
+	}
+	return fmt.Sprintf("[Resolve]\nDNS=%s\nDomains=%s\n", server, strings.Join(routed, " "))
+}
+
+// Install adds tld to the drop-in, creating it if needed, and restarts
+// systemd-resolved.
+func (r *ResolvedResolver) Install(runner CommandRunner, tld, listenIP string, port int) error {
+	if err := validateResolverInputs(tld, listenIP, port); err != nil {
+		return err
+	}
+	_, tlds := r.read()
+	if !slices.Contains(tlds, tld) {
+		tlds = append(tlds, tld)
+	}
+	content := ResolvedDropInContent(tlds, listenIP, port)
+	cmd := fmt.Sprintf("mkdir -p %s && printf '%%s' '%s' > %s && systemctl restart systemd-resolved",
+		filepath.Dir(r.path), content, r.path)
+	if err := runner.Run(cmd); err != nil {
+		return fmt.Errorf("installing resolved drop-in for %s: %w", tld, err)
+	}
+	return nil
+}
+
+// Remove drops tld from the drop-in, deleting the file once no domains
+// are left, and restarts systemd-resolved.
+func (r *ResolvedResolver) Remove(runner CommandRunner, tld string) error {
+	if !safeTLD.MatchString(tld) {
+		return fmt.Errorf("invalid TLD %q", tld)
+	}
+	server, tlds := r.read()
+	tlds = slices.DeleteFunc(tlds, func(d string) bool { return d == tld })
+
+	cmd := fmt.Sprintf("rm -f %s && systemctl restart systemd-resolved", r.path)
+	if len(tlds) > 0 && server != "" {
+		cmd = fmt.Sprintf("printf '%%s' '%s' > %s && systemctl restart systemd-resolved",
+			dropInContent(server, tlds), r.path)
+	}
+	if err := runner.Run(cmd); err != nil {
+		return fmt.Errorf("removing resolved drop-in for %s: %w", tld, err)
+	}
+	return nil
+}
+
+// IsInstalled reports whether the drop-in exists and routes tld.
+func (r *ResolvedResolver) IsInstalled(tld string) bool {
+	_, tlds := r.read()
+	return slices.Contains(tlds, tld)
+}
+
+// read returns the DNS server and routed domains from the current
+// drop-in. Values that are not safe to write back are skipped, leaving
+// server empty or the domain out.
+func (r *ResolvedResolver) read() (server string, tlds []string) {
+	data, err := os.ReadFile(r.path)
+	if err != nil {
+		return "", nil
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		key, value, ok := strings.Cut(strings.TrimSpace(line), "=")
+		if !ok {
+			continue
+		}
+		switch strings.TrimSpace(key) {
+		case "DNS":
+			host, port, err := net.SplitHostPort(strings.TrimSpace(value))
+			if _, perr := strconv.Atoi(port); err == nil && perr == nil && net.ParseIP(host) != nil {
+				server = net.JoinHostPort(host, port)
+			}
+		case "Domains":
+			for _, d := range strings.Fields(value) {
+				if d, ok := strings.CutPrefix(d, "~"); ok && safeTLD.MatchString(d) {
+					tlds = append(tlds, d)
+				}
+			}
+		}
+	}
+	return server, tlds
+}
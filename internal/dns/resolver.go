@@ -6,6 +6,7 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"runtime"
 )
 
 // ResolverFileContent returns the content for a macOS resolver file
@@ -15,13 +16,14 @@ func ResolverFileContent(listenIP string, port int) string {
 }
 
 // ResolverFilePath returns the full path for a resolver file for the
-// given TLD (e.g. "/etc/resolver/test").
+// given TLD or domain (e.g. "/etc/resolver/test").
 func ResolverFilePath(tld string) string {
 	return filepath.Join(ResolverDir, tld)
 }
 
-// safeTLD matches only simple alphanumeric TLD values.
-var safeTLD = regexp.MustCompile(`^[a-z]+$`)
+// safeTLD matches a lowercase TLD or dotted domain name, which is all
+// that may be interpolated into the privileged shell commands below.
+var safeTLD = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]*[a-z0-9])?(\.[a-z0-9]([a-z0-9-]*[a-z0-9])?)*$`)
 
 // InstallResolverFile creates the resolver directory and writes the
 // resolver file for the given TLD. The operation requires elevated
@@ -73,3 +75,43 @@ func IsResolverInstalled(tld string) bool {
 	_, err := os.Stat(ResolverFilePath(tld))
 	return err == nil
 }
+
+// Resolver registers the embedded DNS server with the operating system
+// so that queries for a TLD are routed to it. The tld argument may also be
+// a dotted domain, such as a DNS override host, which routes that domain
+// and every name below it.
+type Resolver interface {
+	// Install routes queries for tld to listenIP:port. Requires elevated
+	// privileges, so it uses the provided CommandRunner.
+	Install(runner CommandRunner, tld, listenIP string, port int) error
+	// Remove undoes Install for tld.
+	Remove(runner CommandRunner, tld string) error
+	// IsInstalled reports whether queries for tld are routed to Hatch.
+	IsInstalled(tld string) bool
+}
+
+// NewResolver returns the Resolver for the current platform: per-TLD
+// /etc/resolver files on macOS and a systemd-resolved drop-in on Linux.
+func NewResolver() Resolver {
+	switch runtime.GOOS {
+	case "linux":
+		return NewResolvedResolver(ResolvedDropInPath)
+	default:
+		return resolverFiles{}
+	}
+}
+
+// resolverFiles adapts the macOS /etc/resolver helpers to Resolver.
+type resolverFiles struct{}
+
+func (resolverFiles) Install(runner CommandRunner, tld, listenIP string, port int) error {
+	return InstallResolverFile(runner, tld, listenIP, port)
+}
+
+func (resolverFiles) Remove(runner CommandRunner, tld string) error {
+	return RemoveResolverFile(runner, tld)
+}
+
+func (resolverFiles) IsInstalled(tld string) bool {
+	return IsResolverInstalled(tld)
+}
@@ -147,3 +147,138 @@ func TestIsResolverInstalled(t *testing.T) {
 		t.Errorf("expected file to exist, got: %v", err)
 	}
 }
+
+func TestResolvedDropInContent(t *testing.T) {
+	got := ResolvedDropInContent([]string{"test"}, "127.0.0.1", 5053)
+	want := "[Resolve]\nDNS=127.0.0.1:5053\nDomains=~test\n"
+	if got != want {
+		t.Errorf("ResolvedDropInContent() = %q, want %q", got, want)
+	}
+}
+
+func TestResolvedResolver_Install(t *testing.T) {
+	runner := &MockRunner{}
+	r := NewResolvedResolver(ResolvedDropInPath)
+	if err := r.Install(runner, "test", "127.0.0.1", 5053); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(runner.Commands) != 1 {
+		t.Fatalf("expected 1 command, got %d", len(runner.Commands))
+	}
+	cmd := runner.Commands[0]
+	for _, want := range []string{
+		"mkdir -p /etc/systemd/resolved.conf.d",
+		"DNS=127.0.0.1:5053",
+		"Domains=~test",
+		"> /etc/systemd/resolved.conf.d/hatch.conf",
+		"systemctl restart systemd-resolved",
+	} {
+		if !strings.Contains(cmd, want) {
+			t.Errorf("expected %q in command, got: %s", want, cmd)
+		}
+	}
+}
+
+func TestResolvedResolver_InstallInvalidTLD(t *testing.T) {
+	runner := &MockRunner{}
+	r := NewResolvedResolver(ResolvedDropInPath)
+	if err := r.Install(runner, "te st; rm -rf /", "127.0.0.1", 5053); err == nil {
+		t.Fatal("expected error for invalid TLD")
+	}
+	if len(runner.Commands) != 0 {
+		t.Errorf("expected no commands, got %v", runner.Commands)
+	}
+}
+
+func TestResolvedResolver_Remove(t *testing.T) {
+	runner := &MockRunner{}
+	r := NewResolvedResolver(ResolvedDropInPath)
+	if err := r.Remove(runner, "test"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := "rm -f /etc/systemd/resolved.conf.d/hatch.conf && systemctl restart systemd-resolved"
+	if runner.Commands[0] != want {
+		t.Errorf("expected %q, got %q", want, runner.Commands[0])
+	}
+}
+
+func TestResolvedResolver_IsInstalled(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hatch.conf")
+	r := NewResolvedResolver(path)
+
+	if r.IsInstalled("test") {
+		t.Error("expected not installed before the drop-in exists")
+	}
+
+	if err := os.WriteFile(path, []byte(ResolvedDropInContent([]string{"test"}, "127.0.0.1", 5053)), 0o644); err != nil {
+		t.Fatalf("writing drop-in: %v", err)
+	}
+	if !r.IsInstalled("test") {
+		t.Error("expected installed for .test")
+	}
+	if r.IsInstalled("dev") {
+		t.Error("expected not installed for .dev")
+	}
+}
+
+func TestResolvedDropInContent_MultipleDomains(t *testing.T) {
+	got := ResolvedDropInContent([]string{"test", "dev", "api.staging.company.com"}, "127.0.0.1", 5053)
+	want := "[Resolve]\nDNS=127.0.0.1:5053\nDomains=~test ~dev ~api.staging.company.com\n"
+	if got != want {
+		t.Errorf("ResolvedDropInContent() = %q, want %q", got, want)
+	}
+}
+
+func TestResolvedResolver_InstallAddsToExisting(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hatch.conf")
+	if err := os.WriteFile(path, []byte(ResolvedDropInContent([]string{"test"}, "127.0.0.1", 5053)), 0o644); err != nil {
+		t.Fatalf("writing drop-in: %v", err)
+	}
+
+	runner := &MockRunner{}
+	r := NewResolvedResolver(path)
+	if err := r.Install(runner, "dev", "127.0.0.1", 5053); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(runner.Commands[0], "Domains=~test ~dev") {
+		t.Errorf("expected both domains in command, got: %s", runner.Commands[0])
+	}
+}
+
+func TestResolvedResolver_RemoveKeepsOtherDomains(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hatch.conf")
+	content := ResolvedDropInContent([]string{"test", "dev"}, "127.0.0.1", 5053)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("writing drop-in: %v", err)
+	}
+
+	runner := &MockRunner{}
+	r := NewResolvedResolver(path)
+	if err := r.Remove(runner, "test"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cmd := runner.Commands[0]
+	if strings.Contains(cmd, "rm -f") {
+		t.Errorf("expected drop-in to be rewritten, got: %s", cmd)
+	}
+	for _, want := range []string{"DNS=127.0.0.1:5053", "Domains=~dev\n", "> " + path} {
+		if !strings.Contains(cmd, want) {
+			t.Errorf("expected %q in command, got: %s", want, cmd)
+		}
+	}
+}
+
+func TestResolverFile_DottedDomain(t *testing.T) {
+	runner := &MockRunner{}
+	if err := InstallResolverFile(runner, "api.staging.company.com", "127.0.0.1", 5053); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(runner.Commands[0], "/etc/resolver/api.staging.company.com") {
+		t.Errorf("expected resolver path in command, got: %s", runner.Commands[0])
+	}
+	for _, bad := range []string{"../etc/passwd", "a..b", "Test", "-test"} {
+		if err := InstallResolverFile(runner, bad, "127.0.0.1", 5053); err == nil {
+			t.Errorf("expected error for %q", bad)
+		}
+	}
+}
@@ -3,24 +3,77 @@ package dns
 import (
 	"fmt"
 	"net"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	mdns "github.com/miekg/dns"
 )
 
 // Server is an embedded DNS server that resolves wildcard queries for
-// the configured TLD to loopback and forwards all other queries
-// upstream.
+// the configured TLDs to loopback, answers overridden hostnames with
+// their configured IP and forwards all other queries upstream.
+//
+// While LAN sharing is on, a second listener on the LAN address serves
+// other devices, and clients not on loopback get the LAN address in
+// place of loopback answers.
 type Server struct {
 	cfg       ServerConfig
 	server    *mdns.Server
 	upstreams []string
-	tldSuffix string // e.g. ".test."
+	zones     atomic.Pointer[zones]
+	lanIP     atomic.Pointer[net.IP] // set while sharing
 
-	mu      sync.Mutex
-	started bool
+	mu    sync.Mutex
+	share *mdns.Server // LAN listener while sharing
+}
+
+// zones is the set of names a Server answers itself. It is replaced as a
+// whole by Update, so queries never see a half-applied config.
+type zones struct {
+	tldSuffixes []string          // e.g. ".test."
+	overrides   map[string]net.IP // fully qualified, e.g. "api.company.com."
+}
+
+func newZones(cfg ServerConfig) *zones {
+	z := &zones{overrides: make(map[string]net.IP, len(cfg.Overrides))}
+	for _, tld := range cfg.TLDs {
+		z.tldSuffixes = append(z.tldSuffixes, "."+strings.Trim(strings.ToLower(tld), ".")+".")
+	}
+	for host, ip := range cfg.Overrides {
+		if parsed := net.ParseIP(ip); parsed != nil {
+			z.overrides[mdns.Fqdn(strings.ToLower(host))] = parsed
+		}
+	}
+	return z
+}
+
+// override returns the IP for the most specific override matching name,
+// which is either the overridden host itself or a name below it.
+func (z *zones) override(name string) (net.IP, bool) {
+	for n := name; n != "" && n != "."; {
+		if ip, ok := z.overrides[n]; ok {
+			return ip, true
+		}
+		_, rest, found := strings.Cut(n, ".")
+		if !found {
+			break
+		}
+		n = rest
+	}
+	return nil, false
+}
+
+// local reports whether name is below one of the configured TLDs.
+func (z *zones) local(name string) bool {
+	for _, suffix := range z.tldSuffixes {
+		if strings.HasSuffix(name, suffix) {
+			return true
+		}
+	}
+	return false
 }
 
 // NewServer creates a DNS server for the given config. It discovers
@@ -32,82 +85,146 @@ func NewServer(cfg ServerConfig) (*Server, error) {
 		_ = err
 	}
 
-	tld := strings.TrimPrefix(cfg.TLD, ".")
-	return &Server{
-		cfg:       cfg,
-		upstreams: upstreams,
-		tldSuffix: "." + tld + ".",
-	}, nil
+	return newServerWithUpstreams(cfg, upstreams), nil
 }
 
 // newServerWithUpstreams creates a Server with explicit upstreams,
 // used in tests to avoid system DNS discovery.
 func newServerWithUpstreams(cfg ServerConfig, upstreams []string) *Server {
-	tld := strings.TrimPrefix(cfg.TLD, ".")
-	return &Server{
+	s := &Server{
 		cfg:       cfg,
 		upstreams: upstreams,
-		tldSuffix: "." + tld + ".",
 	}
+	s.zones.Store(newZones(cfg))
+	return s
+}
+
+// Update replaces the TLDs and overrides the server answers for. The
+// listen address is fixed once the server has started and is ignored.
+func (s *Server) Update(cfg ServerConfig) {
+	s.zones.Store(newZones(cfg))
 }
 
 // Start begins listening for DNS queries on the configured address.
 // It blocks until the server is ready to accept connections, then
 // returns. Use Stop to shut down.
 func (s *Server) Start() error {
-	addr := fmt.Sprintf("%s:%d", s.cfg.ListenIP, s.cfg.Port)
+	srv, err := s.listen(net.JoinHostPort(s.cfg.ListenIP, strconv.Itoa(s.cfg.Port)))
+	if err != nil {
+		return err
+	}
+	s.server = srv
+	return nil
+}
 
+// listen starts a UDP listener on addr that serves handleDNS, waiting
+// until it is ready to accept queries.
+func (s *Server) listen(addr string) (*mdns.Server, error) {
 	mux := mdns.NewServeMux()
 	mux.HandleFunc(".", s.handleDNS)
 
-	s.server = &mdns.Server{
-		Addr:    addr,
-		Net:     "udp",
-		Handler: mux,
-		NotifyStartedFunc: func() {
-			s.mu.Lock()
-			s.started = true
-			s.mu.Unlock()
-		},
+	ready := make(chan struct{})
+	srv := &mdns.Server{
+		Addr:              addr,
+		Net:               "udp",
+		Handler:           mux,
+		NotifyStartedFunc: func() { close(ready) },
 	}
 
 	errCh := make(chan error, 1)
 	go func() {
-		errCh <- s.server.ListenAndServe()
+		errCh <- srv.ListenAndServe()
 	}()
 
-	// Wait for the server to start or fail.
-	for i := 0; i < 100; i++ {
-		s.mu.Lock()
-		ready := s.started
-		s.mu.Unlock()
-		if ready {
-			return nil
-		}
+	select {
+	case <-ready:
+		return srv, nil
+	case err := <-errCh:
+		return nil, fmt.Errorf("dns server failed to start: %w", err)
+	case <-time.After(500 * time.Millisecond):
+		return nil, fmt.Errorf("dns server did not start within timeout")
+	}
+}
+
+// Stop gracefully shuts down the DNS server, including the LAN listener
+// if sharing.
+func (s *Server) Stop() error {
+	if err := s.StopSharing(); err != nil {
+		return err
+	}
+	if s.server == nil {
+		return nil
+	}
+	return s.server.Shutdown()
+}
+
+// StartSharing serves other devices on the local network: it listens on
+// lanIP:port as well and answers clients not on loopback with lanIP.
+// Calling it again with a different address moves the LAN listener.
+func (s *Server) StartSharing(lanIP net.IP, port int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
-		select {
-		case err := <-errCh:
-			return fmt.Errorf("dns server failed to start: %w", err)
-		default:
+	addr := net.JoinHostPort(lanIP.String(), strconv.Itoa(port))
+	if s.share != nil {
+		if s.share.Addr == addr {
+			return nil
 		}
+		s.share.Shutdown()
+		s.share = nil
+		s.lanIP.Store(nil)
+	}
 
-		time.Sleep(5 * time.Millisecond)
+	srv, err := s.listen(addr)
+	if err != nil {
+		return fmt.Errorf("sharing dns on %s: %w", addr, err)
 	}
+	s.share = srv
+	s.lanIP.Store(&lanIP)
+	return nil
+}
 
-	return fmt.Errorf("dns server did not start within timeout")
+// StopSharing closes the LAN listener and returns to loopback answers.
+// It is a no-op when not sharing.
+func (s *Server) StopSharing() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lanIP.Store(nil)
+	if s.share == nil {
+		return nil
+	}
+	err := s.share.Shutdown()
+	s.share = nil
+	return err
 }
 
-// Stop gracefully shuts down the DNS server.
-func (s *Server) Stop() error {
-	if s.server == nil {
+// lanAnswer returns the address to give remote in place of loopback
+// answers: the LAN address while sharing and remote is not on loopback,
+// otherwise nil.
+func (s *Server) lanAnswer(remote net.Addr) net.IP {
+	lan := s.lanIP.Load()
+	if lan == nil {
 		return nil
 	}
-	return s.server.Shutdown()
+	var ip net.IP
+	switch a := remote.(type) {
+	case *net.UDPAddr:
+		ip = a.IP
+	case *net.TCPAddr:
+		ip = a.IP
+	}
+	if ip == nil || ip.IsLoopback() {
+		return nil
+	}
+	return *lan
 }
 
-// handleDNS processes incoming DNS queries. If the query name matches
-// *.<tld>, it responds with A → 127.0.0.1 or AAAA → ::1. Otherwise
-// it forwards the query to upstream DNS servers.
+// handleDNS processes incoming DNS queries. A name matching a DNS
+// override gets that override's IP; a name matching *.<tld> for any
+// configured TLD gets A → 127.0.0.1 or AAAA → ::1. While sharing,
+// LAN clients get the LAN address instead of loopback. Otherwise it
+// forwards the query to upstream DNS servers.
 func (s *Server) handleDNS(w mdns.ResponseWriter, r *mdns.Msg) {
 	if len(r.Question) == 0 {
 		return
@@ -115,10 +232,26 @@ func (s *Server) handleDNS(w mdns.ResponseWriter, r *mdns.Msg) {
 
 	q := r.Question[0]
 	name := strings.ToLower(q.Name)
+	z := s.zones.Load()
+	lan := s.lanAnswer(w.RemoteAddr())
+
+	// Overrides take precedence, so they can also redirect a name under
+	// one of our TLDs.
+	if ip, ok := z.override(name); ok {
+		if lan != nil && ip.IsLoopback() {
+			ip = lan
+		}
+		s.respondIP(w, r, q, ip)
+		return
+	}
 
-	// Check if the query matches our TLD.
-	if strings.HasSuffix(name, s.tldSuffix) {
-		s.respondLocal(w, r, q)
+	// Check if the query matches one of our TLDs.
+	if z.local(name) {
+		if lan != nil {
+			s.respondIP(w, r, q, lan)
+		} else {
+			s.respond(w, r, q, net.ParseIP("127.0.0.1").To4(), net.ParseIP("::1"))
+		}
 		return
 	}
 
@@ -126,15 +259,25 @@ func (s *Server) handleDNS(w mdns.ResponseWriter, r *mdns.Msg) {
 	s.forwardQuery(w, r)
 }
 
-// respondLocal writes a DNS response mapping the queried name to
-// loopback addresses.
-func (s *Server) respondLocal(w mdns.ResponseWriter, r *mdns.Msg, q mdns.Question) {
+// respondIP answers with ip for its own address family only.
+func (s *Server) respondIP(w mdns.ResponseWriter, r *mdns.Msg, q mdns.Question, ip net.IP) {
+	if ip4 := ip.To4(); ip4 != nil {
+		s.respond(w, r, q, ip4, nil)
+	} else {
+		s.respond(w, r, q, nil, ip)
+	}
+}
+
+// respond writes an authoritative DNS response mapping the queried name
+// to ipv4 for A queries and ipv6 for AAAA queries. A nil address leaves
+// the answer for that type empty.
+func (s *Server) respond(w mdns.ResponseWriter, r *mdns.Msg, q mdns.Question, ipv4, ipv6 net.IP) {
 	msg := new(mdns.Msg)
 	msg.SetReply(r)
 	msg.Authoritative = true
 
-	switch q.Qtype {
-	case mdns.TypeA:
+	switch {
+	case q.Qtype == mdns.TypeA && ipv4 != nil:
 		msg.Answer = append(msg.Answer, &mdns.A{
 			Hdr: mdns.RR_Header{
 				Name:   q.Name,
@@ -142,9 +285,9 @@ func (s *Server) respondLocal(w mdns.ResponseWriter, r *mdns.Msg, q mdns.Questio
 				Class:  mdns.ClassINET,
 				Ttl:    0,
 			},
-			A: net.ParseIP("127.0.0.1").To4(),
+			A: ipv4,
 		})
-	case mdns.TypeAAAA:
+	case q.Qtype == mdns.TypeAAAA && ipv6 != nil:
 		msg.Answer = append(msg.Answer, &mdns.AAAA{
 			Hdr: mdns.RR_Header{
 				Name:   q.Name,
@@ -152,7 +295,7 @@ func (s *Server) respondLocal(w mdns.ResponseWriter, r *mdns.Msg, q mdns.Questio
 				Class:  mdns.ClassINET,
 				Ttl:    0,
 			},
-			AAAA: net.ParseIP("::1"),
+			AAAA: ipv6,
 		})
 	}
 
@@ -12,6 +12,13 @@ import (
 // for testing. It returns the server and the address it's listening on.
 func startTestServer(t *testing.T, tld string, upstreams []string) (*Server, string) {
 	t.Helper()
+	return startTestServerConfig(t, ServerConfig{TLDs: []string{tld}}, upstreams)
+}
+
+// startTestServerConfig is startTestServer for a full ServerConfig; the
+// listen address is filled in.
+func startTestServerConfig(t *testing.T, cfg ServerConfig, upstreams []string) (*Server, string) {
+	t.Helper()
 
 	// Find a free port.
 	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
@@ -21,11 +28,8 @@ func startTestServer(t *testing.T, tld string, upstreams []string) (*Server, str
 	port := conn.LocalAddr().(*net.UDPAddr).Port
 	conn.Close()
 
-	cfg := ServerConfig{
-		TLD:      tld,
-		ListenIP: "127.0.0.1",
-		Port:     port,
-	}
+	cfg.ListenIP = "127.0.0.1"
+	cfg.Port = port
 
 	srv := newServerWithUpstreams(cfg, upstreams)
 	if err := srv.Start(); err != nil {
@@ -188,3 +192,183 @@ func TestServer_NonMatchingForwarded(t *testing.T) {
 		t.Errorf("expected upstream response 93.184.216.34, got %s", a.A)
 	}
 }
+
+// query sends a single question to addr and returns the response.
+func query(t *testing.T, addr, name string, qtype uint16) *mdns.Msg {
+	t.Helper()
+	client := &mdns.Client{Net: "udp"}
+	msg := new(mdns.Msg)
+	msg.SetQuestion(name, qtype)
+	resp, _, err := client.Exchange(msg, addr)
+	if err != nil {
+		t.Fatalf("DNS query for %s failed: %v", name, err)
+	}
+	return resp
+}
+
+// answerIP returns the address in the single A or AAAA answer of resp.
+func answerIP(t *testing.T, resp *mdns.Msg) net.IP {
+	t.Helper()
+	if len(resp.Answer) != 1 {
+		t.Fatalf("expected 1 answer, got %d", len(resp.Answer))
+	}
+	switch rr := resp.Answer[0].(type) {
+	case *mdns.A:
+		return rr.A
+	case *mdns.AAAA:
+		return rr.AAAA
+	default:
+		t.Fatalf("expected A or AAAA record, got %T", rr)
+		return nil
+	}
+}
+
+func TestServer_ResolvesMultipleTLDs(t *testing.T) {
+	_, addr := startTestServerConfig(t, ServerConfig{TLDs: []string{"test", "internal"}}, nil)
+
+	for _, name := range []string{"myapp.test.", "myapp.internal."} {
+		if ip := answerIP(t, query(t, addr, name, mdns.TypeA)); !ip.Equal(net.ParseIP("127.0.0.1")) {
+			t.Errorf("%s: expected 127.0.0.1, got %s", name, ip)
+		}
+	}
+}
+
+func TestServer_Overrides(t *testing.T) {
+	cfg := ServerConfig{
+		TLDs: []string{"test"},
+		Overrides: map[string]string{
+			"api.staging.company.com": "127.0.0.1",
+			"db.test":                 "192.168.1.20",
+			"v6.company.com":          "fd00::1",
+		},
+	}
+	_, addr := startTestServerConfig(t, cfg, nil)
+
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"api.staging.company.com.", "127.0.0.1"},
+		{"API.Staging.Company.com.", "127.0.0.1"},
+		{"v1.api.staging.company.com.", "127.0.0.1"},
+		{"db.test.", "192.168.1.20"}, // override wins over the TLD
+		{"other.test.", "127.0.0.1"},
+	}
+	for _, tt := range tests {
+		if ip := answerIP(t, query(t, addr, tt.name, mdns.TypeA)); !ip.Equal(net.ParseIP(tt.want)) {
+			t.Errorf("%s: expected %s, got %s", tt.name, tt.want, ip)
+		}
+	}
+
+	if ip := answerIP(t, query(t, addr, "v6.company.com.", mdns.TypeAAAA)); !ip.Equal(net.ParseIP("fd00::1")) {
+		t.Errorf("expected fd00::1, got %s", ip)
+	}
+
+	// An IPv4 override has no AAAA record, but is still answered locally.
+	resp := query(t, addr, "api.staging.company.com.", mdns.TypeAAAA)
+	if len(resp.Answer) != 0 || resp.Rcode != mdns.RcodeSuccess || !resp.Authoritative {
+		t.Errorf("expected empty authoritative answer, got rcode %d with %d answers", resp.Rcode, len(resp.Answer))
+	}
+}
+
+func TestServer_Update(t *testing.T) {
+	srv, addr := startTestServer(t, "test", nil)
+
+	srv.Update(ServerConfig{
+		TLDs:      []string{"dev"},
+		Overrides: map[string]string{"app.company.com": "10.0.0.5"},
+	})
+
+	if ip := answerIP(t, query(t, addr, "myapp.dev.", mdns.TypeA)); !ip.Equal(net.ParseIP("127.0.0.1")) {
+		t.Errorf("expected 127.0.0.1, got %s", ip)
+	}
+	if ip := answerIP(t, query(t, addr, "app.company.com.", mdns.TypeA)); !ip.Equal(net.ParseIP("10.0.0.5")) {
+		t.Errorf("expected 10.0.0.5, got %s", ip)
+	}
+
+	// .test is no longer ours; with no upstreams the query fails.
+	if resp := query(t, addr, "myapp.test.", mdns.TypeA); resp.Rcode != mdns.RcodeServerFailure {
+		t.Errorf("expected SERVFAIL for a dropped TLD, got rcode %d", resp.Rcode)
+	}
+}
+
+// fakeWriter is an mdns.ResponseWriter that records the reply, so
+// handleDNS can be called as if from any client address.
+type fakeWriter struct {
+	mdns.ResponseWriter
+	remote net.Addr
+	msg    *mdns.Msg
+}
+
+func (w *fakeWriter) RemoteAddr() net.Addr       { return w.remote }
+func (w *fakeWriter) WriteMsg(m *mdns.Msg) error { w.msg = m; return nil }
+
+func TestServer_SharingAnswersLANClients(t *testing.T) {
+	cfg := ServerConfig{
+		TLDs:      []string{"test"},
+		Overrides: map[string]string{"api.company.com": "127.0.0.1", "db.company.com": "10.0.0.9"},
+	}
+	srv := newServerWithUpstreams(cfg, nil)
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("finding free port: %v", err)
+	}
+	port := conn.LocalAddr().(*net.UDPAddr).Port
+	conn.Close()
+
+	lan := net.ParseIP("192.168.1.50")
+	if err := srv.StartSharing(net.ParseIP("127.0.0.1"), port); err != nil {
+		t.Fatalf("StartSharing: %v", err)
+	}
+	srv.lanIP.Store(&lan) // answer as if listening on a real LAN address
+	t.Cleanup(func() { srv.Stop() })
+
+	phone := &net.UDPAddr{IP: net.ParseIP("192.168.1.23"), Port: 5353}
+	local := &net.UDPAddr{IP: net.ParseIP("127.0.0.1"), Port: 5353}
+
+	tests := []struct {
+		name   string
+		remote net.Addr
+		want   string
+	}{
+		{"myapp.test.", phone, "192.168.1.50"},
+		{"myapp.test.", local, "127.0.0.1"},
+		{"api.company.com.", phone, "192.168.1.50"},
+		{"db.company.com.", phone, "10.0.0.9"},
+	}
+	for _, tt := range tests {
+		msg := new(mdns.Msg)
+		msg.SetQuestion(tt.name, mdns.TypeA)
+		w := &fakeWriter{remote: tt.remote}
+		srv.handleDNS(w, msg)
+		if ip := answerIP(t, w.msg); !ip.Equal(net.ParseIP(tt.want)) {
+			t.Errorf("%s from %s: expected %s, got %s", tt.name, tt.remote, tt.want, ip)
+		}
+	}
+
+	// The LAN address is IPv4, so LAN clients get no AAAA answer.
+	msg := new(mdns.Msg)
+	msg.SetQuestion("myapp.test.", mdns.TypeAAAA)
+	w := &fakeWriter{remote: phone}
+	srv.handleDNS(w, msg)
+	if len(w.msg.Answer) != 0 {
+		t.Errorf("expected no AAAA answer for a LAN client, got %v", w.msg.Answer)
+	}
+
+	// The LAN listener answers real queries too.
+	addr := net.JoinHostPort("127.0.0.1", strconv.Itoa(port))
+	if ip := answerIP(t, query(t, addr, "myapp.test.", mdns.TypeA)); !ip.Equal(net.ParseIP("127.0.0.1")) {
+		t.Errorf("expected 127.0.0.1 for a loopback client, got %s", ip)
+	}
+
+	if err := srv.StopSharing(); err != nil {
+		t.Fatalf("StopSharing: %v", err)
+	}
+	w = &fakeWriter{remote: phone}
+	msg.SetQuestion("myapp.test.", mdns.TypeA)
+	srv.handleDNS(w, msg)
+	if ip := answerIP(t, w.msg); !ip.Equal(net.ParseIP("127.0.0.1")) {
+		t.Errorf("expected loopback after StopSharing, got %s", ip)
+	}
+}
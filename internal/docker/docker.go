@@ -0,0 +1,161 @@
+// Package docker discovers projects from Docker containers. Running
+// containers labelled hatch.domain become ephemeral projects proxying to
+// the container's published port; the Provider watches the Docker Engine
+// API for containers starting and stopping and reports the current set.
+package docker
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// DefaultSocket is the Docker Engine API socket used when none is
+// configured.
+const DefaultSocket = "/var/run/docker.sock"
+
+// Labels read from containers. Only containers with LabelDomain are
+// discovered.
+const (
+	LabelDomain    = "hatch.domain"    // domain of the project
+	LabelPort      = "hatch.port"      // container port to proxy to; needed when several are exposed
+	LabelSubdomain = "hatch.subdomain" // optional subdomain of the service
+
+	// labelComposeService names the service of a Docker Compose
+	// container, used as the Hatch service name.
+	labelComposeService = "com.docker.compose.service"
+)
+
+// Container is a running container as listed by the Engine API.
+type Container struct {
+	ID              string            `json:"Id"`
+	Names           []string          `json:"Names"`
+	Labels          map[string]string `json:"Labels"`
+	Ports           []Port            `json:"Ports"`
+	NetworkSettings struct {
+		Networks map[string]struct {
+			IPAddress string `json:"IPAddress"`
+		} `json:"Networks"`
+	} `json:"NetworkSettings"`
+}
+
+// Port is a port exposed by a container and, with PublicPort set,
+// published on the host.
+type Port struct {
+	IP          string `json:"IP,omitempty"`
+	PrivatePort int    `json:"PrivatePort"`
+	PublicPort  int    `json:"PublicPort,omitempty"`
+	Type        string `json:"Type"`
+}
+
+// Name returns the container's name without Docker's leading slash.
+func (c Container) Name() string {
+	if len(c.Names) == 0 {
+		return c.ID
+	}
+	return strings.TrimPrefix(c.Names[0], "/")
+}
+
+// Event is a container event from the Engine API's event stream.
+type Event struct {
+	Type   string `json:"Type"`
+	Action string `json:"Action"`
+	Actor  struct {
+		ID string `json:"ID"`
+	} `json:"Actor"`
+}
+
+// Client talks to the Docker Engine API over its unix socket.
+type Client struct {
+	httpClient *http.Client
+}
+
+// NewClient returns a Client for the Engine API listening on socket.
+func NewClient(socket string) *Client {
+	return &Client{
+		httpClient: &http.Client{
+			Transport: &http.Transport{
+				DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+					var d net.Dialer
+					return d.DialContext(ctx, "unix", socket)
+				},
+			},
+		},
+	}
+}
+
+// labelFilter selects containers and events with LabelDomain.
+func labelFilter(extra map[string][]string) string {
+	filters := map[string][]string{"label": {LabelDomain}}
+	for k, v := range extra {
+		filters[k] = v
+	}
+	data, _ := json.Marshal(filters)
+	return url.Values{"filters": {string(data)}}.Encode()
+}
+
+// Containers returns the running containers labelled with LabelDomain.
+func (c *Client) Containers(ctx context.Context) ([]Container, error) {
+	resp, err := c.get(ctx, "/containers/json?"+labelFilter(nil))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var out []Container
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, fmt.Errorf("docker: decoding containers: %w", err)
+	}
+	return out, nil
+}
+
+// Events streams the start and stop events of containers labelled with
+// LabelDomain to fn until ctx is cancelled or the stream fails.
+func (c *Client) Events(ctx context.Context, fn func(Event)) error {
+	resp, err := c.get(ctx, "/events?"+labelFilter(map[string][]string{
+		"type":  {"container"},
+		"event": {"start", "die"},
+	}))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	dec := json.NewDecoder(bufio.NewReader(resp.Body))
+	for {
+		var ev Event
+		if err := dec.Decode(&ev); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if err == io.EOF {
+				return fmt.Errorf("docker: event stream closed")
+			}
+			return fmt.Errorf("docker: reading events: %w", err)
+		}
+		fn(ev)
+	}
+}
+
+func (c *Client) get(ctx context.Context, path string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://docker"+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("docker: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		resp.Body.Close()
+		return nil, fmt.Errorf("docker: %s: HTTP %d: %s", path, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return resp, nil
+}
@@ -0,0 +1,228 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/paulrose/hatch/internal/config"
+)
+
+// fakeDocker serves the parts of the Engine API the client uses on a unix
+// socket: the container list and a container event stream.
+type fakeDocker struct {
+	socket string
+
+	mu         sync.Mutex
+	containers []Container
+	filters    []string // filters query of each request
+	streams    []chan Event
+}
+
+func newFakeDocker(t *testing.T) *fakeDocker {
+	t.Helper()
+	// Unix socket paths are limited to about 100 bytes, which t.TempDir
+	// can exceed on macOS.
+	dir, err := os.MkdirTemp("", "hatch-docker")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	f := &fakeDocker{socket: filepath.Join(dir, "docker.sock")}
+	ln, err := net.Listen("unix", f.socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /containers/json", f.handleContainers)
+	mux.HandleFunc("GET /events", f.handleEvents)
+	srv := &http.Server{Handler: mux}
+	go srv.Serve(ln)
+	t.Cleanup(func() { srv.Close() })
+	return f
+}
+
+func (f *fakeDocker) handleContainers(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	f.filters = append(f.filters, r.URL.Query().Get("filters"))
+	out := f.containers
+	f.mu.Unlock()
+	if out == nil {
+		out = []Container{}
+	}
+	json.NewEncoder(w).Encode(out)
+}
+
+func (f *fakeDocker) handleEvents(w http.ResponseWriter, r *http.Request) {
+	ch := make(chan Event, 8)
+	f.mu.Lock()
+	f.filters = append(f.filters, r.URL.Query().Get("filters"))
+	f.streams = append(f.streams, ch)
+	f.mu.Unlock()
+
+	w.WriteHeader(http.StatusOK)
+	w.(http.Flusher).Flush()
+	enc := json.NewEncoder(w)
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case ev := <-ch:
+			enc.Encode(ev)
+			w.(http.Flusher).Flush()
+		}
+	}
+}
+
+// set replaces the running containers and announces the change on every
+// open event stream.
+func (f *fakeDocker) set(action string, containers ...Container) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.containers = containers
+	for _, ch := range f.streams {
+		ch <- Event{Type: "container", Action: action}
+	}
+}
+
+func container(name string, labels map[string]string, ports ...Port) Container {
+	return Container{ID: name + "-id", Names: []string{"/" + name}, Labels: labels, Ports: ports}
+}
+
+func TestClient_Containers(t *testing.T) {
+	f := newFakeDocker(t)
+	f.set("start", container("web", map[string]string{LabelDomain: "myapp.test"}, Port{PrivatePort: 80, PublicPort: 8080, Type: "tcp"}))
+
+	got, err := NewClient(f.socket).Containers(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].Name() != "web" || got[0].Ports[0].PublicPort != 8080 {
+		t.Errorf("unexpected containers %+v", got)
+	}
+	if !strings.Contains(f.filters[0], `"label":["hatch.domain"]`) {
+		t.Errorf("expected the list to be filtered by label, got %q", f.filters[0])
+	}
+}
+
+func TestClient_NoDocker(t *testing.T) {
+	_, err := NewClient(filepath.Join(t.TempDir(), "missing.sock")).Containers(context.Background())
+	if err == nil {
+		t.Fatal("expected an error without a socket")
+	}
+}
+
+func TestProjects(t *testing.T) {
+	containers := []Container{
+		container("myapp-web-1", map[string]string{
+			LabelDomain:         "MyApp.test",
+			labelComposeService: "web",
+		}, Port{IP: "0.0.0.0", PrivatePort: 3000, PublicPort: 49153, Type: "tcp"}),
+		container("myapp-api-2", map[string]string{
+			LabelDomain:         "myapp.test",
+			LabelSubdomain:      "api",
+			LabelPort:           "8000",
+			labelComposeService: "api",
+		}, Port{PrivatePort: 9000, PublicPort: 49200, Type: "tcp"}, Port{IP: "127.0.0.1", PrivatePort: 8000, PublicPort: 49155, Type: "tcp"}),
+		container("myapp-api-1", map[string]string{
+			LabelDomain:         "myapp.test",
+			LabelSubdomain:      "api",
+			LabelPort:           "8000",
+			labelComposeService: "api",
+		}, Port{PrivatePort: 8000, PublicPort: 49154, Type: "tcp"}),
+		container("blog", map[string]string{LabelDomain: "blog.test"}, Port{PrivatePort: 2368, Type: "tcp"}),
+		container("many-ports", map[string]string{LabelDomain: "many.test"}, Port{PrivatePort: 80, PublicPort: 8081, Type: "tcp"}, Port{PrivatePort: 443, PublicPort: 8443, Type: "tcp"}),
+		container("bad-port", map[string]string{LabelDomain: "bad.test", LabelPort: "http"}),
+	}
+	// The blog's port is not published; it is reached at its own address.
+	containers[3].NetworkSettings.Networks = map[string]struct {
+		IPAddress string `json:"IPAddress"`
+	}{"bridge": {IPAddress: "172.17.0.5"}}
+
+	projects, errs := Projects(containers)
+	if len(errs) != 2 {
+		t.Errorf("expected errors for many-ports and bad-port, got %v", errs)
+	}
+
+	myapp, ok := projects["docker-myapp-test"]
+	if !ok || myapp.Domain != "myapp.test" || len(myapp.Services) != 2 {
+		t.Fatalf("unexpected myapp project %+v", projects)
+	}
+	if web := myapp.Services["web"]; web.Proxy != "http://127.0.0.1:49153" || web.Subdomain != "" {
+		t.Errorf("unexpected web service %+v", web)
+	}
+	api := myapp.Services["api"]
+	if api.Subdomain != "api" || len(api.Upstreams) != 2 || api.Upstreams[0] != "http://127.0.0.1:49154" || api.Upstreams[1] != "http://127.0.0.1:49155" {
+		t.Errorf("expected both api replicas as upstreams, got %+v", api)
+	}
+
+	blog := projects["docker-blog-test"]
+	if svc := blog.Services["blog"]; svc.Proxy != "http://172.17.0.5:2368" {
+		t.Errorf("expected the container address for an unpublished port, got %+v", blog)
+	}
+	if _, ok := projects["docker-many-test"]; ok {
+		t.Error("expected a container exposing several ports without hatch.port to be skipped")
+	}
+}
+
+func TestProvider(t *testing.T) {
+	f := newFakeDocker(t)
+	f.set("start", container("web", map[string]string{LabelDomain: "myapp.test"}, Port{PrivatePort: 80, PublicPort: 8080, Type: "tcp"}))
+
+	changes := make(chan map[string]config.ProjectConfig, 8)
+	p := NewProvider(ProviderConfig{
+		Socket:   f.socket,
+		OnChange: func(projects map[string]config.ProjectConfig) { changes <- projects },
+	})
+	p.Start()
+	defer p.Close()
+
+	next := func() map[string]config.ProjectConfig {
+		t.Helper()
+		select {
+		case projects := <-changes:
+			return projects
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for a change")
+			return nil
+		}
+	}
+
+	if projects := next(); projects["docker-myapp-test"].Services["web"].Proxy != "http://127.0.0.1:8080" {
+		t.Fatalf("unexpected initial projects %+v", projects)
+	}
+
+	// Wait for the event stream, then add a container.
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+		f.mu.Lock()
+		n := len(f.streams)
+		f.mu.Unlock()
+		if n > 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("provider never subscribed to events")
+		}
+	}
+	f.set("start",
+		container("web", map[string]string{LabelDomain: "myapp.test"}, Port{PrivatePort: 80, PublicPort: 8080, Type: "tcp"}),
+		container("docs", map[string]string{LabelDomain: "docs.test"}, Port{PrivatePort: 80, PublicPort: 8090, Type: "tcp"}),
+	)
+	if projects := next(); len(projects) != 2 {
+		t.Fatalf("expected two projects after start, got %+v", projects)
+	}
+
+	// Stopping every container removes the projects.
+	f.set("die")
+	if projects := next(); len(projects) != 0 {
+		t.Fatalf("expected no projects after the containers stopped, got %+v", projects)
+	}
+}
@@ -0,0 +1,141 @@
+package docker
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/paulrose/hatch/internal/config"
+)
+
+// ProjectName returns the name of the project discovered for domain.
+func ProjectName(domain string) string {
+	return "docker-" + strings.ReplaceAll(domain, ".", "-")
+}
+
+// Projects builds the projects described by the labels of containers,
+// keyed by ProjectName. Containers sharing a domain become services of one
+// project, and those that also share a subdomain, such as replicas of a
+// Compose service, become upstreams of one service. Containers whose
+// labels cannot be used are skipped and reported in errs.
+func Projects(containers []Container) (map[string]config.ProjectConfig, []error) {
+	sorted := make([]Container, len(containers))
+	copy(sorted, containers)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Name() < sorted[j].Name() })
+
+	type group struct {
+		domain, subdomain, name string
+		upstreams               []string
+	}
+	var groups []*group
+	byHost := make(map[string]*group)
+	var errs []error
+	for _, c := range sorted {
+		domain := strings.ToLower(strings.TrimSpace(c.Labels[LabelDomain]))
+		if domain == "" {
+			continue
+		}
+		addr, err := upstream(c)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("container %s: %w", c.Name(), err))
+			continue
+		}
+
+		subdomain := strings.ToLower(strings.TrimSpace(c.Labels[LabelSubdomain]))
+		host := domain
+		if subdomain != "" {
+			host = subdomain + "." + domain
+		}
+		g, ok := byHost[host]
+		if !ok {
+			name := c.Labels[labelComposeService]
+			if name == "" {
+				name = c.Name()
+			}
+			g = &group{domain: domain, subdomain: subdomain, name: name}
+			byHost[host] = g
+			groups = append(groups, g)
+		}
+		g.upstreams = append(g.upstreams, "http://"+addr)
+	}
+
+	projects := make(map[string]config.ProjectConfig)
+	for _, g := range groups {
+		name := ProjectName(g.domain)
+		pc, ok := projects[name]
+		if !ok {
+			pc = config.ProjectConfig{Domain: g.domain, Services: make(map[string]config.Service)}
+		}
+		svcName := g.name
+		for i := 2; ; i++ {
+			if _, taken := pc.Services[svcName]; !taken {
+				break
+			}
+			svcName = fmt.Sprintf("%s-%d", g.name, i)
+		}
+
+		svc := config.Service{Subdomain: g.subdomain}
+		if len(g.upstreams) == 1 {
+			svc.Proxy = g.upstreams[0]
+		} else {
+			svc.Upstreams = g.upstreams
+		}
+		pc.Services[svcName] = svc
+		projects[name] = pc
+	}
+	return projects, errs
+}
+
+// upstream returns the host:port Hatch reaches the container at: the
+// host port its LabelPort (or only exposed) port is published on, or
+// failing that the container's own address.
+func upstream(c Container) (string, error) {
+	var port int
+	if v := c.Labels[LabelPort]; v != "" {
+		p, err := strconv.Atoi(v)
+		if err != nil || p < 1 || p > 65535 {
+			return "", fmt.Errorf("%s %q is not a port number", LabelPort, v)
+		}
+		port = p
+	} else {
+		exposed := make(map[int]bool)
+		for _, p := range c.Ports {
+			if p.Type == "tcp" {
+				exposed[p.PrivatePort] = true
+			}
+		}
+		if len(exposed) != 1 {
+			return "", fmt.Errorf("exposes %d tcp ports; set %s to choose one", len(exposed), LabelPort)
+		}
+		for p := range exposed {
+			port = p
+		}
+	}
+
+	for _, p := range c.Ports {
+		if p.PrivatePort != port || p.PublicPort == 0 || p.Type != "tcp" {
+			continue
+		}
+		host := p.IP
+		if host == "" || host == "0.0.0.0" || host == "::" {
+			host = "127.0.0.1"
+		}
+		return net.JoinHostPort(host, strconv.Itoa(p.PublicPort)), nil
+	}
+
+	// Unpublished ports are only reachable at the container's address,
+	// which works where Docker runs natively rather than in a VM.
+	networks := make([]string, 0, len(c.NetworkSettings.Networks))
+	for name := range c.NetworkSettings.Networks {
+		networks = append(networks, name)
+	}
+	sort.Strings(networks)
+	for _, name := range networks {
+		if ip := c.NetworkSettings.Networks[name].IPAddress; ip != "" {
+			return net.JoinHostPort(ip, strconv.Itoa(port)), nil
+		}
+	}
+	return "", fmt.Errorf("port %d is not published and the container has no IP address", port)
+}
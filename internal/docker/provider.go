@@ -0,0 +1,151 @@
+package docker
+
+import (
+	"context"
+	"reflect"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/paulrose/hatch/internal/config"
+)
+
+const (
+	// minRetry and maxRetry bound the delay before reconnecting to the
+	// Engine API after it could not be reached.
+	minRetry = time.Second
+	maxRetry = 30 * time.Second
+)
+
+// ProviderConfig holds the configuration for a Provider.
+type ProviderConfig struct {
+	Socket string // Engine API socket; default DefaultSocket
+
+	// OnChange is called from the Provider's goroutine with every change
+	// to the discovered projects, keyed by ProjectName.
+	OnChange func(map[string]config.ProjectConfig)
+}
+
+// Provider watches Docker for labelled containers and reports the
+// projects they describe. While Docker cannot be reached it reports none.
+type Provider struct {
+	client   *Client
+	onChange func(map[string]config.ProjectConfig)
+	last     map[string]config.ProjectConfig
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewProvider creates a Provider but does not start it.
+func NewProvider(cfg ProviderConfig) *Provider {
+	socket := cfg.Socket
+	if socket == "" {
+		socket = DefaultSocket
+	}
+	return &Provider{
+		client:   NewClient(socket),
+		onChange: cfg.OnChange,
+	}
+}
+
+// Start begins watching Docker in a background goroutine.
+func (p *Provider) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	p.cancel = cancel
+	p.done = make(chan struct{})
+	go p.run(ctx)
+}
+
+// Close stops watching and waits for the goroutine to return. OnChange is
+// not called once Close returns.
+func (p *Provider) Close() {
+	if p.cancel == nil {
+		return
+	}
+	p.cancel()
+	<-p.done
+}
+
+func (p *Provider) run(ctx context.Context) {
+	defer close(p.done)
+
+	backoff := minRetry
+	for {
+		started := time.Now()
+		err := p.watch(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if time.Since(started) > maxRetry {
+			backoff = minRetry
+		}
+		log.Warn().Err(err).Dur("retry", backoff).Msg("docker: discovery interrupted")
+		p.publish(nil)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(2*backoff, maxRetry)
+	}
+}
+
+// watch syncs the discovered projects and then again after every
+// container event, until the event stream fails.
+func (p *Provider) watch(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Subscribe before listing so that containers starting in between
+	// are not missed.
+	changed := make(chan struct{}, 1)
+	failed := make(chan error, 1)
+	go func() {
+		failed <- p.client.Events(ctx, func(Event) {
+			select {
+			case changed <- struct{}{}:
+			default:
+			}
+		})
+	}()
+
+	for {
+		if err := p.sync(ctx); err != nil {
+			return err
+		}
+		select {
+		case err := <-failed:
+			return err
+		case <-changed:
+		}
+	}
+}
+
+// sync lists the labelled containers and publishes their projects.
+func (p *Provider) sync(ctx context.Context) error {
+	containers, err := p.client.Containers(ctx)
+	if err != nil {
+		return err
+	}
+	projects, errs := Projects(containers)
+	for _, err := range errs {
+		log.Warn().Err(err).Msg("docker: skipping container")
+	}
+	p.publish(projects)
+	return nil
+}
+
+func (p *Provider) publish(projects map[string]config.ProjectConfig) {
+	if len(projects) == 0 && len(p.last) == 0 {
+		return
+	}
+	if reflect.DeepEqual(projects, p.last) {
+		return
+	}
+	p.last = projects
+	if p.onChange != nil {
+		p.onChange(projects)
+	}
+}
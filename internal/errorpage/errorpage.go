@@ -0,0 +1,214 @@
+// Package errorpage serves the page shown when a project's upstream cannot
+// be reached.
+//
+// Caddy's handle_errors routes for proxied services end in a
+// hatch_error_page handler. For gateway errors (502, 503 and 504) it
+// renders a page naming the project, service and upstream, with the
+// service's last health check and a hint for starting it. The page then
+// probes its own URL, marked with ProbeHeader, and reloads as soon as the
+// upstream answers instead of this handler.
+package errorpage
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	caddyv2 "github.com/caddyserver/caddy/v2"
+	"github.com/caddyserver/caddy/v2/modules/caddyhttp"
+	"github.com/rs/zerolog/log"
+
+	"github.com/paulrose/hatch/internal/health"
+)
+
+// ProbeHeader marks the error page's requests checking whether the
+// upstream is back. The handler answers them with the header set and no
+// body; the capture, buffer and chaos handlers let them through untouched.
+const ProbeHeader = "X-Hatch-Probe"
+
+func init() {
+	caddyv2.RegisterModule(Page{})
+}
+
+//go:embed page.html
+var defaultPage string
+
+var defaultTemplate = template.Must(template.New("page").Parse(defaultPage))
+
+// reloadScript probes the page's URL until the upstream, rather than the
+// error page, answers and then reloads.
+const reloadScript template.HTML = `<script>
+  (function () {
+    function probe() {
+      fetch(location.href, { method: "HEAD", cache: "no-store", headers: { "` + ProbeHeader + `": "1" } })
+        .then(function (r) {
+          if (r.headers.get("` + ProbeHeader + `")) {
+            setTimeout(probe, 2000);
+          } else {
+            location.reload();
+          }
+        })
+        .catch(function () { setTimeout(probe, 2000); });
+    }
+    setTimeout(probe, 2000);
+  })();
+</script>`
+
+// StatusFunc returns the health of a service.
+type StatusFunc func(health.ServiceKey) (health.ServiceStatus, bool)
+
+// statuses looks up service health for the page.
+var statuses atomic.Pointer[StatusFunc]
+
+// SetStatusFunc installs the health lookup. Without one, pages show no
+// health details.
+func SetStatusFunc(f StatusFunc) {
+	if f == nil {
+		statuses.Store(nil)
+		return
+	}
+	statuses.Store(&f)
+}
+
+func lookup(key health.ServiceKey) (health.ServiceStatus, bool) {
+	f := statuses.Load()
+	if f == nil {
+		return health.ServiceStatus{}, false
+	}
+	return (*f)(key)
+}
+
+// Data is what error page templates are rendered with.
+type Data struct {
+	Project    string
+	Service    string
+	Host       string   // host the request was made to
+	Upstreams  []string // host:port addresses the service proxies to
+	Dir        string   // project path
+	Hint       string   // command likely to start the service, if known
+	StatusCode int
+	StatusText string
+	Error      string        // why the upstream could not be reached
+	Health     *HealthData   // nil until the service has been checked
+	Time       time.Time     // when the page was rendered
+	Reload     template.HTML // script reloading the page once the service is back
+}
+
+// HealthData is the last health check of the service.
+type HealthData struct {
+	Status    string
+	Error     string
+	Since     time.Time
+	LastCheck time.Time
+}
+
+// Page is the hatch_error_page Caddy handler.
+type Page struct {
+	Project   string   `json:"project,omitempty"`
+	Service   string   `json:"service,omitempty"`
+	Upstreams []string `json:"upstreams,omitempty"`
+	Dir       string   `json:"dir,omitempty"`
+	Template  string   `json:"template,omitempty"` // custom template file
+}
+
+// CaddyModule returns the Caddy module information.
+func (Page) CaddyModule() caddyv2.ModuleInfo {
+	return caddyv2.ModuleInfo{
+		ID:  "http.handlers.hatch_error_page",
+		New: func() caddyv2.Module { return new(Page) },
+	}
+}
+
+// ServeHTTP renders the error page for gateway errors and passes other
+// errors on to next.
+func (p *Page) ServeHTTP(w http.ResponseWriter, r *http.Request, next caddyhttp.Handler) error {
+	status := http.StatusBadGateway
+	var msg string
+	if err, ok := r.Context().Value(caddyhttp.ErrorCtxKey).(error); ok {
+		var he caddyhttp.HandlerError
+		if errors.As(err, &he) {
+			status = he.StatusCode
+			if he.Err != nil {
+				msg = he.Err.Error()
+			}
+		} else {
+			msg = err.Error()
+		}
+	}
+	switch status {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+	default:
+		return next.ServeHTTP(w, r)
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	if r.Header.Get(ProbeHeader) != "" {
+		w.Header().Set(ProbeHeader, "down")
+		w.WriteHeader(status)
+		return nil
+	}
+
+	data := p.data(r, status, msg)
+	if !strings.Contains(r.Header.Get("Accept"), "text/html") {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(status)
+		fmt.Fprintf(w, "%d %s: %s/%s is not reachable at %s\n", status, data.StatusText, p.Project, p.Service, strings.Join(p.Upstreams, ", "))
+		if data.Hint != "" {
+			fmt.Fprintf(w, "Start it with `%s` in %s\n", data.Hint, p.Dir)
+		}
+		return nil
+	}
+
+	tmpl := defaultTemplate
+	if p.Template != "" {
+		custom, err := template.ParseFiles(p.Template)
+		if err != nil {
+			log.Warn().Err(err).Str("project", p.Project).Msg("error page: cannot parse template, using the default")
+		} else {
+			tmpl = custom
+		}
+	}
+	var buf strings.Builder
+	if err := tmpl.Execute(&buf, data); err != nil {
+		log.Warn().Err(err).Str("project", p.Project).Msg("error page: cannot render template, using the default")
+		buf.Reset()
+		defaultTemplate.Execute(&buf, data)
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	_, err := w.Write([]byte(buf.String()))
+	return err
+}
+
+func (p *Page) data(r *http.Request, status int, msg string) Data {
+	d := Data{
+		Project:    p.Project,
+		Service:    p.Service,
+		Host:       r.Host,
+		Upstreams:  p.Upstreams,
+		Dir:        p.Dir,
+		Hint:       Hint(p.Dir),
+		StatusCode: status,
+		StatusText: http.StatusText(status),
+		Error:      msg,
+		Time:       time.Now(),
+		Reload:     reloadScript,
+	}
+	if st, ok := lookup(health.ServiceKey{Project: p.Project, Service: p.Service}); ok && st.Status != health.StatusUnknown {
+		d.Health = &HealthData{
+			Status:    st.Status.String(),
+			Error:     st.Error,
+			Since:     st.Since,
+			LastCheck: st.LastCheck,
+		}
+	}
+	return d
+}
+
+// Interface guard.
+var _ caddyhttp.MiddlewareHandler = (*Page)(nil)
@@ -0,0 +1,103 @@
+package errorpage
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/caddyserver/caddy/v2/modules/caddyhttp"
+
+	"github.com/paulrose/hatch/internal/health"
+)
+
+// serveError runs p as Caddy would after a handler returned err.
+func serveError(t *testing.T, p *Page, err error, accept string, header http.Header) (*httptest.ResponseRecorder, bool) {
+	t.Helper()
+	req := httptest.NewRequest("GET", "https://web.test/dashboard", nil)
+	req = req.WithContext(context.WithValue(req.Context(), caddyhttp.ErrorCtxKey, err))
+	req.Header.Set("Accept", accept)
+	for name, v := range header {
+		req.Header[name] = v
+	}
+
+	w := httptest.NewRecorder()
+	passed := false
+	next := caddyhttp.HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+		passed = true
+		return nil
+	})
+	if err := p.ServeHTTP(w, req, next); err != nil {
+		t.Fatal(err)
+	}
+	return w, passed
+}
+
+func TestPage_RendersGatewayErrors(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "package.json"), []byte(`{"scripts":{"dev":"vite"}}`), 0o644)
+	SetStatusFunc(func(key health.ServiceKey) (health.ServiceStatus, bool) {
+		return health.ServiceStatus{Status: health.StatusUnhealthy, Error: "connection refused", Since: time.Now()}, key.Service == "app"
+	})
+	t.Cleanup(func() { SetStatusFunc(nil) })
+
+	p := &Page{Project: "web", Service: "app", Upstreams: []string{"localhost:5173"}, Dir: dir}
+	w, passed := serveError(t, p, caddyhttp.Error(http.StatusBadGateway, errors.New("dial tcp: connection refused")), "text/html", nil)
+	if passed {
+		t.Fatal("expected the page to handle a 502")
+	}
+	if w.Code != http.StatusBadGateway || !strings.HasPrefix(w.Header().Get("Content-Type"), "text/html") {
+		t.Fatalf("unexpected response %d %v", w.Code, w.Header())
+	}
+	body := w.Body.String()
+	for _, want := range []string{"web/app is not running", "localhost:5173", "npm run dev", dir, "unhealthy", "connection refused", ProbeHeader} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected the page to contain %q", want)
+		}
+	}
+}
+
+func TestPage_PassesOtherErrors(t *testing.T) {
+	_, passed := serveError(t, &Page{Project: "web", Service: "app"}, caddyhttp.Error(http.StatusNotFound, nil), "text/html", nil)
+	if !passed {
+		t.Error("expected a 404 to be passed on")
+	}
+}
+
+func TestPage_Probe(t *testing.T) {
+	w, _ := serveError(t, &Page{Project: "web", Service: "app"}, caddyhttp.Error(http.StatusBadGateway, nil), "*/*", http.Header{ProbeHeader: {"1"}})
+	if w.Code != http.StatusBadGateway || w.Header().Get(ProbeHeader) == "" || w.Body.Len() != 0 {
+		t.Errorf("unexpected probe response %d %v %q", w.Code, w.Header(), w.Body.String())
+	}
+}
+
+func TestPage_PlainTextForNonBrowsers(t *testing.T) {
+	w, _ := serveError(t, &Page{Project: "web", Service: "app", Upstreams: []string{"localhost:3000"}}, caddyhttp.Error(http.StatusBadGateway, nil), "application/json", nil)
+	if !strings.HasPrefix(w.Header().Get("Content-Type"), "text/plain") || !strings.Contains(w.Body.String(), "web/app is not reachable at localhost:3000") {
+		t.Errorf("unexpected response %v %q", w.Header(), w.Body.String())
+	}
+}
+
+func TestPage_CustomTemplate(t *testing.T) {
+	dir := t.TempDir()
+	tmpl := filepath.Join(dir, "down.html")
+	os.WriteFile(tmpl, []byte(`<h1>{{.Project}} is napping ({{.StatusCode}})</h1>{{.Reload}}`), 0o644)
+
+	w, _ := serveError(t, &Page{Project: "web", Service: "app", Template: tmpl}, caddyhttp.Error(http.StatusGatewayTimeout, nil), "text/html", nil)
+	body := w.Body.String()
+	if !strings.Contains(body, "<h1>web is napping (504)</h1>") || !strings.Contains(body, "<script>") {
+		t.Errorf("expected the custom template, got %q", body)
+	}
+
+	// A broken template falls back to the default page.
+	os.WriteFile(tmpl, []byte(`{{.Nope`), 0o644)
+	w, _ = serveError(t, &Page{Project: "web", Service: "app", Template: tmpl}, caddyhttp.Error(http.StatusBadGateway, nil), "text/html", nil)
+	if !strings.Contains(w.Body.String(), "web/app is not running") {
+		t.Error("expected the default page for a broken template")
+	}
+}
@@ -0,0 +1,69 @@
+package errorpage
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+)
+
+// Hint guesses the command that starts the dev server of the project in
+// dir from the files it contains, or returns "" if it cannot tell.
+func Hint(dir string) string {
+	if dir == "" {
+		return ""
+	}
+	exists := func(name string) bool {
+		_, err := os.Stat(filepath.Join(dir, name))
+		return err == nil
+	}
+
+	if script := packageScript(filepath.Join(dir, "package.json")); script != "" {
+		switch {
+		case exists("pnpm-lock.yaml"):
+			return "pnpm " + script
+		case exists("yarn.lock"):
+			return "yarn " + script
+		case exists("bun.lock"), exists("bun.lockb"):
+			return "bun run " + script
+		case script == "start":
+			return "npm start"
+		}
+		return "npm run " + script
+	}
+
+	for _, c := range []struct{ file, cmd string }{
+		{"bin/rails", "bin/rails server"},
+		{"manage.py", "python manage.py runserver"},
+		{"artisan", "php artisan serve"},
+		{"mix.exs", "mix phx.server"},
+		{"Cargo.toml", "cargo run"},
+		{"go.mod", "go run ."},
+		{"Procfile.dev", "bin/dev"},
+	} {
+		if exists(c.file) {
+			return c.cmd
+		}
+	}
+	return ""
+}
+
+// packageScript returns the dev script of a package.json: "dev" if it
+// has one, else "start", else "".
+func packageScript(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	var pkg struct {
+		Scripts map[string]string `json:"scripts"`
+	}
+	if json.Unmarshal(data, &pkg) != nil {
+		return ""
+	}
+	for _, name := range []string{"dev", "start"} {
+		if pkg.Scripts[name] != "" {
+			return name
+		}
+	}
+	return ""
+}
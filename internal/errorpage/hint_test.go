@@ -0,0 +1,34 @@
+package errorpage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestHint(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  string
+	}{
+		{"npm dev", map[string]string{"package.json": `{"scripts":{"dev":"vite","start":"node ."}}`}, "npm run dev"},
+		{"npm start", map[string]string{"package.json": `{"scripts":{"start":"node ."}}`}, "npm start"},
+		{"pnpm", map[string]string{"package.json": `{"scripts":{"dev":"vite"}}`, "pnpm-lock.yaml": ""}, "pnpm dev"},
+		{"yarn", map[string]string{"package.json": `{"scripts":{"dev":"vite"}}`, "yarn.lock": ""}, "yarn dev"},
+		{"no scripts", map[string]string{"package.json": `{}`, "go.mod": ""}, "go run ."},
+		{"django", map[string]string{"manage.py": ""}, "python manage.py runserver"},
+		{"unknown", map[string]string{"README.md": ""}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, content := range tt.files {
+				os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644)
+			}
+			if got := Hint(dir); got != tt.want {
+				t.Errorf("Hint = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,137 @@
+// Package events provides the daemon's typed event bus, used to push
+// health, project and config changes to API clients.
+package events
+
+import (
+	"sync"
+	"time"
+)
+
+// Type names an event. It is used as the SSE event name.
+type Type string
+
+const (
+	HealthChanged   Type = "health.changed"    // a service's aggregate health changed
+	ConfigReloaded  Type = "config.reloaded"   // a new config was applied
+	ProjectAdded    Type = "project.added"     // a project appeared in the config
+	ProjectRemoved  Type = "project.removed"   // a project was removed from the config
+	ProjectToggled  Type = "project.toggled"   // a project was enabled or disabled
+	ProjectUpdated  Type = "project.updated"   // a project's settings changed
+	CaddyLoadFailed Type = "caddy.load_failed" // Caddy rejected a translated config
+	TunnelChanged   Type = "tunnel.changed"    // a public tunnel connected, dropped or stopped
+	ProcessChanged  Type = "process.changed"   // a service's command started, exited or stopped
+	ChaosChanged    Type = "chaos.changed"     // runtime fault injection was set, cleared or expired
+)
+
+// Event is a single published event. Data holds one of the payload types
+// below and is marshaled as the SSE data field.
+type Event struct {
+	Type Type
+	Time time.Time
+	Data any
+}
+
+// HealthChange is the payload of HealthChanged.
+type HealthChange struct {
+	Project string `json:"project"`
+	Service string `json:"service"`
+	From    string `json:"from"`
+	To      string `json:"to"`
+}
+
+// ProjectChange is the payload of ProjectAdded, ProjectRemoved,
+// ProjectToggled and ProjectUpdated.
+type ProjectChange struct {
+	Project string `json:"project"`
+	Enabled bool   `json:"enabled"`
+}
+
+// ConfigReload is the payload of ConfigReloaded.
+type ConfigReload struct {
+	Projects int `json:"projects"`
+}
+
+// CaddyLoadFailure is the payload of CaddyLoadFailed.
+type CaddyLoadFailure struct {
+	Error string `json:"error"`
+}
+
+// TunnelChange is the payload of TunnelChanged. URL is empty once the
+// tunnel is stopped.
+type TunnelChange struct {
+	Project   string `json:"project"`
+	URL       string `json:"url,omitempty"`
+	Connected bool   `json:"connected"`
+	Error     string `json:"error,omitempty"`
+}
+
+// ProcessChange is the payload of ProcessChanged. Exit describes how the
+// last run ended, once one has.
+type ProcessChange struct {
+	Project string `json:"project"`
+	Service string `json:"service"`
+	State   string `json:"state"`
+	PID     int    `json:"pid,omitempty"`
+	Exit    string `json:"exit,omitempty"`
+}
+
+// ChaosChange is the payload of ChaosChanged. Expires is set while an
+// override with a time limit is active.
+type ChaosChange struct {
+	Project string    `json:"project"`
+	Service string    `json:"service"`
+	Active  bool      `json:"active"`
+	Expires time.Time `json:"expires,omitzero"`
+}
+
+// Bus fans published events out to subscribers. Slow subscribers miss
+// events rather than blocking publishers.
+type Bus struct {
+	mu          sync.RWMutex
+	subscribers map[chan Event]struct{}
+}
+
+// NewBus creates a new Bus.
+func NewBus() *Bus {
+	return &Bus{
+		subscribers: make(map[chan Event]struct{}),
+	}
+}
+
+// Publish stamps the event with the current time and sends it to every
+// subscriber. It is safe to call on a nil Bus.
+func (b *Bus) Publish(typ Type, data any) {
+	if b == nil {
+		return
+	}
+	ev := Event{Type: typ, Time: time.Now(), Data: data}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for ch := range b.subscribers {
+		select {
+		case ch <- ev:
+		default:
+			// Drop if subscriber is slow.
+		}
+	}
+}
+
+// Subscribe returns a channel that receives events and a cleanup function
+// that must be called when the subscriber is done.
+func (b *Bus) Subscribe() (<-chan Event, func()) {
+	ch := make(chan Event, 64)
+
+	b.mu.Lock()
+	b.subscribers[ch] = struct{}{}
+	b.mu.Unlock()
+
+	cleanup := func() {
+		b.mu.Lock()
+		delete(b.subscribers, ch)
+		b.mu.Unlock()
+	}
+
+	return ch, cleanup
+}
@@ -0,0 +1,69 @@
+package events
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBus_PublishSubscribe(t *testing.T) {
+	b := NewBus()
+	ch, cleanup := b.Subscribe()
+	defer cleanup()
+
+	b.Publish(ProjectAdded, ProjectChange{Project: "myapp", Enabled: true})
+
+	select {
+	case ev := <-ch:
+		if ev.Type != ProjectAdded {
+			t.Errorf("expected %s, got %s", ProjectAdded, ev.Type)
+		}
+		if ev.Time.IsZero() {
+			t.Error("expected event time to be set")
+		}
+		pc, ok := ev.Data.(ProjectChange)
+		if !ok || pc.Project != "myapp" || !pc.Enabled {
+			t.Errorf("unexpected payload: %#v", ev.Data)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for event")
+	}
+}
+
+func TestBus_Unsubscribe(t *testing.T) {
+	b := NewBus()
+	ch, cleanup := b.Subscribe()
+	cleanup()
+
+	b.Publish(ConfigReloaded, ConfigReload{Projects: 1})
+
+	select {
+	case ev := <-ch:
+		t.Fatalf("unexpected event after cleanup: %v", ev)
+	default:
+	}
+}
+
+func TestBus_SlowSubscriberDoesNotBlock(t *testing.T) {
+	b := NewBus()
+	_, cleanup := b.Subscribe()
+	defer cleanup()
+
+	done := make(chan struct{})
+	go func() {
+		for range 1000 {
+			b.Publish(HealthChanged, HealthChange{})
+		}
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("publish blocked on a slow subscriber")
+	}
+}
+
+func TestBus_NilPublish(t *testing.T) {
+	var b *Bus
+	b.Publish(ConfigReloaded, nil) // must not panic
+}
@@ -3,7 +3,7 @@ package health
 import (
 	"context"
 	"errors"
-	"net"
+	"net/http"
 	"net/url"
 	"sync"
 	"time"
@@ -15,24 +15,28 @@ import (
 
 // CheckerConfig controls the behaviour of a Checker.
 type CheckerConfig struct {
-	Interval time.Duration
-	Timeout  time.Duration
-	OnChange func(key ServiceKey, from, to Status) // optional transition callback
+	Interval    time.Duration
+	Timeout     time.Duration
+	HistorySize int                                   // check results kept per service
+	OnChange    func(key ServiceKey, from, to Status) // optional transition callback
 }
 
-// Checker periodically TCP-dials upstream services and tracks their health.
+// Checker periodically checks upstream services and tracks their health.
+// Services are TCP-dialed unless they configure an HTTP health check.
 type Checker struct {
-	cfg      CheckerConfig
-	mu       sync.Mutex
-	targets  map[ServiceKey]string         // key → dial address
-	statuses map[ServiceKey]*ServiceStatus // key → current status
-	done     chan struct{}
-	wg       sync.WaitGroup
-	running  bool
+	cfg       CheckerConfig
+	client    *http.Client // used for HTTP health checks
+	mu        sync.Mutex
+	targets   map[ServiceKey]*target        // key → check target
+	statuses  map[ServiceKey]*ServiceStatus // key → current status
+	histories map[ServiceKey]*history       // key → recent check results
+	done      chan struct{}
+	wg        sync.WaitGroup
+	running   bool
 }
 
 // NewChecker creates a Checker with the given configuration.
-// Zero-value Interval and Timeout are replaced with defaults.
+// Zero-value Interval, Timeout and HistorySize are replaced with defaults.
 func NewChecker(cfg CheckerConfig) *Checker {
 	if cfg.Interval <= 0 {
 		cfg.Interval = DefaultInterval
@@ -40,16 +44,22 @@ func NewChecker(cfg CheckerConfig) *Checker {
 	if cfg.Timeout <= 0 {
 		cfg.Timeout = DefaultTimeout
 	}
+	if cfg.HistorySize <= 0 {
+		cfg.HistorySize = DefaultHistorySize
+	}
 	return &Checker{
-		cfg:      cfg,
-		targets:  make(map[ServiceKey]string),
-		statuses: make(map[ServiceKey]*ServiceStatus),
+		cfg:       cfg,
+		client:    newProbeClient(),
+		targets:   make(map[ServiceKey]*target),
+		statuses:  make(map[ServiceKey]*ServiceStatus),
+		histories: make(map[ServiceKey]*history),
 	}
 }
 
-// Start extracts dial targets from the application config, runs an immediate
-// health check, and launches a background goroutine that re-checks on each
-// tick of Interval. Returns an error if the checker is already running.
+// Start extracts check targets from the application config, runs an
+// immediate health check, and launches a background goroutine that re-checks
+// each service every Interval, or at its health_check interval when set.
+// Returns an error if the checker is already running.
 func (c *Checker) Start(appCfg config.Config) error {
 	c.mu.Lock()
 	if c.running {
@@ -83,7 +93,7 @@ func (c *Checker) Stop() error {
 	return nil
 }
 
-// UpdateConfig adds, removes, or updates dial targets to match the new
+// UpdateConfig adds, removes, or updates check targets to match the new
 // application config. Existing statuses are preserved for unchanged services.
 func (c *Checker) UpdateConfig(appCfg config.Config) {
 	c.mu.Lock()
@@ -99,7 +109,7 @@ func (c *Checker) ServiceStatuses() map[ServiceKey]ServiceStatus {
 
 	out := make(map[ServiceKey]ServiceStatus, len(c.statuses))
 	for k, v := range c.statuses {
-		out[k] = *v
+		out[k] = v.clone()
 	}
 	return out
 }
@@ -113,16 +123,31 @@ func (c *Checker) ServiceStatus(key ServiceKey) (ServiceStatus, bool) {
 	if !ok {
 		return ServiceStatus{}, false
 	}
-	return *s, true
+	return s.clone(), true
 }
 
-// loop runs an immediate check then re-checks on every ticker tick.
+// History returns the recent check results for a single service, oldest
+// first.
+func (c *Checker) History(key ServiceKey) ([]CheckResult, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	h, ok := c.histories[key]
+	if !ok {
+		return nil, false
+	}
+	return h.snapshot(), true
+}
+
+// loop runs an immediate check, then checks whichever services are due on
+// every tick. Ticks are at most a second apart so that services with a
+// shorter health_check interval than the checker's are honoured.
 func (c *Checker) loop() {
 	defer c.wg.Done()
 
-	c.checkAll()
+	c.checkDue()
 
-	ticker := time.NewTicker(c.cfg.Interval)
+	ticker := time.NewTicker(c.tick())
 	defer ticker.Stop()
 
 	for {
@@ -130,18 +155,31 @@ func (c *Checker) loop() {
 		case <-c.done:
 			return
 		case <-ticker.C:
-			c.checkAll()
+			c.checkDue()
 		}
 	}
 }
 
-// checkAll snapshots the current targets under lock, then dials each one
-// without holding the lock to avoid blocking readers during slow dials.
-func (c *Checker) checkAll() {
+func (c *Checker) tick() time.Duration {
+	return min(c.cfg.Interval, time.Second)
+}
+
+// checkDue snapshots the targets that are due under lock, then checks each
+// one without holding the lock to avoid blocking readers during slow checks.
+func (c *Checker) checkDue() {
+	// Allow half a tick of slack so that a target scheduled one interval
+	// after the previous check is not pushed back a whole tick.
+	now := time.Now()
+	cutoff := now.Add(c.tick() / 2)
+
 	c.mu.Lock()
-	snap := make(map[ServiceKey]string, len(c.targets))
-	for k, v := range c.targets {
-		snap[k] = v
+	snap := make(map[ServiceKey]target)
+	for k, t := range c.targets {
+		if t.next.After(cutoff) {
+			continue
+		}
+		t.next = now.Add(t.interval)
+		snap[k] = *t
 	}
 	c.mu.Unlock()
 
@@ -149,29 +187,25 @@ func (c *Checker) checkAll() {
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	for key, addr := range snap {
+	for key, t := range snap {
 		if ctx.Err() != nil {
 			log.Warn().Msg("health check cycle timed out, skipping remaining targets")
 			return
 		}
-		c.checkOne(ctx, key, addr)
+		c.checkOne(ctx, key, &t)
 	}
 }
 
-// checkOne dials a single target, updates its status, and fires the
-// OnChange callback when the status transitions.
-func (c *Checker) checkOne(ctx context.Context, key ServiceKey, addr string) {
-	dialer := net.Dialer{Timeout: c.cfg.Timeout}
-	conn, err := dialer.DialContext(ctx, "tcp", addr)
-	if conn != nil {
-		conn.Close()
+// checkOne probes each upstream of a service, updates the per-upstream and
+// aggregate statuses, and fires the OnChange callback when the aggregate
+// status transitions.
+func (c *Checker) checkOne(ctx context.Context, key ServiceKey, t *target) {
+	results := make(map[string]probeResult, len(t.upstreams))
+	for _, u := range t.upstreams {
+		results[u.addr] = t.probe(ctx, c.client, u)
 	}
 
 	now := time.Now()
-	newStatus := StatusHealthy
-	if err != nil {
-		newStatus = StatusUnhealthy
-	}
 
 	c.mu.Lock()
 	ss, ok := c.statuses[key]
@@ -180,14 +214,64 @@ func (c *Checker) checkOne(ctx context.Context, key ServiceKey, addr string) {
 		return // service removed while checking
 	}
 
+	type upstreamChange struct {
+		addr     string
+		from, to Status
+		err      string
+	}
+	var changes []upstreamChange
+	for i := range ss.Upstreams {
+		u := &ss.Upstreams[i]
+		res, checked := results[u.Addr]
+		if !checked {
+			continue
+		}
+		if u.Status != res.status {
+			changes = append(changes, upstreamChange{u.Addr, u.Status, res.status, res.err})
+			u.Status = res.status
+			u.Since = now
+		}
+		u.StatusCode = res.statusCode
+		u.Latency = res.latency
+		u.Error = res.err
+	}
+
 	oldStatus := ss.Status
+	newStatus := aggregate(ss.Upstreams)
 	ss.LastCheck = now
 	if oldStatus != newStatus {
 		ss.Status = newStatus
 		ss.Since = now
 	}
+	if len(ss.Upstreams) > 0 {
+		first := ss.Upstreams[0]
+		ss.StatusCode, ss.Latency, ss.Error = first.StatusCode, first.Latency, first.Error
+	}
+	if h, ok := c.histories[key]; ok {
+		h.add(CheckResult{
+			Time:       now,
+			Status:     newStatus,
+			StatusCode: ss.StatusCode,
+			Latency:    ss.Latency,
+			Error:      ss.Error,
+		})
+	}
+	addr := ss.Addr
 	c.mu.Unlock()
 
+	if len(t.upstreams) > 1 {
+		for _, ch := range changes {
+			log.Info().
+				Str("project", key.Project).
+				Str("service", key.Service).
+				Str("addr", ch.addr).
+				Str("from", ch.from.String()).
+				Str("to", ch.to.String()).
+				Str("reason", ch.err).
+				Msg("upstream health changed")
+		}
+	}
+
 	if oldStatus != newStatus {
 		log.Info().
 			Str("project", key.Project).
@@ -204,9 +288,10 @@ func (c *Checker) checkOne(ctx context.Context, key ServiceKey, addr string) {
 }
 
 // applyConfig builds the targets map from the application config and
-// initialises statuses for new services. Must be called with c.mu held.
+// initialises statuses for new services and upstreams. Must be called with
+// c.mu held.
 func (c *Checker) applyConfig(appCfg config.Config) {
-	newTargets := make(map[ServiceKey]string)
+	newTargets := make(map[ServiceKey]*target)
 
 	for projName, proj := range appCfg.Projects {
 		if !proj.Enabled {
@@ -214,7 +299,11 @@ func (c *Checker) applyConfig(appCfg config.Config) {
 		}
 		for svcName, svc := range proj.Services {
 			key := ServiceKey{Project: projName, Service: svcName}
-			newTargets[key] = extractDialAddress(svc.Proxy)
+			t := newTarget(proj, svc, c.cfg.Interval, c.cfg.Timeout)
+			if old, ok := c.targets[key]; ok {
+				t.next = old.next // keep the existing schedule
+			}
+			newTargets[key] = t
 		}
 	}
 
@@ -222,21 +311,38 @@ func (c *Checker) applyConfig(appCfg config.Config) {
 	for key := range c.targets {
 		if _, exists := newTargets[key]; !exists {
 			delete(c.statuses, key)
+			delete(c.histories, key)
 		}
 	}
 
 	// Add or update services.
 	now := time.Now()
-	for key, addr := range newTargets {
-		if existing, ok := c.statuses[key]; ok {
-			// Update address if it changed.
-			existing.Addr = addr
-		} else {
-			c.statuses[key] = &ServiceStatus{
-				Status: StatusUnknown,
-				Addr:   addr,
-				Since:  now,
+	for key, t := range newTargets {
+		existing, ok := c.statuses[key]
+		if !ok {
+			existing = &ServiceStatus{Status: StatusUnknown, Since: now}
+			c.statuses[key] = existing
+			c.histories[key] = newHistory(c.cfg.HistorySize)
+		}
+
+		// Rebuild the upstream list, keeping the state of unchanged addresses.
+		prev := make(map[string]UpstreamStatus, len(existing.Upstreams))
+		for _, u := range existing.Upstreams {
+			prev[u.Addr] = u
+		}
+		upstreams := make([]UpstreamStatus, 0, len(t.upstreams))
+		for _, u := range t.upstreams {
+			us, kept := prev[u.addr]
+			if !kept {
+				us = UpstreamStatus{Addr: u.addr, Status: StatusUnknown, Since: now}
 			}
+			upstreams = append(upstreams, us)
+		}
+		existing.Upstreams = upstreams
+
+		existing.Addr = ""
+		if len(upstreams) > 0 {
+			existing.Addr = upstreams[0].Addr
 		}
 	}
 
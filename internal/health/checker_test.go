@@ -286,6 +286,87 @@ func TestChecker_UpdateConfig(t *testing.T) {
 	}
 }
 
+func TestChecker_UpstreamsTrackedIndividually(t *testing.T) {
+	up := startTestListener(t)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	downAddr := ln.Addr().String()
+	ln.Close()
+
+	cfg := config.Config{
+		Projects: map[string]config.Project{
+			"myproject": {
+				Enabled: true,
+				Services: map[string]config.Service{
+					"web": {Upstreams: []string{"http://" + downAddr, "http://" + up.Addr().String()}},
+				},
+			},
+		},
+	}
+
+	c := newTestChecker()
+	if err := c.Start(cfg); err != nil {
+		t.Fatal(err)
+	}
+	defer c.Stop()
+
+	// One healthy upstream keeps the service healthy.
+	waitForStatus(t, c, testKey, StatusHealthy, 2*time.Second)
+
+	ss, _ := c.ServiceStatus(testKey)
+	if ss.Addr != downAddr {
+		t.Errorf("expected Addr %q, got %q", downAddr, ss.Addr)
+	}
+	if len(ss.Upstreams) != 2 {
+		t.Fatalf("expected 2 upstreams, got %d", len(ss.Upstreams))
+	}
+	if ss.Upstreams[0].Addr != downAddr || ss.Upstreams[0].Status != StatusUnhealthy {
+		t.Errorf("upstream 0: got %+v, want %s unhealthy", ss.Upstreams[0], downAddr)
+	}
+	if ss.Upstreams[1].Addr != up.Addr().String() || ss.Upstreams[1].Status != StatusHealthy {
+		t.Errorf("upstream 1: got %+v, want %s healthy", ss.Upstreams[1], up.Addr())
+	}
+
+	// Dropping the healthy upstream leaves the service unhealthy.
+	svc := cfg.Projects["myproject"].Services["web"]
+	svc.Upstreams = svc.Upstreams[:1]
+	cfg.Projects["myproject"].Services["web"] = svc
+	c.UpdateConfig(cfg)
+
+	waitForStatus(t, c, testKey, StatusUnhealthy, 2*time.Second)
+	ss, _ = c.ServiceStatus(testKey)
+	if len(ss.Upstreams) != 1 {
+		t.Fatalf("expected 1 upstream after update, got %d", len(ss.Upstreams))
+	}
+}
+
+func TestAggregate(t *testing.T) {
+	tests := []struct {
+		name      string
+		upstreams []Status
+		want      Status
+	}{
+		{"none", nil, StatusUnknown},
+		{"all unknown", []Status{StatusUnknown, StatusUnknown}, StatusUnknown},
+		{"one healthy", []Status{StatusUnhealthy, StatusHealthy}, StatusHealthy},
+		{"all unhealthy", []Status{StatusUnhealthy, StatusUnhealthy}, StatusUnhealthy},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ups []UpstreamStatus
+			for _, st := range tt.upstreams {
+				ups = append(ups, UpstreamStatus{Status: st})
+			}
+			if got := aggregate(ups); got != tt.want {
+				t.Errorf("aggregate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestChecker_DisabledProjectSkipped(t *testing.T) {
 	ln := startTestListener(t)
 
@@ -338,3 +419,44 @@ func TestExtractDialAddress(t *testing.T) {
 		})
 	}
 }
+
+func TestChecker_History(t *testing.T) {
+	ln := startTestListener(t)
+
+	c := newTestChecker(func(cfg *CheckerConfig) { cfg.HistorySize = 3 })
+	if err := c.Start(testConfig(ln.Addr().String())); err != nil {
+		t.Fatal(err)
+	}
+	defer c.Stop()
+
+	// Wait for the ring buffer to fill and wrap.
+	deadline := time.After(2 * time.Second)
+	for {
+		h, ok := c.History(testKey)
+		if !ok {
+			t.Fatal("expected history for service")
+		}
+		if len(h) == 3 {
+			for i, r := range h {
+				if r.Status != StatusHealthy {
+					t.Errorf("result %d: expected healthy, got %v", i, r.Status)
+				}
+				if i > 0 && r.Time.Before(h[i-1].Time) {
+					t.Errorf("results not in chronological order: %v before %v", r.Time, h[i-1].Time)
+				}
+			}
+			break
+		}
+		select {
+		case <-deadline:
+			t.Fatalf("timed out waiting for history to fill, got %d results", len(h))
+		case <-time.After(20 * time.Millisecond):
+		}
+	}
+
+	// Removing the service drops its history.
+	c.UpdateConfig(config.Config{})
+	if _, ok := c.History(testKey); ok {
+		t.Error("expected history to be removed with the service")
+	}
+}
@@ -8,7 +8,7 @@ const (
 	// DefaultInterval is the time between health check cycles.
 	DefaultInterval = 10 * time.Second
 
-	// DefaultTimeout is the TCP dial timeout for each check.
+	// DefaultTimeout is the TCP dial or HTTP request timeout for each check.
 	DefaultTimeout = 2 * time.Second
 )
 
@@ -17,8 +17,8 @@ type Status int
 
 const (
 	StatusUnknown   Status = iota // Not yet checked
-	StatusHealthy                 // TCP dial succeeded
-	StatusUnhealthy               // TCP dial failed
+	StatusHealthy                 // TCP dial or HTTP check succeeded
+	StatusUnhealthy               // TCP dial or HTTP check failed
 )
 
 // String returns a human-readable representation of the status.
@@ -39,10 +39,50 @@ type ServiceKey struct {
 	Service string
 }
 
-// ServiceStatus holds the current health state of a service.
+// ServiceStatus holds the current health state of a service. For a
+// load-balanced service, Status is healthy while at least one upstream is
+// healthy, and Upstreams holds the state of each upstream. Addr,
+// StatusCode, Latency and Error describe the first upstream.
 type ServiceStatus struct {
-	Status    Status
-	Addr      string    // host:port being checked
-	Since     time.Time // when current status was first observed
-	LastCheck time.Time // when the last check completed
+	Status     Status
+	Addr       string           // host:port being checked
+	Upstreams  []UpstreamStatus // per-upstream state, in config order
+	StatusCode int              // HTTP status of the last check; 0 for TCP checks
+	Latency    time.Duration    // duration of the last check
+	Error      string           // why the last check failed, if it did
+	Since      time.Time        // when current status was first observed
+	LastCheck  time.Time        // when the last check completed
+}
+
+// UpstreamStatus holds the health state of a single upstream of a service.
+type UpstreamStatus struct {
+	Addr       string // host:port being checked
+	Status     Status
+	StatusCode int           // HTTP status of the last check; 0 for TCP checks
+	Latency    time.Duration // duration of the last check
+	Error      string        // why the last check failed, if it did
+	Since      time.Time     // when current status was first observed
+}
+
+// clone returns a copy of s that shares no memory with it.
+func (s *ServiceStatus) clone() ServiceStatus {
+	out := *s
+	out.Upstreams = append([]UpstreamStatus(nil), s.Upstreams...)
+	return out
+}
+
+// aggregate returns the service status implied by its upstreams: healthy
+// if any upstream is healthy, unknown if none has been checked, and
+// unhealthy otherwise.
+func aggregate(upstreams []UpstreamStatus) Status {
+	result := StatusUnknown
+	for _, u := range upstreams {
+		switch u.Status {
+		case StatusHealthy:
+			return StatusHealthy
+		case StatusUnhealthy:
+			result = StatusUnhealthy
+		}
+	}
+	return result
 }
@@ -0,0 +1,47 @@
+package health
+
+import "time"
+
+// DefaultHistorySize is the number of check results kept per service.
+const DefaultHistorySize = 360
+
+// CheckResult is the recorded outcome of a single health check of a
+// service. StatusCode, Latency and Error describe the first upstream, as
+// in ServiceStatus.
+type CheckResult struct {
+	Time       time.Time
+	Status     Status
+	StatusCode int
+	Latency    time.Duration
+	Error      string
+}
+
+// history is a fixed-size ring buffer of check results.
+type history struct {
+	buf  []CheckResult
+	next int  // index of the next write
+	full bool // whether buf has wrapped
+}
+
+func newHistory(size int) *history {
+	return &history{buf: make([]CheckResult, size)}
+}
+
+// add records r, overwriting the oldest result once the buffer is full.
+func (h *history) add(r CheckResult) {
+	h.buf[h.next] = r
+	h.next = (h.next + 1) % len(h.buf)
+	if h.next == 0 {
+		h.full = true
+	}
+}
+
+// snapshot returns the recorded results, oldest first.
+func (h *history) snapshot() []CheckResult {
+	if !h.full {
+		return append([]CheckResult(nil), h.buf[:h.next]...)
+	}
+	out := make([]CheckResult, 0, len(h.buf))
+	out = append(out, h.buf[h.next:]...)
+	return append(out, h.buf[:h.next]...)
+}
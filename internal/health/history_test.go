@@ -0,0 +1,39 @@
+package health
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHistory_Wraparound(t *testing.T) {
+	h := newHistory(3)
+	if got := h.snapshot(); len(got) != 0 {
+		t.Fatalf("expected empty snapshot, got %d results", len(got))
+	}
+
+	base := time.Unix(0, 0)
+	for i := range 5 {
+		h.add(CheckResult{Time: base.Add(time.Duration(i) * time.Second)})
+	}
+
+	got := h.snapshot()
+	if len(got) != 3 {
+		t.Fatalf("expected 3 results, got %d", len(got))
+	}
+	for i, want := range []int{2, 3, 4} {
+		if !got[i].Time.Equal(base.Add(time.Duration(want) * time.Second)) {
+			t.Errorf("result %d: got %v, want second %d", i, got[i].Time, want)
+		}
+	}
+}
+
+func TestHistory_PartialFill(t *testing.T) {
+	h := newHistory(4)
+	h.add(CheckResult{Status: StatusHealthy})
+	h.add(CheckResult{Status: StatusUnhealthy})
+
+	got := h.snapshot()
+	if len(got) != 2 || got[0].Status != StatusHealthy || got[1].Status != StatusUnhealthy {
+		t.Errorf("unexpected snapshot: %+v", got)
+	}
+}
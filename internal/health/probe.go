@@ -0,0 +1,199 @@
+package health
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"time"
+
+	"github.com/paulrose/hatch/internal/config"
+)
+
+// maxProbeBody caps how much of a response body is read when matching
+// expect_body.
+const maxProbeBody = 1 << 20
+
+// target describes how and how often a single service is checked.
+type target struct {
+	upstreams []upstream
+	http      *httpProbe // nil for a plain TCP dial
+	interval  time.Duration
+	timeout   time.Duration
+	next      time.Time // when the next check is due
+}
+
+// upstream is a single address of a service.
+type upstream struct {
+	addr   string // host:port, or the directory of a static service
+	scheme string // http, https, or file for a static service
+}
+
+// httpProbe holds a service's active HTTP health check settings.
+type httpProbe struct {
+	path         string
+	method       string
+	expectStatus int            // exact code, or class such as 2 for 2xx
+	expectBody   *regexp.Regexp // nil to skip body matching
+}
+
+// probeResult is the outcome of checking one upstream.
+type probeResult struct {
+	status     Status
+	statusCode int
+	latency    time.Duration
+	err        string
+}
+
+// newTarget builds the check target for svc in project p, falling back to
+// the checker's interval and timeout where the service does not override
+// them. A static service has its root directory as its only upstream, and
+// a mock service, answered by Hatch itself, a placeholder.
+func newTarget(p config.Project, svc config.Service, interval, timeout time.Duration) *target {
+	t := &target{interval: interval, timeout: timeout}
+	if svc.IsStatic() {
+		t.upstreams = []upstream{{addr: svc.RootDir(p), scheme: "file"}}
+		return t
+	}
+	if svc.IsMock() {
+		t.upstreams = []upstream{{addr: "mock", scheme: "mock"}}
+		return t
+	}
+	for _, raw := range svc.ProxyURLs() {
+		scheme := "http"
+		if u, err := url.Parse(raw); err == nil && u.Scheme == "https" {
+			scheme = "https"
+		}
+		t.upstreams = append(t.upstreams, upstream{addr: extractDialAddress(raw), scheme: scheme})
+	}
+
+	hc := svc.HealthCheck
+	if hc == nil {
+		return t
+	}
+
+	t.http = &httpProbe{path: hc.Path, method: hc.Method, expectStatus: hc.ExpectStatus}
+	if t.http.method == "" {
+		t.http.method = http.MethodGet
+	}
+	if hc.ExpectBody != "" {
+		// Validated on load; an invalid pattern disables body matching.
+		t.http.expectBody, _ = regexp.Compile(hc.ExpectBody)
+	}
+	if d, err := time.ParseDuration(hc.Interval); err == nil && d > 0 {
+		t.interval = d
+	}
+	if d, err := time.ParseDuration(hc.Timeout); err == nil && d > 0 {
+		t.timeout = d
+	}
+	return t
+}
+
+// newProbeClient returns the HTTP client used for active health checks.
+// Redirects are not followed, matching Caddy's active health checks, and
+// certificate verification is skipped because local dev servers commonly
+// use self-signed certificates.
+func newProbeClient() *http.Client {
+	return &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig:   &tls.Config{InsecureSkipVerify: true},
+			DisableKeepAlives: true,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// probe checks a single upstream with a TCP dial or, when the target has an
+// HTTP health check, an HTTP request. Static services are healthy while
+// their root directory exists, and mock services always.
+func (t *target) probe(ctx context.Context, client *http.Client, u upstream) probeResult {
+	ctx, cancel := context.WithTimeout(ctx, t.timeout)
+	defer cancel()
+
+	switch u.scheme {
+	case "file":
+		return dirProbe(u.addr)
+	case "mock":
+		return probeResult{status: StatusHealthy}
+	}
+	if t.http == nil {
+		return dialProbe(ctx, u.addr)
+	}
+	return t.http.do(ctx, client, u)
+}
+
+func dirProbe(dir string) probeResult {
+	info, err := os.Stat(dir)
+	switch {
+	case err != nil:
+		return probeResult{status: StatusUnhealthy, err: err.Error()}
+	case !info.IsDir():
+		return probeResult{status: StatusUnhealthy, err: dir + " is not a directory"}
+	}
+	return probeResult{status: StatusHealthy}
+}
+
+func dialProbe(ctx context.Context, addr string) probeResult {
+	var dialer net.Dialer
+	start := time.Now()
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	latency := time.Since(start)
+	if err != nil {
+		return probeResult{status: StatusUnhealthy, latency: latency, err: err.Error()}
+	}
+	conn.Close()
+	return probeResult{status: StatusHealthy, latency: latency}
+}
+
+func (p *httpProbe) do(ctx context.Context, client *http.Client, u upstream) probeResult {
+	reqURL := u.scheme + "://" + u.addr + p.path
+	req, err := http.NewRequestWithContext(ctx, p.method, reqURL, nil)
+	if err != nil {
+		return probeResult{status: StatusUnhealthy, err: err.Error()}
+	}
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		return probeResult{status: StatusUnhealthy, latency: time.Since(start), err: err.Error()}
+	}
+	defer resp.Body.Close()
+
+	var body []byte
+	if p.expectBody != nil {
+		body, err = io.ReadAll(io.LimitReader(resp.Body, maxProbeBody))
+	}
+	res := probeResult{statusCode: resp.StatusCode, latency: time.Since(start)}
+
+	switch {
+	case err != nil:
+		res.status, res.err = StatusUnhealthy, fmt.Sprintf("reading body: %v", err)
+	case !statusMatches(resp.StatusCode, p.expectStatus):
+		res.status, res.err = StatusUnhealthy, fmt.Sprintf("unexpected status %d", resp.StatusCode)
+	case p.expectBody != nil && !p.expectBody.Match(body):
+		res.status, res.err = StatusUnhealthy, fmt.Sprintf("body does not match %q", p.expectBody)
+	default:
+		res.status = StatusHealthy
+	}
+	return res
+}
+
+// statusMatches reports whether code satisfies expect, which is either an
+// exact status code or a class (2 matches 2xx). Zero expects any 2xx, as
+// Caddy does.
+func statusMatches(code, expect int) bool {
+	if expect == 0 {
+		expect = 2
+	}
+	if expect < 100 {
+		return code/100 == expect
+	}
+	return code == expect
+}
@@ -0,0 +1,223 @@
+package health
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/paulrose/hatch/internal/config"
+)
+
+// httpCheckConfig builds a config with one service pointing at srv and the
+// given health check.
+func httpCheckConfig(srv *httptest.Server, hc config.HealthCheck) config.Config {
+	return config.Config{
+		Projects: map[string]config.Project{
+			"myproject": {
+				Enabled: true,
+				Services: map[string]config.Service{
+					"web": {Proxy: srv.URL, HealthCheck: &hc},
+				},
+			},
+		},
+	}
+}
+
+func TestChecker_HTTPCheckHealthy(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/healthz" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte("status: ok"))
+	}))
+	defer srv.Close()
+
+	c := newTestChecker()
+	if err := c.Start(httpCheckConfig(srv, config.HealthCheck{Path: "/healthz", ExpectBody: "ok$"})); err != nil {
+		t.Fatal(err)
+	}
+	defer c.Stop()
+
+	waitForStatus(t, c, testKey, StatusHealthy, 2*time.Second)
+
+	ss, _ := c.ServiceStatus(testKey)
+	if ss.StatusCode != http.StatusOK {
+		t.Errorf("expected status code 200, got %d", ss.StatusCode)
+	}
+	if ss.Latency <= 0 {
+		t.Errorf("expected positive latency, got %v", ss.Latency)
+	}
+	if ss.Error != "" {
+		t.Errorf("expected no error, got %q", ss.Error)
+	}
+}
+
+func TestChecker_HTTPCheckListeningButFailing(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	c := newTestChecker()
+	if err := c.Start(httpCheckConfig(srv, config.HealthCheck{Path: "/"})); err != nil {
+		t.Fatal(err)
+	}
+	defer c.Stop()
+
+	waitForStatus(t, c, testKey, StatusUnhealthy, 2*time.Second)
+
+	ss, _ := c.ServiceStatus(testKey)
+	if ss.StatusCode != http.StatusInternalServerError {
+		t.Errorf("expected status code 500, got %d", ss.StatusCode)
+	}
+	if !strings.Contains(ss.Error, "unexpected status 500") {
+		t.Errorf("unexpected error: %q", ss.Error)
+	}
+}
+
+func TestChecker_HTTPCheckBodyMismatch(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("compiling..."))
+	}))
+	defer srv.Close()
+
+	c := newTestChecker()
+	if err := c.Start(httpCheckConfig(srv, config.HealthCheck{Path: "/", ExpectBody: "ready"})); err != nil {
+		t.Fatal(err)
+	}
+	defer c.Stop()
+
+	waitForStatus(t, c, testKey, StatusUnhealthy, 2*time.Second)
+
+	ss, _ := c.ServiceStatus(testKey)
+	if !strings.Contains(ss.Error, "body does not match") {
+		t.Errorf("unexpected error: %q", ss.Error)
+	}
+}
+
+func TestChecker_HTTPCheckMethodAndExpectStatus(t *testing.T) {
+	var gotMethod atomic.Value
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotMethod.Store(r.Method)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	c := newTestChecker()
+	hc := config.HealthCheck{Path: "/", Method: http.MethodHead, ExpectStatus: http.StatusNoContent}
+	if err := c.Start(httpCheckConfig(srv, hc)); err != nil {
+		t.Fatal(err)
+	}
+	defer c.Stop()
+
+	waitForStatus(t, c, testKey, StatusHealthy, 2*time.Second)
+
+	if m, _ := gotMethod.Load().(string); m != http.MethodHead {
+		t.Errorf("expected HEAD request, got %q", m)
+	}
+}
+
+func TestChecker_HTTPCheckInterval(t *testing.T) {
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+	}))
+	defer srv.Close()
+
+	// The checker ticks every 50ms, but the service asks for 10m, so only
+	// the immediate check should run.
+	c := newTestChecker()
+	if err := c.Start(httpCheckConfig(srv, config.HealthCheck{Path: "/", Interval: "10m"})); err != nil {
+		t.Fatal(err)
+	}
+	defer c.Stop()
+
+	waitForStatus(t, c, testKey, StatusHealthy, 2*time.Second)
+	time.Sleep(300 * time.Millisecond)
+
+	if n := hits.Load(); n != 1 {
+		t.Errorf("expected 1 check, got %d", n)
+	}
+}
+
+func TestChecker_StaticService(t *testing.T) {
+	projectDir := t.TempDir()
+	cfg := config.Config{
+		Projects: map[string]config.Project{
+			"myproject": {
+				Path:    projectDir,
+				Enabled: true,
+				Services: map[string]config.Service{
+					"web": {Root: "dist", SPA: true},
+				},
+			},
+		},
+	}
+
+	c := newTestChecker()
+	if err := c.Start(cfg); err != nil {
+		t.Fatal(err)
+	}
+	defer c.Stop()
+
+	waitForStatus(t, c, testKey, StatusUnhealthy, 2*time.Second)
+
+	dist := filepath.Join(projectDir, "dist")
+	if err := os.Mkdir(dist, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	waitForStatus(t, c, testKey, StatusHealthy, 2*time.Second)
+
+	ss, _ := c.ServiceStatus(testKey)
+	if ss.Addr != dist {
+		t.Errorf("expected addr %q, got %q", dist, ss.Addr)
+	}
+}
+
+func TestChecker_MockService(t *testing.T) {
+	cfg := config.Config{
+		Projects: map[string]config.Project{
+			"myproject": {
+				Enabled: true,
+				Services: map[string]config.Service{
+					"web": {Mock: []config.MockRule{{Path: "/"}}},
+				},
+			},
+		},
+	}
+
+	c := newTestChecker()
+	if err := c.Start(cfg); err != nil {
+		t.Fatal(err)
+	}
+	defer c.Stop()
+
+	waitForStatus(t, c, testKey, StatusHealthy, 2*time.Second)
+}
+
+func TestStatusMatches(t *testing.T) {
+	tests := []struct {
+		code, expect int
+		want         bool
+	}{
+		{200, 0, true},
+		{204, 0, true},
+		{301, 0, false},
+		{500, 0, false},
+		{404, 4, true},
+		{500, 4, false},
+		{201, 201, true},
+		{200, 201, false},
+	}
+	for _, tt := range tests {
+		if got := statusMatches(tt.code, tt.expect); got != tt.want {
+			t.Errorf("statusMatches(%d, %d) = %v, want %v", tt.code, tt.expect, got, tt.want)
+		}
+	}
+}
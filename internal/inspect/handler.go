@@ -0,0 +1,201 @@
+package inspect
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+
+	caddyv2 "github.com/caddyserver/caddy/v2"
+	"github.com/caddyserver/caddy/v2/modules/caddyhttp"
+
+	"github.com/paulrose/hatch/internal/errorpage"
+)
+
+func init() {
+	caddyv2.RegisterModule(Capture{})
+}
+
+// active is the buffer capture handlers record into.
+var active atomic.Pointer[Buffer]
+
+// SetBuffer installs the buffer capture handlers record into. With no
+// buffer set, they pass requests through untouched.
+func SetBuffer(b *Buffer) {
+	active.Store(b)
+}
+
+// Capture is the hatch_capture Caddy handler. It records every request
+// passing through it, tagged with its project and service.
+type Capture struct {
+	Project string `json:"project,omitempty"`
+	Service string `json:"service,omitempty"`
+	MaxBody int    `json:"max_body,omitempty"` // bytes kept per body; 0 for DefaultMaxBody
+}
+
+// CaddyModule returns the Caddy module information.
+func (Capture) CaddyModule() caddyv2.ModuleInfo {
+	return caddyv2.ModuleInfo{
+		ID:  "http.handlers.hatch_capture",
+		New: func() caddyv2.Module { return new(Capture) },
+	}
+}
+
+// ServeHTTP records the request and the response written by next.
+func (c *Capture) ServeHTTP(w http.ResponseWriter, r *http.Request, next caddyhttp.Handler) error {
+	buf := active.Load()
+	if buf == nil || r.Header.Get(errorpage.ProbeHeader) != "" {
+		return next.ServeHTTP(w, r)
+	}
+	limit := c.MaxBody
+	if limit <= 0 {
+		limit = DefaultMaxBody
+	}
+
+	start := time.Now()
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	rec := Record{
+		Time:           start,
+		Project:        c.Project,
+		Service:        c.Service,
+		Method:         r.Method,
+		URL:            scheme + "://" + r.Host + r.URL.RequestURI(),
+		Proto:          r.Proto,
+		RemoteAddr:     r.RemoteAddr,
+		RequestHeaders: r.Header.Clone(),
+	}
+	if id, err := strconv.ParseUint(r.Header.Get(ReplayHeader), 10, 64); err == nil {
+		rec.ReplayOf = id
+	}
+
+	reqBody := &bodyCapture{limit: limit}
+	if r.Body != nil && r.Body != http.NoBody {
+		r.Body = &teeBody{ReadCloser: r.Body, capture: reqBody}
+	}
+	rw := &captureWriter{
+		ResponseWriterWrapper: &caddyhttp.ResponseWriterWrapper{ResponseWriter: w},
+		body:                  bodyCapture{limit: limit},
+		start:                 start,
+	}
+
+	err := next.ServeHTTP(rw, r)
+
+	rec.Duration = time.Since(start)
+	rec.RequestBody = reqBody.result()
+	rec.Status, rec.ResponseHeaders, rec.Wait = rw.status, rw.header, rw.wait
+	rec.ResponseBody = rw.body.result()
+	if err != nil {
+		rec.Error = err.Error()
+		if rec.Status == 0 {
+			// Caddy writes the error response after this handler returns.
+			rec.Status = http.StatusInternalServerError
+			var he caddyhttp.HandlerError
+			if errors.As(err, &he) && he.StatusCode != 0 {
+				rec.Status = he.StatusCode
+			}
+		}
+	}
+	buf.Add(rec)
+	return err
+}
+
+// Interface guard.
+var _ caddyhttp.MiddlewareHandler = (*Capture)(nil)
+
+// bodyCapture keeps the first limit bytes written to it and counts the
+// rest. It is locked because a proxy may still be sending the request body
+// when the response is complete.
+type bodyCapture struct {
+	mu    sync.Mutex
+	limit int
+	buf   bytes.Buffer
+	size  int64
+}
+
+func (b *bodyCapture) write(p []byte) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.size += int64(len(p))
+	if room := b.limit - b.buf.Len(); room > 0 {
+		b.buf.Write(p[:min(room, len(p))])
+	}
+}
+
+func (b *bodyCapture) result() Body {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	body := Body{Size: b.size, Truncated: b.size > int64(b.buf.Len())}
+	data := b.buf.Bytes()
+	if body.Truncated {
+		// Don't split a multi-byte character at the cut.
+		for i := 0; i < utf8.UTFMax && len(data) > 0 && !utf8.Valid(data); i++ {
+			data = data[:len(data)-1]
+		}
+	}
+	if utf8.Valid(data) {
+		body.Text = string(data)
+	} else {
+		body.Text = base64.StdEncoding.EncodeToString(b.buf.Bytes())
+		body.Encoding = "base64"
+	}
+	return body
+}
+
+// teeBody captures a request body as the handlers read it.
+type teeBody struct {
+	io.ReadCloser
+	capture *bodyCapture
+}
+
+func (t *teeBody) Read(p []byte) (int, error) {
+	n, err := t.ReadCloser.Read(p)
+	t.capture.write(p[:n])
+	return n, err
+}
+
+// captureWriter records the status, headers and body of a response.
+type captureWriter struct {
+	*caddyhttp.ResponseWriterWrapper
+	body   bodyCapture
+	start  time.Time
+	status int
+	header http.Header
+	wait   time.Duration
+}
+
+func (w *captureWriter) WriteHeader(status int) {
+	// Informational responses other than 101 precede the real one.
+	if w.status == 0 && (status >= 200 || status == http.StatusSwitchingProtocols) {
+		w.status = status
+		w.header = w.Header().Clone()
+		w.wait = time.Since(w.start)
+	}
+	w.ResponseWriterWrapper.WriteHeader(status)
+}
+
+func (w *captureWriter) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+	n, err := w.ResponseWriterWrapper.Write(p)
+	w.body.write(p[:n])
+	return n, err
+}
+
+// ReadFrom copies through Write so the body is captured; the embedded
+// ReadFrom would bypass it.
+func (w *captureWriter) ReadFrom(r io.Reader) (int64, error) {
+	return io.Copy(writerOnly{w}, r)
+}
+
+// writerOnly hides a writer's ReadFrom from io.Copy.
+type writerOnly struct{ io.Writer }
@@ -0,0 +1,123 @@
+package inspect
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/caddyserver/caddy/v2/modules/caddyhttp"
+)
+
+// serve runs a Capture handler in front of next with a fresh buffer and
+// returns the single record it captured.
+func serve(t *testing.T, c *Capture, req *http.Request, next caddyhttp.HandlerFunc) (Record, *httptest.ResponseRecorder, error) {
+	t.Helper()
+	buf := NewBuffer(10)
+	SetBuffer(buf)
+	t.Cleanup(func() { SetBuffer(nil) })
+
+	w := httptest.NewRecorder()
+	err := c.ServeHTTP(w, req, next)
+	list := buf.List(Filter{})
+	if len(list) != 1 {
+		t.Fatalf("expected 1 record, got %d", len(list))
+	}
+	return list[0], w, err
+}
+
+func TestCapture_RecordsExchange(t *testing.T) {
+	req := httptest.NewRequest("POST", "https://web.test/api/items?x=1", strings.NewReader(`{"name":"a"}`))
+	req.Header.Set("Content-Type", "application/json")
+
+	rec, w, err := serve(t, &Capture{Project: "web", Service: "app"}, req, func(w http.ResponseWriter, r *http.Request) error {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "text/plain")
+		w.WriteHeader(http.StatusCreated)
+		io.Copy(w, strings.NewReader("got "+string(body))) // exercises ReadFrom
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w.Body.String() != `got {"name":"a"}` {
+		t.Errorf("response not passed through: %q", w.Body.String())
+	}
+
+	if rec.Project != "web" || rec.Service != "app" || rec.Method != "POST" {
+		t.Errorf("unexpected record %+v", rec)
+	}
+	if rec.URL != "https://web.test/api/items?x=1" {
+		t.Errorf("unexpected URL %q", rec.URL)
+	}
+	if rec.RequestHeaders.Get("Content-Type") != "application/json" {
+		t.Errorf("request headers not captured: %v", rec.RequestHeaders)
+	}
+	if rec.RequestBody.Text != `{"name":"a"}` || rec.RequestBody.Size != 12 {
+		t.Errorf("unexpected request body %+v", rec.RequestBody)
+	}
+	if rec.Status != http.StatusCreated || rec.ResponseHeaders.Get("Content-Type") != "text/plain" {
+		t.Errorf("unexpected response %d %v", rec.Status, rec.ResponseHeaders)
+	}
+	if rec.ResponseBody.Text != `got {"name":"a"}` {
+		t.Errorf("unexpected response body %+v", rec.ResponseBody)
+	}
+	if rec.Duration <= 0 || rec.Wait > rec.Duration {
+		t.Errorf("unexpected timings wait=%s duration=%s", rec.Wait, rec.Duration)
+	}
+}
+
+func TestCapture_TruncatesBodies(t *testing.T) {
+	req := httptest.NewRequest("GET", "https://web.test/", nil)
+	rec, _, _ := serve(t, &Capture{MaxBody: 2}, req, func(w http.ResponseWriter, r *http.Request) error {
+		w.Write([]byte("héllo world")) // the cut falls inside "é"
+		return nil
+	})
+
+	body := rec.ResponseBody
+	if body.Text != "h" || !body.Truncated || body.Size != 12 || body.Encoding != "" {
+		t.Errorf("unexpected truncated body %+v", body)
+	}
+	if rec.Status != http.StatusOK {
+		t.Errorf("expected implicit 200, got %d", rec.Status)
+	}
+}
+
+func TestCapture_BinaryBody(t *testing.T) {
+	req := httptest.NewRequest("GET", "https://web.test/img", nil)
+	rec, _, _ := serve(t, &Capture{}, req, func(w http.ResponseWriter, r *http.Request) error {
+		w.Write([]byte{0xff, 0xd8, 0xff})
+		return nil
+	})
+	if rec.ResponseBody.Encoding != "base64" || rec.ResponseBody.Text != "/9j/" {
+		t.Errorf("unexpected binary body %+v", rec.ResponseBody)
+	}
+}
+
+func TestCapture_HandlerError(t *testing.T) {
+	req := httptest.NewRequest("GET", "https://web.test/", nil)
+	rec, _, err := serve(t, &Capture{}, req, func(w http.ResponseWriter, r *http.Request) error {
+		return caddyhttp.Error(http.StatusBadGateway, errors.New("dial tcp: connection refused"))
+	})
+	if err == nil {
+		t.Fatal("expected the handler error to be returned")
+	}
+	if rec.Status != http.StatusBadGateway || !strings.Contains(rec.Error, "connection refused") {
+		t.Errorf("unexpected record status=%d error=%q", rec.Status, rec.Error)
+	}
+}
+
+func TestCapture_NoBuffer(t *testing.T) {
+	SetBuffer(nil)
+	called := false
+	err := (&Capture{}).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil),
+		caddyhttp.HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+			called = true
+			return nil
+		}))
+	if err != nil || !called {
+		t.Errorf("expected pass-through, got called=%v err=%v", called, err)
+	}
+}
@@ -0,0 +1,208 @@
+package inspect
+
+import (
+	"net/http"
+	"net/url"
+	"sort"
+	"time"
+)
+
+// HAR is an HTTP Archive (version 1.2) document, as imported by browser
+// developer tools and HTTP clients.
+type HAR struct {
+	Log HARLog `json:"log"`
+}
+
+// HARLog is the root of a HAR document.
+type HARLog struct {
+	Version string     `json:"version"`
+	Creator HARCreator `json:"creator"`
+	Entries []HAREntry `json:"entries"`
+}
+
+// HARCreator names the application that wrote a HAR document.
+type HARCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// HAREntry is one request and response.
+type HAREntry struct {
+	StartedDateTime string      `json:"startedDateTime"`
+	Time            float64     `json:"time"` // total milliseconds
+	Request         HARRequest  `json:"request"`
+	Response        HARResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         HARTimings  `json:"timings"`
+	Comment         string      `json:"comment,omitempty"`
+}
+
+// HARRequest is the request half of an entry.
+type HARRequest struct {
+	Method      string         `json:"method"`
+	URL         string         `json:"url"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []HARNameValue `json:"cookies"`
+	Headers     []HARNameValue `json:"headers"`
+	QueryString []HARNameValue `json:"queryString"`
+	PostData    *HARPostData   `json:"postData,omitempty"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int64          `json:"bodySize"`
+}
+
+// HARResponse is the response half of an entry.
+type HARResponse struct {
+	Status      int            `json:"status"`
+	StatusText  string         `json:"statusText"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []HARNameValue `json:"cookies"`
+	Headers     []HARNameValue `json:"headers"`
+	Content     HARContent     `json:"content"`
+	RedirectURL string         `json:"redirectURL"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int64          `json:"bodySize"`
+}
+
+// HARNameValue is a header, cookie or query parameter.
+type HARNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// HARPostData is a request body.
+type HARPostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+	Encoding string `json:"encoding,omitempty"`
+	Comment  string `json:"comment,omitempty"`
+}
+
+// HARContent is a response body.
+type HARContent struct {
+	Size     int64  `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text,omitempty"`
+	Encoding string `json:"encoding,omitempty"`
+	Comment  string `json:"comment,omitempty"`
+}
+
+// HARTimings splits an entry's time. Hatch measures only the wait for the
+// response header and the time to receive the body.
+type HARTimings struct {
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+}
+
+// truncatedComment marks bodies cut at the capture limit.
+const truncatedComment = "truncated by hatch"
+
+// NewHAR converts records to a HAR document, oldest first.
+func NewHAR(records []Record, version string) HAR {
+	sorted := append([]Record(nil), records...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].ID < sorted[j].ID })
+
+	entries := make([]HAREntry, 0, len(sorted))
+	for _, rec := range sorted {
+		entries = append(entries, harEntry(rec))
+	}
+	return HAR{Log: HARLog{
+		Version: "1.2",
+		Creator: HARCreator{Name: "hatch", Version: version},
+		Entries: entries,
+	}}
+}
+
+func harEntry(rec Record) HAREntry {
+	req := HARRequest{
+		Method:      rec.Method,
+		URL:         rec.URL,
+		HTTPVersion: rec.Proto,
+		Cookies:     harCookies((&http.Request{Header: rec.RequestHeaders}).Cookies()),
+		Headers:     harHeaders(rec.RequestHeaders),
+		QueryString: []HARNameValue{},
+		HeadersSize: -1,
+		BodySize:    rec.RequestBody.Size,
+	}
+	if u, err := url.Parse(rec.URL); err == nil {
+		req.QueryString = harValues(u.Query())
+	}
+	if rec.RequestBody.Size > 0 {
+		req.PostData = &HARPostData{
+			MimeType: rec.RequestHeaders.Get("Content-Type"),
+			Text:     rec.RequestBody.Text,
+			Encoding: rec.RequestBody.Encoding,
+		}
+		if rec.RequestBody.Truncated {
+			req.PostData.Comment = truncatedComment
+		}
+	}
+
+	resp := HARResponse{
+		Status:      rec.Status,
+		StatusText:  http.StatusText(rec.Status),
+		HTTPVersion: rec.Proto,
+		Cookies:     harCookies((&http.Response{Header: rec.ResponseHeaders}).Cookies()),
+		Headers:     harHeaders(rec.ResponseHeaders),
+		Content: HARContent{
+			Size:     rec.ResponseBody.Size,
+			MimeType: rec.ResponseHeaders.Get("Content-Type"),
+			Text:     rec.ResponseBody.Text,
+			Encoding: rec.ResponseBody.Encoding,
+		},
+		RedirectURL: rec.ResponseHeaders.Get("Location"),
+		HeadersSize: -1,
+		BodySize:    rec.ResponseBody.Size,
+	}
+	if rec.ResponseBody.Truncated {
+		resp.Content.Comment = truncatedComment
+	}
+
+	comment := rec.Project
+	if rec.Service != "" {
+		comment += "/" + rec.Service
+	}
+	return HAREntry{
+		StartedDateTime: rec.Time.Format(time.RFC3339Nano),
+		Time:            ms(rec.Duration),
+		Request:         req,
+		Response:        resp,
+		Timings: HARTimings{
+			Wait:    ms(rec.Wait),
+			Receive: ms(rec.Duration - rec.Wait),
+		},
+		Comment: comment,
+	}
+}
+
+func ms(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// harHeaders flattens headers, sorted by name for stable output.
+func harHeaders(h http.Header) []HARNameValue {
+	return harValues(url.Values(h))
+}
+
+func harValues(values map[string][]string) []HARNameValue {
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	out := []HARNameValue{}
+	for _, name := range names {
+		for _, v := range values[name] {
+			out = append(out, HARNameValue{Name: name, Value: v})
+		}
+	}
+	return out
+}
+
+func harCookies(cookies []*http.Cookie) []HARNameValue {
+	out := []HARNameValue{}
+	for _, c := range cookies {
+		out = append(out, HARNameValue{Name: c.Name, Value: c.Value})
+	}
+	return out
+}
@@ -0,0 +1,89 @@
+package inspect
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestNewHAR(t *testing.T) {
+	start := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	records := []Record{
+		{
+			ID: 2, Time: start.Add(time.Second), Project: "web", Method: "GET",
+			URL: "https://web.test/b", Proto: "HTTP/2.0", Status: 302,
+			RequestHeaders:  http.Header{},
+			ResponseHeaders: http.Header{"Location": {"/login"}, "Set-Cookie": {"sid=abc; Path=/"}},
+		},
+		{
+			ID: 1, Time: start, Project: "web", Service: "api", Method: "POST",
+			URL: "https://web.test/a?q=1&q=2", Proto: "HTTP/1.1", Status: 200,
+			RequestHeaders:  http.Header{"Content-Type": {"application/json"}, "Cookie": {"theme=dark"}},
+			RequestBody:     Body{Text: `{"a":1}`, Size: 7},
+			ResponseHeaders: http.Header{"Content-Type": {"text/html"}},
+			ResponseBody:    Body{Text: "<p>", Size: 100, Truncated: true},
+			Wait:            20 * time.Millisecond,
+			Duration:        25 * time.Millisecond,
+		},
+	}
+
+	har := NewHAR(records, "1.2.3")
+	if har.Log.Version != "1.2" || har.Log.Creator.Version != "1.2.3" {
+		t.Errorf("unexpected log header %+v", har.Log)
+	}
+	if len(har.Log.Entries) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(har.Log.Entries))
+	}
+
+	post := har.Log.Entries[0] // oldest first
+	if post.Request.Method != "POST" || post.Comment != "web/api" {
+		t.Errorf("entries not sorted oldest first: %+v", post)
+	}
+	if post.StartedDateTime != "2026-03-01T12:00:00Z" || post.Time != 25 {
+		t.Errorf("unexpected timing fields %q %v", post.StartedDateTime, post.Time)
+	}
+	if post.Timings.Wait != 20 || post.Timings.Receive != 5 {
+		t.Errorf("unexpected timings %+v", post.Timings)
+	}
+	if len(post.Request.QueryString) != 2 || post.Request.QueryString[1].Value != "2" {
+		t.Errorf("unexpected query string %+v", post.Request.QueryString)
+	}
+	if len(post.Request.Cookies) != 1 || post.Request.Cookies[0].Name != "theme" {
+		t.Errorf("unexpected request cookies %+v", post.Request.Cookies)
+	}
+	if post.Request.PostData == nil || post.Request.PostData.MimeType != "application/json" || post.Request.PostData.Text != `{"a":1}` {
+		t.Errorf("unexpected post data %+v", post.Request.PostData)
+	}
+	if post.Response.Content.Comment != truncatedComment || post.Response.Content.Size != 100 {
+		t.Errorf("expected truncated content, got %+v", post.Response.Content)
+	}
+
+	redirect := har.Log.Entries[1]
+	if redirect.Request.PostData != nil {
+		t.Error("expected no post data for a bodyless GET")
+	}
+	if redirect.Response.RedirectURL != "/login" || redirect.Response.StatusText != "Found" {
+		t.Errorf("unexpected redirect response %+v", redirect.Response)
+	}
+	if len(redirect.Response.Cookies) != 1 || redirect.Response.Cookies[0].Value != "abc" {
+		t.Errorf("unexpected response cookies %+v", redirect.Response.Cookies)
+	}
+
+	// Required arrays must marshal as [], not null.
+	data, err := json.Marshal(har)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var raw struct {
+		Log struct {
+			Entries []struct {
+				Request map[string]any `json:"request"`
+			} `json:"entries"`
+		} `json:"log"`
+	}
+	json.Unmarshal(data, &raw)
+	if raw.Log.Entries[1].Request["headers"] == nil {
+		t.Error("expected empty headers to marshal as an array")
+	}
+}
@@ -0,0 +1,189 @@
+// Package inspect captures proxied HTTP traffic for browsing and export.
+//
+// Projects with capture enabled get a hatch_capture handler at the front
+// of their Caddy routes. It records each request and response, with
+// bodies truncated to a limit, into the Buffer installed with SetBuffer:
+// a bounded ring that keeps the most recent records.
+package inspect
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultBufferSize is how many records the daemon's buffer keeps.
+	DefaultBufferSize = 500
+	// DefaultMaxBody is how many bytes of each body are kept.
+	DefaultMaxBody = 16 << 10
+)
+
+// Record is one captured request and its response.
+type Record struct {
+	ID              uint64        `json:"id"`
+	Time            time.Time     `json:"time"`
+	Project         string        `json:"project"`
+	Service         string        `json:"service"`
+	Method          string        `json:"method"`
+	URL             string        `json:"url"`
+	Proto           string        `json:"proto"`
+	RemoteAddr      string        `json:"remote_addr"`
+	RequestHeaders  http.Header   `json:"request_headers"`
+	RequestBody     Body          `json:"request_body"`
+	Status          int           `json:"status"`
+	ResponseHeaders http.Header   `json:"response_headers"`
+	ResponseBody    Body          `json:"response_body"`
+	Wait            time.Duration `json:"wait"`     // until the response header was written
+	Duration        time.Duration `json:"duration"` // until the response was complete
+	Error           string        `json:"error,omitempty"`
+	ReplayOf        uint64        `json:"replay_of,omitempty"` // ID of the replayed record
+}
+
+// Body is a captured message body. Text holds at most the body limit;
+// non-UTF-8 bodies are base64 encoded, with Encoding set to "base64".
+type Body struct {
+	Text      string `json:"text,omitempty"`
+	Encoding  string `json:"encoding,omitempty"`
+	Size      int64  `json:"size"` // full size, including any truncated part
+	Truncated bool   `json:"truncated,omitempty"`
+}
+
+// Filter selects records. Zero fields match everything.
+type Filter struct {
+	Project string
+	Method  string
+	Status  StatusFilter
+	Limit   int // most recent records returned; 0 for all
+}
+
+// Buffer is a bounded, concurrency-safe ring of records.
+type Buffer struct {
+	mu      sync.Mutex
+	records []Record // ring storage
+	next    int      // index of the next write
+	full    bool
+	lastID  uint64
+}
+
+// NewBuffer returns a Buffer holding up to size records.
+func NewBuffer(size int) *Buffer {
+	if size <= 0 {
+		size = DefaultBufferSize
+	}
+	return &Buffer{records: make([]Record, size)}
+}
+
+// Add stores rec, evicting the oldest record when full, and returns it
+// with its assigned ID.
+func (b *Buffer) Add(rec Record) Record {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.lastID++
+	rec.ID = b.lastID
+	b.records[b.next] = rec
+	b.next = (b.next + 1) % len(b.records)
+	if b.next == 0 {
+		b.full = true
+	}
+	return rec
+}
+
+// List returns the records matching f, newest first.
+func (b *Buffer) List(f Filter) []Record {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	out := []Record{}
+	n := b.next
+	if b.full {
+		n = len(b.records)
+	}
+	for i := range n {
+		rec := b.records[(b.next-1-i+len(b.records))%len(b.records)]
+		if !f.match(rec) {
+			continue
+		}
+		out = append(out, rec)
+		if f.Limit > 0 && len(out) == f.Limit {
+			break
+		}
+	}
+	return out
+}
+
+// Get returns the record with the given ID, if still buffered.
+func (b *Buffer) Get(id uint64) (Record, bool) {
+	for _, rec := range b.List(Filter{}) {
+		if rec.ID == id {
+			return rec, true
+		}
+	}
+	return Record{}, false
+}
+
+// Clear removes all records. IDs keep increasing.
+func (b *Buffer) Clear() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	clear(b.records)
+	b.next = 0
+	b.full = false
+}
+
+func (f Filter) match(rec Record) bool {
+	if f.Project != "" && rec.Project != f.Project {
+		return false
+	}
+	if f.Method != "" && !strings.EqualFold(rec.Method, f.Method) {
+		return false
+	}
+	return f.Status.Match(rec.Status)
+}
+
+// StatusFilter matches response status codes: an exact code such as 404,
+// or a class such as 5xx. The zero value matches everything.
+type StatusFilter struct {
+	code  int
+	class int
+}
+
+// ParseStatusFilter parses "404", "5xx" and the like.
+func ParseStatusFilter(s string) (StatusFilter, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "" {
+		return StatusFilter{}, nil
+	}
+	if len(s) == 3 && s[1:] == "xx" && s[0] >= '1' && s[0] <= '5' {
+		return StatusFilter{class: int(s[0] - '0')}, nil
+	}
+	code, err := strconv.Atoi(s)
+	if err != nil || code < 100 || code > 599 {
+		return StatusFilter{}, fmt.Errorf("status must be a code such as 404 or a class such as 5xx, got %q", s)
+	}
+	return StatusFilter{code: code}, nil
+}
+
+// Match reports whether status passes the filter.
+func (f StatusFilter) Match(status int) bool {
+	switch {
+	case f.code != 0:
+		return status == f.code
+	case f.class != 0:
+		return status/100 == f.class
+	}
+	return true
+}
+
+// String returns the filter in the form ParseStatusFilter accepts.
+func (f StatusFilter) String() string {
+	switch {
+	case f.code != 0:
+		return strconv.Itoa(f.code)
+	case f.class != 0:
+		return strconv.Itoa(f.class) + "xx"
+	}
+	return ""
+}
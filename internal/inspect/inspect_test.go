@@ -0,0 +1,118 @@
+package inspect
+
+import (
+	"testing"
+)
+
+func TestBuffer_RingEvictsOldest(t *testing.T) {
+	b := NewBuffer(3)
+	for i := range 5 {
+		rec := b.Add(Record{Status: 200 + i})
+		if rec.ID != uint64(i+1) {
+			t.Fatalf("expected ID %d, got %d", i+1, rec.ID)
+		}
+	}
+
+	list := b.List(Filter{})
+	if len(list) != 3 {
+		t.Fatalf("expected 3 records, got %d", len(list))
+	}
+	for i, want := range []uint64{5, 4, 3} {
+		if list[i].ID != want {
+			t.Errorf("list[%d].ID = %d, want %d", i, list[i].ID, want)
+		}
+	}
+	if _, ok := b.Get(2); ok {
+		t.Error("expected record 2 to be evicted")
+	}
+	if rec, ok := b.Get(4); !ok || rec.Status != 203 {
+		t.Errorf("Get(4) = %+v, %v", rec, ok)
+	}
+}
+
+func TestBuffer_Filter(t *testing.T) {
+	b := NewBuffer(10)
+	b.Add(Record{Project: "web", Method: "GET", Status: 200})
+	b.Add(Record{Project: "web", Method: "POST", Status: 502})
+	b.Add(Record{Project: "api", Method: "GET", Status: 500})
+	b.Add(Record{Project: "web", Method: "GET", Status: 404})
+
+	fivexx, _ := ParseStatusFilter("5xx")
+	tests := []struct {
+		name   string
+		filter Filter
+		want   []uint64
+	}{
+		{"all", Filter{}, []uint64{4, 3, 2, 1}},
+		{"project", Filter{Project: "web"}, []uint64{4, 2, 1}},
+		{"method", Filter{Method: "get"}, []uint64{4, 3, 1}},
+		{"status", Filter{Status: fivexx}, []uint64{3, 2}},
+		{"combined", Filter{Project: "web", Status: fivexx}, []uint64{2}},
+		{"limit", Filter{Limit: 2}, []uint64{4, 3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			list := b.List(tt.filter)
+			var ids []uint64
+			for _, rec := range list {
+				ids = append(ids, rec.ID)
+			}
+			if len(ids) != len(tt.want) {
+				t.Fatalf("got IDs %v, want %v", ids, tt.want)
+			}
+			for i := range ids {
+				if ids[i] != tt.want[i] {
+					t.Fatalf("got IDs %v, want %v", ids, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestBuffer_Clear(t *testing.T) {
+	b := NewBuffer(2)
+	b.Add(Record{})
+	b.Add(Record{})
+	b.Clear()
+	if list := b.List(Filter{}); len(list) != 0 {
+		t.Fatalf("expected no records after Clear, got %d", len(list))
+	}
+	if rec := b.Add(Record{}); rec.ID != 3 {
+		t.Errorf("expected IDs to continue at 3, got %d", rec.ID)
+	}
+}
+
+func TestParseStatusFilter(t *testing.T) {
+	tests := []struct {
+		in      string
+		match   []int
+		noMatch []int
+	}{
+		{"", []int{200, 404, 503}, nil},
+		{"5xx", []int{500, 503}, []int{200, 404}},
+		{"4XX", []int{400, 499}, []int{500}},
+		{"404", []int{404}, []int{400, 500}},
+	}
+	for _, tt := range tests {
+		f, err := ParseStatusFilter(tt.in)
+		if err != nil {
+			t.Fatalf("ParseStatusFilter(%q): %v", tt.in, err)
+		}
+		for _, code := range tt.match {
+			if !f.Match(code) {
+				t.Errorf("%q should match %d", tt.in, code)
+			}
+		}
+		for _, code := range tt.noMatch {
+			if f.Match(code) {
+				t.Errorf("%q should not match %d", tt.in, code)
+			}
+		}
+	}
+
+	for _, bad := range []string{"6xx", "abc", "99", "5x"} {
+		if _, err := ParseStatusFilter(bad); err == nil {
+			t.Errorf("ParseStatusFilter(%q): expected an error", bad)
+		}
+	}
+}
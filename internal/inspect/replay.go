@@ -0,0 +1,283 @@
+package inspect
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ReplayHeader is set on replayed requests to the ID of the original, so
+// a capture of the replay can be traced back to it.
+const ReplayHeader = "X-Hatch-Replay"
+
+// ErrTruncatedBody is returned by Replay when the original request body
+// was cut at the capture limit and no replacement body was given.
+var ErrTruncatedBody = errors.New("request body was truncated when captured; supply the body to replay it")
+
+// Edit changes a request before it is replayed. Zero fields keep the
+// original.
+type Edit struct {
+	Method string `json:"method,omitempty"`
+	// Headers replaces the named headers; an empty value removes one.
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    *string           `json:"body,omitempty"`
+}
+
+// ReplayResult pairs a captured request with its replay.
+type ReplayResult struct {
+	Original Record     `json:"original"`
+	Replay   Record     `json:"replay"`
+	Diff     []DiffLine `json:"diff"`
+}
+
+// hopHeaders are connection-specific and set by the transport.
+var hopHeaders = []string{
+	"Connection", "Content-Length", "Keep-Alive", "Proxy-Connection",
+	"Te", "Trailer", "Transfer-Encoding", "Upgrade",
+}
+
+// NewReplayRequest rebuilds the request captured in rec, with edit
+// applied.
+func NewReplayRequest(ctx context.Context, rec Record, edit Edit) (*http.Request, error) {
+	method := rec.Method
+	if edit.Method != "" {
+		method = strings.ToUpper(edit.Method)
+	}
+
+	var body []byte
+	switch {
+	case edit.Body != nil:
+		body = []byte(*edit.Body)
+	case rec.RequestBody.Truncated:
+		return nil, ErrTruncatedBody
+	default:
+		var err error
+		if body, err = rec.RequestBody.bytes(); err != nil {
+			return nil, err
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, rec.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("rebuild request: %w", err)
+	}
+	if len(body) == 0 {
+		req.Body, req.ContentLength = http.NoBody, 0
+	}
+	req.Header = rec.RequestHeaders.Clone()
+	if req.Header == nil {
+		req.Header = http.Header{}
+	}
+	for _, name := range hopHeaders {
+		req.Header.Del(name)
+	}
+	for name, v := range edit.Headers {
+		if v == "" {
+			req.Header.Del(name)
+		} else {
+			req.Header.Set(name, v)
+		}
+	}
+	req.Header.Set(ReplayHeader, strconv.FormatUint(rec.ID, 10))
+	return req, nil
+}
+
+// Replay re-sends the request captured in rec with client and records the
+// exchange. Redirects are not followed. The returned record has no ID; if
+// the route captures traffic, the replay is also added to the buffer.
+func Replay(ctx context.Context, client *http.Client, rec Record, edit Edit) (Record, error) {
+	req, err := NewReplayRequest(ctx, rec, edit)
+	if err != nil {
+		return Record{}, err
+	}
+	reqBody := &bodyCapture{limit: DefaultMaxBody}
+	if req.Body != http.NoBody {
+		req.Body = &teeBody{ReadCloser: req.Body, capture: reqBody}
+	}
+
+	out := Record{
+		Time:           time.Now(),
+		Project:        rec.Project,
+		Service:        rec.Service,
+		Method:         req.Method,
+		URL:            rec.URL,
+		RequestHeaders: req.Header.Clone(),
+		ReplayOf:       rec.ID,
+	}
+	c := *client
+	c.CheckRedirect = func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+	resp, err := c.Do(req)
+	if err != nil {
+		return Record{}, fmt.Errorf("replay request %d: %w", rec.ID, err)
+	}
+	defer resp.Body.Close()
+	out.Wait = time.Since(out.Time)
+
+	respBody := &bodyCapture{limit: DefaultMaxBody}
+	if _, err := io.Copy(writerFunc(respBody.write), resp.Body); err != nil {
+		out.Error = err.Error()
+	}
+	out.Duration = time.Since(out.Time)
+	out.Proto = resp.Proto
+	out.Status = resp.StatusCode
+	out.ResponseHeaders = resp.Header
+	out.RequestBody = reqBody.result()
+	out.ResponseBody = respBody.result()
+	return out, nil
+}
+
+// writerFunc adapts a capture's write method to io.Writer.
+type writerFunc func([]byte)
+
+func (f writerFunc) Write(p []byte) (int, error) {
+	f(p)
+	return len(p), nil
+}
+
+// bytes returns the captured body, decoding base64.
+func (b Body) bytes() ([]byte, error) {
+	if b.Encoding == "base64" {
+		data, err := base64.StdEncoding.DecodeString(b.Text)
+		if err != nil {
+			return nil, fmt.Errorf("decode body: %w", err)
+		}
+		return data, nil
+	}
+	return []byte(b.Text), nil
+}
+
+// DiffLine is one line of a response diff. Op is "-" for a line only in
+// the original, "+" for one only in the replay and " " for both.
+type DiffLine struct {
+	Op   string `json:"op"`
+	Text string `json:"text"`
+}
+
+// volatileHeaders change on every response and are left out of diffs.
+var volatileHeaders = map[string]bool{"Date": true}
+
+// DiffResponses compares the responses of two records line by line: the
+// status line, the headers sorted by name, then the body.
+func DiffResponses(a, b Record) []DiffLine {
+	return diffLines(responseLines(a), responseLines(b))
+}
+
+func responseLines(rec Record) []string {
+	lines := []string{strings.TrimSpace(fmt.Sprintf("%d %s", rec.Status, http.StatusText(rec.Status)))}
+	names := make([]string, 0, len(rec.ResponseHeaders))
+	for name := range rec.ResponseHeaders {
+		if !volatileHeaders[name] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, v := range rec.ResponseHeaders[name] {
+			lines = append(lines, name+": "+v)
+		}
+	}
+
+	body := rec.ResponseBody
+	if body.Size == 0 {
+		return lines
+	}
+	lines = append(lines, "")
+	if body.Encoding == "base64" {
+		return append(lines, fmt.Sprintf("(binary body, %d bytes)", body.Size))
+	}
+	lines = append(lines, strings.Split(strings.TrimRight(body.Text, "\n"), "\n")...)
+	if body.Truncated {
+		lines = append(lines, fmt.Sprintf("(truncated at %d of %d bytes)", len(body.Text), body.Size))
+	}
+	return lines
+}
+
+// maxDiffCells bounds the LCS table diffLines builds, to about 8 MB: a
+// 16 KiB body can have some 16k lines, and a full table for two of those
+// would take gigabytes.
+const maxDiffCells = 1 << 20
+
+// diffLines returns a line diff of a and b: their common prefix and
+// suffix, and a minimal diff of the lines in between from their longest
+// common subsequence. When those are too many to compare, they are shown
+// as all removed and all added instead.
+func diffLines(a, b []string) []DiffLine {
+	pre := 0
+	for pre < len(a) && pre < len(b) && a[pre] == b[pre] {
+		pre++
+	}
+	suf := 0
+	for suf < len(a)-pre && suf < len(b)-pre && a[len(a)-1-suf] == b[len(b)-1-suf] {
+		suf++
+	}
+
+	out := make([]DiffLine, 0, max(len(a), len(b)))
+	for _, line := range a[:pre] {
+		out = append(out, DiffLine{Op: " ", Text: line})
+	}
+	midA, midB := a[pre:len(a)-suf], b[pre:len(b)-suf]
+	if (len(midA)+1)*(len(midB)+1) > maxDiffCells {
+		for _, line := range midA {
+			out = append(out, DiffLine{Op: "-", Text: line})
+		}
+		for _, line := range midB {
+			out = append(out, DiffLine{Op: "+", Text: line})
+		}
+	} else {
+		out = append(out, lcsDiff(midA, midB)...)
+	}
+	for _, line := range a[len(a)-suf:] {
+		out = append(out, DiffLine{Op: " ", Text: line})
+	}
+	return out
+}
+
+// lcsDiff returns a minimal line diff of a and b from their longest
+// common subsequence, in O(len(a)*len(b)) time and space.
+func lcsDiff(a, b []string) []DiffLine {
+	// lcs[i][j] is the LCS length of a[i:] and b[j:].
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	out := make([]DiffLine, 0, max(len(a), len(b)))
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			out = append(out, DiffLine{Op: " ", Text: a[i]})
+			i, j = i+1, j+1
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			out = append(out, DiffLine{Op: "-", Text: a[i]})
+			i++
+		default:
+			out = append(out, DiffLine{Op: "+", Text: b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		out = append(out, DiffLine{Op: "-", Text: a[i]})
+	}
+	for ; j < len(b); j++ {
+		out = append(out, DiffLine{Op: "+", Text: b[j]})
+	}
+	return out
+}
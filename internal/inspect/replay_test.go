@@ -0,0 +1,142 @@
+package inspect
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestReplay_ResendsRequest(t *testing.T) {
+	var got *http.Request
+	var gotBody string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		b, _ := io.ReadAll(r.Body)
+		gotBody = string(b)
+		w.Header().Set("Location", "/next")
+		w.WriteHeader(http.StatusFound)
+		w.Write([]byte("moved"))
+	}))
+	defer srv.Close()
+
+	rec := Record{
+		ID:             7,
+		Project:        "web",
+		Method:         "POST",
+		URL:            srv.URL + "/hook?x=1",
+		RequestHeaders: http.Header{"X-Event": {"push"}, "Content-Length": {"99"}},
+		RequestBody:    Body{Text: "payload", Size: 7},
+	}
+	out, err := Replay(context.Background(), srv.Client(), rec, Edit{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got.Method != "POST" || got.URL.RequestURI() != "/hook?x=1" || gotBody != "payload" {
+		t.Errorf("unexpected request %s %s %q", got.Method, got.URL, gotBody)
+	}
+	if got.Header.Get("X-Event") != "push" || got.Header.Get(ReplayHeader) != "7" {
+		t.Errorf("unexpected headers %v", got.Header)
+	}
+	if out.Status != http.StatusFound || out.ResponseBody.Text != "moved" {
+		t.Errorf("redirect should be recorded, not followed: %d %+v", out.Status, out.ResponseBody)
+	}
+	if out.ReplayOf != 7 || out.Project != "web" || out.RequestBody.Text != "payload" {
+		t.Errorf("unexpected record %+v", out)
+	}
+}
+
+func TestReplay_Edit(t *testing.T) {
+	rec := Record{
+		ID:             1,
+		Method:         "POST",
+		URL:            "https://web.test/hook",
+		RequestHeaders: http.Header{"X-Signature": {"old"}, "X-Drop": {"1"}},
+		RequestBody:    Body{Text: "ab", Size: 100, Truncated: true},
+	}
+	if _, err := NewReplayRequest(context.Background(), rec, Edit{}); !errors.Is(err, ErrTruncatedBody) {
+		t.Fatalf("expected ErrTruncatedBody, got %v", err)
+	}
+
+	body := "new"
+	req, err := NewReplayRequest(context.Background(), rec, Edit{
+		Method:  "put",
+		Headers: map[string]string{"X-Signature": "new", "x-drop": ""},
+		Body:    &body,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := io.ReadAll(req.Body)
+	if req.Method != "PUT" || string(b) != "new" || req.ContentLength != 3 {
+		t.Errorf("unexpected request %s %q (%d)", req.Method, b, req.ContentLength)
+	}
+	if req.Header.Get("X-Signature") != "new" || req.Header.Get("X-Drop") != "" {
+		t.Errorf("unexpected headers %v", req.Header)
+	}
+}
+
+func TestReplay_DecodesBinaryBody(t *testing.T) {
+	rec := Record{Method: "POST", URL: "https://web.test/", RequestBody: Body{Text: "/w==", Encoding: "base64", Size: 1}}
+	req, err := NewReplayRequest(context.Background(), rec, Edit{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := io.ReadAll(req.Body)
+	if string(b) != "\xff" {
+		t.Errorf("unexpected body %x", b)
+	}
+}
+
+func TestDiffResponses(t *testing.T) {
+	a := Record{
+		Status:          500,
+		ResponseHeaders: http.Header{"Content-Type": {"text/plain"}, "Date": {"Mon"}},
+		ResponseBody:    Body{Text: "line one\nerror\nline three\n", Size: 25},
+	}
+	b := Record{
+		Status:          200,
+		ResponseHeaders: http.Header{"Content-Type": {"text/plain"}, "Date": {"Tue"}},
+		ResponseBody:    Body{Text: "line one\nok\nline three\n", Size: 22},
+	}
+
+	var lines []string
+	for _, l := range DiffResponses(a, b) {
+		lines = append(lines, l.Op+l.Text)
+	}
+	want := []string{
+		"-500 Internal Server Error",
+		"+200 OK",
+		" Content-Type: text/plain",
+		" ",
+		" line one",
+		"-error",
+		"+ok",
+		" line three",
+	}
+	if strings.Join(lines, "\n") != strings.Join(want, "\n") {
+		t.Errorf("unexpected diff:\n%s\nwant:\n%s", strings.Join(lines, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestDiffLines_Large(t *testing.T) {
+	// Two newline-heavy bodies too different to compare line by line.
+	a := strings.Split(strings.Repeat("a\n", 16000), "\n")
+	b := strings.Split(strings.Repeat("b\n", 16000), "\n")
+	a[0], b[0] = "same", "same"
+
+	diff := diffLines(a, b)
+	if len(diff) != len(a)+len(b)-2 {
+		t.Fatalf("expected the differing lines removed and added, got %d lines", len(diff))
+	}
+	if diff[0] != (DiffLine{Op: " ", Text: "same"}) || diff[1].Op != "-" || diff[len(diff)-2].Op != "+" {
+		t.Errorf("unexpected diff %v ... %v", diff[:2], diff[len(diff)-2:])
+	}
+	if last := diff[len(diff)-1]; last != (DiffLine{Op: " ", Text: ""}) {
+		t.Errorf("expected the common last line kept, got %v", last)
+	}
+}
@@ -0,0 +1,204 @@
+// Package mock serves canned responses for services whose backend does
+// not exist yet. The hatch_mock Caddy handler answers each request from
+// the first of its service's mock rules that matches, see
+// config.MockRule.
+package mock
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/template"
+
+	caddyv2 "github.com/caddyserver/caddy/v2"
+	"github.com/caddyserver/caddy/v2/modules/caddyhttp"
+
+	"github.com/paulrose/hatch/internal/config"
+)
+
+// Header marks mocked responses, naming the index of the rule that
+// answered, so that they are not mistaken for a real backend's.
+const Header = "X-Hatch-Mock"
+
+func init() {
+	caddyv2.RegisterModule(Handler{})
+}
+
+// Handler is the hatch_mock Caddy handler. Rule files must be absolute.
+type Handler struct {
+	Rules []config.MockRule `json:"rules,omitempty"`
+
+	compiled []rule
+}
+
+// rule is a config.MockRule ready to match requests.
+type rule struct {
+	config.MockRule
+	segments []string // path segments; {name} for parameters
+	rest     bool     // a final * matches the remaining segments
+	body     *template.Template
+}
+
+// CaddyModule returns the Caddy module information.
+func (Handler) CaddyModule() caddyv2.ModuleInfo {
+	return caddyv2.ModuleInfo{
+		ID:  "http.handlers.hatch_mock",
+		New: func() caddyv2.Module { return new(Handler) },
+	}
+}
+
+// Provision compiles the rules' path patterns and inline templates.
+func (h *Handler) Provision(caddyv2.Context) error {
+	h.compiled = make([]rule, 0, len(h.Rules))
+	for i, r := range h.Rules {
+		c, err := compile(r)
+		if err != nil {
+			return fmt.Errorf("mock rule %d: %w", i, err)
+		}
+		h.compiled = append(h.compiled, c)
+	}
+	return nil
+}
+
+func compile(r config.MockRule) (rule, error) {
+	c := rule{MockRule: r}
+	segments := strings.Split(strings.TrimPrefix(r.Path, "/"), "/")
+	if last := len(segments) - 1; segments[last] == "*" {
+		c.rest = true
+		segments = segments[:last]
+	}
+	c.segments = segments
+	if r.Template && r.File == "" {
+		t, err := template.New("body").Option("missingkey=zero").Parse(r.Body)
+		if err != nil {
+			return rule{}, err
+		}
+		c.body = t
+	}
+	return c, nil
+}
+
+// match reports whether the rule answers r, and with which parameters.
+func (c rule) match(r *http.Request) (map[string]string, bool) {
+	if c.Method != "" && c.Method != r.Method {
+		return nil, false
+	}
+	segments := strings.Split(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if len(segments) < len(c.segments) || (!c.rest && len(segments) != len(c.segments)) {
+		return nil, false
+	}
+	params := make(map[string]string)
+	for i, want := range c.segments {
+		got := segments[i]
+		if strings.HasPrefix(want, "{") && strings.HasSuffix(want, "}") {
+			if got == "" {
+				return nil, false
+			}
+			params[want[1:len(want)-1]] = got
+			continue
+		}
+		if got != want {
+			return nil, false
+		}
+	}
+	return params, true
+}
+
+// templateData is what a templated body is rendered with.
+type templateData struct {
+	Method string
+	Path   string
+	Params map[string]string
+	Query  url.Values
+}
+
+// ServeHTTP answers with the first matching rule, or 404 when none does.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request, _ caddyhttp.Handler) error {
+	for i, c := range h.compiled {
+		params, ok := c.match(r)
+		if !ok {
+			continue
+		}
+		body, err := c.render(templateData{Method: r.Method, Path: r.URL.Path, Params: params, Query: r.URL.Query()})
+		if err != nil {
+			return caddyhttp.Error(http.StatusInternalServerError, err)
+		}
+
+		for name, value := range c.Headers {
+			w.Header().Set(name, value)
+		}
+		if w.Header().Get("Content-Type") == "" && len(body) > 0 {
+			w.Header().Set("Content-Type", contentType(c.File, body))
+		}
+		w.Header().Set(Header, strconv.Itoa(i))
+		status := c.Status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		w.WriteHeader(status)
+		if r.Method != http.MethodHead {
+			w.Write(body)
+		}
+		return nil
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set(Header, "none")
+	w.WriteHeader(http.StatusNotFound)
+	fmt.Fprintf(w, "hatch mock: no rule matches %s %s\n", r.Method, r.URL.Path)
+	return nil
+}
+
+// render returns the rule's body. Files are read on every request, so
+// that edits show up without a reload.
+func (c rule) render(data templateData) ([]byte, error) {
+	tmpl := c.body
+	if c.File != "" {
+		raw, err := os.ReadFile(c.File)
+		if err != nil {
+			return nil, fmt.Errorf("mock body: %w", err)
+		}
+		if !c.Template {
+			return raw, nil
+		}
+		if tmpl, err = template.New(filepath.Base(c.File)).Option("missingkey=zero").Parse(string(raw)); err != nil {
+			return nil, fmt.Errorf("mock body: %w", err)
+		}
+	}
+	if tmpl == nil {
+		return []byte(c.Body), nil
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("mock body: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// contentType guesses the type of a body from its file's extension or,
+// for inline bodies, its content.
+func contentType(file string, body []byte) string {
+	if file != "" {
+		if t := mime.TypeByExtension(filepath.Ext(file)); t != "" {
+			return t
+		}
+	}
+	if json.Valid(body) {
+		return "application/json"
+	}
+	return http.DetectContentType(body)
+}
+
+// Interface guards.
+var (
+	_ caddyv2.Provisioner         = (*Handler)(nil)
+	_ caddyhttp.MiddlewareHandler = (*Handler)(nil)
+)
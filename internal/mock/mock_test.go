@@ -0,0 +1,112 @@
+package mock
+
+import (
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	caddyv2 "github.com/caddyserver/caddy/v2"
+
+	"github.com/paulrose/hatch/internal/config"
+)
+
+func newHandler(t *testing.T, rules ...config.MockRule) *Handler {
+	t.Helper()
+	h := &Handler{Rules: rules}
+	if err := h.Provision(caddyv2.Context{}); err != nil {
+		t.Fatal(err)
+	}
+	return h
+}
+
+func serve(t *testing.T, h *Handler, method, target string) *httptest.ResponseRecorder {
+	t.Helper()
+	w := httptest.NewRecorder()
+	if err := h.ServeHTTP(w, httptest.NewRequest(method, target, nil), nil); err != nil {
+		t.Fatal(err)
+	}
+	return w
+}
+
+func TestHandler_Rules(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "payments.json")
+	if err := os.WriteFile(file, []byte(`[{"id": 1}]`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	h := newHandler(t,
+		config.MockRule{Method: "GET", Path: "/api/payments", File: file},
+		config.MockRule{Method: "GET", Path: "/api/payments/{id}", Body: `{"id": "{{.Params.id}}", "page": "{{.Query.Get "page"}}"}`, Template: true},
+		config.MockRule{Method: "POST", Path: "/api/payments", Status: 201, Headers: map[string]string{"Location": "/api/payments/2"}},
+		config.MockRule{Path: "/api/payments/*", Status: 418, Body: "teapot"},
+	)
+
+	w := serve(t, h, "GET", "https://web.test/api/payments")
+	if w.Code != 200 || w.Body.String() != `[{"id": 1}]` || w.Header().Get("Content-Type") != "application/json" || w.Header().Get(Header) != "0" {
+		t.Errorf("unexpected file response %d %v %q", w.Code, w.Header(), w.Body.String())
+	}
+
+	w = serve(t, h, "GET", "https://web.test/api/payments/42?page=3")
+	if w.Body.String() != `{"id": "42", "page": "3"}` {
+		t.Errorf("expected the path parameter and query in the body, got %q", w.Body.String())
+	}
+
+	w = serve(t, h, "POST", "https://web.test/api/payments")
+	if w.Code != 201 || w.Header().Get("Location") != "/api/payments/2" || w.Body.Len() != 0 {
+		t.Errorf("unexpected created response %d %v %q", w.Code, w.Header(), w.Body.String())
+	}
+
+	// The catch-all matches any method and the rest of the path.
+	w = serve(t, h, "DELETE", "https://web.test/api/payments/42/refunds")
+	if w.Code != 418 || w.Body.String() != "teapot" || w.Header().Get(Header) != "3" {
+		t.Errorf("expected the catch-all rule, got %d %q", w.Code, w.Body.String())
+	}
+
+	w = serve(t, h, "GET", "https://web.test/api/orders")
+	if w.Code != 404 || w.Header().Get(Header) != "none" {
+		t.Errorf("expected 404 without a matching rule, got %d", w.Code)
+	}
+
+	// Files are read per request.
+	if err := os.WriteFile(file, []byte(`[]`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if w = serve(t, h, "GET", "https://web.test/api/payments"); w.Body.String() != "[]" {
+		t.Errorf("expected the edited file, got %q", w.Body.String())
+	}
+}
+
+func TestHandler_MissingFile(t *testing.T) {
+	h := newHandler(t, config.MockRule{Path: "/", File: filepath.Join(t.TempDir(), "missing.json")})
+	err := h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "https://web.test/", nil), nil)
+	if err == nil {
+		t.Error("expected an error for a missing body file")
+	}
+}
+
+func TestRule_Match(t *testing.T) {
+	tests := []struct {
+		pattern, path string
+		want          bool
+	}{
+		{"/", "/", true},
+		{"/", "/a", false},
+		{"/a/{id}", "/a/1", true},
+		{"/a/{id}", "/a/", false},
+		{"/a/{id}", "/a/1/b", false},
+		{"/a/*", "/a", true},
+		{"/a/*", "/a/b/c", true},
+		{"/a/*", "/b", false},
+	}
+	for _, tt := range tests {
+		c, err := compile(config.MockRule{Path: tt.pattern})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, got := c.match(httptest.NewRequest("GET", tt.path, nil)); got != tt.want {
+			t.Errorf("%s matching %s = %v, want %v", tt.pattern, tt.path, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,401 @@
+// Package process supervises the dev servers of services that have a
+// command. Each started service runs under the user's shell in its own
+// process group, with its output appended to a per-service log file, and
+// is restarted with exponential backoff whenever it exits until stopped.
+package process
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/paulrose/hatch/internal/config"
+	"github.com/paulrose/hatch/internal/health"
+)
+
+// States reported in Status.State.
+const (
+	StateStarting   = "starting"
+	StateRunning    = "running"
+	StateRestarting = "restarting" // exited; waiting out the backoff
+	StateStopped    = "stopped"
+)
+
+const (
+	// minBackoff and maxBackoff bound the delay before restarting a
+	// process that exited; the delay doubles with each quick exit.
+	minBackoff = time.Second
+	maxBackoff = 30 * time.Second
+
+	// stableAfter is how long a process must run for its next exit to
+	// restart it after minBackoff again.
+	stableAfter = 10 * time.Second
+
+	// stopTimeout is how long a stopped process has to exit after
+	// SIGTERM before it is killed.
+	stopTimeout = 5 * time.Second
+
+	// maxLogSize is the size past which a log file is rotated to .1
+	// when its process starts.
+	maxLogSize = 10 << 20
+)
+
+// Spec describes how to run a service's command.
+type Spec struct {
+	Command string
+	Dir     string
+	Env     map[string]string // added to the daemon's environment
+	Log     string            // file stdout and stderr are appended to
+}
+
+func (s Spec) equal(o Spec) bool {
+	return s.Command == o.Command && s.Dir == o.Dir && s.Log == o.Log && maps.Equal(s.Env, o.Env)
+}
+
+// Specs returns the spec of every service with a command in the enabled
+// projects of cfg.
+func Specs(cfg config.Config) map[health.ServiceKey]Spec {
+	specs := make(map[health.ServiceKey]Spec)
+	for name, p := range cfg.Projects {
+		if !p.Enabled {
+			continue
+		}
+		for svcName, svc := range p.Services {
+			if svc.Command == "" {
+				continue
+			}
+			specs[health.ServiceKey{Project: name, Service: svcName}] = Spec{
+				Command: svc.Command,
+				Dir:     svc.WorkDir(p),
+				Env:     svc.Env,
+				Log:     config.ServiceLogFile(name, svcName),
+			}
+		}
+	}
+	return specs
+}
+
+// Status is the state of a supervised service.
+type Status struct {
+	Project  string    `json:"project"`
+	Service  string    `json:"service"`
+	Command  string    `json:"command"`
+	State    string    `json:"state"`
+	PID      int       `json:"pid,omitempty"`
+	Started  time.Time `json:"started"` // when the current or last run started
+	Restarts int       `json:"restarts"`
+	Exit     string    `json:"exit,omitempty"` // how the last run ended
+	Log      string    `json:"log"`
+}
+
+// Config holds the configuration for a Manager.
+type Config struct {
+	// OnChange, if set, is called with a service's status whenever its
+	// state changes. It must not call back into the Manager.
+	OnChange func(Status)
+}
+
+// Manager supervises service processes.
+type Manager struct {
+	onChange func(Status)
+
+	mu    sync.Mutex
+	procs map[health.ServiceKey]*proc
+}
+
+// proc is a supervised service. Its status fields are guarded by the
+// Manager's mutex.
+type proc struct {
+	spec   Spec
+	status Status
+	stop   chan struct{} // closed to stop the process
+	done   chan struct{} // closed once the supervisor has returned
+}
+
+// NewManager creates a Manager with no running processes.
+func NewManager(cfg Config) *Manager {
+	return &Manager{
+		onChange: cfg.OnChange,
+		procs:    make(map[health.ServiceKey]*proc),
+	}
+}
+
+// errStopped is returned by run when the process was stopped.
+var errStopped = errors.New("stopped")
+
+// Start runs the service's command unless it is already running with the
+// same spec; a changed spec restarts it.
+func (m *Manager) Start(key health.ServiceKey, spec Spec) error {
+	if err := os.MkdirAll(filepath.Dir(spec.Log), 0o755); err != nil {
+		return fmt.Errorf("create log directory: %w", err)
+	}
+
+	for {
+		m.mu.Lock()
+		p, ok := m.procs[key]
+		if !ok {
+			break
+		}
+		m.mu.Unlock()
+		if p.spec.equal(spec) {
+			return nil
+		}
+		m.Stop(key)
+	}
+	p := &proc{
+		spec: spec,
+		status: Status{
+			Project: key.Project,
+			Service: key.Service,
+			Command: spec.Command,
+			State:   StateStarting,
+			Log:     spec.Log,
+		},
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	m.procs[key] = p
+	m.mu.Unlock()
+
+	go m.supervise(p)
+	return nil
+}
+
+// Restart stops the service's process, if running, and starts it again.
+func (m *Manager) Restart(key health.ServiceKey, spec Spec) error {
+	m.Stop(key)
+	return m.Start(key, spec)
+}
+
+// Stop stops the service's process and waits for it to exit. It reports
+// whether the service was started.
+func (m *Manager) Stop(key health.ServiceKey) bool {
+	m.mu.Lock()
+	p, ok := m.procs[key]
+	if ok {
+		delete(m.procs, key)
+	}
+	m.mu.Unlock()
+	if !ok {
+		return false
+	}
+	close(p.stop)
+	<-p.done
+	return true
+}
+
+// Apply brings started services in line with specs: services no longer
+// listed are stopped and those whose spec changed are restarted.
+func (m *Manager) Apply(specs map[health.ServiceKey]Spec) {
+	m.mu.Lock()
+	current := make(map[health.ServiceKey]Spec, len(m.procs))
+	for key, p := range m.procs {
+		current[key] = p.spec
+	}
+	m.mu.Unlock()
+
+	for key, spec := range current {
+		next, ok := specs[key]
+		switch {
+		case !ok:
+			log.Info().Str("project", key.Project).Str("service", key.Service).Msg("process: stopping removed service")
+			m.Stop(key)
+		case !next.equal(spec):
+			log.Info().Str("project", key.Project).Str("service", key.Service).Msg("process: restarting changed service")
+			if err := m.Restart(key, next); err != nil {
+				log.Error().Err(err).Str("project", key.Project).Str("service", key.Service).Msg("process: cannot restart")
+			}
+		}
+	}
+}
+
+// Status returns the status of a service, reporting whether it is started.
+func (m *Manager) Status(key health.ServiceKey) (Status, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	p, ok := m.procs[key]
+	if !ok {
+		return Status{}, false
+	}
+	return p.status, true
+}
+
+// List returns the status of every started service, ordered by project
+// and service.
+func (m *Manager) List() []Status {
+	m.mu.Lock()
+	out := make([]Status, 0, len(m.procs))
+	for _, p := range m.procs {
+		out = append(out, p.status)
+	}
+	m.mu.Unlock()
+
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Project != out[j].Project {
+			return out[i].Project < out[j].Project
+		}
+		return out[i].Service < out[j].Service
+	})
+	return out
+}
+
+// Close stops every process and waits for them to exit.
+func (m *Manager) Close() {
+	m.mu.Lock()
+	keys := make([]health.ServiceKey, 0, len(m.procs))
+	for key := range m.procs {
+		keys = append(keys, key)
+	}
+	m.mu.Unlock()
+
+	var wg sync.WaitGroup
+	for _, key := range keys {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			m.Stop(key)
+		}()
+	}
+	wg.Wait()
+}
+
+// update applies f to p's status and reports the change.
+func (m *Manager) update(p *proc, f func(*Status)) {
+	m.mu.Lock()
+	f(&p.status)
+	st := p.status
+	m.mu.Unlock()
+	if m.onChange != nil {
+		m.onChange(st)
+	}
+}
+
+// supervise runs p until it is stopped, restarting it after each exit.
+func (m *Manager) supervise(p *proc) {
+	defer close(p.done)
+	logger := log.With().Str("project", p.status.Project).Str("service", p.status.Service).Logger()
+
+	backoff := minBackoff
+	for {
+		started := time.Now()
+		err := m.run(p)
+		if errors.Is(err, errStopped) {
+			m.update(p, func(st *Status) { st.State, st.PID = StateStopped, 0 })
+			logger.Info().Msg("process: stopped")
+			return
+		}
+
+		if time.Since(started) >= stableAfter {
+			backoff = minBackoff
+		}
+		exit := describeExit(err)
+		m.update(p, func(st *Status) { st.State, st.PID, st.Exit = StateRestarting, 0, exit })
+		logger.Warn().Str("exit", exit).Dur("backoff", backoff).Msg("process: exited, restarting")
+
+		select {
+		case <-p.stop:
+			m.update(p, func(st *Status) { st.State = StateStopped })
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(2*backoff, maxBackoff)
+		m.mu.Lock()
+		p.status.Restarts++
+		m.mu.Unlock()
+	}
+}
+
+// run runs p's command once and returns when it exits, or errStopped once
+// it has been stopped.
+func (m *Manager) run(p *proc) error {
+	out, err := openLog(p.spec.Log)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	cmd := exec.Command(shell(), "-l", "-c", p.spec.Command)
+	cmd.Dir = p.spec.Dir
+	cmd.Env = os.Environ()
+	for name, value := range p.spec.Env {
+		cmd.Env = append(cmd.Env, name+"="+value)
+	}
+	cmd.Stdout, cmd.Stderr = out, out
+	// A process group of its own lets Stop signal the command's children,
+	// such as the server a package manager script starts.
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+
+	fmt.Fprintf(out, "==> %s hatch: starting %s\n", time.Now().Format(time.RFC3339), p.spec.Command)
+	if err := cmd.Start(); err != nil {
+		fmt.Fprintf(out, "==> hatch: %v\n", err)
+		return err
+	}
+	m.update(p, func(st *Status) {
+		st.State, st.PID, st.Started = StateRunning, cmd.Process.Pid, time.Now()
+	})
+
+	exited := make(chan error, 1)
+	go func() { exited <- cmd.Wait() }()
+
+	select {
+	case err := <-exited:
+		fmt.Fprintf(out, "==> %s hatch: %s\n", time.Now().Format(time.RFC3339), describeExit(err))
+		return err
+	case <-p.stop:
+		terminate(cmd.Process.Pid, exited)
+		fmt.Fprintf(out, "==> %s hatch: stopped\n", time.Now().Format(time.RFC3339))
+		return errStopped
+	}
+}
+
+// terminate sends SIGTERM to the process group pgid, then SIGKILL if it
+// has not exited within stopTimeout, and waits for it to exit.
+func terminate(pgid int, exited <-chan error) {
+	syscall.Kill(-pgid, syscall.SIGTERM)
+	select {
+	case <-exited:
+		return
+	case <-time.After(stopTimeout):
+	}
+	syscall.Kill(-pgid, syscall.SIGKILL)
+	<-exited
+}
+
+func describeExit(err error) string {
+	if err == nil {
+		return "exited"
+	}
+	return err.Error()
+}
+
+// openLog opens path for appending, first rotating it to path.1 if it has
+// grown past maxLogSize.
+func openLog(path string) (*os.File, error) {
+	if info, err := os.Stat(path); err == nil && info.Size() > maxLogSize {
+		os.Rename(path, path+".1")
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("open log: %w", err)
+	}
+	return f, nil
+}
+
+// shell returns the user's shell, run as a login shell so commands see
+// the PATH set up in their profile even when the daemon was started by
+// launchd or systemd.
+func shell() string {
+	if sh := os.Getenv("SHELL"); sh != "" {
+		return sh
+	}
+	return "/bin/sh"
+}
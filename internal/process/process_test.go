@@ -0,0 +1,166 @@
+package process
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/paulrose/hatch/internal/config"
+	"github.com/paulrose/hatch/internal/health"
+)
+
+var web = health.ServiceKey{Project: "myapp", Service: "web"}
+
+func newSpec(t *testing.T, command string) Spec {
+	t.Helper()
+	t.Setenv("SHELL", "/bin/sh")
+	dir := t.TempDir()
+	return Spec{Command: command, Dir: dir, Log: filepath.Join(dir, "logs", "web.log")}
+}
+
+// waitFor polls cond until it holds or fails the test after a few seconds.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(20 * time.Millisecond) {
+		if cond() {
+			return
+		}
+	}
+	t.Fatalf("timed out waiting for %s", what)
+}
+
+func readLog(t *testing.T, spec Spec) string {
+	t.Helper()
+	data, _ := os.ReadFile(spec.Log)
+	return string(data)
+}
+
+func TestManager_StartStop(t *testing.T) {
+	var mu sync.Mutex
+	var states []string
+	m := NewManager(Config{OnChange: func(st Status) {
+		mu.Lock()
+		states = append(states, st.State)
+		mu.Unlock()
+	}})
+	spec := newSpec(t, "echo \"$GREETING from $(pwd)\"; exec sleep 30")
+	spec.Env = map[string]string{"GREETING": "hello"}
+
+	if err := m.Start(web, spec); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "output", func() bool { return strings.Contains(readLog(t, spec), "hello from "+spec.Dir) })
+	st, ok := m.Status(web)
+	if !ok || st.State != StateRunning || st.PID == 0 || st.Command != spec.Command {
+		t.Fatalf("unexpected status %+v", st)
+	}
+
+	// Starting again with the same spec leaves the process alone.
+	pid := st.PID
+	m.Start(web, spec)
+	if st, _ := m.Status(web); st.PID != pid {
+		t.Errorf("expected pid %d to keep running, got %d", pid, st.PID)
+	}
+
+	start := time.Now()
+	if !m.Stop(web) {
+		t.Fatal("expected Stop to report a started service")
+	}
+	if time.Since(start) > stopTimeout {
+		t.Error("expected SIGTERM to stop the process")
+	}
+	if _, ok := m.Status(web); ok {
+		t.Error("expected no status after Stop")
+	}
+	if m.Stop(web) {
+		t.Error("expected a second Stop to report nothing to stop")
+	}
+	if !strings.Contains(readLog(t, spec), "hatch: stopped") {
+		t.Errorf("expected the stop to be logged, got %q", readLog(t, spec))
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(states) != 2 || states[0] != StateRunning || states[1] != StateStopped {
+		t.Errorf("expected running then stopped, got %v", states)
+	}
+}
+
+func TestManager_RestartsAfterExit(t *testing.T) {
+	m := NewManager(Config{})
+	defer m.Close()
+	spec := newSpec(t, "echo run; exit 3")
+
+	if err := m.Start(web, spec); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "a restart", func() bool {
+		st, _ := m.Status(web)
+		return st.Restarts >= 1 && strings.Count(readLog(t, spec), "run\n") >= 2
+	})
+	st, _ := m.Status(web)
+	if st.Exit != "exit status 3" {
+		t.Errorf("expected the exit status to be recorded, got %q", st.Exit)
+	}
+}
+
+func TestManager_Apply(t *testing.T) {
+	m := NewManager(Config{})
+	defer m.Close()
+	api := health.ServiceKey{Project: "myapp", Service: "api"}
+	webSpec := newSpec(t, "exec sleep 30")
+	apiSpec := newSpec(t, "exec sleep 30")
+	m.Start(web, webSpec)
+	m.Start(api, apiSpec)
+	waitFor(t, "both running", func() bool {
+		a, _ := m.Status(web)
+		b, _ := m.Status(api)
+		return a.State == StateRunning && b.State == StateRunning
+	})
+	before, _ := m.Status(web)
+
+	changed := webSpec
+	changed.Env = map[string]string{"PORT": "3001"}
+	m.Apply(map[health.ServiceKey]Spec{web: changed})
+
+	if _, ok := m.Status(api); ok {
+		t.Error("expected the removed service to be stopped")
+	}
+	waitFor(t, "web to restart", func() bool {
+		st, _ := m.Status(web)
+		return st.State == StateRunning && st.PID != before.PID
+	})
+
+	// Apply does not start services that were never started.
+	m.Apply(map[health.ServiceKey]Spec{web: changed, api: apiSpec})
+	if _, ok := m.Status(api); ok {
+		t.Error("expected Apply not to start a stopped service")
+	}
+}
+
+func TestSpecs(t *testing.T) {
+	cfg := config.Config{Projects: map[string]config.Project{
+		"myapp": {Path: "/home/dev/myapp", Enabled: true, Services: map[string]config.Service{
+			"web": {Proxy: "http://localhost:5173", Command: "npm run dev", Cwd: "frontend", Env: map[string]string{"PORT": "5173"}},
+			"api": {Proxy: "http://localhost:3000"},
+		}},
+		"old": {Path: "/home/dev/old", Enabled: false, Services: map[string]config.Service{
+			"web": {Proxy: "http://localhost:4000", Command: "make serve"},
+		}},
+	}}
+
+	specs := Specs(cfg)
+	if len(specs) != 1 {
+		t.Fatalf("expected only myapp/web, got %v", specs)
+	}
+	spec := specs[web]
+	if spec.Command != "npm run dev" || spec.Dir != "/home/dev/myapp/frontend" || spec.Env["PORT"] != "5173" {
+		t.Errorf("unexpected spec %+v", spec)
+	}
+	if spec.Log != config.ServiceLogFile("myapp", "web") {
+		t.Errorf("unexpected log file %q", spec.Log)
+	}
+}
@@ -0,0 +1,163 @@
+// Package share supports LAN sharing, which lets phones and other devices
+// on the local network reach Hatch projects through the host's LAN
+// address instead of loopback.
+package share
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"rsc.io/qr"
+
+	"github.com/paulrose/hatch/internal/config"
+)
+
+// CAPath is the URL path the root CA certificate is served at over plain
+// HTTP while sharing, so devices can install it before they trust Hatch's
+// HTTPS certificates.
+const CAPath = "/hatch/ca.crt"
+
+// LANAddress returns the host's IPv4 address on the local network: the
+// source address of the default route, or failing that the first private
+// address on an interface that is up.
+func LANAddress() (net.IP, error) {
+	// Dialing UDP sends no packets; it only picks a route and source address.
+	if conn, err := net.Dial("udp4", "8.8.8.8:53"); err == nil {
+		ip := conn.LocalAddr().(*net.UDPAddr).IP
+		conn.Close()
+		if ip.IsPrivate() {
+			return ip.To4(), nil
+		}
+	}
+
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil, fmt.Errorf("listing interfaces: %w", err)
+	}
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, addr := range addrs {
+			ipNet, ok := addr.(*net.IPNet)
+			if ok && ipNet.IP.To4() != nil && ipNet.IP.IsPrivate() {
+				return ipNet.IP.To4(), nil
+			}
+		}
+	}
+	return nil, errors.New("no private IPv4 address found on any interface")
+}
+
+// ProjectURL is the HTTPS address of one enabled project.
+type ProjectURL struct {
+	Project string `json:"project"`
+	URL     string `json:"url"`
+}
+
+// Info describes how a device on the LAN reaches Hatch. Active is false
+// when sharing is enabled in the config but the daemon has no LAN address
+// (or is not running), in which case the address fields are empty.
+type Info struct {
+	Enabled bool         `json:"enabled"`
+	Active  bool         `json:"active"`
+	LANIP   string       `json:"lan_ip,omitempty"`
+	CAURL   string       `json:"ca_url,omitempty"`
+	URLs    []ProjectURL `json:"urls"`
+}
+
+// NewInfo builds the sharing details for cfg. lanIP is the address the
+// DNS server is sharing on, or nil when it is not.
+func NewInfo(cfg config.Config, lanIP net.IP) Info {
+	info := Info{
+		Enabled: cfg.Settings.LANSharing,
+		URLs:    projectURLs(cfg),
+	}
+	if lanIP == nil {
+		return info
+	}
+
+	info.Active = true
+	info.LANIP = lanIP.String()
+	host := info.LANIP
+	if cfg.Settings.HTTPPort != 80 {
+		host = net.JoinHostPort(host, strconv.Itoa(cfg.Settings.HTTPPort))
+	}
+	info.CAURL = (&url.URL{Scheme: "http", Host: host, Path: CAPath}).String()
+	return info
+}
+
+// projectURLs returns the HTTPS URL of each enabled project's domain,
+// ordered by project name.
+func projectURLs(cfg config.Config) []ProjectURL {
+	names := make([]string, 0, len(cfg.Projects))
+	for name, proj := range cfg.Projects {
+		if proj.Enabled {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	urls := make([]ProjectURL, 0, len(names))
+	for _, name := range names {
+		host := cfg.Projects[name].Domain
+		if cfg.Settings.HTTPSPort != 443 {
+			host = net.JoinHostPort(host, strconv.Itoa(cfg.Settings.HTTPSPort))
+		}
+		urls = append(urls, ProjectURL{
+			Project: name,
+			URL:     (&url.URL{Scheme: "https", Host: host}).String(),
+		})
+	}
+	return urls
+}
+
+// QRCode encodes text as a QR code.
+func QRCode(text string) (*qr.Code, error) {
+	code, err := qr.Encode(text, qr.M)
+	if err != nil {
+		return nil, fmt.Errorf("encoding QR code: %w", err)
+	}
+	return code, nil
+}
+
+// TerminalQR renders text as a QR code for a terminal, two modules per
+// character cell using half blocks, with the quiet zone QR readers need.
+func TerminalQR(text string) (string, error) {
+	code, err := QRCode(text)
+	if err != nil {
+		return "", err
+	}
+
+	const quiet = 2
+	// Black modules are drawn as spaces on a light background, so the code
+	// reads correctly on dark terminal themes.
+	white := func(x, y int) bool { return !code.Black(x, y) }
+
+	var b strings.Builder
+	for y := -quiet; y < code.Size+quiet; y += 2 {
+		for x := -quiet; x < code.Size+quiet; x++ {
+			top, bottom := white(x, y), white(x, y+1)
+			switch {
+			case top && bottom:
+				b.WriteString("█")
+			case top:
+				b.WriteString("▀")
+			case bottom:
+				b.WriteString("▄")
+			default:
+				b.WriteString(" ")
+			}
+		}
+		b.WriteString("\n")
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,87 @@
+package share
+
+import (
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/paulrose/hatch/internal/config"
+)
+
+func shareConfig() config.Config {
+	cfg := config.DefaultConfig()
+	cfg.Settings.LANSharing = true
+	cfg.Projects["web"] = config.Project{Domain: "web.test", Enabled: true}
+	cfg.Projects["api"] = config.Project{Domain: "api.test", Enabled: true}
+	cfg.Projects["old"] = config.Project{Domain: "old.test"}
+	return cfg
+}
+
+func TestNewInfo(t *testing.T) {
+	info := NewInfo(shareConfig(), net.ParseIP("192.168.1.50"))
+
+	want := Info{
+		Enabled: true,
+		Active:  true,
+		LANIP:   "192.168.1.50",
+		CAURL:   "http://192.168.1.50/hatch/ca.crt",
+		URLs: []ProjectURL{
+			{Project: "api", URL: "https://api.test"},
+			{Project: "web", URL: "https://web.test"},
+		},
+	}
+	if !reflect.DeepEqual(info, want) {
+		t.Errorf("NewInfo() = %+v, want %+v", info, want)
+	}
+}
+
+func TestNewInfo_CustomPorts(t *testing.T) {
+	cfg := shareConfig()
+	cfg.Settings.HTTPPort = 8080
+	cfg.Settings.HTTPSPort = 8443
+
+	info := NewInfo(cfg, net.ParseIP("10.0.0.4"))
+	if info.CAURL != "http://10.0.0.4:8080/hatch/ca.crt" {
+		t.Errorf("unexpected CA URL %q", info.CAURL)
+	}
+	if info.URLs[0].URL != "https://api.test:8443" {
+		t.Errorf("unexpected project URL %q", info.URLs[0].URL)
+	}
+}
+
+func TestNewInfo_NotSharing(t *testing.T) {
+	info := NewInfo(shareConfig(), nil)
+	if !info.Enabled || info.Active {
+		t.Errorf("expected enabled but inactive, got %+v", info)
+	}
+	if info.LANIP != "" || info.CAURL != "" {
+		t.Errorf("expected no addresses, got %+v", info)
+	}
+}
+
+func TestTerminalQR(t *testing.T) {
+	out, err := TerminalQR("http://192.168.1.50/hatch/ca.crt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(out, "\n"), "\n")
+	code, _ := QRCode("http://192.168.1.50/hatch/ca.crt")
+	if want := (code.Size + 4 + 1) / 2; len(lines) != want {
+		t.Errorf("expected %d lines, got %d", want, len(lines))
+	}
+	// The first row is quiet zone: all light.
+	if strings.Trim(lines[0], "█") != "" {
+		t.Errorf("expected a blank quiet zone, got %q", lines[0])
+	}
+}
+
+func TestLANAddress(t *testing.T) {
+	ip, err := LANAddress()
+	if err != nil {
+		t.Skipf("no LAN address in this environment: %v", err)
+	}
+	if ip.To4() == nil || !ip.IsPrivate() {
+		t.Errorf("expected a private IPv4 address, got %s", ip)
+	}
+}
@@ -0,0 +1,22 @@
+// Package shell runs shell commands for the packages that take a
+// CommandRunner, such as the service managers and trust stores.
+package shell
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// Runner runs commands through sh as the current user. Failed commands
+// are reported with their combined output.
+type Runner struct{}
+
+// Run runs command and waits for it to finish.
+func (Runner) Run(command string) error {
+	out, err := exec.Command("sh", "-c", command).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%s: %s: %w", command, strings.TrimSpace(string(out)), err)
+	}
+	return nil
+}
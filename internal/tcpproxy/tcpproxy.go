@@ -0,0 +1,378 @@
+// Package tcpproxy forwards raw TCP connections for tcp services. Services
+// that share a listen port are told apart by the TLS server name (SNI) of
+// the incoming connection, which is either passed through untouched or
+// terminated with a certificate from the Hatch CA.
+package tcpproxy
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/paulrose/hatch/internal/config"
+)
+
+// DefaultListenIP is the address tcp services listen on.
+const DefaultListenIP = "127.0.0.1"
+
+const (
+	defaultDialTimeout = 5 * time.Second
+	// helloTimeout bounds how long a client may take to send its TLS
+	// ClientHello on a port routed by SNI.
+	helloTimeout = 10 * time.Second
+	// renewBefore is how long before expiry a terminating certificate is
+	// re-issued.
+	renewBefore = time.Hour
+)
+
+// Route is a tcp service reachable on a listen port.
+type Route struct {
+	Project  string
+	Service  string
+	Host     string // hostname matched against SNI
+	Upstream string // host:port
+	TLS      string // "", config.TLSPassthrough or config.TLSTerminate
+}
+
+// Routes returns the tcp services of enabled projects grouped by listen
+// port, each group sorted by host.
+func Routes(cfg config.Config) map[int][]Route {
+	out := make(map[int][]Route)
+	for projName, proj := range cfg.Projects {
+		if !proj.Enabled {
+			continue
+		}
+		for svcName, svc := range proj.Services {
+			if !svc.IsTCP() {
+				continue
+			}
+			upstream := svc.Proxy
+			if u, err := url.Parse(svc.Proxy); err == nil {
+				upstream = u.Host
+			}
+			out[svc.Listen] = append(out[svc.Listen], Route{
+				Project:  projName,
+				Service:  svcName,
+				Host:     strings.ToLower(svc.Host(proj)),
+				Upstream: upstream,
+				TLS:      svc.TLS,
+			})
+		}
+	}
+	for _, routes := range out {
+		sort.Slice(routes, func(i, j int) bool { return routes[i].Host < routes[j].Host })
+	}
+	return out
+}
+
+// Ports returns the listen ports of the tcp services in cfg, sorted.
+func Ports(cfg config.Config) []int {
+	routes := Routes(cfg)
+	ports := make([]int, 0, len(routes))
+	for port := range routes {
+		ports = append(ports, port)
+	}
+	sort.Ints(ports)
+	return ports
+}
+
+// ServerConfig holds the configuration for the TCP proxy.
+type ServerConfig struct {
+	ListenIP    string
+	DialTimeout time.Duration // defaults to 5s
+	// Issuer returns a certificate for host. It is required for services
+	// that terminate TLS.
+	Issuer func(host string) (*tls.Certificate, error)
+}
+
+// Server listens on the ports of all tcp services and forwards their
+// connections upstream.
+type Server struct {
+	cfg ServerConfig
+
+	mu        sync.Mutex
+	listeners map[int]*listener
+	conns     map[net.Conn]struct{}
+	certs     map[string]*tls.Certificate
+	wg        sync.WaitGroup
+}
+
+// listener is a single listen port and the routes served on it.
+type listener struct {
+	ln net.Listener
+
+	mu     sync.RWMutex
+	routes []Route
+}
+
+// NewServer creates a TCP proxy. Call Apply to start listening.
+func NewServer(cfg ServerConfig) *Server {
+	if cfg.DialTimeout == 0 {
+		cfg.DialTimeout = defaultDialTimeout
+	}
+	return &Server{
+		cfg:       cfg,
+		listeners: make(map[int]*listener),
+		conns:     make(map[net.Conn]struct{}),
+		certs:     make(map[string]*tls.Certificate),
+	}
+}
+
+// Apply reconciles the listeners with the tcp services in cfg: ports no
+// longer used are closed, new ports are opened and the routes of existing
+// ports are replaced. Established connections are left alone. Ports that
+// cannot be opened are reported in the returned error; the rest are still
+// applied.
+func (s *Server) Apply(cfg config.Config) error {
+	routes := Routes(cfg)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for port, l := range s.listeners {
+		if _, ok := routes[port]; !ok {
+			l.ln.Close()
+			delete(s.listeners, port)
+			log.Info().Int("port", port).Msg("tcp listener closed")
+		}
+	}
+
+	var errs []error
+	for _, port := range Ports(cfg) {
+		if l, ok := s.listeners[port]; ok {
+			l.setRoutes(routes[port])
+			continue
+		}
+		ln, err := net.Listen("tcp", net.JoinHostPort(s.cfg.ListenIP, fmt.Sprint(port)))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("listen on port %d: %w", port, err))
+			continue
+		}
+		l := &listener{ln: ln, routes: routes[port]}
+		s.listeners[port] = l
+		s.wg.Add(1)
+		go s.serve(l)
+		log.Info().Int("port", port).Int("services", len(routes[port])).Msg("tcp listener started")
+	}
+
+	return errors.Join(errs...)
+}
+
+// Addr returns the address the listener for port is bound to, or nil if
+// the port is not being served.
+func (s *Server) Addr(port int) net.Addr {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if l, ok := s.listeners[port]; ok {
+		return l.ln.Addr()
+	}
+	return nil
+}
+
+// Stop closes all listeners and open connections and waits for them to
+// finish.
+func (s *Server) Stop() error {
+	s.mu.Lock()
+	for port, l := range s.listeners {
+		l.ln.Close()
+		delete(s.listeners, port)
+	}
+	for c := range s.conns {
+		c.Close()
+	}
+	s.mu.Unlock()
+
+	s.wg.Wait()
+	return nil
+}
+
+func (l *listener) setRoutes(routes []Route) {
+	l.mu.Lock()
+	l.routes = routes
+	l.mu.Unlock()
+}
+
+func (l *listener) snapshot() []Route {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.routes
+}
+
+// serve accepts connections on l until it is closed.
+func (s *Server) serve(l *listener) {
+	defer s.wg.Done()
+	for {
+		conn, err := l.ln.Accept()
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				log.Warn().Err(err).Str("addr", l.ln.Addr().String()).Msg("tcp accept failed")
+			}
+			return
+		}
+		if !s.track(conn) {
+			conn.Close()
+			return
+		}
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			defer s.untrack(conn)
+			s.handle(conn, l.snapshot())
+		}()
+	}
+}
+
+// track registers an open connection so Stop can close it. It reports
+// false if the server is shutting down.
+func (s *Server) track(c net.Conn) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.listeners) == 0 {
+		return false
+	}
+	s.conns[c] = struct{}{}
+	return true
+}
+
+func (s *Server) untrack(c net.Conn) {
+	s.mu.Lock()
+	delete(s.conns, c)
+	s.mu.Unlock()
+	c.Close()
+}
+
+// handle routes a single client connection. A port with one plain TCP
+// route forwards immediately; otherwise the route is picked by SNI.
+func (s *Server) handle(conn net.Conn, routes []Route) {
+	if len(routes) == 0 {
+		return
+	}
+	if len(routes) == 1 && routes[0].TLS == "" {
+		s.forward(conn, routes[0])
+		return
+	}
+
+	conn.SetReadDeadline(time.Now().Add(helloTimeout))
+	hello, peeked, err := peekClientHello(conn)
+	conn.SetReadDeadline(time.Time{})
+	if err != nil {
+		log.Debug().Err(err).Str("remote", conn.RemoteAddr().String()).Msg("tcp: reading TLS client hello")
+		return
+	}
+
+	route, ok := matchRoute(routes, hello.ServerName)
+	if !ok {
+		log.Debug().Str("sni", hello.ServerName).Msg("tcp: no service for server name")
+		return
+	}
+
+	client := &prefixConn{Conn: conn, r: peeked}
+	if route.TLS != config.TLSTerminate {
+		s.forward(client, route)
+		return
+	}
+
+	tlsConn := tls.Server(client, &tls.Config{
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			return s.certificate(route.Host)
+		},
+	})
+	if err := tlsConn.Handshake(); err != nil {
+		log.Debug().Err(err).Str("host", route.Host).Msg("tcp: TLS handshake failed")
+		return
+	}
+	s.forward(tlsConn, route)
+}
+
+// matchRoute returns the route for serverName. A connection without SNI
+// is accepted only when the port serves a single route.
+func matchRoute(routes []Route, serverName string) (Route, bool) {
+	if serverName == "" {
+		if len(routes) == 1 {
+			return routes[0], true
+		}
+		return Route{}, false
+	}
+	serverName = strings.ToLower(serverName)
+	for _, r := range routes {
+		if r.Host == serverName {
+			return r, true
+		}
+	}
+	return Route{}, false
+}
+
+// forward dials the route's upstream and copies data in both directions
+// until both sides are done.
+func (s *Server) forward(client net.Conn, route Route) {
+	upstream, err := net.DialTimeout("tcp", route.Upstream, s.cfg.DialTimeout)
+	if err != nil {
+		log.Warn().Err(err).Str("project", route.Project).Str("service", route.Service).Msg("tcp: upstream unreachable")
+		return
+	}
+	defer upstream.Close()
+
+	done := make(chan struct{}, 2)
+	pipe := func(dst, src net.Conn) {
+		io.Copy(dst, src)
+		closeWrite(dst)
+		done <- struct{}{}
+	}
+	go pipe(upstream, client)
+	go pipe(client, upstream)
+	<-done
+	<-done
+}
+
+// certificate returns a cached certificate for host, issuing a new one when
+// none is cached or the cached one is close to expiry.
+func (s *Server) certificate(host string) (*tls.Certificate, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if c, ok := s.certs[host]; ok && time.Until(c.Leaf.NotAfter) > renewBefore {
+		return c, nil
+	}
+	if s.cfg.Issuer == nil {
+		return nil, fmt.Errorf("no certificate issuer configured")
+	}
+	c, err := s.cfg.Issuer(host)
+	if err != nil {
+		return nil, fmt.Errorf("issuing certificate for %s: %w", host, err)
+	}
+	if c.Leaf == nil {
+		if c.Leaf, err = x509Leaf(c); err != nil {
+			return nil, err
+		}
+	}
+	s.certs[host] = c
+	return c, nil
+}
+
+// x509Leaf parses the leaf of c, for issuers that do not set Leaf.
+func x509Leaf(c *tls.Certificate) (*x509.Certificate, error) {
+	if len(c.Certificate) == 0 {
+		return nil, fmt.Errorf("issued certificate is empty")
+	}
+	return x509.ParseCertificate(c.Certificate[0])
+}
+
+// closeWrite half-closes c if it supports it, so the peer sees EOF while
+// the other direction keeps flowing.
+func closeWrite(c net.Conn) {
+	if cw, ok := c.(interface{ CloseWrite() error }); ok {
+		cw.CloseWrite()
+		return
+	}
+	c.Close()
+}
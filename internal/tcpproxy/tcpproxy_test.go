@@ -0,0 +1,299 @@
+package tcpproxy
+
+import (
+	"bufio"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/paulrose/hatch/internal/certs"
+	"github.com/paulrose/hatch/internal/config"
+)
+
+// freePort returns a loopback port that is not in use.
+func freePort(t *testing.T) int {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	return ln.Addr().(*net.TCPAddr).Port
+}
+
+// echoServer accepts connections on ln and answers each line with
+// "<name>: <line>".
+func echoServer(t *testing.T, ln net.Listener, name string) {
+	t.Helper()
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				sc := bufio.NewScanner(conn)
+				for sc.Scan() {
+					fmt.Fprintf(conn, "%s: %s\n", name, sc.Text())
+				}
+			}()
+		}
+	}()
+}
+
+// plainUpstream starts a plain TCP echo server and returns its address.
+func plainUpstream(t *testing.T, name string) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	echoServer(t, ln, name)
+	return ln.Addr().String()
+}
+
+// testCA creates a root and intermediate CA and returns their paths and a
+// pool containing the root.
+func testCA(t *testing.T) (certs.CAPaths, *x509.CertPool) {
+	t.Helper()
+	paths := certs.NewCAPaths(t.TempDir())
+	if err := certs.GenerateCA(paths); err != nil {
+		t.Fatal(err)
+	}
+	if err := certs.GenerateIntermediateCA(paths); err != nil {
+		t.Fatal(err)
+	}
+	root, _, err := certs.LoadCA(paths)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(root)
+	return paths, pool
+}
+
+// tlsUpstream starts a TLS echo server for host and returns its address.
+func tlsUpstream(t *testing.T, paths certs.CAPaths, host, name string) string {
+	t.Helper()
+	cert, err := certs.IssueLeafCert(paths, host)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: []tls.Certificate{*cert}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	echoServer(t, ln, name)
+	return ln.Addr().String()
+}
+
+func tcpConfig(projects map[string]config.Project) config.Config {
+	return config.Config{Version: 1, Projects: projects}
+}
+
+func tcpProject(domain string, services map[string]config.Service) config.Project {
+	return config.Project{Domain: domain, Enabled: true, Services: services}
+}
+
+func startServer(t *testing.T, cfg config.Config, issuer func(string) (*tls.Certificate, error)) *Server {
+	t.Helper()
+	s := NewServer(ServerConfig{ListenIP: "127.0.0.1", Issuer: issuer})
+	if err := s.Apply(cfg); err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	t.Cleanup(func() { s.Stop() })
+	return s
+}
+
+// roundTrip writes a line to conn and returns the response line.
+func roundTrip(t *testing.T, conn net.Conn, line string) string {
+	t.Helper()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	if _, err := fmt.Fprintf(conn, "%s\n", line); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	resp, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	return strings.TrimSpace(resp)
+}
+
+func TestRoutes(t *testing.T) {
+	cfg := tcpConfig(map[string]config.Project{
+		"myapp": tcpProject("myapp.test", map[string]config.Service{
+			"web":   {Proxy: "http://localhost:3000"},
+			"db":    {Type: config.ServiceTCP, Listen: 5432, Proxy: "tcp://localhost:15432", Subdomain: "db"},
+			"redis": {Type: config.ServiceTCP, Listen: 6379, Proxy: "tcp://localhost:16379", TLS: config.TLSTerminate},
+		}),
+		"other": tcpProject("other.test", map[string]config.Service{
+			"redis": {Type: config.ServiceTCP, Listen: 6379, Proxy: "tcp://localhost:26379", TLS: config.TLSPassthrough},
+		}),
+		"off": {Domain: "off.test", Services: map[string]config.Service{
+			"db": {Type: config.ServiceTCP, Listen: 7000, Proxy: "tcp://localhost:7001"},
+		}},
+	})
+
+	routes := Routes(cfg)
+	if len(routes) != 2 {
+		t.Fatalf("expected 2 ports, got %v", routes)
+	}
+
+	db := routes[5432]
+	if len(db) != 1 || db[0].Host != "db.myapp.test" || db[0].Upstream != "localhost:15432" {
+		t.Errorf("unexpected routes for 5432: %+v", db)
+	}
+
+	redis := routes[6379]
+	if len(redis) != 2 || redis[0].Host != "myapp.test" || redis[1].Host != "other.test" {
+		t.Errorf("unexpected routes for 6379: %+v", redis)
+	}
+
+	if got := Ports(cfg); len(got) != 2 || got[0] != 5432 || got[1] != 6379 {
+		t.Errorf("Ports = %v", got)
+	}
+}
+
+func TestServer_PlainForward(t *testing.T) {
+	upstream := plainUpstream(t, "db")
+	port := freePort(t)
+	startServer(t, tcpConfig(map[string]config.Project{
+		"myapp": tcpProject("myapp.test", map[string]config.Service{
+			"db": {Type: config.ServiceTCP, Listen: port, Proxy: "tcp://" + upstream},
+		}),
+	}), nil)
+
+	conn, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	if got := roundTrip(t, conn, "ping"); got != "db: ping" {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestServer_SNIPassthrough(t *testing.T) {
+	paths, pool := testCA(t)
+	a := tlsUpstream(t, paths, "a.test", "a")
+	b := tlsUpstream(t, paths, "b.test", "b")
+	port := freePort(t)
+	startServer(t, tcpConfig(map[string]config.Project{
+		"a": tcpProject("a.test", map[string]config.Service{
+			"grpc": {Type: config.ServiceTCP, Listen: port, Proxy: "tcp://" + a, TLS: config.TLSPassthrough},
+		}),
+		"b": tcpProject("b.test", map[string]config.Service{
+			"grpc": {Type: config.ServiceTCP, Listen: port, Proxy: "tcp://" + b, TLS: config.TLSPassthrough},
+		}),
+	}), nil)
+
+	for _, host := range []string{"a.test", "b.test"} {
+		conn, err := tls.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", port), &tls.Config{ServerName: host, RootCAs: pool})
+		if err != nil {
+			t.Fatalf("dial %s: %v", host, err)
+		}
+		want := strings.TrimSuffix(host, ".test") + ": hello"
+		if got := roundTrip(t, conn, "hello"); got != want {
+			t.Errorf("%s: got %q, want %q", host, got, want)
+		}
+		conn.Close()
+	}
+}
+
+func TestServer_TLSTerminate(t *testing.T) {
+	paths, pool := testCA(t)
+	upstream := plainUpstream(t, "redis")
+	port := freePort(t)
+	issued := 0
+	startServer(t, tcpConfig(map[string]config.Project{
+		"myapp": tcpProject("myapp.test", map[string]config.Service{
+			"redis": {Type: config.ServiceTCP, Listen: port, Proxy: "tcp://" + upstream, Subdomain: "redis", TLS: config.TLSTerminate},
+		}),
+	}), func(host string) (*tls.Certificate, error) {
+		issued++
+		return certs.IssueLeafCert(paths, host)
+	})
+
+	for i := 0; i < 2; i++ {
+		conn, err := tls.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", port), &tls.Config{ServerName: "redis.myapp.test", RootCAs: pool})
+		if err != nil {
+			t.Fatalf("dial: %v", err)
+		}
+		if got := roundTrip(t, conn, "PING"); got != "redis: PING" {
+			t.Errorf("got %q", got)
+		}
+		conn.Close()
+	}
+	if issued != 1 {
+		t.Errorf("expected certificate to be issued once, got %d", issued)
+	}
+}
+
+func TestServer_UnknownServerName(t *testing.T) {
+	paths, pool := testCA(t)
+	a := tlsUpstream(t, paths, "a.test", "a")
+	port := freePort(t)
+	startServer(t, tcpConfig(map[string]config.Project{
+		"a": tcpProject("a.test", map[string]config.Service{
+			"grpc": {Type: config.ServiceTCP, Listen: port, Proxy: "tcp://" + a, TLS: config.TLSPassthrough},
+		}),
+	}), nil)
+
+	_, err := tls.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", port), &tls.Config{ServerName: "nope.test", RootCAs: pool})
+	if err == nil {
+		t.Fatal("expected handshake to fail for an unknown server name")
+	}
+}
+
+func TestServer_ApplyReconcilesPorts(t *testing.T) {
+	upstream := plainUpstream(t, "db")
+	p1, p2 := freePort(t), freePort(t)
+	svc := func(port int) map[string]config.Service {
+		return map[string]config.Service{"db": {Type: config.ServiceTCP, Listen: port, Proxy: "tcp://" + upstream}}
+	}
+
+	s := startServer(t, tcpConfig(map[string]config.Project{"myapp": tcpProject("myapp.test", svc(p1))}), nil)
+	if s.Addr(p1) == nil {
+		t.Fatalf("expected listener on %d", p1)
+	}
+
+	if err := s.Apply(tcpConfig(map[string]config.Project{"myapp": tcpProject("myapp.test", svc(p2))})); err != nil {
+		t.Fatal(err)
+	}
+	if s.Addr(p1) != nil {
+		t.Errorf("expected listener on %d to be closed", p1)
+	}
+	if s.Addr(p2) == nil {
+		t.Errorf("expected listener on %d", p2)
+	}
+	if _, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", p1)); err == nil {
+		t.Errorf("expected port %d to be closed", p1)
+	}
+}
+
+func TestServer_ApplyPortInUse(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	port := ln.Addr().(*net.TCPAddr).Port
+
+	s := NewServer(ServerConfig{ListenIP: "127.0.0.1"})
+	defer s.Stop()
+	err = s.Apply(tcpConfig(map[string]config.Project{
+		"myapp": tcpProject("myapp.test", map[string]config.Service{
+			"db": {Type: config.ServiceTCP, Listen: port, Proxy: "tcp://localhost:1"},
+		}),
+	}))
+	if err == nil || !strings.Contains(err.Error(), fmt.Sprintf("listen on port %d", port)) {
+		t.Errorf("expected listen error, got %v", err)
+	}
+}
@@ -2,11 +2,13 @@ package tray
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"net/url"
 	"os"
 	"os/exec"
 	"sort"
+	"strings"
 	"sync"
 	"time"
 
@@ -15,9 +17,11 @@ import (
 	"github.com/wailsapp/wails/v3/pkg/application"
 	"github.com/wailsapp/wails/v3/pkg/events"
 
+	"github.com/paulrose/hatch/internal/api"
 	"github.com/paulrose/hatch/internal/config"
 	"github.com/paulrose/hatch/internal/daemon"
 	"github.com/paulrose/hatch/internal/health"
+	"github.com/paulrose/hatch/internal/process"
 )
 
 // ManagerConfig holds the dependencies for a Manager.
@@ -136,6 +140,16 @@ func (m *Manager) refresh() {
 	if m.checker != nil {
 		statuses = m.checker.ServiceStatuses()
 	}
+	processes := make(map[health.ServiceKey]process.Status)
+	if running {
+		client := api.NewClient()
+		client.HTTPClient.Timeout = 2 * time.Second
+		if list, err := client.Processes(context.Background()); err == nil {
+			for _, st := range list {
+				processes[health.ServiceKey{Project: st.Project, Service: st.Service}] = st
+			}
+		}
+	}
 
 	// Build menu.
 	menu := m.app.NewMenu()
@@ -161,7 +175,7 @@ func (m *Manager) refresh() {
 
 	for _, name := range projectNames {
 		proj := cfg.Projects[name]
-		m.buildProjectItem(menu, name, proj, statuses)
+		m.buildProjectItem(menu, name, proj, statuses, processes)
 	}
 
 	if len(projectNames) > 0 {
@@ -206,7 +220,7 @@ func (m *Manager) refresh() {
 }
 
 // buildProjectItem adds a submenu item for a single project.
-func (m *Manager) buildProjectItem(menu *application.Menu, name string, proj config.Project, statuses map[health.ServiceKey]health.ServiceStatus) {
+func (m *Manager) buildProjectItem(menu *application.Menu, name string, proj config.Project, statuses map[health.ServiceKey]health.ServiceStatus, processes map[health.ServiceKey]process.Status) {
 	var dot string
 	if !proj.Enabled {
 		dot = "○"
@@ -252,6 +266,19 @@ func (m *Manager) buildProjectItem(menu *application.Menu, name string, proj con
 		})
 	}
 
+	// Start / Stop the services' commands.
+	if proj.Enabled && hasCommands(proj) {
+		if projectRunning(name, processes) {
+			sub.Add("Stop Processes").OnClick(func(_ *application.Context) {
+				go m.runHatchAndRefresh("stop", projName)
+			})
+		} else {
+			sub.Add("Start Processes").OnClick(func(_ *application.Context) {
+				go m.runHatchAndRefresh("start", projName)
+			})
+		}
+	}
+
 	sub.AddSeparator()
 
 	// Per-service health rows.
@@ -273,9 +300,45 @@ func (m *Manager) buildProjectItem(menu *application.Menu, name string, proj con
 				indicator = "✗"
 			}
 		}
-		addr := svc.Proxy
-		sub.Add(fmt.Sprintf("%s  %s  %s", svcName, addr, indicator)).SetEnabled(false)
+		addr := strings.Join(svc.ProxyURLs(), ", ")
+		if svc.IsStatic() {
+			addr = svc.RootDir(proj)
+		}
+		if svc.IsMock() {
+			addr = "mock"
+		}
+		row := fmt.Sprintf("%s  %s  %s", svcName, addr, indicator)
+		if svc.Command != "" {
+			state := process.StateStopped
+			if st, ok := processes[key]; ok {
+				state = st.State
+			}
+			row += "  ▸ " + state
+		}
+		sub.Add(row).SetEnabled(false)
+	}
+}
+
+// hasCommands reports whether any of the project's services has a command
+// for the daemon to run.
+func hasCommands(proj config.Project) bool {
+	for _, svc := range proj.Services {
+		if svc.Command != "" {
+			return true
+		}
 	}
+	return false
+}
+
+// projectRunning reports whether the daemon is running any of the
+// project's commands.
+func projectRunning(name string, processes map[health.ServiceKey]process.Status) bool {
+	for key := range processes {
+		if key.Project == name {
+			return true
+		}
+	}
+	return false
 }
 
 func (m *Manager) showWindow() {
@@ -313,7 +376,14 @@ func (m *Manager) toggleProject(name string, enabled bool) {
 		log.Warn().Err(err).Msg("tray: config save failed")
 		return
 	}
-	m.restartDaemon()
+	// The daemon stops a disabled project's commands and resumes those
+	// started before on enable; enabling also starts any not yet started.
+	m.runHatch("restart")
+	if enabled && hasCommands(proj) {
+		m.runHatch("start", name)
+	}
+	time.Sleep(500 * time.Millisecond)
+	m.refresh()
 }
 
 func (m *Manager) stopDaemon() {
@@ -334,6 +404,12 @@ func (m *Manager) restartDaemon() {
 	m.refresh()
 }
 
+func (m *Manager) runHatchAndRefresh(args ...string) {
+	m.runHatch(args...)
+	time.Sleep(500 * time.Millisecond)
+	m.refresh()
+}
+
 func (m *Manager) runHatch(args ...string) {
 	exe, err := os.Executable()
 	if err != nil {
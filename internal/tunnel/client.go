@@ -0,0 +1,509 @@
+package tunnel
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// dialTimeout bounds connecting to the relay and its upgrade handshake.
+	dialTimeout = 10 * time.Second
+	// maxBackoff caps the delay between reconnection attempts.
+	maxBackoff = 30 * time.Second
+)
+
+// ErrUnauthorized is returned when the relay rejects the client's token.
+var ErrUnauthorized = errors.New("relay rejected the token")
+
+// ClientConfig holds the settings for a Client.
+type ClientConfig struct {
+	// RelayURL is the relay's base URL, e.g. https://tunnel.example.com.
+	RelayURL string
+	// Token is sent as a bearer token when the relay requires one.
+	Token string
+	// Local is where tunnelled requests are sent, e.g. https://127.0.0.1:443.
+	Local *url.URL
+	// Transport returns the round tripper used for requests to a project
+	// domain, e.g. one trusting the hatch CA with the domain as TLS server
+	// name. Optional; defaults to http.DefaultTransport.
+	Transport func(domain string) http.RoundTripper
+	// OnChange, when set, is called whenever a tunnel's status changes.
+	OnChange func(Status)
+	// Allow, when set, is asked whether a request from clientIP, the
+	// visitor's address as reported by the relay ("" if it sent none),
+	// may reach project. Refused requests get a 403 without reaching
+	// Local, whose view of them is a loopback connection.
+	Allow func(project, clientIP string) bool
+}
+
+// Status describes a project's tunnel.
+type Status struct {
+	Project   string `json:"project"`
+	Domain    string `json:"domain"`
+	URL       string `json:"url,omitempty"` // public URL, once assigned
+	Connected bool   `json:"connected"`
+	Error     string `json:"error,omitempty"` // last connection error
+}
+
+// Client is the local end of the tunnels: it keeps one control connection
+// to the relay per shared project and serves the relay's data connections
+// by proxying to Local.
+type Client struct {
+	cfg   ClientConfig
+	relay *url.URL
+
+	mu      sync.Mutex
+	tunnels map[string]*clientTunnel // by project
+}
+
+// clientTunnel is one shared project.
+type clientTunnel struct {
+	status Status // guarded by Client.mu
+	name   string // requested tunnel name
+	proxy  *httputil.ReverseProxy
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewClient creates a Client for the given config.
+func NewClient(cfg ClientConfig) (*Client, error) {
+	relay, err := url.Parse(cfg.RelayURL)
+	if err != nil {
+		return nil, fmt.Errorf("parse relay URL: %w", err)
+	}
+	if (relay.Scheme != "http" && relay.Scheme != "https") || relay.Host == "" {
+		return nil, fmt.Errorf("relay URL %q must be an http:// or https:// URL", cfg.RelayURL)
+	}
+	if cfg.Local == nil {
+		return nil, errors.New("no local address to tunnel to")
+	}
+	return &Client{
+		cfg:     cfg,
+		relay:   relay,
+		tunnels: make(map[string]*clientTunnel),
+	}, nil
+}
+
+// Name returns the tunnel name requested for a project: the project name
+// reduced to a single lowercase DNS label.
+func Name(project string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(project) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+		} else {
+			b.WriteByte('-')
+		}
+	}
+	name := b.String()
+	if len(name) > 63 {
+		name = name[:63]
+	}
+	if name = strings.Trim(name, "-"); name == "" {
+		name = "project"
+	}
+	return name
+}
+
+// Start opens a tunnel for project, whose requests are sent to Local with
+// the Host header set to domain. The first connection is made before
+// returning, so errors such as a bad token are reported directly; after
+// that the tunnel reconnects in the background until stopped. Starting a
+// project that is already shared returns its current status.
+func (c *Client) Start(ctx context.Context, project, domain string) (Status, error) {
+	c.mu.Lock()
+	if t := c.tunnels[project]; t != nil {
+		st := t.status
+		c.mu.Unlock()
+		return st, nil
+	}
+	c.mu.Unlock()
+
+	t := &clientTunnel{
+		status: Status{Project: project, Domain: domain},
+		name:   Name(project),
+		done:   make(chan struct{}),
+	}
+	t.ctx, t.cancel = context.WithCancel(context.Background())
+	t.proxy = c.newProxy(domain)
+
+	conn, dec, ready, err := c.connect(ctx, t.name)
+	if err != nil {
+		t.cancel()
+		return Status{}, err
+	}
+	t.status.URL = ready.URL
+	t.status.Connected = true
+
+	c.mu.Lock()
+	if existing := c.tunnels[project]; existing != nil {
+		// Lost a race with another Start for the same project.
+		st := existing.status
+		c.mu.Unlock()
+		t.cancel()
+		conn.Close()
+		return st, nil
+	}
+	c.tunnels[project] = t
+	st := t.status
+	c.mu.Unlock()
+
+	log.Info().Str("project", project).Str("url", ready.URL).Msg("tunnel connected")
+	c.notify(st)
+	go c.run(t, conn, dec)
+	return st, nil
+}
+
+// Stop closes the project's tunnel. It reports whether one was open.
+func (c *Client) Stop(project string) bool {
+	c.mu.Lock()
+	t := c.tunnels[project]
+	delete(c.tunnels, project)
+	c.mu.Unlock()
+	if t == nil {
+		return false
+	}
+
+	t.cancel()
+	<-t.done
+	log.Info().Str("project", project).Msg("tunnel stopped")
+	c.notify(Status{Project: project, Domain: t.status.Domain})
+	return true
+}
+
+// List returns the status of every open tunnel, sorted by project.
+func (c *Client) List() []Status {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	list := make([]Status, 0, len(c.tunnels))
+	for _, t := range c.tunnels {
+		list = append(list, t.status)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Project < list[j].Project })
+	return list
+}
+
+// Close stops all tunnels.
+func (c *Client) Close() {
+	c.mu.Lock()
+	projects := make([]string, 0, len(c.tunnels))
+	for p := range c.tunnels {
+		projects = append(projects, p)
+	}
+	c.mu.Unlock()
+	for _, p := range projects {
+		c.Stop(p)
+	}
+}
+
+func (c *Client) notify(st Status) {
+	if c.cfg.OnChange != nil {
+		c.cfg.OnChange(st)
+	}
+}
+
+// setStatus records a connection change for t and reports it.
+func (c *Client) setStatus(t *clientTunnel, url string, connected bool, err error) {
+	c.mu.Lock()
+	if c.tunnels[t.status.Project] != t {
+		c.mu.Unlock()
+		return // stopped
+	}
+	if url != "" {
+		t.status.URL = url
+	}
+	t.status.Connected = connected
+	t.status.Error = ""
+	if err != nil {
+		t.status.Error = err.Error()
+	}
+	st := t.status
+	c.mu.Unlock()
+	c.notify(st)
+}
+
+// run serves t's control connection, reconnecting with backoff whenever it
+// drops, until t is stopped.
+func (c *Client) run(t *clientTunnel, conn net.Conn, dec *json.Decoder) {
+	defer close(t.done)
+	for {
+		err := c.serve(t, conn, dec)
+		if t.ctx.Err() != nil {
+			return
+		}
+		log.Warn().Err(err).Str("project", t.status.Project).Msg("tunnel disconnected, reconnecting")
+		c.setStatus(t, "", false, err)
+
+		var ready message
+		for backoff := time.Second; ; backoff = min(backoff*2, maxBackoff) {
+			select {
+			case <-t.ctx.Done():
+				return
+			case <-time.After(backoff):
+			}
+			if conn, dec, ready, err = c.connect(t.ctx, t.name); err == nil {
+				break
+			}
+			c.setStatus(t, "", false, err)
+		}
+		log.Info().Str("project", t.status.Project).Str("url", ready.URL).Msg("tunnel reconnected")
+		c.setStatus(t, ready.URL, true, nil)
+	}
+}
+
+// serve reads control messages until the connection fails or t is stopped.
+func (c *Client) serve(t *clientTunnel, conn net.Conn, dec *json.Decoder) error {
+	defer conn.Close()
+	stop := context.AfterFunc(t.ctx, func() { conn.Close() })
+	defer stop()
+
+	for {
+		// The relay pings idle connections, so a long silence means it is gone.
+		conn.SetReadDeadline(time.Now().Add(2 * pingInterval))
+		var m message
+		if err := dec.Decode(&m); err != nil {
+			return err
+		}
+		if m.Type == msgConnect {
+			go c.serveData(t, m.ID)
+		}
+	}
+}
+
+// connect opens a control connection for the named tunnel and waits for
+// the relay's ready message.
+func (c *Client) connect(ctx context.Context, name string) (net.Conn, *json.Decoder, message, error) {
+	header := http.Header{NameHeader: {name}}
+	conn, br, err := c.dial(ctx, ControlPath, nil, header)
+	if err != nil {
+		return nil, nil, message{}, err
+	}
+
+	conn.SetReadDeadline(time.Now().Add(dialTimeout))
+	dec := json.NewDecoder(br)
+	var ready message
+	if err := dec.Decode(&ready); err != nil {
+		conn.Close()
+		return nil, nil, message{}, fmt.Errorf("waiting for relay: %w", err)
+	}
+	if ready.Type != msgReady || ready.URL == "" {
+		conn.Close()
+		return nil, nil, message{}, fmt.Errorf("unexpected relay message %q", ready.Type)
+	}
+	return conn, dec, ready, nil
+}
+
+// serveData opens the requested data connection and serves HTTP on it.
+func (c *Client) serveData(t *clientTunnel, id string) {
+	ctx, cancel := context.WithTimeout(t.ctx, dialTimeout)
+	conn, br, err := c.dial(ctx, DataPath, url.Values{"id": {id}}, nil)
+	cancel()
+	if err != nil {
+		log.Debug().Err(err).Str("project", t.status.Project).Msg("tunnel data connection failed")
+		return
+	}
+
+	l := newConnListener(wrapBuffered(conn, br))
+	srv := &http.Server{
+		Handler:           c.guard(t.status.Project, t.proxy),
+		ReadHeaderTimeout: 30 * time.Second,
+		IdleTimeout:       2 * time.Minute,
+		ConnState: func(_ net.Conn, state http.ConnState) {
+			if state == http.StateClosed || state == http.StateHijacked {
+				l.Close()
+			}
+		},
+	}
+	stop := context.AfterFunc(t.ctx, func() { srv.Close() })
+	defer stop()
+	srv.Serve(l)
+}
+
+// dial connects to the relay and upgrades an HTTP request on path to a
+// tunnel connection.
+func (c *Client) dial(ctx context.Context, path string, query url.Values, header http.Header) (net.Conn, *bufio.Reader, error) {
+	addr := c.relay.Host
+	if c.relay.Port() == "" {
+		port := "80"
+		if c.relay.Scheme == "https" {
+			port = "443"
+		}
+		addr = net.JoinHostPort(c.relay.Hostname(), port)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, dialTimeout)
+	defer cancel()
+	var conn net.Conn
+	var err error
+	if c.relay.Scheme == "https" {
+		d := &tls.Dialer{Config: &tls.Config{ServerName: c.relay.Hostname()}}
+		conn, err = d.DialContext(ctx, "tcp", addr)
+	} else {
+		var d net.Dialer
+		conn, err = d.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("connect to relay: %w", err)
+	}
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	req := &http.Request{
+		Method:     http.MethodGet,
+		URL:        &url.URL{Path: path, RawQuery: query.Encode()},
+		Host:       c.relay.Host,
+		Header:     header,
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+	}
+	if req.Header == nil {
+		req.Header = make(http.Header)
+	}
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", UpgradeProtocol)
+	if c.cfg.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.cfg.Token)
+	}
+	if err := req.Write(conn); err != nil {
+		conn.Close()
+		return nil, nil, fmt.Errorf("connect to relay: %w", err)
+	}
+
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		conn.Close()
+		return nil, nil, fmt.Errorf("connect to relay: %w", err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		conn.Close()
+		if resp.StatusCode == http.StatusUnauthorized {
+			return nil, nil, ErrUnauthorized
+		}
+		return nil, nil, fmt.Errorf("relay returned %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	if !stop() {
+		return nil, nil, ctx.Err() // closed by the context while upgrading
+	}
+	return conn, br, nil
+}
+
+// newProxy returns the handler for a tunnel's requests: a reverse proxy to
+// Local with the Host header rewritten to the project domain, keeping the
+// X-Forwarded headers set by the relay.
+func (c *Client) newProxy(domain string) *httputil.ReverseProxy {
+	transport := http.DefaultTransport
+	if c.cfg.Transport != nil {
+		transport = c.cfg.Transport(domain)
+	}
+	return &httputil.ReverseProxy{
+		Rewrite: func(pr *httputil.ProxyRequest) {
+			pr.SetURL(c.cfg.Local)
+			pr.Out.Host = domain
+			for _, h := range []string{"X-Forwarded-For", "X-Forwarded-Host", "X-Forwarded-Proto"} {
+				if v := pr.In.Header.Values(h); len(v) > 0 {
+					pr.Out.Header[h] = v
+				}
+			}
+		},
+		Transport: transport,
+		ModifyResponse: func(resp *http.Response) error {
+			// Point redirects to the project domain back at the public host.
+			loc, err := resp.Location()
+			if err != nil || !strings.EqualFold(loc.Hostname(), domain) {
+				return nil
+			}
+			public := resp.Request.Header.Get("X-Forwarded-Host")
+			if public == "" {
+				return nil
+			}
+			loc.Host = public
+			if proto := resp.Request.Header.Get("X-Forwarded-Proto"); proto != "" {
+				loc.Scheme = proto
+			}
+			resp.Header.Set("Location", loc.String())
+			return nil
+		},
+		ErrorHandler: func(w http.ResponseWriter, req *http.Request, err error) {
+			http.Error(w, "hatch: "+domain+" is unavailable: "+err.Error(), http.StatusBadGateway)
+		},
+	}
+}
+
+// guard wraps next with the Allow check for project's requests.
+func (c *Client) guard(project string, next http.Handler) http.Handler {
+	if c.cfg.Allow == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !c.cfg.Allow(project, clientIP(r)) {
+			http.Error(w, "hatch: "+project+" is not shared with this address", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// clientIP returns the visitor's address the relay appended to
+// X-Forwarded-For, or "" if there is none. Earlier entries come from the
+// visitor and cannot be trusted.
+func clientIP(r *http.Request) string {
+	values := r.Header.Values("X-Forwarded-For")
+	if len(values) == 0 {
+		return ""
+	}
+	hops := strings.Split(values[len(values)-1], ",")
+	return strings.TrimSpace(hops[len(hops)-1])
+}
+
+// connListener is a net.Listener that accepts a single connection, for
+// serving HTTP on a data connection.
+type connListener struct {
+	conn      chan net.Conn
+	addr      net.Addr
+	closeOnce sync.Once
+	closed    chan struct{}
+}
+
+func newConnListener(conn net.Conn) *connListener {
+	l := &connListener{
+		conn:   make(chan net.Conn, 1),
+		addr:   conn.LocalAddr(),
+		closed: make(chan struct{}),
+	}
+	l.conn <- conn
+	return l
+}
+
+func (l *connListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.conn:
+		return conn, nil
+	case <-l.closed:
+		return nil, net.ErrClosed
+	}
+}
+
+func (l *connListener) Close() error {
+	l.closeOnce.Do(func() { close(l.closed) })
+	return nil
+}
+
+func (l *connListener) Addr() net.Addr { return l.addr }
@@ -0,0 +1,335 @@
+package tunnel
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// dataDialTimeout bounds how long the relay waits for a client to open
+	// a requested data connection.
+	dataDialTimeout = 10 * time.Second
+	// pingInterval is how often the relay pings idle control connections,
+	// which also detects clients that vanished without closing.
+	pingInterval = 30 * time.Second
+)
+
+// validName matches a tunnel name usable as a single DNS label.
+var validName = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?$`)
+
+// RelayConfig holds the settings for a Relay.
+type RelayConfig struct {
+	// Domain is the relay's public domain; tunnels are served on its
+	// subdomains, e.g. myapp.tunnel.example.com.
+	Domain string
+	// Token, when set, must be presented by clients as a bearer token.
+	Token string
+	// Scheme and Port describe public URLs: "https" when the relay or a
+	// proxy in front of it terminates TLS. A zero Port is left out.
+	Scheme string
+	Port   int
+}
+
+// Relay is the public end of the tunnels. It is an http.Handler serving
+// both the tunnel endpoints and the tunnelled public traffic.
+type Relay struct {
+	cfg RelayConfig
+
+	mu      sync.Mutex
+	tunnels map[string]*relayTunnel  // by name
+	pending map[string]chan net.Conn // data connections awaited, by id
+}
+
+// relayTunnel is one registered tunnel and its control connection.
+type relayTunnel struct {
+	name    string
+	ctrl    net.Conn
+	writeMu sync.Mutex // serializes control messages
+	proxy   *httputil.ReverseProxy
+	done    chan struct{}
+}
+
+// NewRelay creates a Relay for the given config.
+func NewRelay(cfg RelayConfig) *Relay {
+	if cfg.Scheme == "" {
+		cfg.Scheme = "https"
+	}
+	cfg.Domain = strings.ToLower(strings.TrimSuffix(cfg.Domain, "."))
+	return &Relay{
+		cfg:     cfg,
+		tunnels: make(map[string]*relayTunnel),
+		pending: make(map[string]chan net.Conn),
+	}
+}
+
+// ServeHTTP routes requests for a tunnel's host through that tunnel and
+// everything else to the relay's own endpoints. Tunnel hosts are matched
+// first, so tunnelled apps can use any path.
+func (r *Relay) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if name, ok := r.tunnelName(req.Host); ok {
+		r.mu.Lock()
+		t := r.tunnels[name]
+		r.mu.Unlock()
+		if t == nil {
+			http.Error(w, fmt.Sprintf("no tunnel named %q", name), http.StatusNotFound)
+			return
+		}
+		t.proxy.ServeHTTP(w, req)
+		return
+	}
+
+	switch req.URL.Path {
+	case ControlPath:
+		r.handleControl(w, req)
+	case DataPath:
+		r.handleData(w, req)
+	default:
+		http.Error(w, "hatch relay", http.StatusNotFound)
+	}
+}
+
+// tunnelName returns the tunnel name if host is a subdomain of the relay
+// domain.
+func (r *Relay) tunnelName(host string) (string, bool) {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	name, ok := strings.CutSuffix(strings.ToLower(host), "."+r.cfg.Domain)
+	if !ok || strings.Contains(name, ".") {
+		return "", false
+	}
+	return name, true
+}
+
+// publicURL returns the public URL of the named tunnel.
+func (r *Relay) publicURL(name string) string {
+	host := name + "." + r.cfg.Domain
+	if r.cfg.Port != 0 {
+		host = net.JoinHostPort(host, strconv.Itoa(r.cfg.Port))
+	}
+	return r.cfg.Scheme + "://" + host
+}
+
+// authorized reports whether req carries the relay token, if one is set.
+func (r *Relay) authorized(req *http.Request) bool {
+	if r.cfg.Token == "" {
+		return true
+	}
+	got, ok := strings.CutPrefix(req.Header.Get("Authorization"), "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(got), []byte(r.cfg.Token)) == 1
+}
+
+// upgrade checks an upgrade request and hijacks its connection, writing
+// the 101 response.
+func (r *Relay) upgrade(w http.ResponseWriter, req *http.Request) (net.Conn, *bufio.Reader, bool) {
+	if !r.authorized(req) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return nil, nil, false
+	}
+	if !strings.EqualFold(req.Header.Get("Upgrade"), UpgradeProtocol) {
+		http.Error(w, "expected Upgrade: "+UpgradeProtocol, http.StatusUpgradeRequired)
+		return nil, nil, false
+	}
+	hj, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "connection cannot be upgraded", http.StatusInternalServerError)
+		return nil, nil, false
+	}
+	conn, brw, err := hj.Hijack()
+	if err != nil {
+		return nil, nil, false
+	}
+	conn.SetDeadline(time.Time{}) // clear any server timeouts
+	fmt.Fprintf(conn, "HTTP/1.1 101 Switching Protocols\r\nUpgrade: %s\r\nConnection: Upgrade\r\n\r\n", UpgradeProtocol)
+	return conn, brw.Reader, true
+}
+
+// handleControl registers a tunnel for the lifetime of the control
+// connection.
+func (r *Relay) handleControl(w http.ResponseWriter, req *http.Request) {
+	if !r.authorized(req) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	requested := strings.ToLower(req.Header.Get(NameHeader))
+	if !validName.MatchString(requested) {
+		http.Error(w, fmt.Sprintf("invalid tunnel name %q", requested), http.StatusBadRequest)
+		return
+	}
+
+	conn, br, ok := r.upgrade(w, req)
+	if !ok {
+		return
+	}
+
+	t := &relayTunnel{ctrl: conn, done: make(chan struct{})}
+	t.proxy = &httputil.ReverseProxy{
+		Rewrite: func(pr *httputil.ProxyRequest) {
+			pr.Out.URL.Scheme = "http"
+			pr.Out.URL.Host = t.name // ignored by the dialer
+			pr.Out.Host = pr.In.Host
+			pr.SetXForwarded()
+		},
+		Transport: &http.Transport{
+			DialContext:         func(ctx context.Context, _, _ string) (net.Conn, error) { return r.dialData(ctx, t) },
+			MaxIdleConnsPerHost: 16,
+			IdleConnTimeout:     90 * time.Second,
+		},
+		ErrorHandler: func(w http.ResponseWriter, req *http.Request, err error) {
+			http.Error(w, "tunnel unavailable: "+err.Error(), http.StatusBadGateway)
+		},
+	}
+	t.name = r.register(requested, t)
+	defer r.unregister(t)
+
+	log.Info().Str("tunnel", t.name).Str("remote", req.RemoteAddr).Msg("tunnel registered")
+	if err := t.send(message{Type: msgReady, Name: t.name, URL: r.publicURL(t.name)}); err != nil {
+		return
+	}
+
+	// Ping until the client goes away; it sends nothing, so any read
+	// result ends the tunnel.
+	go func() {
+		ticker := time.NewTicker(pingInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-t.done:
+				return
+			case <-ticker.C:
+				if err := t.send(message{Type: msgPing}); err != nil {
+					conn.Close()
+					return
+				}
+			}
+		}
+	}()
+	br.ReadByte()
+	log.Info().Str("tunnel", t.name).Msg("tunnel closed")
+}
+
+// register adds t under name, or under name plus a random suffix if name
+// is taken, and returns the name used.
+func (r *Relay) register(name string, t *relayTunnel) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for r.tunnels[name] != nil {
+		name = strings.TrimRight(name[:min(len(name), 56)], "-") + "-" + randomID(3)
+	}
+	r.tunnels[name] = t
+	return name
+}
+
+func (r *Relay) unregister(t *relayTunnel) {
+	r.mu.Lock()
+	if r.tunnels[t.name] == t {
+		delete(r.tunnels, t.name)
+	}
+	r.mu.Unlock()
+	close(t.done)
+	t.ctrl.Close()
+	t.proxy.Transport.(*http.Transport).CloseIdleConnections()
+}
+
+// send writes a control message to the tunnel's client.
+func (t *relayTunnel) send(m message) error {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	t.writeMu.Lock()
+	defer t.writeMu.Unlock()
+	t.ctrl.SetWriteDeadline(time.Now().Add(10 * time.Second))
+	_, err = t.ctrl.Write(append(data, '\n'))
+	return err
+}
+
+// dialData asks t's client for a new data connection and waits for it.
+func (r *Relay) dialData(ctx context.Context, t *relayTunnel) (net.Conn, error) {
+	id := randomID(16)
+	ch := make(chan net.Conn, 1)
+	r.mu.Lock()
+	r.pending[id] = ch
+	r.mu.Unlock()
+	defer func() {
+		r.mu.Lock()
+		delete(r.pending, id)
+		r.mu.Unlock()
+		// A connection handed over after the wait gave up is never used.
+		select {
+		case conn := <-ch:
+			conn.Close()
+		default:
+		}
+	}()
+
+	if err := t.send(message{Type: msgConnect, ID: id}); err != nil {
+		return nil, fmt.Errorf("requesting data connection: %w", err)
+	}
+
+	timer := time.NewTimer(dataDialTimeout)
+	defer timer.Stop()
+	select {
+	case conn := <-ch:
+		return conn, nil
+	case <-t.done:
+		return nil, errors.New("tunnel closed")
+	case <-timer.C:
+		return nil, errors.New("client did not open a data connection in time")
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// handleData hands an upgraded data connection to the dial waiting for it.
+func (r *Relay) handleData(w http.ResponseWriter, req *http.Request) {
+	id := req.URL.Query().Get("id")
+	r.mu.Lock()
+	ch := r.pending[id]
+	r.mu.Unlock()
+	if ch == nil {
+		http.Error(w, "unknown data connection", http.StatusNotFound)
+		return
+	}
+
+	conn, br, ok := r.upgrade(w, req)
+	if !ok {
+		return
+	}
+	// Hand over only while the dial still waits; it drains ch once it
+	// stops, so nothing may be sent after it has left pending.
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.pending[id] != ch {
+		conn.Close()
+		return
+	}
+	select {
+	case ch <- wrapBuffered(conn, br):
+	default:
+		conn.Close() // already served
+	}
+}
+
+// randomID returns n random bytes, hex encoded.
+func randomID(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,63 @@
+// Package tunnel exposes local projects outside the machine through a
+// self-hostable relay ("hatch relay").
+//
+// A Client in the daemon opens a control connection to the Relay and
+// registers a tunnel name. The relay serves <name>.<relay domain>
+// publicly: for each connection its reverse proxy needs, it asks the
+// client over the control connection to open a data connection, and the
+// client serves HTTP on that connection by proxying to local Caddy with
+// the Host header rewritten to the project domain. Both connection kinds
+// are plain HTTP upgrades, so the relay can sit behind any TLS terminator.
+package tunnel
+
+import (
+	"bufio"
+	"net"
+)
+
+const (
+	// ControlPath is where clients open their control connection.
+	ControlPath = "/_hatch/tunnel"
+	// DataPath is where clients open a data connection on request.
+	DataPath = "/_hatch/tunnel/data"
+
+	// UpgradeProtocol is the Upgrade header value for both connection kinds.
+	UpgradeProtocol = "hatch-tunnel"
+
+	// NameHeader carries the requested tunnel name on a control request.
+	NameHeader = "X-Hatch-Tunnel"
+)
+
+// message is a newline-delimited JSON message sent by the relay on a
+// control connection.
+type message struct {
+	Type string `json:"type"`           // msgReady, msgConnect or msgPing
+	Name string `json:"name,omitempty"` // msgReady: the assigned tunnel name
+	URL  string `json:"url,omitempty"`  // msgReady: the public URL
+	ID   string `json:"id,omitempty"`   // msgConnect: the data connection to open
+}
+
+const (
+	msgReady   = "ready"
+	msgConnect = "connect"
+	msgPing    = "ping"
+)
+
+// bufferedConn is a net.Conn whose reads drain a bufio.Reader first, for
+// connections where bytes past an HTTP upgrade may already be buffered.
+type bufferedConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func (c *bufferedConn) Read(p []byte) (int, error) {
+	return c.r.Read(p)
+}
+
+// wrapBuffered returns conn, reading through r if it holds buffered data.
+func wrapBuffered(conn net.Conn, r *bufio.Reader) net.Conn {
+	if r == nil || r.Buffered() == 0 {
+		return conn
+	}
+	return &bufferedConn{Conn: conn, r: r}
+}
@@ -0,0 +1,236 @@
+package tunnel
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/paulrose/hatch/internal/config"
+)
+
+const testToken = "s3cret"
+
+// startRelay starts a relay serving *.relay.test over plain HTTP.
+func startRelay(t *testing.T) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(NewRelay(RelayConfig{Domain: "relay.test", Token: testToken, Scheme: "http"}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// startBackend starts a local server standing in for Caddy, echoing what
+// it received.
+func startBackend(t *testing.T) *url.URL {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/redirect" {
+			http.Redirect(w, r, "https://"+r.Host+"/login", http.StatusFound)
+			return
+		}
+		fmt.Fprintf(w, "host=%s path=%s fwd=%s", r.Host, r.URL.Path, r.Header.Get("X-Forwarded-Host"))
+	}))
+	t.Cleanup(srv.Close)
+	u, _ := url.Parse(srv.URL)
+	return u
+}
+
+func newTestClient(t *testing.T, relay *httptest.Server, token string, onChange func(Status)) *Client {
+	t.Helper()
+	c, err := NewClient(ClientConfig{
+		RelayURL: relay.URL,
+		Token:    token,
+		Local:    startBackend(t),
+		OnChange: onChange,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(c.Close)
+	return c
+}
+
+// get requests path on the relay as if sent to host.
+func get(t *testing.T, relay *httptest.Server, host, path string) (int, string, http.Header) {
+	t.Helper()
+	req, _ := http.NewRequest(http.MethodGet, relay.URL+path, nil)
+	req.Host = host
+	client := &http.Client{
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(body), resp.Header
+}
+
+func TestTunnel_EndToEnd(t *testing.T) {
+	relay := startRelay(t)
+	var mu sync.Mutex
+	var changes []Status
+	c := newTestClient(t, relay, testToken, func(st Status) {
+		mu.Lock()
+		changes = append(changes, st)
+		mu.Unlock()
+	})
+
+	st, err := c.Start(context.Background(), "MyApp", "myapp.test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.URL != "http://myapp.relay.test" || !st.Connected {
+		t.Fatalf("unexpected status %+v", st)
+	}
+
+	for i := range 3 { // later requests reuse data connections
+		code, body, _ := get(t, relay, "myapp.relay.test", fmt.Sprintf("/page/%d", i))
+		want := fmt.Sprintf("host=myapp.test path=/page/%d fwd=myapp.relay.test", i)
+		if code != http.StatusOK || body != want {
+			t.Fatalf("request %d: got %d %q, want %q", i, code, body, want)
+		}
+	}
+
+	_, _, header := get(t, relay, "myapp.relay.test", "/redirect")
+	if loc := header.Get("Location"); loc != "http://myapp.relay.test/login" {
+		t.Errorf("expected redirect to the public host, got %q", loc)
+	}
+
+	if list := c.List(); len(list) != 1 || list[0].Project != "MyApp" {
+		t.Errorf("unexpected tunnel list %+v", list)
+	}
+
+	if !c.Stop("MyApp") {
+		t.Fatal("expected Stop to report an open tunnel")
+	}
+	if c.Stop("MyApp") {
+		t.Error("expected a second Stop to report no tunnel")
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		code, _, _ := get(t, relay, "myapp.relay.test", "/")
+		if code == http.StatusNotFound {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected 404 after stop, got %d", code)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(changes) != 2 || !changes[0].Connected || changes[1].Connected {
+		t.Errorf("expected connected then stopped changes, got %+v", changes)
+	}
+}
+
+func TestTunnel_AllowIPs(t *testing.T) {
+	tests := []struct {
+		name     string
+		allowIPs []string
+		want     int
+	}{
+		// The relay sees the test's requests come from loopback, which a
+		// project's allow_ips only covers when listed explicitly.
+		{"not covered", []string{"192.168.1.0/24"}, http.StatusForbidden},
+		{"covered", []string{"192.168.1.0/24", "127.0.0.1"}, http.StatusOK},
+		{"no allowlist", nil, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			relay := startRelay(t)
+			auth := &config.Auth{AllowIPs: tt.allowIPs}
+			c, err := NewClient(ClientConfig{
+				RelayURL: relay.URL,
+				Token:    testToken,
+				Local:    startBackend(t),
+				Allow: func(project, clientIP string) bool {
+					return project == "web" && auth.Allows(clientIP)
+				},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer c.Close()
+			if _, err := c.Start(context.Background(), "web", "web.test"); err != nil {
+				t.Fatal(err)
+			}
+
+			if code, body, _ := get(t, relay, "web.relay.test", "/"); code != tt.want {
+				t.Errorf("expected %d, got %d %q", tt.want, code, body)
+			}
+		})
+	}
+}
+
+func TestTunnel_WrongToken(t *testing.T) {
+	relay := startRelay(t)
+	c := newTestClient(t, relay, "wrong", nil)
+
+	if _, err := c.Start(context.Background(), "myapp", "myapp.test"); !errors.Is(err, ErrUnauthorized) {
+		t.Fatalf("expected ErrUnauthorized, got %v", err)
+	}
+	if len(c.List()) != 0 {
+		t.Error("expected no tunnel after a failed start")
+	}
+}
+
+func TestTunnel_NameTaken(t *testing.T) {
+	relay := startRelay(t)
+	first := newTestClient(t, relay, testToken, nil)
+	second := newTestClient(t, relay, testToken, nil)
+
+	a, err := first.Start(context.Background(), "web", "web.test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := second.Start(context.Background(), "web", "web.test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.URL != "http://web.relay.test" {
+		t.Errorf("unexpected first URL %q", a.URL)
+	}
+	if b.URL == a.URL || !strings.HasPrefix(b.URL, "http://web-") {
+		t.Errorf("expected a suffixed second URL, got %q", b.URL)
+	}
+}
+
+func TestRelay_UnknownHost(t *testing.T) {
+	relay := startRelay(t)
+
+	if code, _, _ := get(t, relay, "nope.relay.test", "/"); code != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown tunnel, got %d", code)
+	}
+	if code, _, _ := get(t, relay, "elsewhere.example", "/"); code != http.StatusNotFound {
+		t.Errorf("expected 404 for a foreign host, got %d", code)
+	}
+	if code, _, _ := get(t, relay, "relay.test", ControlPath); code != http.StatusUnauthorized {
+		t.Errorf("expected 401 for a control request without a token, got %d", code)
+	}
+}
+
+func TestName(t *testing.T) {
+	tests := map[string]string{
+		"web":                   "web",
+		"My_App":                "my-app",
+		"--api--":               "api",
+		"!!!":                   "project",
+		strings.Repeat("a", 70): strings.Repeat("a", 63),
+	}
+	for in, want := range tests {
+		if got := Name(in); got != want {
+			t.Errorf("Name(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package webhook
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	caddyv2 "github.com/caddyserver/caddy/v2"
+	"github.com/caddyserver/caddy/v2/modules/caddyhttp"
+
+	"github.com/paulrose/hatch/internal/errorpage"
+	"github.com/paulrose/hatch/internal/health"
+)
+
+func init() {
+	caddyv2.RegisterModule(Buffer{})
+}
+
+// active is the manager buffer handlers hold requests with.
+var active atomic.Pointer[Manager]
+
+// SetManager installs the manager buffer handlers hold requests with.
+// With no manager set, they pass requests through untouched.
+func SetManager(m *Manager) {
+	active.Store(m)
+}
+
+// Buffer is the hatch_buffer Caddy handler. While its service is being
+// held it stores requests with the manager instead of passing them on.
+type Buffer struct {
+	Project string `json:"project,omitempty"`
+	Service string `json:"service,omitempty"`
+}
+
+// CaddyModule returns the Caddy module information.
+func (Buffer) CaddyModule() caddyv2.ModuleInfo {
+	return caddyv2.ModuleInfo{
+		ID:  "http.handlers.hatch_buffer",
+		New: func() caddyv2.Module { return new(Buffer) },
+	}
+}
+
+// ServeHTTP holds the request if the service is down, otherwise passes it
+// to next.
+func (b *Buffer) ServeHTTP(w http.ResponseWriter, r *http.Request, next caddyhttp.Handler) error {
+	m := active.Load()
+	if m == nil || r.Header.Get(errorpage.ProbeHeader) != "" {
+		return next.ServeHTTP(w, r)
+	}
+	if token := r.Header.Get(FlushHeader); token != "" {
+		r.Header.Del(FlushHeader)
+		if token == m.token {
+			return next.ServeHTTP(w, r)
+		}
+	}
+	key := health.ServiceKey{Project: b.Project, Service: b.Service}
+	if !m.Holding(key) {
+		return next.ServeHTTP(w, r)
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, DefaultMaxBody))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return caddyhttp.Error(http.StatusRequestEntityTooLarge, fmt.Errorf("request body too large to hold while %s/%s is down", b.Project, b.Service))
+		}
+		return caddyhttp.Error(http.StatusBadRequest, err)
+	}
+
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	held, err := m.Enqueue(key, Request{
+		Time:       time.Now(),
+		Method:     r.Method,
+		URL:        scheme + "://" + r.Host + r.URL.RequestURI(),
+		Header:     r.Header.Clone(),
+		Body:       body,
+		RemoteAddr: r.RemoteAddr,
+	})
+	switch {
+	case errors.Is(err, ErrFull):
+		w.Header().Set("Retry-After", "30")
+		return caddyhttp.Error(http.StatusServiceUnavailable, err)
+	case err != nil:
+		return caddyhttp.Error(http.StatusInternalServerError, err)
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("X-Hatch-Queued", strconv.FormatUint(held.ID, 10))
+	w.WriteHeader(http.StatusAccepted)
+	fmt.Fprintf(w, "%s/%s is down; hatch is holding this request and will deliver it when the service recovers\n", b.Project, b.Service)
+	return nil
+}
+
+// Interface guard.
+var _ caddyhttp.MiddlewareHandler = (*Buffer)(nil)
@@ -0,0 +1,64 @@
+package webhook
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/caddyserver/caddy/v2/modules/caddyhttp"
+
+	"github.com/paulrose/hatch/internal/health"
+)
+
+func TestBuffer_HoldsWhileDown(t *testing.T) {
+	m := NewManager(ManagerConfig{Dir: t.TempDir()})
+	SetManager(m)
+	t.Cleanup(func() { SetManager(nil) })
+
+	passed := 0
+	next := caddyhttp.HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+		passed++
+		return nil
+	})
+	serve := func(req *http.Request) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		if err := (&Buffer{Project: "web", Service: "hooks"}).ServeHTTP(w, req, next); err != nil {
+			t.Fatal(err)
+		}
+		return w
+	}
+
+	serve(httptest.NewRequest("POST", "https://web.test/hook", strings.NewReader("a")))
+	if passed != 1 {
+		t.Fatal("expected the request to pass through while the service is up")
+	}
+
+	m.SetStatus(hooks, health.StatusUnhealthy)
+	req := httptest.NewRequest("POST", "https://web.test/hook?x=1", strings.NewReader("payload"))
+	req.Header.Set("X-Event", "push")
+	w := serve(req)
+	if passed != 1 || w.Code != http.StatusAccepted || w.Header().Get("X-Hatch-Queued") != "1" {
+		t.Fatalf("expected the request to be held, got %d %v", w.Code, w.Header())
+	}
+	held, err := readRequest(m.queue(hooks).path(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if held.Method != "POST" || held.URL != "https://web.test/hook?x=1" || string(held.Body) != "payload" || held.Header.Get("X-Event") != "push" {
+		t.Errorf("unexpected held request %+v", held)
+	}
+
+	flush := httptest.NewRequest("POST", "https://web.test/hook", nil)
+	flush.Header.Set(FlushHeader, m.token)
+	serve(flush)
+	if passed != 2 || flush.Header.Get(FlushHeader) != "" {
+		t.Error("expected a flushed request to pass through with the token removed")
+	}
+
+	forged := httptest.NewRequest("POST", "https://web.test/hook", nil)
+	forged.Header.Set(FlushHeader, "guess")
+	if w := serve(forged); w.Code != http.StatusAccepted || passed != 2 {
+		t.Error("expected a request with a wrong token to be held")
+	}
+}
@@ -0,0 +1,317 @@
+// Package webhook holds requests for services that are down and delivers
+// them once the services recover.
+//
+// Services with buffer_when_down get a hatch_buffer handler at the front
+// of their Caddy routes. While the health checker reports the service
+// unhealthy, or earlier requests are still waiting, the handler stores
+// each request on disk and answers 202 Accepted. When the service turns
+// healthy again the Manager re-sends the stored requests through Caddy,
+// oldest first.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/paulrose/hatch/internal/health"
+)
+
+const (
+	// DefaultMaxPending is how many requests are held per service.
+	DefaultMaxPending = 1000
+	// DefaultMaxBody is the largest request body that is held.
+	DefaultMaxBody = 1 << 20
+
+	// FlushHeader carries the Manager's token on re-sent requests, so the
+	// handler lets them through instead of holding them again.
+	FlushHeader = "X-Hatch-Flush"
+
+	// retryDelay is how long a flush waits after the upstream fails again.
+	retryDelay = 5 * time.Second
+)
+
+// ErrFull is returned by Enqueue when a service already holds the maximum
+// number of requests.
+var ErrFull = errors.New("webhook queue is full")
+
+// Request is a held request as stored on disk.
+type Request struct {
+	ID         uint64      `json:"id"`
+	Time       time.Time   `json:"time"`
+	Method     string      `json:"method"`
+	URL        string      `json:"url"`
+	Header     http.Header `json:"header"`
+	Body       []byte      `json:"body,omitempty"`
+	RemoteAddr string      `json:"remote_addr"`
+}
+
+// ManagerConfig configures a Manager.
+type ManagerConfig struct {
+	Dir        string                       // directory holding one subdirectory per service
+	MaxPending int                          // requests held per service; 0 for DefaultMaxPending
+	Client     func() (*http.Client, error) // returns the client flushed requests are sent with
+}
+
+// Manager holds requests per service and flushes them when the service
+// recovers.
+type Manager struct {
+	cfg    ManagerConfig
+	token  string
+	mu     sync.Mutex
+	queues map[health.ServiceKey]*queue
+}
+
+// queue is the state of one service, guarded by Manager.mu.
+type queue struct {
+	dir      string
+	ids      []uint64 // held requests, oldest first
+	lastID   uint64
+	down     bool
+	flushing bool
+}
+
+// NewManager returns a Manager storing requests under cfg.Dir.
+func NewManager(cfg ManagerConfig) *Manager {
+	if cfg.MaxPending <= 0 {
+		cfg.MaxPending = DefaultMaxPending
+	}
+	b := make([]byte, 16)
+	rand.Read(b)
+	return &Manager{
+		cfg:    cfg,
+		token:  hex.EncodeToString(b),
+		queues: make(map[health.ServiceKey]*queue),
+	}
+}
+
+// queue returns the state for key, loading held requests from disk on
+// first use. Must be called with m.mu held.
+func (m *Manager) queue(key health.ServiceKey) *queue {
+	if q, ok := m.queues[key]; ok {
+		return q
+	}
+	q := &queue{dir: filepath.Join(m.cfg.Dir, url.PathEscape(key.Project), url.PathEscape(key.Service))}
+	entries, err := os.ReadDir(q.dir)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		log.Error().Err(err).Str("dir", q.dir).Msg("webhook: cannot read queue")
+	}
+	for _, e := range entries {
+		id, err := strconv.ParseUint(strings.TrimSuffix(e.Name(), ".json"), 10, 64)
+		if err != nil || !strings.HasSuffix(e.Name(), ".json") {
+			continue
+		}
+		q.ids = append(q.ids, id)
+	}
+	sort.Slice(q.ids, func(i, j int) bool { return q.ids[i] < q.ids[j] })
+	if len(q.ids) > 0 {
+		q.lastID = q.ids[len(q.ids)-1]
+	}
+	m.queues[key] = q
+	return q
+}
+
+func (q *queue) path(id uint64) string {
+	return filepath.Join(q.dir, fmt.Sprintf("%020d.json", id))
+}
+
+// Holding reports whether requests for key are being held: the service is
+// down, or earlier requests have not been delivered yet.
+func (m *Manager) Holding(key health.ServiceKey) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	q := m.queue(key)
+	return q.down || q.flushing || len(q.ids) > 0
+}
+
+// Pending returns the number of requests held for key.
+func (m *Manager) Pending(key health.ServiceKey) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.queue(key).ids)
+}
+
+// Enqueue stores req for key and returns it with its assigned ID.
+func (m *Manager) Enqueue(key health.ServiceKey, req Request) (Request, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	q := m.queue(key)
+	if len(q.ids) >= m.cfg.MaxPending {
+		return Request{}, ErrFull
+	}
+	req.ID = q.lastID + 1
+
+	data, err := json.Marshal(req)
+	if err != nil {
+		return Request{}, fmt.Errorf("encode request: %w", err)
+	}
+	if err := os.MkdirAll(q.dir, 0o700); err != nil {
+		return Request{}, fmt.Errorf("create queue dir: %w", err)
+	}
+	tmp := q.path(req.ID) + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return Request{}, fmt.Errorf("write request: %w", err)
+	}
+	if err := os.Rename(tmp, q.path(req.ID)); err != nil {
+		os.Remove(tmp)
+		return Request{}, fmt.Errorf("write request: %w", err)
+	}
+	q.lastID = req.ID
+	q.ids = append(q.ids, req.ID)
+	return req, nil
+}
+
+// SetStatus records a health transition of key. While unhealthy, requests
+// are held; on becoming healthy, held requests are flushed.
+func (m *Manager) SetStatus(key health.ServiceKey, status health.Status) {
+	m.mu.Lock()
+	q := m.queue(key)
+	q.down = status == health.StatusUnhealthy
+	start := status == health.StatusHealthy && !q.flushing && len(q.ids) > 0
+	if start {
+		q.flushing = true
+	}
+	m.mu.Unlock()
+
+	if start {
+		go m.flush(key)
+	}
+}
+
+// retry restarts an interrupted flush of key unless the service has gone
+// down since, in which case the next healthy transition restarts it.
+func (m *Manager) retry(key health.ServiceKey) {
+	m.mu.Lock()
+	q := m.queue(key)
+	start := !q.down && !q.flushing && len(q.ids) > 0
+	if start {
+		q.flushing = true
+	}
+	m.mu.Unlock()
+
+	if start {
+		m.flush(key)
+	}
+}
+
+// flush delivers the requests held for key in order. Requests that reach
+// the service are removed whatever its response; if the service cannot be
+// reached, the flush stops and is retried later.
+func (m *Manager) flush(key health.ServiceKey) {
+	logger := log.With().Str("project", key.Project).Str("service", key.Service).Logger()
+	client, err := m.cfg.Client()
+	if err != nil {
+		logger.Error().Err(err).Msg("webhook: cannot flush queue")
+		m.pause(key)
+		return
+	}
+	defer client.CloseIdleConnections()
+
+	delivered := 0
+	for {
+		m.mu.Lock()
+		q := m.queue(key)
+		if q.down || len(q.ids) == 0 {
+			q.flushing = false
+			m.mu.Unlock()
+			break
+		}
+		id, path := q.ids[0], q.path(q.ids[0])
+		m.mu.Unlock()
+
+		req, err := readRequest(path)
+		if err != nil {
+			logger.Error().Err(err).Uint64("id", id).Msg("webhook: dropping unreadable request")
+			m.remove(key, id)
+			continue
+		}
+		status, err := m.send(client, req)
+		if err != nil {
+			logger.Warn().Err(err).Uint64("id", id).Msg("webhook: delivery failed, will retry")
+			m.pause(key)
+			return
+		}
+		logger.Info().Uint64("id", id).Str("method", req.Method).Str("url", req.URL).Int("status", status).Msg("webhook: delivered held request")
+		m.remove(key, id)
+		delivered++
+	}
+	if delivered > 0 {
+		logger.Info().Int("count", delivered).Msg("webhook: queue flushed")
+	}
+}
+
+// pause stops the flush of key and schedules a retry.
+func (m *Manager) pause(key health.ServiceKey) {
+	m.mu.Lock()
+	m.queue(key).flushing = false
+	m.mu.Unlock()
+	time.AfterFunc(retryDelay, func() { m.retry(key) })
+}
+
+func (m *Manager) remove(key health.ServiceKey, id uint64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	q := m.queue(key)
+	if err := os.Remove(q.path(id)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		log.Error().Err(err).Msg("webhook: cannot remove delivered request")
+	}
+	if len(q.ids) > 0 && q.ids[0] == id {
+		q.ids = q.ids[1:]
+	}
+}
+
+// send re-sends req and returns the response status. Gateway errors,
+// which mean the service could not be reached, are returned as errors.
+func (m *Manager) send(client *http.Client, r Request) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, r.Method, r.URL, bytes.NewReader(r.Body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header = r.Header.Clone()
+	if req.Header == nil {
+		req.Header = http.Header{}
+	}
+	req.Header.Set(FlushHeader, m.token)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return resp.StatusCode, fmt.Errorf("upstream answered %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+func readRequest(path string) (Request, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Request{}, err
+	}
+	var req Request
+	if err := json.Unmarshal(data, &req); err != nil {
+		return Request{}, fmt.Errorf("decode %s: %w", filepath.Base(path), err)
+	}
+	return req, nil
+}
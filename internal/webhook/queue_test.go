@@ -0,0 +1,134 @@
+package webhook
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/paulrose/hatch/internal/health"
+)
+
+var hooks = health.ServiceKey{Project: "web", Service: "hooks"}
+
+// upstream records the bodies it receives and answers with status.
+type upstream struct {
+	*httptest.Server
+	mu     sync.Mutex
+	bodies []string
+	status int
+	token  string
+}
+
+func newUpstream(t *testing.T) *upstream {
+	u := &upstream{status: http.StatusOK}
+	u.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		u.mu.Lock()
+		defer u.mu.Unlock()
+		u.token = r.Header.Get(FlushHeader)
+		if u.status == http.StatusOK {
+			u.bodies = append(u.bodies, string(b))
+		}
+		w.WriteHeader(u.status)
+	}))
+	t.Cleanup(u.Close)
+	return u
+}
+
+func (u *upstream) received() []string {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return append([]string(nil), u.bodies...)
+}
+
+func newTestManager(t *testing.T, dir string, u *upstream) *Manager {
+	return NewManager(ManagerConfig{
+		Dir:        dir,
+		MaxPending: 3,
+		Client:     func() (*http.Client, error) { return u.Client(), nil },
+	})
+}
+
+// waitFlushed waits for the manager to stop flushing key.
+func waitFlushed(t *testing.T, m *Manager, key health.ServiceKey) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		m.mu.Lock()
+		flushing := m.queue(key).flushing
+		m.mu.Unlock()
+		if !flushing {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("flush did not finish")
+}
+
+func TestManager_HoldsAndFlushesInOrder(t *testing.T) {
+	u := newUpstream(t)
+	dir := t.TempDir()
+	m := newTestManager(t, dir, u)
+
+	if m.Holding(hooks) {
+		t.Fatal("expected no holding before the service goes down")
+	}
+	m.SetStatus(hooks, health.StatusUnhealthy)
+	if !m.Holding(hooks) {
+		t.Fatal("expected holding while the service is down")
+	}
+	for _, body := range []string{"one", "two", "three"} {
+		if _, err := m.Enqueue(hooks, Request{Method: "POST", URL: u.URL + "/hook", Body: []byte(body)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := m.Enqueue(hooks, Request{Method: "POST", URL: u.URL}); err != ErrFull {
+		t.Fatalf("expected ErrFull, got %v", err)
+	}
+
+	// A new manager, as after a daemon restart, picks up the held requests.
+	m = newTestManager(t, dir, u)
+	if got := m.Pending(hooks); got != 3 {
+		t.Fatalf("expected 3 pending after reload, got %d", got)
+	}
+	if !m.Holding(hooks) {
+		t.Error("expected holding while requests are pending")
+	}
+
+	m.SetStatus(hooks, health.StatusHealthy)
+	waitFlushed(t, m, hooks)
+	got := u.received()
+	if len(got) != 3 || got[0] != "one" || got[1] != "two" || got[2] != "three" {
+		t.Errorf("expected requests delivered in order, got %v", got)
+	}
+	if u.token != m.token {
+		t.Error("expected flushed requests to carry the manager's token")
+	}
+	if m.Pending(hooks) != 0 || m.Holding(hooks) {
+		t.Error("expected the queue to be empty and released")
+	}
+}
+
+func TestManager_FlushStopsOnGatewayError(t *testing.T) {
+	u := newUpstream(t)
+	u.status = http.StatusBadGateway
+	m := newTestManager(t, t.TempDir(), u)
+	t.Cleanup(func() { m.SetStatus(hooks, health.StatusUnhealthy) }) // no retries after the test
+
+	m.SetStatus(hooks, health.StatusUnhealthy)
+	if _, err := m.Enqueue(hooks, Request{Method: "POST", URL: u.URL, Body: []byte("one")}); err != nil {
+		t.Fatal(err)
+	}
+	m.SetStatus(hooks, health.StatusHealthy)
+	waitFlushed(t, m, hooks)
+
+	if m.Pending(hooks) != 1 {
+		t.Errorf("expected the request to stay queued, got %d pending", m.Pending(hooks))
+	}
+	if !m.Holding(hooks) {
+		t.Error("expected holding to continue until the request is delivered")
+	}
+}